@@ -0,0 +1,63 @@
+package sqlair
+
+import (
+	"reflect"
+
+	"github.com/canonical/sqlair/internal/parse"
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// Aliased is an object that statements refer to by an alias,
+// rather than by the name of its type. It is returned by Alias.
+type Aliased struct {
+	name  string
+	value any
+}
+
+// Alias returns the input object under the input name, by which DSL
+// statements refer to it in place of the name of its type. This allows a
+// type to be used under more than one name in the same statement, such as
+// in a self-join, without declaring new types.
+// The same alias must be used for the object when preparing the statement
+// and for the inputs and outputs when executing it.
+//
+// Example:
+//
+//     stmt, err := sqlair.Prepare(`
+//     SELECT p.* AS &Person.*,
+//            m.* AS &Manager.*
+//       FROM person AS p
+//       JOIN person AS m
+//         ON p.manager_id = m.id`, Person{}, sqlair.Alias("Manager", Person{}))
+//
+//     err = db.Query(ctx, stmt).Get(&p, sqlair.Alias("Manager", &m))
+//
+func Alias(name string, value any) Aliased {
+	return Aliased{name: name, value: value}
+}
+
+// objectName returns the name by which statements refer to the input
+// object, along with the reflected value of the object. An alias is
+// unwrapped and its name returned in place of the name of the type.
+func objectName(obj any) (string, reflect.Value) {
+	if a, ok := obj.(Aliased); ok {
+		return a.name, reflect.ValueOf(a.value)
+	}
+
+	v := reflect.ValueOf(obj)
+	if !v.IsValid() {
+		return "", v
+	}
+	return sqlairreflect.TypeName(reflect.Indirect(v).Type()), v
+}
+
+// validateAlias returns an error if the input alias
+// could not be used as a type name in a DSL statement.
+func validateAlias(name string) error {
+	tokens, err := parse.Tokens(name)
+	if err != nil || len(tokens) != 1 || !isNameToken(tokens[0]) {
+		return errors.Errorf("alias %q is not a valid type name", name)
+	}
+	return nil
+}
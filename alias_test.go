@@ -0,0 +1,86 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Employee struct {
+	ID        string `db:"id"`
+	Name      string `db:"name"`
+	ManagerID string `db:"manager_id"`
+}
+
+func setupEmployeeDB(t *testing.T) *sql.DB {
+	db := setupDB(t)
+	db.SetMaxOpenConns(1)
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE employee (id TEXT, name TEXT, manager_id TEXT)"); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO employee VALUES ('1', 'Lorn', ''), ('2', 'Onos', '1'), ('3', 'Kruppe', '1')`)
+		return err
+	})
+
+	return db
+}
+
+func TestAliasSelfJoin(t *testing.T) {
+	stmt, err := Prepare(`
+SELECT e.* AS &Employee.*, m.* AS &Manager.*
+  FROM employee AS e
+  JOIN employee AS m ON e.manager_id = m.id
+ WHERE e.id = $Employee.id AND m.name = $Manager.name
+ ORDER BY e.id`, Employee{}, Alias("Manager", Employee{}))
+	assert.Nil(t, err)
+
+	plan := stmt.BindingPlan()
+	assert.Equal(t, "Manager", plan.Inputs[1].TypeName)
+	assert.Equal(t, "Manager", plan.Outputs[3].TypeName)
+
+	db := NewDB(setupEmployeeDB(t))
+
+	var e, m Employee
+	err = db.Query(context.Background(), stmt, Employee{ID: "2"}, Alias("Manager", Employee{Name: "Lorn"})).
+		Get(&e, Alias("Manager", &m))
+	assert.Nil(t, err)
+	assert.Equal(t, Employee{ID: "2", Name: "Onos", ManagerID: "1"}, e)
+	assert.Equal(t, Employee{ID: "1", Name: "Lorn"}, m)
+}
+
+func TestAliasGetAll(t *testing.T) {
+	stmt, err := Prepare(`
+SELECT e.* AS &Employee.*, m.* AS &Manager.*
+  FROM employee AS e
+  JOIN employee AS m ON e.manager_id = m.id
+ ORDER BY e.id`, Employee{}, Alias("Manager", Employee{}))
+	assert.Nil(t, err)
+
+	var employees, managers []Employee
+	err = NewDB(setupEmployeeDB(t)).Query(context.Background(), stmt).GetAll(&employees, Alias("Manager", &managers))
+	assert.Nil(t, err)
+
+	assert.Len(t, employees, 2)
+	assert.Equal(t, "Kruppe", employees[1].Name)
+	assert.Equal(t, []string{"Lorn", "Lorn"}, []string{managers[0].Name, managers[1].Name})
+}
+
+func TestAliasErrors(t *testing.T) {
+	_, err := Prepare("SELECT &Manager.* FROM employee", Alias("Manager.x", Employee{}))
+	assert.EqualError(t, err, `alias "Manager.x" is not a valid type name`)
+
+	_, err = Prepare("SELECT &Employee.* FROM employee", Employee{}, Alias("Employee", Employee{}))
+	assert.Equal(t, NewErrTypeNameNotUnique("Employee"), err)
+
+	stmt, err := Prepare("SELECT &Manager.* FROM employee", Alias("Manager", Employee{}))
+	assert.Nil(t, err)
+
+	// Without the alias, the output is of a type not used by the statement.
+	var m Employee
+	err = NewDB(setupEmployeeDB(t)).Query(context.Background(), stmt).Get(&m)
+	assert.Equal(t, NewErrSuperfluousType("Employee"), err)
+}
@@ -0,0 +1,12 @@
+package sqlair
+
+import "github.com/canonical/sqlair/internal/parse"
+
+// Literals returns the string and number literals written in the statement,
+// so that they can be reviewed for values that should instead be supplied
+// as inputs. They are in the order written in the statement's SQL. The
+// position of a literal from a Condition added by Where is within the DSL
+// of that condition.
+func (s *Statement) Literals() []Token {
+	return parse.Literals(s.expression)
+}
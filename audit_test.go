@@ -0,0 +1,40 @@
+package sqlair
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareStrict(t *testing.T) {
+	_, err := Prepare("SELECT &Employee.* FROM employee WHERE name = 'it''s'", WithStrictness(true), Employee{})
+	assert.Nil(t, err)
+
+	_, err = Prepare("SELECT &Employee.* FROM employee WHERE name = 'Fred", Employee{}, WithStrictness(true))
+	assert.EqualError(t, err, "unterminated string literal at line 1, column 47")
+
+	_, err = PrepareReader(strings.NewReader("SELECT &Employee.* FROM employee WHERE name = #Fred"), WithStrictness(true), Employee{})
+	assert.EqualError(t, err, "unrecognised character \"#\" at line 1, column 47")
+
+	// Without Strict, the text is passed to the database as written.
+	stmt, err := Prepare("SELECT &Employee.* FROM employee WHERE name = 'Fred", Employee{})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name, manager_id FROM employee WHERE name = 'Fred", stmt.sql)
+}
+
+func TestStatementLiterals(t *testing.T) {
+	stmt, err := Prepare("SELECT &Employee.* FROM employee WHERE name = 'Fred' AND id > 10", Employee{})
+	assert.Nil(t, err)
+
+	cond, err := PrepareCondition("id < 20")
+	assert.Nil(t, err)
+	stmt, err = stmt.Where(cond)
+	assert.Nil(t, err)
+
+	var literals []string
+	for _, tok := range stmt.Literals() {
+		literals = append(literals, tok.Type.String()+" "+tok.Literal)
+	}
+	assert.Equal(t, []string{"STRING 'Fred'", "NUM 10", "NUM 20"}, literals)
+}
@@ -0,0 +1,274 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/canonical/sqlair/internal/parse"
+	"github.com/pkg/errors"
+)
+
+// Batcher is a connection that sends queued statements to the database
+// together, in a single round trip, and collects their results. Database
+// drivers batch statements by their own APIs, such as the SendBatch method
+// of a pgx connection, which pipelines them, so a Batcher adapts that of
+// a connection. A DB whose connection is a Batcher sends the statements of
+// a Batch with it; see DB.SendBatch.
+type Batcher interface {
+	// SendBatch sends the input queries together and returns their
+	// results, one for each query, in the same order. An error returned
+	// by SendBatch itself is an error sending the batch, while an error
+	// running a query is that of its result.
+	SendBatch(ctx context.Context, queries []BatchedQuery) ([]BatchedResult, error)
+}
+
+// BatchedQuery is a statement sent by a Batcher,
+// with its SQL compiled for the DB's dialect.
+type BatchedQuery struct {
+	// SQL is the text of the statement.
+	SQL string
+
+	// Args are the parameters of the statement.
+	Args []any
+
+	// Rows is true if the statement returns rows, which must be
+	// read into the result, rather than only being executed.
+	Rows bool
+}
+
+// BatchedResult is the result of a BatchedQuery.
+type BatchedResult struct {
+	// Columns are the names of the result columns of a statement
+	// that returns rows.
+	Columns []string
+
+	// Rows holds the values of each row returned, as read
+	// from the driver, such as by scanning into *any.
+	Rows [][]any
+
+	// RowsAffected is the number of rows affected
+	// by a statement that does not return rows.
+	RowsAffected int64
+
+	// Err is the error, if any, from running the statement.
+	Err error
+}
+
+// Batch is a queue of statements to be run together, with their results
+// collected for each; see DB.SendBatch. It is not safe for concurrent use.
+//
+// Example:
+//
+//     var batch sqlair.Batch
+//     insert := batch.Queue(insertPerson, fred)
+//     people := batch.Queue(selectPeople)
+//
+//     if err := db.SendBatch(ctx, &batch); err != nil {
+//         return err
+//     }
+//     if err := people.Query().GetAll(&all); err != nil {
+//         return err
+//     }
+//
+type Batch struct {
+	queries []*BatchQuery
+}
+
+// Queue adds the input statement to the batch, with parameters sourced
+// from the input objects, and returns the BatchQuery from which its
+// results are read once the batch has been sent.
+func (b *Batch) Queue(s *Statement, inputs ...any) *BatchQuery {
+	q := &BatchQuery{stmt: s, inputs: inputs}
+	b.queries = append(b.queries, q)
+	return q
+}
+
+// Len returns the number of statements queued in the batch.
+func (b *Batch) Len() int {
+	return len(b.queries)
+}
+
+// BatchQuery is a statement queued in a Batch, the results of which
+// are collected when the batch is sent.
+type BatchQuery struct {
+	stmt   *Statement
+	inputs []any
+
+	// ctx and db are those with which the batch was sent,
+	// or nil if it has not been.
+	ctx context.Context
+	db  *DB
+
+	// rows holds the rows returned by the statement, if it returns rows.
+	rows *cachedResult
+
+	// result is the result of the statement, if it does not return rows.
+	result sql.Result
+
+	// err is the error, if any, from running the statement.
+	err error
+}
+
+// Result returns the result of running the statement, which must not
+// return rows, or the error from running it.
+func (q *BatchQuery) Result() (sql.Result, error) {
+	if q.db == nil {
+		return nil, errors.New("batch has not been sent")
+	}
+	if q.err != nil {
+		return nil, q.err
+	}
+	if q.result == nil {
+		return nil, errors.Errorf("statement %q returns rows", q.stmt.sql)
+	}
+	return q.result, nil
+}
+
+// Query returns a Query for decoding the rows returned by the statement,
+// which were read when the batch was sent. The statement is not executed
+// again. Any error from running the statement is returned by the Query.
+func (q *BatchQuery) Query() *Query {
+	query := &Query{ctx: q.ctx, db: q.db, stmt: q.stmt, inputs: q.inputs}
+	switch {
+	case q.db == nil:
+		query.err = errors.New("batch has not been sent")
+	case q.err != nil:
+		query.err = q.err
+	case q.rows == nil:
+		query.err = errors.Errorf("statement %q does not return rows", q.stmt.sql)
+	default:
+		query.rows = q.rows
+	}
+	return query
+}
+
+// SendBatch runs the statements of the input batch, collecting the results
+// of each, which are read from its BatchQuery. If the DB's connection is a
+// Batcher, the statements are sent together with it; otherwise, they are
+// run in turn on the connection, as by Exec and Query. The first error from
+// running a statement is returned, as well as being that of its result.
+//
+// When the statements are sent together, each is sent to the DB's primary,
+// and the rows of those that only read are neither served from nor stored
+// in the result cache, while the timeouts given by the statements'
+// directives do not apply, the batch being bounded only by the input
+// context. Keys generated for inputs are assigned only when the statements
+// are run in turn.
+func (db *DB) SendBatch(ctx context.Context, b *Batch) error {
+	for _, q := range b.queries {
+		q.ctx, q.db = ctx, db
+		q.rows, q.result, q.err = nil, nil, nil
+	}
+
+	if batcher, ok := db.conn.(Batcher); ok {
+		return db.sendBatch(ctx, batcher, b.queries)
+	}
+
+	var first error
+	for _, q := range b.queries {
+		if returnsRows(q.stmt) {
+			q.rows, q.err = db.queryResult(ctx, q.stmt, q.inputs)
+		} else {
+			q.result, q.err = db.Exec(ctx, q.stmt, q.inputs...)
+		}
+		if first == nil {
+			first = q.err
+		}
+	}
+	return first
+}
+
+// sendBatch sends the input queries together with the input Batcher.
+func (db *DB) sendBatch(ctx context.Context, batcher Batcher, queries []*BatchQuery) error {
+	batched := make([]BatchedQuery, len(queries))
+	args := make([][]any, len(queries))
+	for i, q := range queries {
+		q.stmt.recordUse()
+		var err error
+		if args[i], err = q.stmt.bindInputs(ctx, q.inputs); err != nil {
+			return failBatch(queries, errors.Wrapf(err, "batched statement %d", i))
+		}
+		query, params, err := db.sqlFor(q.stmt, args[i])
+		if err != nil {
+			return failBatch(queries, errors.Wrapf(q.stmt.redactError(err, args[i]), "batched statement %d", i))
+		}
+		batched[i] = BatchedQuery{
+			SQL:  withComment(query, db.comment(ctx, q.stmt)),
+			Args: params,
+			Rows: returnsRows(q.stmt),
+		}
+	}
+
+	start := time.Now()
+	results, err := batcher.SendBatch(ctx, batched)
+	if err != nil {
+		return failBatch(queries, errors.Wrap(err, "sending batch"))
+	}
+	if len(results) != len(queries) {
+		return failBatch(queries, errors.Errorf("expected %d batched results, got %d", len(queries), len(results)))
+	}
+
+	var first error
+	for i, q := range queries {
+		result := results[i]
+		q.err = db.logExecution(ctx, q.stmt, args[i], start, result.Err)
+		if q.err == nil {
+			db.ran(q.stmt)
+			if batched[i].Rows {
+				q.rows = &cachedResult{columns: result.Columns, rows: result.Rows}
+			} else {
+				q.result = batchResult(result.RowsAffected)
+			}
+		}
+		if first == nil {
+			first = q.err
+		}
+	}
+	return first
+}
+
+// failBatch sets the input error, which prevented the batch from being
+// sent, as that of each of the input queries, and returns it.
+func failBatch(queries []*BatchQuery, err error) error {
+	for _, q := range queries {
+		q.err = err
+	}
+	return err
+}
+
+// queryResult runs the input statement, with parameters sourced from the
+// input objects, and returns the rows that it returns, read in full.
+func (db *DB) queryResult(ctx context.Context, s *Statement, inputs []any) (*cachedResult, error) {
+	rows, err := s.QueryRows(ctx, db, inputs...)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	return readResult(rows, columns, nil)
+}
+
+// returnsRows returns true if the input statement returns rows: if it
+// only reads, or has output targets, such as those of a RETURNING clause.
+func returnsRows(s *Statement) bool {
+	return len(s.outputs) > 0 || parse.Classify(s.expression) == parse.KindQuery
+}
+
+// batchResult is the result of a statement sent by a Batcher,
+// which reports only the number of rows affected.
+type batchResult int64
+
+// LastInsertId implements sql.Result. The ID is not
+// reported for statements sent by a Batcher.
+func (r batchResult) LastInsertId() (int64, error) {
+	return 0, errors.New("last insert ID is not reported for batched statements")
+}
+
+// RowsAffected implements sql.Result.
+func (r batchResult) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
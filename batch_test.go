@@ -0,0 +1,204 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+// sqlBatcher is a Batcher that runs the queries of each batch
+// in turn on a *sql.DB, recording the batches sent.
+type sqlBatcher struct {
+	*sql.DB
+	batches [][]BatchedQuery
+}
+
+func (b *sqlBatcher) SendBatch(ctx context.Context, queries []BatchedQuery) ([]BatchedResult, error) {
+	b.batches = append(b.batches, queries)
+
+	results := make([]BatchedResult, len(queries))
+	for i, q := range queries {
+		if !q.Rows {
+			res, err := b.ExecContext(ctx, q.SQL, q.Args...)
+			if err == nil {
+				results[i].RowsAffected, err = res.RowsAffected()
+			}
+			results[i].Err = err
+			continue
+		}
+
+		rows, err := b.QueryContext(ctx, q.SQL, q.Args...)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Columns, _ = rows.Columns()
+		for rows.Next() {
+			row, err := readRow(rows, len(results[i].Columns))
+			if err != nil {
+				results[i].Err = err
+				break
+			}
+			results[i].Rows = append(results[i].Rows, row)
+		}
+		_ = rows.Close()
+	}
+	return results, nil
+}
+
+func prepareBatchStatements(t *testing.T) (insert, selectAll, bad *Statement) {
+	var err error
+	insert, err = Prepare("INSERT INTO person (id, name) VALUES ($Person.id, $Person.name)", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	selectAll, err = Prepare("SELECT &Person.* FROM person ORDER BY id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	bad, err = Prepare("SELECT &Person.* FROM missing", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	return insert, selectAll, bad
+}
+
+func TestSendBatchInTurn(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+	insert, selectAll, bad := prepareBatchStatements(t)
+
+	var batch Batch
+	inserted := batch.Queue(insert, sqlairtesting.Person{ID: "4", Name: "Kruppe"})
+	selected := batch.Queue(selectAll)
+	failed := batch.Queue(bad)
+	assert.Equal(t, 3, batch.Len())
+
+	err := db.SendBatch(context.Background(), &batch)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no such table: missing")
+	}
+
+	res, err := inserted.Result()
+	assert.Nil(t, err)
+	n, err := res.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+
+	var people []sqlairtesting.Person
+	err = selected.Query().GetAll(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, append(samplePeople(), sqlairtesting.Person{ID: "4", Name: "Kruppe"}), people)
+
+	// The rows are decoded as they were read, not selected again.
+	people = nil
+	err = selected.Query().GetAll(&people)
+	assert.Nil(t, err)
+	assert.Len(t, people, 4)
+
+	err = failed.Query().GetAll(&people)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no such table: missing")
+	}
+
+	_, err = selected.Result()
+	assert.EqualError(t, err, `statement "SELECT id, name FROM person ORDER BY id" returns rows`)
+	err = inserted.Query().GetAll(&people)
+	assert.EqualError(t, err, `statement "INSERT INTO person (id, name) VALUES (?, ?)" does not return rows`)
+}
+
+func TestSendBatchWithBatcher(t *testing.T) {
+	batcher := &sqlBatcher{DB: setupPersonDB(t)}
+	db := NewDB(batcher)
+	insert, selectAll, bad := prepareBatchStatements(t)
+
+	var batch Batch
+	inserted := batch.Queue(insert, sqlairtesting.Person{ID: "4", Name: "Kruppe"})
+	selected := batch.Queue(selectAll)
+	failed := batch.Queue(bad)
+
+	err := db.SendBatch(context.Background(), &batch)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no such table: missing")
+	}
+
+	// The statements are sent together.
+	if assert.Len(t, batcher.batches, 1) {
+		assert.Equal(t, []BatchedQuery{{
+			SQL:  "INSERT INTO person (id, name) VALUES (?, ?)",
+			Args: []any{"4", "Kruppe"},
+		}, {
+			SQL:  "SELECT id, name FROM person ORDER BY id",
+			Args: []any{},
+			Rows: true,
+		}, {
+			SQL:  "SELECT id, name FROM missing",
+			Args: []any{},
+			Rows: true,
+		}}, batcher.batches[0])
+	}
+
+	res, err := inserted.Result()
+	assert.Nil(t, err)
+	n, err := res.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = res.LastInsertId()
+	assert.Error(t, err)
+
+	var people []sqlairtesting.Person
+	err = selected.Query().GetAll(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, append(samplePeople(), sqlairtesting.Person{ID: "4", Name: "Kruppe"}), people)
+
+	err = failed.Query().GetAll(&people)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no such table: missing")
+	}
+}
+
+func TestBatchNotSentError(t *testing.T) {
+	insert, _, _ := prepareBatchStatements(t)
+
+	var batch Batch
+	q := batch.Queue(insert, sqlairtesting.Person{})
+
+	_, err := q.Result()
+	assert.EqualError(t, err, "batch has not been sent")
+	err = q.Query().GetAll(&[]sqlairtesting.Person{})
+	assert.EqualError(t, err, "batch has not been sent")
+}
+
+func TestSendBatchWithBatcherBindError(t *testing.T) {
+	batcher := &sqlBatcher{DB: setupPersonDB(t)}
+	db := NewDB(batcher)
+	insert, selectAll, _ := prepareBatchStatements(t)
+
+	var batch Batch
+	selected := batch.Queue(selectAll)
+	inserted := batch.Queue(insert)
+
+	// The batch is not sent, and the error binding the
+	// inputs is that of each of the queued statements.
+	err := db.SendBatch(context.Background(), &batch)
+	assert.EqualError(t, err, `batched statement 1: no input of type "Person" supplied for statement`)
+	assert.Len(t, batcher.batches, 0)
+
+	_, err = inserted.Result()
+	assert.EqualError(t, err, `batched statement 1: no input of type "Person" supplied for statement`)
+	err = selected.Query().GetAll(&[]sqlairtesting.Person{})
+	assert.EqualError(t, err, `batched statement 1: no input of type "Person" supplied for statement`)
+	_, err = selected.Result()
+	assert.EqualError(t, err, `batched statement 1: no input of type "Person" supplied for statement`)
+}
+
+func TestBatchQueue(t *testing.T) {
+	first, second := &Statement{}, &Statement{}
+
+	var batch Batch
+	assert.Equal(t, 0, batch.Len())
+
+	q := batch.Queue(first, 1, "two")
+	batch.Queue(second)
+	assert.Equal(t, 2, batch.Len())
+
+	assert.Same(t, first, q.stmt)
+	assert.Equal(t, []any{1, "two"}, q.inputs)
+	assert.Same(t, second, batch.queries[1].stmt)
+}
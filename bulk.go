@@ -0,0 +1,286 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+
+	"github.com/canonical/sqlair/internal/parse"
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// bulkParamLimit is the greatest number of parameters in the
+// statements inserting batches of rows, which is the least limit
+// of the supported databases, that of SQLite before 3.32.0.
+const bulkParamLimit = 999
+
+// BulkLoader inserts many rows into a single table, each from a struct of
+// a single type, at a higher rate than executing an INSERT for each. Rows
+// are inserted in batches by INSERT statements with many rows, or, for
+// databases whose backend supports it, with COPY; see Backend.
+type BulkLoader struct {
+	// insert inserts a single row. The parameters of each row loaded
+	// are bound by it, so that they are prepared as for Exec.
+	insert *Statement
+
+	// table and columns are those into which rows are loaded.
+	table   string
+	columns []string
+}
+
+// NewBulkLoader returns a BulkLoader that inserts rows into the input
+// table, with the values of the tagged fields of structs of the input
+// object's type, other than any tagged "autoincrement". As for Exec, the
+// BeforeInsert hook and Validate method of each struct are called before
+// its values are bound, and the values are encoded by any transformers.
+// The table's name may be qualified by a schema, as in "main.person", and
+// must otherwise be a valid identifier. A table or column name that is a
+// reserved word is quoted.
+//
+// Example:
+//
+//     loader, err := sqlair.NewBulkLoader(Person{}, "person")
+//
+//     n, err := loader.Load(ctx, db, people)
+//
+func NewBulkLoader(obj any, table string) (*BulkLoader, error) {
+	argTypes, err := typesForStatement([]any{obj})
+	if err != nil {
+		return nil, err
+	}
+	name, _ := objectName(obj)
+	info, ok := argTypes[name].(sqlairreflect.Struct)
+	if !ok {
+		return nil, errors.Errorf("can not load rows from non-struct type %q", name)
+	}
+
+	if _, err := tableParts(table); err != nil {
+		return nil, err
+	}
+
+	l := &BulkLoader{table: table}
+	var names, sources []string
+	for _, tag := range info.Tags() {
+		if info.Fields[tag].AutoIncrement {
+			continue
+		}
+		l.columns = append(l.columns, tag)
+		names = append(names, generatedName(tag))
+		sources = append(sources, "$"+name+"."+tag)
+	}
+	if len(l.columns) == 0 {
+		return nil, errors.Errorf("type %q has no columns to load", name)
+	}
+
+	generated, _ := generatedTable(table)
+	insert := "INSERT INTO " + generated + " (" + strings.Join(names, ", ") + ")" +
+		" VALUES (" + strings.Join(sources, ", ") + ")"
+	if l.insert, err = prepareGenerated(insert, obj, generatedNames(info, table)); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Load inserts a row for each of the input rows using the input DB, and
+// returns the number inserted. The rows are a slice of structs of the
+// loader's type, or of pointers to them, or a channel of either, from
+// which rows are received until it is closed or the input context is done.
+// Keys generated by the database are not assigned to the rows.
+//
+// If the DB's backend supports COPY, the rows are loaded in a single COPY
+// statement, in a transaction begun for it unless the DB's connection is
+// already a *sql.Tx. Otherwise, they are inserted in batches of as many
+// rows as a statement's parameters allow, each in its own statement, so
+// that if loading fails, the rows of earlier batches remain inserted
+// unless the DB's connection is a transaction that is rolled back. Each
+// batch is logged and commented as for Exec.
+func (l *BulkLoader) Load(ctx context.Context, db *DB, rows any) (int64, error) {
+	next, err := l.rowSource(ctx, rows)
+	if err != nil {
+		return 0, err
+	}
+
+	if !db.backend.Copy {
+		return l.insertRows(ctx, db, next)
+	}
+	n, err := l.copyRows(ctx, db, next)
+	if n > 0 {
+		db.ran(l.insert)
+	}
+	return n, err
+}
+
+// rowSource returns a function returning the parameters of each of the
+// input rows in turn, bound by the loader's insert statement, and false
+// once there are no more.
+func (l *BulkLoader) rowSource(ctx context.Context, rows any) (func() ([]any, bool, error), error) {
+	v := reflect.ValueOf(rows)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		i := 0
+		return func() ([]any, bool, error) {
+			if i >= v.Len() {
+				return nil, false, nil
+			}
+			i++
+			args, err := l.insert.bindInputs(ctx, []any{v.Index(i - 1).Interface()})
+			return args, err == nil, err
+		}, nil
+	case reflect.Chan:
+		if v.Type().ChanDir()&reflect.RecvDir == 0 {
+			return nil, errors.Errorf("can not receive rows from send-only channel of type %T", rows)
+		}
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: v},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		}
+		return func() ([]any, bool, error) {
+			chosen, row, ok := reflect.Select(cases)
+			if chosen == 1 {
+				return nil, false, ctx.Err()
+			}
+			if !ok {
+				return nil, false, nil
+			}
+			args, err := l.insert.bindInputs(ctx, []any{row.Interface()})
+			return args, err == nil, err
+		}, nil
+	}
+	return nil, errors.Errorf("expected slice or channel of rows, got %T", rows)
+}
+
+// insertRows inserts the rows returned by the input
+// source in batches, returning the number inserted.
+func (l *BulkLoader) insertRows(ctx context.Context, db *DB, next func() ([]any, bool, error)) (int64, error) {
+	batchRows := bulkParamLimit / len(l.columns)
+	if batchRows == 0 {
+		batchRows = 1
+	}
+
+	full, err := l.batch(batchRows)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	args := make([]any, 0, batchRows*len(l.columns))
+	flush := func() error {
+		if len(args) == 0 {
+			return nil
+		}
+		rows := len(args) / len(l.columns)
+		stmt := full
+		if rows < batchRows {
+			var err error
+			if stmt, err = l.batch(rows); err != nil {
+				return err
+			}
+		}
+		if _, err := db.execArgs(ctx, stmt, args, generatedKey{}, false); err != nil {
+			return errors.Wrapf(err, "inserting %d rows into %q", rows, l.table)
+		}
+		n += int64(rows)
+		args = args[:0]
+		return nil
+	}
+
+	for {
+		row, ok, err := next()
+		if err != nil {
+			return n, err
+		}
+		if !ok {
+			return n, flush()
+		}
+		args = append(args, row...)
+		if len(args) == cap(args) {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+}
+
+// batch returns the statement inserting the input number of rows,
+// derived from the loader's insert statement by repeating the row
+// of its VALUES clause. Its inputs are those of the insert statement,
+// repeated for each row.
+func (l *BulkLoader) batch(rows int) (*Statement, error) {
+	exp := &parse.SQLExpression{}
+	for _, child := range l.insert.expression.Expressions() {
+		if values, ok := child.(*parse.ValuesExpression); ok {
+			child = values.Repeat(rows)
+		}
+		exp.AppendExpression(child)
+	}
+	return l.insert.recompile(exp, l.insert.argTypes)
+}
+
+// copySQL returns the COPY statement loading the rows, quoted as for
+// lib/pq's CopyIn, or CopyInSchema if the table is qualified by a schema.
+func (l *BulkLoader) copySQL() string {
+	columns := make([]string, len(l.columns))
+	for i, column := range l.columns {
+		columns[i] = quoteIdentifier(column)
+	}
+	parts, _ := tableParts(l.table)
+	for i, part := range parts {
+		parts[i] = quoteIdentifier(part)
+	}
+	return "COPY " + strings.Join(parts, ".") + " (" + strings.Join(columns, ",") + ") FROM STDIN"
+}
+
+// copyRows loads the rows returned by the input source with a single COPY
+// statement, returning the number loaded. Each row is sent by executing
+// the prepared COPY statement with its parameters, and the rows are
+// flushed by executing it without any.
+func (l *BulkLoader) copyRows(ctx context.Context, db *DB, next func() ([]any, bool, error)) (n int64, err error) {
+	conn := db.conn
+	if _, ok := conn.(*sql.Tx); !ok {
+		beginner, ok := conn.(interface {
+			BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error)
+		})
+		if !ok {
+			return l.insertRows(ctx, db, next)
+		}
+		tx, err := beginner.BeginTx(ctx, nil)
+		if err != nil {
+			return 0, errors.Wrap(err, "beginning transaction for COPY")
+		}
+		defer func() {
+			if err != nil {
+				n = 0
+				_ = tx.Rollback()
+			} else if err = tx.Commit(); err != nil {
+				n = 0
+			}
+		}()
+		conn = tx
+	}
+
+	stmt, err := conn.PrepareContext(ctx, l.copySQL())
+	if err != nil {
+		return 0, errors.Wrapf(err, "preparing COPY into %q", l.table)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for {
+		row, ok, err := next()
+		if err != nil {
+			return n, err
+		}
+		if !ok {
+			break
+		}
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return n, errors.Wrapf(err, "copying row into %q", l.table)
+		}
+		n++
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return n, errors.Wrapf(err, "copying rows into %q", l.table)
+	}
+	return n, nil
+}
@@ -0,0 +1,122 @@
+package sqlair
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkLoaderSlice(t *testing.T) {
+	conn := setupPersonDB(t)
+	db := NewDB(conn)
+	ctx := context.Background()
+
+	loader, err := NewBulkLoader(sqlairtesting.Person{}, "person")
+	assert.Nil(t, err)
+
+	// More rows than fit in one statement's parameters.
+	var people []*sqlairtesting.Person
+	for i := 0; i < 1200; i++ {
+		people = append(people, &sqlairtesting.Person{ID: strconv.Itoa(100 + i), Name: "Bulk"})
+	}
+	n, err := loader.Load(ctx, db, people)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1200), n)
+
+	var count int
+	err = conn.QueryRow("SELECT count(*) FROM person WHERE name = 'Bulk'").Scan(&count)
+	assert.Nil(t, err)
+	assert.Equal(t, 1200, count)
+}
+
+func TestBulkLoaderChannel(t *testing.T) {
+	conn := setupPersonDB(t)
+	db := NewDB(conn)
+
+	loader, err := NewBulkLoader(sqlairtesting.Person{}, "person")
+	assert.Nil(t, err)
+
+	rows := make(chan sqlairtesting.Person)
+	go func() {
+		defer close(rows)
+		rows <- sqlairtesting.Person{ID: "4", Name: "Ralph"}
+		rows <- sqlairtesting.Person{ID: "5", Name: "Jane"}
+	}()
+	n, err := loader.Load(context.Background(), db, rows)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	var name string
+	err = conn.QueryRow("SELECT name FROM person WHERE id = '5'").Scan(&name)
+	assert.Nil(t, err)
+	assert.Equal(t, "Jane", name)
+
+	// Loading stops when the context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = loader.Load(ctx, db, make(chan sqlairtesting.Person))
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestBulkLoaderErrors(t *testing.T) {
+	loader, err := NewBulkLoader(sqlairtesting.Person{}, "person")
+	assert.Nil(t, err)
+	assert.Equal(t, `COPY "person" ("id","name") FROM STDIN`, loader.copySQL())
+
+	db := NewDB(setupPersonDB(t))
+	_, err = loader.Load(context.Background(), db, sqlairtesting.Person{})
+	assert.EqualError(t, err, "expected slice or channel of rows, got testing.Person")
+
+	_, err = loader.Load(context.Background(), db, []Item{{}})
+	assert.NotNil(t, err)
+
+	loader, err = NewBulkLoader(sqlairtesting.Person{}, "public.person")
+	assert.Nil(t, err)
+	assert.Equal(t, `COPY "public"."person" ("id","name") FROM STDIN`, loader.copySQL())
+	assert.Equal(t, `INSERT INTO public.person (id, name) VALUES (?, ?)`, batchSQL(t, loader, 1, Dialect{}))
+
+	_, err = NewBulkLoader(sqlairtesting.Person{}, "person (id) VALUES ('x'); --")
+	assert.EqualError(t, err, `invalid table name "person (id) VALUES ('x'); --"`)
+
+	loader, err = NewBulkLoader(sqlairtesting.Person{}, "select")
+	if assert.Nil(t, err) {
+		assert.Equal(t, `INSERT INTO "select" (id, name) VALUES (?, ?)`, batchSQL(t, loader, 1, Dialect{}))
+		assert.Equal(t, `INSERT INTO "select" (id, name) VALUES ($1, $2), ($3, $4)`, batchSQL(t, loader, 2, Dialect{NumberedPlaceholders: true}))
+	}
+}
+
+func TestBulkLoaderLogsBatches(t *testing.T) {
+	var entries []LogEntry
+	db := NewDB(setupPersonDB(t), WithLogger(func(_ context.Context, e LogEntry) {
+		entries = append(entries, e)
+	}))
+
+	loader, err := NewBulkLoader(sqlairtesting.Person{}, "person")
+	assert.Nil(t, err)
+
+	people := []sqlairtesting.Person{{ID: "4", Name: "Ralph"}, {ID: "5", Name: "Jane"}}
+	n, err := loader.Load(context.Background(), db, people)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, `INSERT INTO person (id, name) VALUES (?, ?), (?, ?)`, entries[0].SQL)
+		assert.Equal(t, []any{"4", "Ralph", "5", "Jane"}, entries[0].Args)
+		assert.Nil(t, entries[0].Err)
+	}
+}
+
+// batchSQL returns the SQL of the loader's statement inserting
+// the input number of rows, as it is run for the input dialect.
+func batchSQL(t *testing.T, loader *BulkLoader, rows int, dialect Dialect) string {
+	stmt, err := loader.batch(rows)
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	query, _, err := NewDB(nil, WithDialect(dialect)).sqlFor(stmt, make([]any, len(stmt.inputs)))
+	assert.Nil(t, err)
+	return query
+}
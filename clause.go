@@ -0,0 +1,58 @@
+package sqlair
+
+import (
+	"github.com/canonical/sqlair/internal/parse"
+	"github.com/pkg/errors"
+)
+
+// Clause is a prepared Sqlair DSL clause, such as "ORDER BY name DESC" or
+// "LIMIT $Page.size", that can be added to statements by WithClause. It is
+// parsed, and its input sources validated, once, however many statements
+// it is added to.
+type Clause struct {
+	// expression is the parsed expression tree for this clause.
+	expression parse.Expression
+
+	// argTypes holds the reflection info for types used in this clause.
+	argTypes typeMap
+}
+
+// PrepareClause accepts a raw DSL ORDER BY or LIMIT clause and optionally,
+// objects from which to infer type information. The clause is parsed and
+// its input sources are validated against the objects in the same way as
+// Prepare.
+func PrepareClause(clause string, args ...any) (*Clause, error) {
+	exp, argTypes, err := parseClause(clause, args)
+	if err != nil {
+		return nil, err
+	}
+	if !isOrderBy(exp) && !isLimit(exp) {
+		return nil, errors.Errorf("%q is neither an ORDER BY nor a LIMIT clause", clause)
+	}
+	return &Clause{expression: exp, argTypes: argTypes}, nil
+}
+
+// WithClause returns an option that replaces the clause of the statement
+// of the same kind as the input clause with it, adding it if there is
+// none, as do WithOrderBy and WithLimit.
+func WithClause(c *Clause) DeriveOption {
+	return deriveFunc(func(d *derivation) error {
+		var err error
+		if d.argTypes, err = mergeTypes(d.argTypes, c.argTypes); err != nil {
+			return err
+		}
+
+		if isOrderBy(c.expression) {
+			d.replaceOrInsert(c.expression, isOrderBy, isLimit)
+		} else {
+			d.replaceOrInsert(c.expression, isLimit, isLimit)
+		}
+		return nil
+	})
+}
+
+// IsOrderBy returns true if the clause is an
+// ORDER BY clause, rather than a LIMIT clause.
+func (c *Clause) IsOrderBy() bool {
+	return isOrderBy(c.expression)
+}
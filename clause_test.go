@@ -0,0 +1,35 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareClause(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+
+	orderBy, err := PrepareClause("ORDER BY name DESC")
+	assert.Nil(t, err)
+	assert.True(t, orderBy.IsOrderBy())
+	limit, err := PrepareClause("LIMIT $PageSpec.size", PageSpec{})
+	assert.Nil(t, err)
+	assert.False(t, limit.IsOrderBy())
+
+	stmt, err := Prepare("SELECT &Person.* FROM person ORDER BY id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	derived, err := stmt.Derive(WithClause(orderBy), WithClause(limit))
+	assert.Nil(t, err)
+
+	var people []sqlairtesting.Person
+	err = db.Query(context.Background(), derived, PageSpec{Size: 2}).GetAll(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, []sqlairtesting.Person{{ID: "2", Name: "Onos"}, {ID: "1", Name: "Lorn"}}, people)
+
+	_, err = PrepareClause("name")
+	assert.EqualError(t, err, `"name" is neither an ORDER BY nor a LIMIT clause`)
+	_, err = PrepareClause("LIMIT $PageSpec.size")
+	assert.Equal(t, NewErrTypeInfoNotPresent("PageSpec"), err)
+}
@@ -0,0 +1,16 @@
+// Command sqlairvet checks the Sqlair statements prepared by Go packages.
+// It is run by go vet:
+//
+//     go vet -vettool=$(which sqlairvet) ./...
+//
+// See package sqlairvet for the checks made.
+package main
+
+import (
+	"github.com/canonical/sqlair/sqlairvet"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+func main() {
+	unitchecker.Main(sqlairvet.Analyzer)
+}
@@ -0,0 +1,114 @@
+package sqlair
+
+import (
+	"strings"
+
+	"github.com/canonical/sqlair/internal/parse"
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// columnAlias maps a database column to a field of an output
+// type, in place of the column named by the field's tag.
+type columnAlias struct {
+	column string
+	target string
+}
+
+// WithAlias returns an option causing the field identified by the input
+// target, in the form "Type.tag", to be decoded from the input column
+// for that statement only.
+// The column may be qualified by a table name. This allows structs to be used
+// with legacy schemas that have awkward column names, without changing their
+// tags everywhere.
+//
+// Example:
+//
+//     stmt, err := sqlair.Prepare(`
+//     SELECT &Person.*
+//       FROM person`, Person{}, sqlair.WithAlias("person.full_name", "Person.name"))
+//
+func WithAlias(column, target string) PrepareOption {
+	return func(o *prepareOptions) {
+		o.aliases = append(o.aliases, columnAlias{column: column, target: target})
+	}
+}
+
+// columnAliases holds the columns from which fields are
+// decoded in place of their tags, indexed by "Type.tag".
+type columnAliases map[string]string
+
+// newColumnAliases returns the input aliases indexed by their targets,
+// or an error if any is not a valid column name or any target has more
+// than one alias.
+func newColumnAliases(aliases []columnAlias) (columnAliases, error) {
+	var byTarget columnAliases
+	for _, a := range aliases {
+		if !isColumnName(a.column) {
+			return nil, errors.Errorf("alias %q for %q is not a valid column name", a.column, a.target)
+		}
+		if _, ok := byTarget[a.target]; ok {
+			return nil, errors.Errorf("more than one column alias for %q", a.target)
+		}
+		if byTarget == nil {
+			byTarget = make(columnAliases)
+		}
+		byTarget[a.target] = a.column
+	}
+	return byTarget, nil
+}
+
+// validate returns an error if any of the aliases
+// is not for a tagged field of one of the input types.
+func (a columnAliases) validate(argTypes typeMap) error {
+	for target := range a {
+		i := strings.LastIndex(target, ".")
+		if i < 0 {
+			return errors.Errorf("column alias target %q is not of the form \"Type.tag\"", target)
+		}
+		typeName, tag := target[:i], target[i+1:]
+
+		info, ok := argTypes[typeName]
+		if !ok {
+			return NewErrTypeInfoNotPresent(typeName)
+		}
+		st, ok := info.(sqlairreflect.Struct)
+		if !ok {
+			return errors.Errorf("type %q of column alias target %q is not a struct", typeName, target)
+		}
+		if _, ok := st.Fields[tag]; !ok {
+			return NewErrFieldNotPresent(typeName, tag)
+		}
+	}
+	return nil
+}
+
+// column returns the column from which the field with the input tag of the
+// named type is decoded, and true, or false if the field has no alias.
+func (a columnAliases) column(typeName, tag string) (string, bool) {
+	column, ok := a[typeName+"."+tag]
+	return column, ok
+}
+
+// isColumnName returns true if the input is a column
+// name, optionally qualified by a table name.
+func isColumnName(column string) bool {
+	tokens, err := parse.Tokens(column)
+	if err != nil {
+		return false
+	}
+
+	switch len(tokens) {
+	case 1:
+		return isNameToken(tokens[0])
+	case 3:
+		return isNameToken(tokens[0]) && tokens[1].Type == parse.PERIOD && isNameToken(tokens[2])
+	}
+	return false
+}
+
+// isNameToken returns true if the input token
+// can be used as the name of a table or column.
+func isNameToken(tok parse.Token) bool {
+	return tok.Type == parse.IDENT || tok.Type == parse.KEYWORD
+}
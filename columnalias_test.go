@@ -0,0 +1,70 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileColumnAliases(t *testing.T) {
+	stmt, err := Prepare("SELECT &Employee.* FROM employee WHERE id = $Employee.id",
+		Employee{}, WithAlias("employee.full_name", "Employee.name"))
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, employee.full_name AS name, manager_id FROM employee WHERE id = ?", stmt.sql)
+
+	stmt, err = Prepare("SELECT e.* AS &Employee.* FROM employee AS e",
+		Employee{}, WithAlias("employee.full_name", "Employee.name"), WithAlias("boss", "Employee.manager_id"))
+	assert.Nil(t, err)
+	assert.Equal(t,
+		`SELECT e.id AS e_id, e.full_name AS e_name, e.boss AS e_manager_id FROM employee AS e`,
+		stmt.sql)
+}
+
+func TestColumnAliasErrors(t *testing.T) {
+	tests := []struct {
+		alias PrepareOption
+		err   string
+	}{
+		{WithAlias("full name", "Employee.name"), `alias "full name" for "Employee.name" is not a valid column name`},
+		{WithAlias("full_name", "name"), `column alias target "name" is not of the form "Type.tag"`},
+		{WithAlias("full_name", "Person.name"), `identity "Person" has no associated object`},
+		{WithAlias("full_name", "Employee.full_name"), `type "Employee" has no field with tag "full_name"`},
+	}
+
+	for _, test := range tests {
+		_, err := Prepare("SELECT &Employee.* FROM employee", Employee{}, test.alias)
+		if assert.Error(t, err, test.alias) {
+			assert.Contains(t, err.Error(), test.err)
+		}
+	}
+
+	_, err := Prepare("SELECT &Employee.* FROM employee",
+		Employee{}, WithAlias("a", "Employee.name"), WithAlias("b", "Employee.name"))
+	assert.EqualError(t, err, `more than one column alias for "Employee.name"`)
+}
+
+func TestQueryColumnAliases(t *testing.T) {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE staff (id TEXT, full_name TEXT, manager_id TEXT)"); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO staff VALUES ('1', 'Lorn', '')`)
+		return err
+	})
+
+	stmt, err := Prepare("SELECT &Employee.* FROM staff",
+		Employee{}, WithAlias("staff.full_name", "Employee.name"))
+	assert.Nil(t, err)
+
+	// Derived statements keep the aliases.
+	stmt, err = stmt.Derive(WithLimit("LIMIT 1"))
+	assert.Nil(t, err)
+
+	var e Employee
+	err = NewDB(db).Query(context.Background(), stmt).Get(&e)
+	assert.Nil(t, err)
+	assert.Equal(t, Employee{ID: "1", Name: "Lorn"}, e)
+}
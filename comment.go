@@ -0,0 +1,78 @@
+package sqlair
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Commenter returns labels describing the context in which a statement is
+// run, such as the application and the call site, which a DB appends to
+// the statement's SQL as a comment so that the statements recorded in the
+// database's logs can be attributed to the code that ran them. The labels
+// are written as by sqlcommenter, as in "/*app='juju',caller='unitworker'*/",
+// with the keys sorted and both keys and values URL-encoded, so that they
+// can not end the comment. No comment is appended if there are no labels.
+type Commenter func(ctx context.Context, s *Statement) map[string]string
+
+// WithCommenter returns an option with which a DB appends the labels
+// returned by the input commenter to the SQL of the statements it runs.
+// The SQL of log entries, and the keys under which results are cached, do
+// not include the comment.
+//
+// Example:
+//
+//     db = db.With(sqlair.WithCommenter(func(ctx context.Context, s *sqlair.Statement) map[string]string {
+//         return map[string]string{"app": "juju", "caller": callerFromContext(ctx)}
+//     }))
+//
+func WithCommenter(c Commenter) DBOption {
+	return func(db *DB) {
+		db.commenter = c
+	}
+}
+
+// comment returns the comment holding the labels returned by the DB's
+// commenter for running the input statement, preceded by a space, or ""
+// if the DB has no commenter or there are no labels.
+func (db *DB) comment(ctx context.Context, s *Statement) string {
+	if db.commenter == nil {
+		return ""
+	}
+	labels := db.commenter(ctx, s)
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = commentEscape(key) + "='" + commentEscape(labels[key]) + "'"
+	}
+	return " /*" + strings.Join(pairs, ",") + "*/"
+}
+
+// commentEscape returns the input key or value of a label URL-encoded,
+// with spaces written as "%20", as sqlcommenter expects.
+func commentEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// withComment returns the input SQL with the input comment
+// appended, before the semicolon ending the statement, if any.
+func withComment(query, comment string) string {
+	if comment == "" {
+		return query
+	}
+	trimmed := strings.TrimRight(query, " \t\r\n")
+	if strings.HasSuffix(trimmed, ";") {
+		return strings.TrimRight(trimmed[:len(trimmed)-1], " \t\r\n") + comment + ";"
+	}
+	return trimmed + comment
+}
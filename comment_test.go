@@ -0,0 +1,61 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingConn is a Conn that records the SQL of the statements it runs.
+type recordingConn struct {
+	*sql.DB
+	queries []string
+}
+
+func (c *recordingConn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	c.queries = append(c.queries, query)
+	return c.DB.QueryContext(ctx, query, args...)
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	c.queries = append(c.queries, query)
+	return c.DB.ExecContext(ctx, query, args...)
+}
+
+type callerKey struct{}
+
+func TestCommenter(t *testing.T) {
+	conn := &recordingConn{DB: setupPersonDB(t)}
+	db := NewDB(conn).With(WithCommenter(func(ctx context.Context, s *Statement) map[string]string {
+		caller, _ := ctx.Value(callerKey{}).(string)
+		if caller == "" {
+			return nil
+		}
+		return map[string]string{"caller": caller, "app": "juju"}
+	}))
+	ctx := context.WithValue(context.Background(), callerKey{}, "unit worker */")
+
+	query, err := Prepare("SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	var p sqlairtesting.Person
+	err = db.Query(ctx, query, sqlairtesting.Person{ID: "1"}).Get(&p)
+	assert.Nil(t, err)
+
+	update, err := Prepare("UPDATE person SET name = 'Fred' WHERE id = $Person.id;", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	_, err = db.Exec(ctx, update, sqlairtesting.Person{ID: "1"})
+	assert.Nil(t, err)
+
+	// No comment is appended without labels.
+	_, err = db.Exec(context.Background(), update, sqlairtesting.Person{ID: "1"})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{
+		`SELECT id, name FROM person WHERE id = ? /*app='juju',caller='unit%20worker%20%2A%2F'*/`,
+		`UPDATE person SET name = 'Fred' WHERE id = ? /*app='juju',caller='unit%20worker%20%2A%2F'*/;`,
+		`UPDATE person SET name = 'Fred' WHERE id = ? ;`,
+	}, conn.queries)
+}
@@ -0,0 +1,737 @@
+package sqlair
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/canonical/sqlair/internal/parse"
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// inputBinding describes the source of a single statement parameter.
+type inputBinding struct {
+	// typeName is the name of the type from which the parameter is sourced.
+	typeName string
+
+	// column is the "db" tag of the field holding the parameter value.
+	column string
+
+	// field is the struct field holding the parameter value.
+	field sqlairreflect.Field
+}
+
+// outputBinding describes the destination of a single result column.
+type outputBinding struct {
+	// column is the name of the result column.
+	column string
+
+	// typeName is the name of the type into which the column is decoded.
+	typeName string
+
+	// field is the struct field receiving the column value.
+	field sqlairreflect.Field
+
+	// tag is the "db" tag of the field.
+	tag string
+
+	// source is the qualified column selected under the alias of a
+	// column expanded from a qualified wildcard, such as "p.id" for
+	// the column "p_id", or empty for other columns.
+	source string
+}
+
+// templateBinding describes the position of an identifier
+// template within the SQL generated for a statement.
+type templateBinding struct {
+	// name is the name of the template.
+	name string
+
+	// offset is the byte offset in the SQL at which the
+	// identifier is to be inserted.
+	offset int
+}
+
+// identifierBinding describes an identifier that Sqlair writes into
+// the SQL generated for a statement, such as a column name derived from
+// a struct tag, which is written for the dialect of the DB executing it.
+type identifierBinding struct {
+	// offset is the byte offset in the SQL of the identifier,
+	// which is written there as for the default dialect.
+	offset int
+
+	// name is the identifier, unquoted.
+	name string
+
+	// quoted is true if the identifier is quoted in every
+	// dialect, not only those of which it is a reserved word.
+	quoted bool
+}
+
+// listBinding describes a parameter holding a slice of values that
+// is the operand of a quantified comparison, such as "id = ANY(?)".
+type listBinding struct {
+	// param is the index of the parameter in placeholder order.
+	param int
+
+	// comparison is the SQL preceding the parameter's placeholder,
+	// from the comparison operator to the parenthesis, such as " = ANY(".
+	comparison string
+
+	// in is the SQL replacing the comparison when the values are expanded
+	// into a list of parameters: " IN (" or " NOT IN (", or empty if the
+	// comparison can not be expressed with a list.
+	in string
+}
+
+// compiler generates the SQL to be passed to the database
+// for a DSL expression tree, along with the bindings for
+// its parameters and result columns.
+type compiler struct {
+	argTypes typeMap
+
+	// quoting is the policy by which other column
+	// names derived from struct tags are quoted.
+	quoting IdentifierQuoting
+
+	// aliases holds the columns from which output fields
+	// are decoded in place of those named by their tags.
+	aliases columnAliases
+
+	// generated holds the names written into the DSL of a generated
+	// statement by generatedName and generatedTable.
+	generated map[string]bool
+
+	// shared holds the columns that are decoded into more than one
+	// output target type, which must be distinguished in the results.
+	shared map[string]bool
+
+	// inLimit is true while the counts of a LIMIT clause are compiled.
+	inLimit bool
+
+	// insert, if not nil, describes the expansion of the column
+	// list of an INSERT statement from the output targets of its
+	// SELECT, which are not bound to result columns.
+	insert *insertSelect
+
+	sql         strings.Builder
+	inputs      []inputBinding
+	outputs     []outputBinding
+	templates   []templateBinding
+	identifiers []identifierBinding
+
+	// limits holds the indexes of the inputs
+	// that are the counts of LIMIT clauses.
+	limits []int
+
+	// lists holds the inputs that are slices of values
+	// compared with a quantifier such as ANY.
+	lists []listBinding
+
+	// operators holds the indexes, among the question marks
+	// outside quotes in the SQL, of those within operators.
+	operators []int
+}
+
+// newCompiler returns a reference to a new compiler that uses the input
+// type information, column aliases, identifier quoting policy and the
+// names written by generated statements.
+func newCompiler(argTypes typeMap, aliases columnAliases, quoting IdentifierQuoting, generated []string) *compiler {
+	c := &compiler{
+		argTypes:  argTypes,
+		quoting:   quoting,
+		aliases:   aliases,
+		generated: make(map[string]bool, len(generated)),
+	}
+	for _, name := range generated {
+		c.generated[name] = true
+	}
+	return c
+}
+
+// writeIdentifier writes the input identifier as for the default dialect,
+// recording its position so that it is written for the dialect of the DB
+// executing the statement; see Dialect. It is quoted if
+// quoted is true or it can not be written bare under the quoting policy,
+// and otherwise only if it is a reserved word. The text written is
+// returned.
+func (c *compiler) writeIdentifier(name string, quoted bool) string {
+	quoted = quoted || !c.quoting.isBare(name)
+	c.identifiers = append(c.identifiers, identifierBinding{offset: c.sql.Len(), name: name, quoted: quoted})
+	text := Dialect{}.identifier(name, quoted)
+	c.sql.WriteString(text)
+	return text
+}
+
+// generatedIdentity returns the name written by a generated statement as
+// the input identity, and whether it is quoted in every dialect, and true,
+// or false if it is not one. A generated name is written as by
+// generatedName, or quoted. Identities that are written in any other way,
+// as a bare keyword is, are not generated names.
+func (c *compiler) generatedIdentity(e *parse.IdentityExpression) (name string, quoted bool, ok bool) {
+	name = parse.Unquote(e.String())
+	if !c.generated[name] {
+		return "", false, false
+	}
+	switch e.String() {
+	case generatedName(name):
+		return name, false, true
+	case quoteIdentifier(name):
+		return name, true, true
+	}
+	return "", false, false
+}
+
+// compileStatement writes the SQL for the input statement expression,
+// accumulating parameter and result column bindings.
+// Columns decoded into more than one type are given
+// aliases so that each can be decoded into its own target.
+// It is an error for a result column to be decoded into more than one
+// field nonetheless, as when a type's columns are selected twice.
+func (c *compiler) compileStatement(exp parse.Expression) error {
+	c.shared = c.sharedColumns(exp)
+	insert, err := c.insertSelectFor(exp)
+	if err != nil {
+		return err
+	}
+	c.insert = insert
+	if err := c.compile(exp); err != nil {
+		return err
+	}
+
+	// The output targets of the SELECT of an INSERT, which precede any
+	// of a RETURNING clause, name the columns of the rows inserted,
+	// rather than receiving result columns.
+	if insert != nil {
+		c.outputs = c.outputs[len(insert.columns):]
+	}
+	return c.checkColumnCollisions()
+}
+
+// checkColumnCollisions returns an error listing the result columns that
+// are decoded into more than one output field, along with those fields.
+func (c *compiler) checkColumnCollisions() error {
+	var columns []string
+	fields := make(map[string][]string)
+	for _, out := range c.outputs {
+		if _, ok := fields[out.column]; !ok {
+			columns = append(columns, out.column)
+		}
+		fields[out.column] = append(fields[out.column], out.typeName+"."+out.field.Name)
+	}
+
+	var collisions []string
+	for _, column := range columns {
+		if len(fields[column]) > 1 {
+			collisions = append(collisions, fmt.Sprintf("%q (%s)", column, strings.Join(fields[column], ", ")))
+		}
+	}
+	if len(collisions) == 0 {
+		return nil
+	}
+	return errors.Errorf("result columns decoded into more than one output field: %s", strings.Join(collisions, "; "))
+}
+
+// sharedColumns returns the columns that are decoded into
+// more than one type by the output targets of the input expression.
+func (c *compiler) sharedColumns(exp parse.Expression) map[string]bool {
+	typesForColumn := make(map[string]map[string]bool)
+	_ = parse.Walk(exp, func(exp parse.Expression) error {
+		e, ok := exp.(*parse.OutputTargetExpression)
+		if !ok {
+			return nil
+		}
+
+		info, err := c.structInfo(e)
+		if err != nil {
+			return nil
+		}
+		columns, err := targetColumns(info, e.Field().String())
+		if err != nil {
+			return nil
+		}
+
+		for _, column := range columns {
+			if typesForColumn[column] == nil {
+				typesForColumn[column] = make(map[string]bool)
+			}
+			typesForColumn[column][e.TypeName().String()] = true
+		}
+		return nil
+	})
+
+	shared := make(map[string]bool)
+	for column, types := range typesForColumn {
+		if len(types) > 1 {
+			shared[column] = true
+		}
+	}
+	return shared
+}
+
+// compile writes the SQL for the input expression,
+// accumulating parameter and result column bindings.
+func (c *compiler) compile(exp parse.Expression) error {
+	switch e := exp.(type) {
+	case *parse.OutputTargetExpression:
+		return c.compileOutputTarget(e)
+	case *parse.InputSourceExpression:
+		return c.compileInputSource(e)
+	case *parse.TemplateExpression:
+		c.templates = append(c.templates, templateBinding{
+			name:   e.Name().String(),
+			offset: c.sql.Len(),
+		})
+	case *parse.SQLExpression:
+		return c.compileList(e.Expressions(), " ")
+	case *parse.PassThroughExpression:
+		return c.compileList(e.Expressions(), " ")
+	case *parse.CommentExpression:
+		c.sql.WriteString(e.Block())
+	case *parse.HintExpression:
+		c.sql.WriteString(e.String())
+	case *parse.IdentityExpression:
+		if name, quoted, ok := c.generatedIdentity(e); ok {
+			c.writeIdentifier(name, quoted)
+			return nil
+		}
+		c.sql.WriteString(e.String())
+	case *parse.QualifiedIdentityExpression:
+		if err := c.compile(e.Qualifier()); err != nil {
+			return err
+		}
+		c.sql.WriteByte('.')
+		return c.compile(e.Name())
+	case *parse.GroupedColumnsExpression:
+		if c.insert != nil && e == c.insert.wildcard {
+			c.compileInsertColumns()
+			return nil
+		}
+		c.sql.WriteByte('(')
+		if err := c.compileList(e.Expressions(), ", "); err != nil {
+			return err
+		}
+		c.sql.WriteByte(')')
+	case *parse.CompoundExpression:
+		return c.compileCompound(e)
+	case *parse.SubqueryExpression:
+		c.sql.WriteByte('(')
+		if err := c.compileList(e.Expressions(), " "); err != nil {
+			return err
+		}
+		c.sql.WriteByte(')')
+	case *parse.WithExpression:
+		c.sql.WriteString(e.Keyword() + " ")
+		return c.compileList(e.Expressions(), ", ")
+	case *parse.CommonTableExpression:
+		c.sql.WriteString(e.Name().String() + " ")
+		if columns := e.Columns(); columns != nil {
+			c.sql.WriteString(columns.String() + " ")
+		}
+		c.sql.WriteString(e.As() + " ")
+		return c.compile(e.Query())
+	case *parse.OrderByExpression:
+		c.sql.WriteString(e.Keyword() + " ")
+		return c.compileList(e.Expressions(), ", ")
+	case *parse.OrderingTermExpression:
+		if err := c.compile(e.Expression()); err != nil {
+			return err
+		}
+		if modifiers := e.Modifiers(); modifiers != "" {
+			c.sql.WriteString(" " + modifiers)
+		}
+	case *parse.ValuesExpression:
+		c.sql.WriteString(e.Keyword() + " ")
+		return c.compileList(e.Expressions(), ", ")
+	case *parse.LimitExpression:
+		c.sql.WriteString(e.Keyword() + " ")
+		c.inLimit = true
+		defer func() { c.inLimit = false }()
+		return c.compileList(e.Expressions(), e.Separator())
+	case *parse.FunctionCallExpression:
+		c.sql.WriteString(e.Name().String())
+		c.sql.WriteByte('(')
+		if err := c.compileList(e.Arguments(), ", "); err != nil {
+			return err
+		}
+		c.sql.WriteByte(')')
+	case *parse.QuantifiedExpression:
+		c.sql.WriteString(e.Quantifier())
+		return c.compile(e.Operand())
+	case *parse.PrefixExpression:
+		c.writeOperator(e.Operator())
+		return c.compile(e.Right())
+	case *parse.InfixExpression:
+		if q, ok := e.Right().(*parse.QuantifiedExpression); ok && q.InputSource() != nil {
+			return c.compileQuantifiedSource(e, q)
+		}
+		if err := c.compile(e.Left()); err != nil {
+			return err
+		}
+		c.sql.WriteString(e.Spacing())
+		c.writeOperator(e.Operator())
+		c.sql.WriteString(e.Spacing())
+		return c.compile(e.Right())
+	default:
+		c.sql.WriteString(exp.String())
+	}
+	return nil
+}
+
+// writeOperator writes the input operator. The question marks of
+// operators such as PostgreSQL's "?|" are recorded, so that they are
+// not mistaken for parameter placeholders; see Dialect.
+func (c *compiler) writeOperator(op string) {
+	for i := strings.Count(op, "?"); i > 0; i-- {
+		c.operators = append(c.operators, len(c.inputs)+len(c.operators))
+	}
+	c.sql.WriteString(op)
+}
+
+// compileQuantifiedSource writes the SQL for the input comparison, the
+// right-hand operand of which is the input quantified input source, as in
+// "id = ANY($Filter.ids)". A source field that is a slice, other than of
+// bytes, or an array, holds the values to which the comparison applies.
+func (c *compiler) compileQuantifiedSource(e *parse.InfixExpression, q *parse.QuantifiedExpression) error {
+	if err := c.compile(e.Left()); err != nil {
+		return err
+	}
+	comparison := e.Spacing() + e.Operator() + e.Spacing() + q.Quantifier() + "("
+	c.sql.WriteString(comparison)
+
+	param := len(c.inputs)
+	if err := c.compileInputSource(q.InputSource()); err != nil {
+		return err
+	}
+	c.sql.WriteByte(')')
+
+	in := c.inputs[param]
+	t := c.argTypes[in.typeName].Type().Field(in.field.Index).Type
+	if t.Kind() != reflect.Array && (t.Kind() != reflect.Slice || t.Elem().Kind() == reflect.Uint8) {
+		return nil
+	}
+
+	list := listBinding{param: param, comparison: comparison}
+	switch quantifier := strings.ToUpper(q.Quantifier()); {
+	case (quantifier == "ANY" || quantifier == "SOME") && e.Operator() == "=":
+		list.in = " IN ("
+	case quantifier == "ALL" && (e.Operator() == "<>" || e.Operator() == "!="):
+		list.in = " NOT IN ("
+	}
+	c.lists = append(c.lists, list)
+	return nil
+}
+
+// compileList writes the SQL for each of the
+// input expressions, separated by the input string.
+// A sequence such as "m.* AS &Manager.*" is compiled by
+// compileColumnsAsOutputTarget. Where the separator is a space, plain SQL
+// that was adjacent to the expression preceding it in the statement, as
+// is "]" in "[c]", is written without it, so that it is passed through
+// as it was written.
+func (c *compiler) compileList(exps []parse.Expression, sep string) error {
+	for i := 0; i < len(exps); i++ {
+		if i > 0 && (sep != " " || !parse.Adjacent(exps[i]) || !endsPlain(exps[i-1])) {
+			c.sql.WriteString(sep)
+		}
+
+		if i+2 < len(exps) && isKeywordIdentity(exps[i+1], "AS") {
+			if target, ok := exps[i+2].(*parse.OutputTargetExpression); ok {
+				if err := c.compileColumnsAsOutputTarget(exps[i], target); err != nil {
+					return err
+				}
+				i += 2
+				continue
+			}
+		}
+
+		if err := c.compile(exps[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// endsPlain returns true if the input expression ends with plain SQL,
+// which is written to the database as it is, rather than with an input
+// source or output target.
+func endsPlain(exp parse.Expression) bool {
+	switch e := exp.(type) {
+	case *parse.IdentityExpression, *parse.QualifiedIdentityExpression:
+		return true
+	case *parse.InfixExpression:
+		return endsPlain(e.Right())
+	case *parse.PrefixExpression:
+		return endsPlain(e.Right())
+	}
+	return false
+}
+
+// compileColumnsAsOutputTarget writes the columns of the input source
+// expression that are to be decoded into the output target. A qualified
+// wildcard source, such as "m.*", expands to the target's columns qualified
+// in the same way, and requires a wildcard target. Each of them is given an
+// alias prefixing the column name with the qualifier, as in "m.id AS m_id",
+// so that columns of the same name from different tables, as in a
+// self-join, can be told apart in a result row. Other columns, and those
+// whose prefixed alias is already taken, are given an alias combining the
+// target's type name and the column name.
+// A column alias replaces the column for its field, taking the qualifier of
+// the source in place of any of its own. A scalar subquery, which may be
+// correlated with the enclosing query, can be decoded into a single field,
+// as in "(SELECT count(*) FROM orders o WHERE o.pid = p.id) AS &Stats.orders",
+// but can not itself contain output targets.
+func (c *compiler) compileColumnsAsOutputTarget(source parse.Expression, e *parse.OutputTargetExpression) error {
+	info, err := c.structInfo(e)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := source.(*parse.SubqueryExpression); ok {
+		err := parse.Walk(source, func(exp parse.Expression) error {
+			if _, ok := exp.(*parse.OutputTargetExpression); ok {
+				return errors.Errorf("subquery decoded into output target %q can not contain output target %q", e.String(), exp.String())
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var qualifier string
+	wildcard := source.String() == "*"
+	if q, ok := source.(*parse.QualifiedIdentityExpression); ok && q.Name().String() == "*" {
+		qualifier = q.Qualifier().String() + "."
+		wildcard = true
+	}
+	if wildcard != (e.Field().String() == "*") {
+		return errors.Errorf("columns %q can not be decoded into output target %q", source.String(), e.String())
+	}
+
+	columns, err := targetColumns(info, e.Field().String())
+	if err != nil {
+		return err
+	}
+
+	typeName := e.TypeName().String()
+	for i, column := range columns {
+		if i > 0 {
+			c.sql.WriteString(", ")
+		}
+
+		out := outputBinding{
+			column:   typeName + "." + column,
+			typeName: typeName,
+			field:    info.Fields[column],
+			tag:      column,
+		}
+
+		if wildcard {
+			c.sql.WriteString(qualifier)
+			var selected string
+			if aliased, ok := c.aliases.column(typeName, column); ok {
+				selected = aliased
+				if i := strings.LastIndex(aliased, "."); i >= 0 && qualifier != "" {
+					selected = aliased[i+1:]
+				}
+				c.sql.WriteString(selected)
+			} else {
+				selected = c.writeIdentifier(column, false)
+			}
+
+			prefixed := strings.ReplaceAll(qualifier, ".", "_") + column
+			if qualifier != "" && !c.hasOutputColumn(prefixed) {
+				out.column, out.source = prefixed, qualifier+selected
+			}
+		} else if err := c.compile(source); err != nil {
+			return err
+		}
+
+		c.sql.WriteString(" AS ")
+		c.writeIdentifier(out.column, out.source == "")
+		c.outputs = append(c.outputs, out)
+	}
+	return nil
+}
+
+// hasOutputColumn returns true if a result column with the
+// input name is already decoded into an output target.
+func (c *compiler) hasOutputColumn(column string) bool {
+	for _, out := range c.outputs {
+		if out.column == column {
+			return true
+		}
+	}
+	return false
+}
+
+// isKeywordIdentity returns true if the input expression
+// is an identity for the input keyword, regardless of case.
+func isKeywordIdentity(exp parse.Expression, keyword string) bool {
+	_, ok := exp.(*parse.IdentityExpression)
+	return ok && strings.EqualFold(exp.String(), keyword)
+}
+
+// compileCompound writes the SQL for each query combined in the compound.
+// Queries with output targets must all decode the same columns into the
+// same types, so that every result row decodes in the same way.
+// Only the first query's output bindings are retained.
+func (c *compiler) compileCompound(e *parse.CompoundExpression) error {
+	var first []outputBinding
+	for i, branch := range e.Expressions() {
+		if i > 0 {
+			c.sql.WriteString(" " + e.Operator(i) + " ")
+		}
+
+		before := len(c.outputs)
+		if err := c.compile(branch); err != nil {
+			return err
+		}
+
+		outputs := c.outputs[before:]
+		if len(outputs) == 0 {
+			continue
+		}
+		if first == nil {
+			first = outputs
+			continue
+		}
+
+		if !sameOutputs(first, outputs) {
+			return errors.Errorf("output targets in %q are inconsistent with those of the preceding query", branch.String())
+		}
+		c.outputs = c.outputs[:before]
+	}
+	return nil
+}
+
+// sameOutputs returns true if the input output bindings
+// decode the same columns into the same types.
+func sameOutputs(a, b []outputBinding) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].column != b[i].column || a[i].typeName != b[i].typeName {
+			return false
+		}
+	}
+	return true
+}
+
+// compileOutputTarget writes the columns that are to be decoded into the
+// target type. A wildcard field expands to every tagged field of the type.
+// Columns named by reserved words are quoted. A field with a column alias
+// is selected from the aliased column under the name of its tag. A column
+// shared with another target type is selected under an alias combining the
+// type name and the column name, as by compileColumnsAsOutputTarget.
+func (c *compiler) compileOutputTarget(e *parse.OutputTargetExpression) error {
+	info, err := c.structInfo(e)
+	if err != nil {
+		return err
+	}
+
+	columns, err := targetColumns(info, e.Field().String())
+	if err != nil {
+		return err
+	}
+
+	typeName := e.TypeName().String()
+	for i, column := range columns {
+		if i > 0 {
+			c.sql.WriteString(", ")
+		}
+
+		aliased, hasAlias := c.aliases.column(typeName, column)
+		result := column
+		switch {
+		case c.shared[column]:
+			result = typeName + "." + column
+			if hasAlias {
+				c.sql.WriteString(aliased)
+			} else {
+				c.writeIdentifier(column, false)
+			}
+			c.sql.WriteString(" AS ")
+			c.writeIdentifier(result, true)
+		case hasAlias:
+			c.sql.WriteString(aliased + " AS ")
+			c.writeIdentifier(column, false)
+		default:
+			c.writeIdentifier(column, false)
+		}
+
+		c.outputs = append(c.outputs, outputBinding{
+			column:   result,
+			typeName: typeName,
+			field:    info.Fields[column],
+			tag:      column,
+		})
+	}
+	return nil
+}
+
+// compileInputSource writes a parameter placeholder
+// for the field of the source type.
+func (c *compiler) compileInputSource(e *parse.InputSourceExpression) error {
+	info, err := c.structInfo(e)
+	if err != nil {
+		return err
+	}
+
+	column := e.Field().String()
+	if column == "*" {
+		return errors.Errorf("input source %q must reference a single field", e.String())
+	}
+
+	typeName := e.TypeName().String()
+	field, ok := info.Fields[column]
+	if !ok {
+		return NewErrFieldNotPresent(typeName, column)
+	}
+
+	if c.inLimit {
+		c.limits = append(c.limits, len(c.inputs))
+	}
+	c.sql.WriteByte('?')
+	c.inputs = append(c.inputs, inputBinding{
+		typeName: typeName,
+		column:   column,
+		field:    field,
+	})
+	return nil
+}
+
+// structInfo returns the struct reflection information
+// for the type named in the input expression.
+func (c *compiler) structInfo(e parse.TypeMappingExpression) (sqlairreflect.Struct, error) {
+	typeName := e.TypeName().String()
+
+	info, ok := c.argTypes[typeName]
+	if !ok {
+		return sqlairreflect.Struct{}, NewErrTypeInfoNotPresent(typeName)
+	}
+
+	st, ok := info.(sqlairreflect.Struct)
+	if !ok {
+		return sqlairreflect.Struct{}, errors.Errorf("type %q in %q is not a struct", typeName, e.String())
+	}
+	return st, nil
+}
+
+// targetColumns returns the columns of the input struct that are referenced
+// by the field of a type mapping expression. Those of "*" are in the order
+// in which the struct's fields are declared, so that the SQL generated for
+// a statement is the same whenever it is prepared.
+func targetColumns(info sqlairreflect.Struct, field string) ([]string, error) {
+	if field != "*" {
+		if _, ok := info.Fields[field]; !ok {
+			return nil, NewErrFieldNotPresent(info.Name(), field)
+		}
+		return []string{field}, nil
+	}
+
+	return info.Tags(), nil
+}
@@ -0,0 +1,395 @@
+package sqlair
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileExpandsOutputTarget(t *testing.T) {
+	stmt, err := prepareExpression(
+		expressionForStatement("SELECT &Person.* FROM person WHERE id = $Person.id"),
+		[]any{sqlairtesting.Person{}},
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "SELECT id, name FROM person WHERE id = ?", stmt.sql)
+
+	assert.Len(t, stmt.inputs, 1)
+	assert.Equal(t, "Person", stmt.inputs[0].typeName)
+	assert.Equal(t, "ID", stmt.inputs[0].field.Name)
+
+	assert.Len(t, stmt.outputs, 2)
+	assert.Equal(t, "id", stmt.outputs[0].column)
+	assert.Equal(t, "ID", stmt.outputs[0].field.Name)
+	assert.Equal(t, "name", stmt.outputs[1].column)
+	assert.Equal(t, "Name", stmt.outputs[1].field.Name)
+}
+
+func TestCompileColumnOrder(t *testing.T) {
+	type Reversed struct {
+		Zed   string `db:"zed"`
+		Alpha string `db:"alpha"`
+		Mid   string `db:"mid"`
+	}
+
+	// Expanded columns are in declaration order every time.
+	for i := 0; i < 10; i++ {
+		stmt, err := Prepare("SELECT &Reversed.* FROM t", Reversed{})
+		assert.Nil(t, err)
+		assert.Equal(t, "SELECT zed, alpha, mid FROM t", stmt.sql)
+	}
+}
+
+func TestCompileValues(t *testing.T) {
+	stmt, err := Prepare("INSERT INTO person (id, name) VALUES ($Person.id, $Person.name), (2, 'Onos')", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	assert.Equal(t, "INSERT INTO person (id, name) VALUES (?, ?), (2, 'Onos')", stmt.sql)
+	assert.Len(t, stmt.inputs, 2)
+
+	_, err = Prepare("INSERT INTO person (id, name) VALUES ($Person.id, $Person.name), (2)", sqlairtesting.Person{})
+	assert.EqualError(t, err, "expected 2 values in row, got 1 at line 1, column 66")
+}
+
+func TestCompileJSONOperators(t *testing.T) {
+	stmt, err := Prepare(`
+SELECT data->>'name' AS &Person.name
+  FROM person
+ WHERE data @> $Person.name AND data ? 'id' AND data ?| '{a,b}'
+ LIMIT $PageSpec.size`, sqlairtesting.Person{}, PageSpec{})
+	assert.Nil(t, err)
+
+	// Key existence operators are written as they are, and are
+	// not mistaken for placeholders when those are numbered.
+	assert.Equal(t, `SELECT data ->> 'name' AS "Person.name" FROM person `+
+		`WHERE data @> ? AND data ? 'id' AND data ?| '{a,b}' LIMIT ?`, stmt.sql)
+
+	args, err := stmt.bindInputs(context.Background(), []any{sqlairtesting.Person{Name: "{}"}, PageSpec{Size: 10}})
+	assert.Nil(t, err)
+
+	query, params, err := NewDB(nil, WithDialect(Dialect{NumberedPlaceholders: true})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT data ->> 'name' AS "Person.name" FROM person `+
+		`WHERE data @> $1 AND data ? 'id' AND data ?| '{a,b}' LIMIT $2`, query)
+	assert.Equal(t, []any{"{}", 10}, params)
+
+	query, params, err = NewDB(nil, WithDialect(Dialect{NumberedPlaceholders: true, InlineLimits: true})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT data ->> 'name' AS "Person.name" FROM person `+
+		`WHERE data @> $1 AND data ? 'id' AND data ?| '{a,b}' LIMIT 10`, query)
+	assert.Equal(t, []any{"{}"}, params)
+
+	// Without numbered placeholders, the driver
+	// would mistake the operators for placeholders.
+	_, _, err = NewDB(nil).sqlFor(stmt, args)
+	assert.EqualError(t, err, `operators containing "?" require a dialect with numbered placeholders`)
+
+	data, err := json.Marshal(stmt)
+	assert.Nil(t, err)
+	loaded, err := LoadStatement(data, sqlairtesting.Person{}, PageSpec{})
+	assert.Nil(t, err)
+	assert.Equal(t, stmt.operators, loaded.operators)
+}
+
+func TestCompileSingleFieldOutputTarget(t *testing.T) {
+	stmt, err := prepareExpression(
+		expressionForStatement("SELECT &Person.name FROM person"),
+		[]any{sqlairtesting.Person{}},
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "SELECT name FROM person", stmt.sql)
+	assert.Len(t, stmt.outputs, 1)
+	assert.Equal(t, "Name", stmt.outputs[0].field.Name)
+}
+
+func TestCompileMissingFieldError(t *testing.T) {
+	_, err := prepareExpression(
+		expressionForStatement("SELECT &Person.* FROM person WHERE id = $Person.surname"),
+		[]any{sqlairtesting.Person{}},
+	)
+	assert.Equal(t, NewErrFieldNotPresent("Person", "surname"), err)
+}
+
+func TestCompileInputSourceWildcardError(t *testing.T) {
+	_, err := prepareExpression(
+		expressionForStatement("SELECT name FROM person WHERE id = $Person.*"),
+		[]any{sqlairtesting.Person{}},
+	)
+	assert.EqualError(t, err, `input source "$Person.*" must reference a single field`)
+}
+
+func TestCompileNonStructError(t *testing.T) {
+	type count int
+
+	_, err := prepareExpression(
+		expressionForStatement("SELECT &count.* FROM person"),
+		[]any{count(0)},
+	)
+	assert.EqualError(t, err, `type "count" in "&count.*" is not a struct`)
+}
+
+func TestCompileNestedOperators(t *testing.T) {
+	stmt, err := Prepare(
+		"SELECT &Person.* FROM person WHERE (id = $Person.id OR name = $Person.name) AND NOT id = '-1'",
+		sqlairtesting.Person{},
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "SELECT id, name FROM person WHERE (id = ? OR name = ?) AND NOT id = '-1'", stmt.sql)
+	assert.Len(t, stmt.inputs, 2)
+	assert.Equal(t, "ID", stmt.inputs[0].field.Name)
+	assert.Equal(t, "Name", stmt.inputs[1].field.Name)
+}
+
+func TestCompileFunctionCallArguments(t *testing.T) {
+	stmt, err := Prepare(
+		"SELECT &Person.name FROM person WHERE name = coalesce($Person.name, 'Lorn')",
+		sqlairtesting.Person{},
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "SELECT name FROM person WHERE name = coalesce(?, 'Lorn')", stmt.sql)
+	assert.Len(t, stmt.inputs, 1)
+}
+
+func TestCompileCommonTableExpression(t *testing.T) {
+	stmt, err := Prepare(`
+WITH named (id) AS (SELECT id FROM person WHERE name = $Person.name)
+SELECT &Person.* FROM person WHERE id IN (SELECT id FROM named)`,
+		sqlairtesting.Person{},
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t,
+		"WITH named (id) AS (SELECT id FROM person WHERE name = ?) "+
+			"SELECT id, name FROM person WHERE id IN (SELECT id FROM named)",
+		stmt.sql)
+	assert.Len(t, stmt.inputs, 1)
+}
+
+func TestCompileCommonTableExpressionMissingTypeError(t *testing.T) {
+	_, err := Prepare(
+		"WITH m AS (SELECT id FROM person WHERE id = $Manager.id) SELECT &Person.* FROM person JOIN m",
+		sqlairtesting.Person{},
+	)
+	assert.Equal(t, NewErrTypeInfoNotPresent("Manager"), err)
+}
+
+func TestCompileCompoundQuery(t *testing.T) {
+	type Manager sqlairtesting.Person
+
+	stmt, err := Prepare(`
+SELECT &Person.* FROM person WHERE id = $Person.id
+UNION
+SELECT &Person.* FROM manager WHERE id = $Manager.id
+UNION ALL
+SELECT id, name FROM contractor`,
+		sqlairtesting.Person{}, Manager{},
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t,
+		"SELECT id, name FROM person WHERE id = ? "+
+			"UNION SELECT id, name FROM manager WHERE id = ? "+
+			"UNION ALL SELECT id, name FROM contractor",
+		stmt.sql)
+	assert.Len(t, stmt.inputs, 2)
+	assert.Len(t, stmt.outputs, 2)
+}
+
+func TestCompileCompoundQueryInconsistentOutputsError(t *testing.T) {
+	_, err := Prepare(
+		"SELECT &Person.* FROM person UNION SELECT &Person.name FROM manager",
+		sqlairtesting.Person{},
+	)
+	assert.EqualError(t, err,
+		`output targets in "SELECT &Person.name FROM manager" are inconsistent with those of the preceding query`)
+}
+
+func TestCompileLimitOffsetInputs(t *testing.T) {
+	type Page struct {
+		Size   int `db:"size"`
+		Offset int `db:"offset"`
+	}
+
+	stmt, err := Prepare(
+		"SELECT &Person.* FROM person ORDER BY name DESC LIMIT $Page.size OFFSET $Page.offset",
+		sqlairtesting.Person{}, Page{},
+	)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "SELECT id, name FROM person ORDER BY name DESC LIMIT ? OFFSET ?", stmt.sql)
+	if assert.Len(t, stmt.inputs, 2) {
+		assert.Equal(t, "Size", stmt.inputs[0].field.Name)
+		assert.Equal(t, "Offset", stmt.inputs[1].field.Name)
+	}
+}
+
+func TestCompileCasts(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id::text = $Person.id::text AND created > now() - '1 day'::interval", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person WHERE id::text = ?::text AND created > now() - '1 day'::interval", stmt.sql)
+}
+
+func TestCompilePassesThroughLiterals(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected string
+	}{
+		{"SELECT 0x1F, 1e3, 2.5E-3, .5 FROM t", "SELECT 0x1F, 1e3, 2.5E-3, .5 FROM t"},
+		{`SELECT "a b", [c] FROM t`, `SELECT "a b", [c] FROM t`},
+		{`SELECT e'\n', X'1F' FROM t`, `SELECT e'\n', X'1F' FROM t`},
+		{"SELECT @var, @@version", "SELECT @var, @@version"},
+		{"SELECT &Person.* FROM person WHERE id = $Person.id*.5", "SELECT id, name FROM person WHERE id = ? * .5"},
+	}
+	for _, test := range tests {
+		stmt, err := Prepare(test.stmt, sqlairtesting.Person{}, WithSuperfluousTypes(true))
+		if assert.Nil(t, err, test.stmt) {
+			assert.Equal(t, test.expected, stmt.sql)
+		}
+	}
+}
+
+func TestCompileQuotedIdentifiers(t *testing.T) {
+	stmt, err := Prepare("SELECT \"order\", `group`, p.\"select\" AS &Person.name FROM \"my table\" AS p ORDER BY \"order\"", sqlairtesting.Person{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "SELECT \"order\", `group`, p.\"select\" AS \"Person.name\" FROM \"my table\" AS p ORDER BY \"order\"", stmt.sql)
+	}
+}
+
+func TestCompileInputArithmetic(t *testing.T) {
+	type Now struct {
+		Time   int `db:"time"`
+		Period int `db:"period"`
+	}
+
+	stmt, err := Prepare(
+		"SELECT &Person.* FROM person WHERE expires_at < $Now.time + 3600 AND (expires_at - $Now.time) % $Now.period = 0",
+		sqlairtesting.Person{}, Now{})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person WHERE expires_at < ? + 3600 AND (expires_at - ?) % ? = 0", stmt.sql)
+	if assert.Len(t, stmt.inputs, 3) {
+		assert.Equal(t, "Time", stmt.inputs[0].field.Name)
+		assert.Equal(t, "Time", stmt.inputs[1].field.Name)
+		assert.Equal(t, "Period", stmt.inputs[2].field.Name)
+	}
+}
+
+func TestCompileQuantified(t *testing.T) {
+	stmt, err := Prepare(`
+SELECT &Person.* FROM person AS p
+WHERE NOT EXISTS (SELECT 1 FROM manager AS m WHERE m.id = p.id AND m.name = $Person.name)
+AND p.id = ANY (SELECT id FROM team)`, sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t,
+		"SELECT id, name FROM person AS p WHERE NOT EXISTS(SELECT 1 FROM manager AS m WHERE m.id = p.id AND m.name = ?) AND p.id = ANY(SELECT id FROM team)",
+		stmt.sql)
+	assert.Empty(t, stmt.lists)
+}
+
+func TestCompileComments(t *testing.T) {
+	stmt, err := Prepare(`
+-- Get a person by name.
+SELECT /*+ INDEX(person idx_name) */ &Person.*
+  FROM person -- Not the manager table.
+ WHERE name = $Person.name`, sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	assert.Equal(t,
+		"SELECT /* Get a person by name. */ /*+ INDEX(person idx_name) */ id, name FROM person /* Not the manager table. */ WHERE name = ?",
+		stmt.sql)
+
+	// Hints are written verbatim, even as the first expression.
+	stmt, err = Prepare("/*+ MAX_EXECUTION_TIME(1000) */ SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT /*+ MAX_EXECUTION_TIME(1000) */ id, name FROM person", stmt.sql)
+}
+
+func TestCompileColumnsAsOutputTarget(t *testing.T) {
+	stmt, err := Prepare(
+		"SELECT p.* AS &Person.*, upper(p.name) AS &Upper.name FROM person AS p",
+		sqlairtesting.Person{}, Alias("Upper", sqlairtesting.Person{}))
+	assert.Nil(t, err)
+
+	expected := `SELECT p.id AS p_id, p.name AS p_name , upper(p.name) AS "Upper.name" FROM person AS p`
+	assert.Equal(t, expected, stmt.sql)
+
+	assert.Len(t, stmt.outputs, 3)
+	assert.Equal(t, "p_id", stmt.outputs[0].column)
+	assert.Equal(t, "p.id", stmt.outputs[0].source)
+	assert.Equal(t, "Upper.name", stmt.outputs[2].column)
+	assert.Equal(t, "", stmt.outputs[2].source)
+	assert.Equal(t, "Name", stmt.outputs[2].field.Name)
+}
+
+func TestCompilePrefixedAliases(t *testing.T) {
+	stmt, err := Prepare(`
+SELECT m.* AS &Manager.*, p.* AS &Person.*, p.* AS &Copy.*
+FROM person AS p JOIN person AS m ON p.id = m.id`,
+		sqlairtesting.Person{}, Alias("Manager", sqlairtesting.Person{}), Alias("Copy", sqlairtesting.Person{}))
+	assert.Nil(t, err)
+
+	// Columns whose prefixed alias is taken are aliased by type name.
+	assert.Equal(t,
+		`SELECT m.id AS m_id, m.name AS m_name , p.id AS p_id, p.name AS p_name , p.id AS "Copy.id", p.name AS "Copy.name" FROM person AS p JOIN person AS m ON p.id = m.id`,
+		stmt.sql)
+
+	expected := `m.id AS m_id -> Manager.ID
+m.name AS m_name -> Manager.Name
+p.id AS p_id -> Person.ID
+p.name AS p_name -> Person.Name
+Copy.id -> Copy.ID
+Copy.name -> Copy.Name
+`
+	assert.Equal(t, expected, stmt.BindingPlan().String())
+
+	data, err := json.Marshal(stmt)
+	assert.Nil(t, err)
+	loaded, err := LoadStatement(data,
+		sqlairtesting.Person{}, Alias("Manager", sqlairtesting.Person{}), Alias("Copy", sqlairtesting.Person{}))
+	if assert.Nil(t, err) {
+		assert.Equal(t, stmt.outputs, loaded.outputs)
+	}
+}
+
+func TestCompileColumnCollisions(t *testing.T) {
+	type Tagged struct {
+		ID string `db:"p_id"`
+	}
+
+	tests := []struct {
+		stmt     string
+		args     []any
+		expected string
+	}{{
+		"SELECT &Person.*, &Person.name FROM person",
+		[]any{sqlairtesting.Person{}},
+		`result columns decoded into more than one output field: "name" (Person.Name, Person.Name)`,
+	}, {
+		"SELECT p.* AS &Person.*, &Tagged.* FROM person AS p",
+		[]any{sqlairtesting.Person{}, Tagged{}},
+		`result columns decoded into more than one output field: "p_id" (Person.ID, Tagged.ID)`,
+	}}
+
+	for _, test := range tests {
+		_, err := Prepare(test.stmt, test.args...)
+		assert.EqualError(t, err, test.expected, test.stmt)
+	}
+
+	// Columns shared by different types are given aliases, so do not collide.
+	_, err := Prepare("SELECT &Person.*, &Manager.* FROM person", sqlairtesting.Person{}, Alias("Manager", sqlairtesting.Person{}))
+	assert.Nil(t, err)
+}
+
+func TestCompileColumnsAsOutputTargetErrors(t *testing.T) {
+	_, err := Prepare("SELECT p.* AS &Person.name FROM person AS p", sqlairtesting.Person{})
+	assert.EqualError(t, err, `columns "p.*" can not be decoded into output target "&Person.name"`)
+
+	_, err = Prepare("SELECT p.name AS &Person.* FROM person AS p", sqlairtesting.Person{})
+	assert.EqualError(t, err, `columns "p.name" can not be decoded into output target "&Person.*"`)
+}
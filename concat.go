@@ -0,0 +1,92 @@
+package sqlair
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/canonical/sqlair/internal/parse"
+)
+
+// ConcatenationHook is called by Prepare for each statement in which a
+// string or number literal is compared directly with a column, as in
+// "name = 'Fred'", with the literals so compared. Such literals are often
+// values concatenated into the DSL, rather than supplied as inputs, which
+// allows their text to change the meaning of the statement. The hook can
+// report the statement, such as by logging it or by failing a test.
+// Statements prepared with Unsafe are not passed to the hook.
+type ConcatenationHook func(s *Statement, literals []Token)
+
+// concatenationHook holds the hook set by SetConcatenationHook.
+var concatenationHook struct {
+	sync.RWMutex
+	fn ConcatenationHook
+}
+
+// SetConcatenationHook sets the hook called by Prepare for statements
+// that appear to be built by concatenation, replacing any set before.
+// A nil hook removes it.
+//
+// Example:
+//
+//     sqlair.SetConcatenationHook(func(s *sqlair.Statement, literals []sqlair.Token) {
+//         log.Printf("statement may be built by concatenation: %v", literals)
+//     })
+//
+func SetConcatenationHook(fn ConcatenationHook) {
+	concatenationHook.Lock()
+	defer concatenationHook.Unlock()
+
+	concatenationHook.fn = fn
+}
+
+// Unsafe returns an option marking the statement as one whose literals are
+// deliberately written into its DSL, so that they are not reported by its
+// Warnings or passed to the ConcatenationHook. It must not be used for
+// statements built from untrusted input.
+//
+// Example:
+//
+//     stmt, err := sqlair.Prepare(`
+//     SELECT &Person.*
+//       FROM person
+//      WHERE status = 'active'`, sqlair.Unsafe(), Person{})
+//
+func Unsafe() PrepareOption {
+	return func(o *prepareOptions) {
+		o.unsafe = true
+	}
+}
+
+// concatenatedLiterals returns the literals of the statement that are
+// compared directly with a column, unless it is marked Unsafe.
+func (s *Statement) concatenatedLiterals() []Token {
+	if s.unsafe {
+		return nil
+	}
+	return parse.ComparedLiterals(s.expression)
+}
+
+// reportConcatenation passes the statement to the ConcatenationHook,
+// if one is set and the statement appears to be built by concatenation.
+func (s *Statement) reportConcatenation() {
+	concatenationHook.RLock()
+	fn := concatenationHook.fn
+	concatenationHook.RUnlock()
+
+	if fn == nil {
+		return
+	}
+	if literals := s.concatenatedLiterals(); len(literals) > 0 {
+		fn(s, literals)
+	}
+}
+
+// concatenationWarnings returns a warning for each literal of the
+// statement that is compared directly with a column.
+func (s *Statement) concatenationWarnings() []string {
+	var warnings []string
+	for _, tok := range s.concatenatedLiterals() {
+		warnings = append(warnings, fmt.Sprintf("literal %s at line %d, column %d is compared with a column, so may have been concatenated into the statement; supply it as an input, or prepare the statement with Unsafe", tok.Literal, tok.Pos.Line, tok.Pos.Column))
+	}
+	return warnings
+}
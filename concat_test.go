@@ -0,0 +1,55 @@
+package sqlair
+
+import (
+	"encoding/json"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcatenationWarnings(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE name = 'Fred' OR id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"literal 'Fred' at line 1, column 43 is compared with a column, so may have been concatenated into the statement; supply it as an input, or prepare the statement with Unsafe",
+	}, stmt.Warnings())
+
+	stmt, err = Prepare("SELECT &Person.* FROM person WHERE name = 'Fred'", Unsafe(), sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Empty(t, stmt.Warnings())
+
+	// Unsafe is retained by derived and loaded statements.
+	derived, err := stmt.Derive(WithLimit("LIMIT 10"))
+	assert.Nil(t, err)
+	assert.Empty(t, derived.Warnings())
+
+	data, err := json.Marshal(stmt)
+	assert.Nil(t, err)
+	loaded, err := LoadStatement(data, sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Empty(t, loaded.Warnings())
+}
+
+func TestConcatenationHook(t *testing.T) {
+	var reported []string
+	SetConcatenationHook(func(s *Statement, literals []Token) {
+		for _, tok := range literals {
+			reported = append(reported, tok.Literal)
+		}
+	})
+	defer SetConcatenationHook(nil)
+
+	_, err := Prepare("SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Empty(t, reported)
+
+	_, err = Prepare("DELETE FROM person WHERE id = 3 OR name LIKE 'F%'")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3", "'F%'"}, reported)
+
+	reported = nil
+	_, err = Prepare("DELETE FROM person WHERE id = 3", Unsafe())
+	assert.Nil(t, err)
+	assert.Empty(t, reported)
+}
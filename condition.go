@@ -0,0 +1,227 @@
+package sqlair
+
+import (
+	"strings"
+
+	"github.com/canonical/sqlair/internal/parse"
+	"github.com/pkg/errors"
+)
+
+// Condition is a prepared Sqlair DSL predicate, such as
+// "name = $Person.name", that can be added to the WHERE
+// clause of a Statement when it is known at runtime to apply.
+type Condition struct {
+	// expression is the parsed expression tree for this condition.
+	expression parse.Expression
+
+	// argTypes holds the reflection info for types used in this condition.
+	argTypes typeMap
+
+	// backslashEscapes is true if a backslash within a string literal
+	// of the condition escapes the character following it.
+	backslashEscapes bool
+
+	// generated holds the names written into the DSL of a generated
+	// condition, such as that of KeyCondition, by generatedName.
+	generated []string
+}
+
+// PrepareCondition accepts a raw DSL predicate and optionally, objects from
+// which to infer type information. The predicate is parsed and its input
+// sources are validated against the objects in the same way as Prepare.
+// A condition can not contain output targets.
+func PrepareCondition(cond string, args ...any) (*Condition, error) {
+	opts, args := optionsFromArgs(args)
+
+	exp, err := opts.limits.newParser(newLexer(cond, opts.escapes)).Run()
+	if err != nil {
+		if opts.location != nil {
+			err = opts.location.locateError(cond, err)
+		}
+		return nil, limitError(err)
+	}
+	if len(exp.Expressions()) == 0 {
+		return nil, errors.New("empty condition")
+	}
+	err = parse.Walk(exp, func(e parse.Expression) error {
+		if _, ok := e.(*parse.OutputTargetExpression); ok {
+			return errors.Errorf("condition %q can not contain output target %q", cond, e.String())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	argTypes, err := typesInCache(opts.typeCache(), args)
+	if err != nil {
+		return nil, err
+	}
+	if err := interpret(exp, argTypes); err != nil {
+		return nil, err
+	}
+
+	return &Condition{
+		expression:       exp,
+		argTypes:         argTypes,
+		backslashEscapes: opts.escapes,
+		generated:        opts.generated,
+	}, nil
+}
+
+// whereClauseFollowers are the keywords beginning the clauses that may
+// follow a WHERE clause, before which one is added to a statement that has
+// none, as is one before a final semicolon. They are matched only where
+// they begin a clause; see parse.IsClauseKeyword. The end of an existing
+// WHERE clause is found by parse.PredicateEnd.
+var whereClauseFollowers = map[string]bool{
+	"GROUP": true, "HAVING": true, "WINDOW": true, "ORDER": true,
+	"LIMIT": true, "OFFSET": true, "FETCH": true, "FOR": true,
+	"LOCK": true, "RETURNING": true,
+}
+
+// Where returns a new Statement in which the input conditions are ANDed
+// into the WHERE clause of this statement. If this statement has no WHERE
+// clause, one is added. The statement must be a single SELECT, UPDATE or
+// DELETE; this statement is not modified. Neither the statement nor the
+// conditions are parsed again: their expression trees are spliced together
+// and the result compiled. If the statement or any of the conditions was
+// prepared with WithBackslashEscapes, so is the new statement.
+func (s *Statement) Where(conditions ...*Condition) (*Statement, error) {
+	if len(conditions) == 0 {
+		return s, nil
+	}
+
+	argTypes := s.argTypes
+	escapes := s.backslashEscapes
+	generated := s.generated
+	for _, cond := range conditions {
+		var err error
+		if argTypes, err = mergeTypes(argTypes, cond.argTypes); err != nil {
+			return nil, err
+		}
+		escapes = escapes || cond.backslashEscapes
+		generated = append(generated[:len(generated):len(generated)], cond.generated...)
+	}
+
+	exp, err := withConditions(s.expression, conditions)
+	if err != nil {
+		return nil, err
+	}
+	merged := *s
+	merged.generated = generated
+	stmt, err := merged.recompile(exp, argTypes)
+	if err != nil {
+		return nil, err
+	}
+	stmt.backslashEscapes = escapes
+	return stmt, nil
+}
+
+// withConditions returns a new expression tree for the input statement, with
+// the input conditions ANDed into its WHERE clause. Each predicate is
+// parenthesised so that operators within it can not bind to those of
+// another. Only the top level of the tree is copied; no existing expression
+// is modified.
+func withConditions(stmt parse.Expression, conditions []*Condition) (parse.Expression, error) {
+	children := stmt.Expressions()
+
+	start := 0
+	if len(children) > 0 {
+		if _, ok := children[0].(*parse.WithExpression); ok {
+			start = 1
+		}
+	}
+	if start >= len(children) || !isOneOfKeywords(children[start], "SELECT", "UPDATE", "DELETE") {
+		return nil, errors.Errorf("conditions can only be added to a SELECT, UPDATE or DELETE statement, got %q", stmt.String())
+	}
+
+	// Find the predicate of any existing WHERE clause,
+	// and the position at which the clause ends.
+	where, end := -1, len(children)
+	for i := start; i < len(children); i++ {
+		switch child := children[i].(type) {
+		case *parse.OrderByExpression, *parse.LimitExpression:
+			end = i
+		case *parse.IdentityExpression:
+			if strings.EqualFold(child.String(), "WHERE") {
+				where = i
+				end = parse.PredicateEnd(children, i+1)
+			} else if child.String() == ";" || parse.IsClauseKeyword(children, i, whereClauseFollowers) {
+				end = i
+			}
+		}
+		if where >= 0 || end < len(children) {
+			break
+		}
+	}
+	exp := &parse.SQLExpression{}
+	var predicates [][]parse.Expression
+
+	if where < 0 {
+		for _, child := range children[:end] {
+			exp.AppendExpression(child)
+		}
+	} else {
+		for _, child := range children[:where] {
+			exp.AppendExpression(child)
+		}
+		predicates = append(predicates, children[where+1:end])
+	}
+	for _, cond := range conditions {
+		predicates = append(predicates, cond.expression.Expressions())
+	}
+
+	exp.AppendExpression(parse.NewIdentityExpression(keywordToken("WHERE")))
+	for i, predicate := range predicates {
+		if i > 0 {
+			exp.AppendExpression(parse.NewIdentityExpression(keywordToken("AND")))
+		}
+		exp.AppendExpression(groupExpressions(predicate))
+	}
+
+	for _, child := range children[end:] {
+		exp.AppendExpression(child)
+	}
+	return exp, nil
+}
+
+// groupExpressions returns a parenthesised expression
+// comprising the input sequence of expressions.
+func groupExpressions(exps []parse.Expression) parse.Expression {
+	group := &parse.GroupedColumnsExpression{}
+	if len(exps) == 1 {
+		group.AppendExpression(exps[0])
+		return group
+	}
+
+	seq := &parse.SQLExpression{}
+	for _, exp := range exps {
+		seq.AppendExpression(exp)
+	}
+	group.AppendExpression(seq)
+	return group
+}
+
+// isOneOfKeywords returns true if the input expression
+// is an identity for one of the input keywords.
+func isOneOfKeywords(exp parse.Expression, keywords ...string) bool {
+	if _, ok := exp.(*parse.IdentityExpression); !ok {
+		return false
+	}
+	for _, keyword := range keywords {
+		if strings.EqualFold(exp.String(), keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// keywordToken returns a token for the input keyword,
+// for use in expressions that are not parsed from a statement.
+func keywordToken(keyword string) parse.Token {
+	return parse.Token{
+		Type:    parse.KEYWORD,
+		Literal: keyword,
+	}
+}
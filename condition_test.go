@@ -0,0 +1,136 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhereAddsClause(t *testing.T) {
+	type Filter struct {
+		Prefix string `db:"prefix"`
+	}
+
+	stmt, err := Prepare("SELECT &Person.* FROM person ORDER BY id LIMIT 10", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	cond, err := PrepareCondition("name LIKE $Filter.prefix || '%' OR id = '3'", Filter{})
+	assert.Nil(t, err)
+
+	filtered, err := stmt.Where(cond)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "SELECT id, name FROM person WHERE (name LIKE ? || '%' OR id = '3') ORDER BY id LIMIT 10", filtered.sql)
+	assert.Len(t, filtered.inputs, 1)
+
+	// The original statement is unchanged.
+	assert.Equal(t, "SELECT id, name FROM person ORDER BY id LIMIT 10", stmt.sql)
+}
+
+func TestWhereExtendsClause(t *testing.T) {
+	type Filter struct {
+		Name string `db:"name"`
+	}
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id = $Person.id OR id = '2';", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	byName, err := PrepareCondition("name = $Filter.name", Filter{})
+	assert.Nil(t, err)
+	notFred, err := PrepareCondition("name <> 'Fred'")
+	assert.Nil(t, err)
+
+	filtered, err := stmt.Where(byName, notFred)
+	assert.Nil(t, err)
+
+	assert.Equal(t,
+		"SELECT id, name FROM person WHERE (id = ? OR id = '2') AND (name = ?) AND (name <> 'Fred') ;", filtered.sql)
+
+	var people []sqlairtesting.Person
+	err = NewDB(setupPersonDB(t)).Query(
+		context.Background(), filtered, sqlairtesting.Person{ID: "1"}, Filter{Name: "Onos"}).GetAll(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, []sqlairtesting.Person{{ID: "2", Name: "Onos"}}, people)
+}
+
+func TestWhereTrailingClauses(t *testing.T) {
+	cond, err := PrepareCondition("name <> 'Fred'")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		stmt     string
+		expected string
+	}{{
+		"SELECT &Person.* FROM person FOR UPDATE",
+		"SELECT id, name FROM person WHERE (name <> 'Fred') FOR UPDATE",
+	}, {
+		"SELECT &Person.* FROM person WHERE id = $Person.id FOR UPDATE",
+		"SELECT id, name FROM person WHERE (id = ?) AND (name <> 'Fred') FOR UPDATE",
+	}, {
+		"SELECT &Person.* FROM person WHERE id BETWEEN '1' AND '2' OFFSET 1",
+		"SELECT id, name FROM person WHERE (id BETWEEN '1' AND '2') AND (name <> 'Fred') OFFSET 1",
+	}, {
+		"SELECT &Person.* FROM person WHERE id = '1' FETCH FIRST 1 ROWS ONLY",
+		"SELECT id, name FROM person WHERE (id = '1') AND (name <> 'Fred') FETCH FIRST 1 ROWS ONLY",
+	}, {
+		// Columns and aliases named by keywords do not begin clauses.
+		"SELECT offset AS &Person.name FROM person",
+		`SELECT offset AS "Person.name" FROM person WHERE (name <> 'Fred')`,
+	}, {
+		"SELECT &Person.id, offset, window FROM person AS lock ORDER BY id",
+		"SELECT id , offset, window FROM person AS lock WHERE (name <> 'Fred') ORDER BY id",
+	}, {
+		"UPDATE person SET offset = $Person.id, fetch = 1 RETURNING offset",
+		"UPDATE person SET offset = ? , fetch = 1 WHERE (name <> 'Fred') RETURNING offset",
+	}}
+
+	for _, test := range tests {
+		stmt, err := Prepare(test.stmt, sqlairtesting.Person{})
+		if !assert.Nil(t, err, test.stmt) {
+			continue
+		}
+		filtered, err := stmt.Where(cond)
+		if assert.Nil(t, err, test.stmt) {
+			assert.Equal(t, test.expected, filtered.sql)
+		}
+	}
+}
+
+func TestPrepareConditionOutputTargetError(t *testing.T) {
+	_, err := PrepareCondition("id = (SELECT &Person.id FROM person)", sqlairtesting.Person{})
+	assert.EqualError(t, err, `condition "id = (SELECT &Person.id FROM person)" can not contain output target "&Person.id"`)
+}
+
+func TestPrepareConditionMissingTypeError(t *testing.T) {
+	_, err := PrepareCondition("name = $Person.name")
+	assert.Equal(t, NewErrTypeInfoNotPresent("Person"), err)
+}
+
+func TestWhereConflictingTypeError(t *testing.T) {
+	type Person struct {
+		Name string `db:"name"`
+	}
+
+	stmt, err := Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	cond, err := PrepareCondition("name = $Person.name", Person{})
+	assert.Nil(t, err)
+
+	_, err = stmt.Where(cond)
+	assert.Equal(t, NewErrTypeNameNotUnique("Person"), err)
+}
+
+func TestWhereInsertError(t *testing.T) {
+	stmt, err := Prepare("INSERT INTO person VALUES ($Person.id, $Person.name)", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	cond, err := PrepareCondition("name <> 'Fred'")
+	assert.Nil(t, err)
+
+	_, err = stmt.Where(cond)
+	assert.EqualError(t, err,
+		`conditions can only be added to a SELECT, UPDATE or DELETE statement, got "INSERT INTO person VALUES ($Person.id, $Person.name)"`)
+}
@@ -0,0 +1,133 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/pkg/errors"
+)
+
+// Open returns a reference to a new DB that executes statements using a
+// pool of connections to the input data source, opened with the named
+// driver as for sql.Open, and configured by the input options.
+// The DB must be closed when it is no longer required.
+//
+// Example:
+//
+//     db, err := sqlair.Open("sqlite3", "app.db", sqlair.WithInit("PRAGMA foreign_keys = ON"))
+//
+func Open(driverName, dataSourceName string, options ...DBOption) (*DB, error) {
+	pool, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	drv := pool.Driver()
+	_ = pool.Close()
+
+	var connector driver.Connector
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dataSourceName); err != nil {
+			return nil, err
+		}
+	} else {
+		connector = dsnConnector{dsn: dataSourceName, driver: drv}
+	}
+
+	return OpenConnector(connector, options...), nil
+}
+
+// OpenConnector is like Open, but opens connections using the input
+// connector, as for sql.OpenDB.
+func OpenConnector(connector driver.Connector, options ...DBOption) *DB {
+	db := (&DB{}).With(options...)
+	db.pool = sql.OpenDB(initConnector{Connector: connector, init: db.init})
+	db.conn = db.pool
+	return db
+}
+
+// WithInit returns an option causing each of the input statements, such
+// as "PRAGMA foreign_keys = ON" or "SET search_path TO app", to be executed
+// in order on every new connection opened by Open or OpenConnector, before
+// it is added to the pool. It has no effect on a DB using a connection
+// supplied to NewDB.
+func WithInit(statements ...string) DBOption {
+	return func(db *DB) {
+		db.init = statements
+	}
+}
+
+// Close closes the connection pool of a DB returned by Open or
+// OpenConnector. A DB using a connection supplied to NewDB does not own it,
+// so closing it does nothing; the connection must be closed by its owner.
+func (db *DB) Close() error {
+	if db.pool == nil {
+		return nil
+	}
+	return db.pool.Close()
+}
+
+// initConnector is a connector that executes
+// statements on every connection that it opens.
+type initConnector struct {
+	driver.Connector
+	init []string
+}
+
+// Connect implements driver.Connector, executing the init
+// statements on the new connection before returning it.
+func (c initConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range c.init {
+		if err := execOnConn(ctx, conn, stmt); err != nil {
+			_ = conn.Close()
+			return nil, errors.Wrapf(err, "initialising connection with %q", stmt)
+		}
+	}
+	return conn, nil
+}
+
+// execOnConn executes the input statement, without
+// parameters, on the input driver connection.
+func execOnConn(ctx context.Context, conn driver.Conn, query string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, nil)
+		return err
+	}
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+// dsnConnector is a connector for drivers that do not implement
+// driver.DriverContext, opening connections by data source name.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+// Connect implements driver.Connector.
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver implements driver.Connector.
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
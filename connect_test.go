@@ -0,0 +1,69 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenInitialisesConnections(t *testing.T) {
+	db, err := Open("sqlite3", ":memory:", WithInit("PRAGMA foreign_keys = ON", "PRAGMA user_version = 7"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	type Pragma struct {
+		ForeignKeys int `db:"foreign_keys"`
+		Version     int `db:"user_version"`
+	}
+	stmt, err := Prepare(`
+SELECT foreign_keys AS &Pragma.foreign_keys, user_version AS &Pragma.user_version
+  FROM pragma_foreign_keys, pragma_user_version`, Pragma{})
+	assert.Nil(t, err)
+
+	// Hold one connection open so that the query must use another.
+	ctx := context.Background()
+	held, err := db.pool.Conn(ctx)
+	assert.Nil(t, err)
+	defer held.Close()
+
+	for _, conn := range []Conn{held, db.pool} {
+		var p Pragma
+		err = NewDB(conn).Query(ctx, stmt).Get(&p)
+		assert.Nil(t, err)
+		assert.Equal(t, Pragma{ForeignKeys: 1, Version: 7}, p)
+	}
+}
+
+func TestOpenInitError(t *testing.T) {
+	db, err := Open("sqlite3", ":memory:", WithInit("PRAGMA bogus syntax"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	_, err = db.pool.Conn(context.Background())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `initialising connection with "PRAGMA bogus syntax"`)
+	}
+}
+
+func TestOpenWithOptions(t *testing.T) {
+	d := Dialect{NumberedPlaceholders: true}
+	db, err := Open("sqlite3", ":memory:", WithDialect(d))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	assert.Equal(t, d, db.dialect)
+	assert.NotNil(t, db.pool)
+	assert.Equal(t, db.pool, db.conn)
+}
+
+func TestOpenUnknownDriver(t *testing.T) {
+	_, err := Open("nosuchdriver", "")
+	assert.Error(t, err)
+}
+
+func TestCloseSuppliedConn(t *testing.T) {
+	conn := setupDB(t)
+	assert.Nil(t, NewDB(conn).Close())
+	assert.Nil(t, conn.Ping())
+}
@@ -0,0 +1,109 @@
+package sqlair
+
+import (
+	"strings"
+
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// CRUDStatements holds the statements generated by CRUD
+// for accessing the rows of a single table.
+type CRUDStatements struct {
+	// Insert inserts a row with the values of the tagged fields of an
+	// input, other than any tagged "autoincrement", whose value is
+	// generated by the database; see DB.Exec.
+	Insert *Statement
+
+	// Select reads the row with the key of an input into an output.
+	Select *Statement
+
+	// Update writes the values of the tagged fields of an input, other
+	// than those of the key, to the row with the input's key. It is nil
+	// if every tagged field is part of the key.
+	Update *Statement
+
+	// Delete deletes the row with the key of an input.
+	Delete *Statement
+}
+
+// CRUD returns statements for inserting, selecting, updating and deleting
+// rows of the input table, each mapped to a struct of the input object's
+// type, so that access to a single table needs no hand-written SQL. Rows
+// are identified by the fields of the type's primary key; see KeyCondition.
+// The table's name may be qualified by a schema, as in "main.person", and
+// must otherwise be a valid identifier. A table or column name that is a
+// reserved word is quoted.
+//
+// Example:
+//
+//     type Person struct {
+//         ID   int64  `db:"id,pk,autoincrement"`
+//         Name string `db:"name"`
+//     }
+//
+//     people, err := sqlair.CRUD(Person{}, "person")
+//
+//     p := &Person{Name: "Fred"}
+//     _, err = db.Exec(ctx, people.Insert, p)
+//     err = db.Query(ctx, people.Select, Person{ID: p.ID}).Get(p)
+//
+func CRUD(obj any, table string) (*CRUDStatements, error) {
+	argTypes, err := typesForStatement([]any{obj})
+	if err != nil {
+		return nil, err
+	}
+	name, _ := objectName(obj)
+
+	key, err := keyPredicate(name, argTypes[name])
+	if err != nil {
+		return nil, err
+	}
+	info := argTypes[name].(sqlairreflect.Struct)
+	names := generatedNames(info, table)
+	if table, err = generatedTable(table); err != nil {
+		return nil, err
+	}
+
+	var inserted, insertSources, updated []string
+	for _, tag := range info.Tags() {
+		field := info.Fields[tag]
+		source := "$" + name + "." + tag
+		if !field.AutoIncrement {
+			inserted = append(inserted, generatedName(tag))
+			insertSources = append(insertSources, source)
+		}
+		if !field.PrimaryKey {
+			updated = append(updated, generatedName(tag)+" = "+source)
+		}
+	}
+
+	insert := "INSERT INTO " + table + " (" + strings.Join(inserted, ", ") + ")" +
+		" VALUES (" + strings.Join(insertSources, ", ") + ")"
+
+	var crud CRUDStatements
+	if crud.Insert, err = prepareGenerated(insert, obj, names); err != nil {
+		return nil, err
+	}
+	if crud.Select, err = prepareGenerated("SELECT &"+name+".* FROM "+table+" WHERE "+key, obj, names); err != nil {
+		return nil, err
+	}
+	if len(updated) > 0 {
+		update := "UPDATE " + table + " SET " + strings.Join(updated, ", ") + " WHERE " + key
+		if crud.Update, err = prepareGenerated(update, obj, names); err != nil {
+			return nil, err
+		}
+	}
+	if crud.Delete, err = prepareGenerated("DELETE FROM "+table+" WHERE "+key, obj, names); err != nil {
+		return nil, err
+	}
+	return &crud, nil
+}
+
+// prepareGenerated prepares the input generated DSL statement with the
+// input object and the names generated for it, identifying the statement
+// in any error.
+func prepareGenerated(dsl string, obj any, names []string) (*Statement, error) {
+	stmt, err := Prepare(dsl, withGeneratedNames(names), obj)
+	return stmt, errors.Wrapf(err, "preparing generated statement %q", dsl)
+}
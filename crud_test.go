@@ -0,0 +1,110 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+type Item struct {
+	ID    int64  `db:"id,pk,autoincrement"`
+	Name  string `db:"name"`
+	Count int    `db:"count"`
+}
+
+func TestCRUD(t *testing.T) {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`CREATE TABLE item (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, count INTEGER)`)
+		return err
+	})
+	sqlairDB := NewDB(db)
+	ctx := context.Background()
+
+	items, err := CRUD(Item{}, "item")
+	assert.Nil(t, err)
+	assert.Equal(t, `INSERT INTO item (name, count) VALUES (?, ?)`, items.Insert.sql)
+	assert.Equal(t, `SELECT id, name, count FROM item WHERE id = ?`, items.Select.sql)
+	assert.Equal(t, `UPDATE item SET name = ? , count = ? WHERE id = ?`, items.Update.sql)
+	assert.Equal(t, `DELETE FROM item WHERE id = ?`, items.Delete.sql)
+
+	item := &Item{Name: "pen", Count: 1}
+	_, err = sqlairDB.Exec(ctx, items.Insert, item)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), item.ID)
+
+	item.Name = "pencil"
+	_, err = sqlairDB.Exec(ctx, items.Update, item)
+	assert.Nil(t, err)
+
+	var got Item
+	assert.Nil(t, sqlairDB.Query(ctx, items.Select, Item{ID: 1}).Get(&got))
+	assert.Equal(t, Item{ID: 1, Name: "pencil", Count: 1}, got)
+
+	_, err = sqlairDB.Exec(ctx, items.Delete, Item{ID: 1})
+	assert.Nil(t, err)
+	err = sqlairDB.Query(ctx, items.Select, Item{ID: 1}).Get(&got)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func TestCRUDCompositeKey(t *testing.T) {
+	memberships, err := CRUD(Membership{}, "membership")
+	assert.Nil(t, err)
+	assert.Equal(t, "UPDATE membership SET role = ? WHERE group_id = ? AND user_id = ?", memberships.Update.sql)
+
+	type Link struct {
+		From string `db:"from_id,pk"`
+		To   string `db:"to_id,pk"`
+	}
+	links, err := CRUD(Link{}, "link")
+	assert.Nil(t, err)
+	assert.Nil(t, links.Update)
+	assert.Equal(t, "DELETE FROM link WHERE from_id = ? AND to_id = ?", links.Delete.sql)
+
+	_, err = CRUD(sqlairtesting.Person{}, "person")
+	assert.EqualError(t, err, `type "Person" has no field tagged "pk"`)
+
+	// Table names are identifiers, optionally qualified by a schema.
+	memberships, err = CRUD(Membership{}, "main.membership")
+	assert.Nil(t, err)
+	assert.Equal(t, "DELETE FROM main.membership WHERE group_id = ? AND user_id = ?", memberships.Delete.sql)
+
+	for _, table := range []string{"membership WHERE 1=1; DROP TABLE x; SELECT * FROM membership", "a.b.c", "main.", `"membership"`, ""} {
+		_, err = CRUD(Membership{}, table)
+		assert.EqualError(t, err, fmt.Sprintf("invalid table name %q", table))
+	}
+}
+
+func TestCRUDReservedWords(t *testing.T) {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`CREATE TABLE "group" (id INTEGER PRIMARY KEY, "order" INTEGER)`)
+		return err
+	})
+	sqlairDB := NewDB(db)
+	ctx := context.Background()
+
+	type Group struct {
+		ID    int64 `db:"id,pk"`
+		Order int   `db:"order"`
+	}
+	groups, err := CRUD(Group{}, "group")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, `INSERT INTO "group" (id, "order") VALUES (?, ?)`, groups.Insert.sql)
+	assert.Equal(t, `UPDATE "group" SET "order" = ? WHERE id = ?`, groups.Update.sql)
+
+	_, err = sqlairDB.Exec(ctx, groups.Insert, Group{ID: 1, Order: 2})
+	assert.Nil(t, err)
+	_, err = sqlairDB.Exec(ctx, groups.Update, Group{ID: 1, Order: 3})
+	assert.Nil(t, err)
+
+	var got Group
+	assert.Nil(t, sqlairDB.Query(ctx, groups.Select, Group{ID: 1}).Get(&got))
+	assert.Equal(t, Group{ID: 1, Order: 3}, got)
+}
@@ -0,0 +1,289 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"github.com/canonical/sqlair/internal/parse"
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+)
+
+// Conn describes the minimal set of methods that Sqlair requires from a
+// database connection in order to execute statements.
+// It is satisfied by *sql.DB, *sql.Conn and *sql.Tx, and by types that
+// wrap them, such as the dqlite connection returned by NewDqliteConn.
+// As its methods return database/sql types, the connection must go
+// through database/sql; a driver is supplied by opening a *sql.DB with it.
+type Conn interface {
+	// PrepareContext creates a prepared statement for later queries or
+	// executions.
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+
+	// QueryContext executes a query that returns rows,
+	// typically a SELECT.
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+
+	// ExecContext executes a query without returning any rows.
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// DB executes prepared Sqlair statements using a database connection.
+//
+// The context with which a statement is run is passed to everything that
+// the DB calls while running it: the hooks of its inputs and outputs, the
+// transformers of their fields, and the DB's logger. Values carried by the
+// context, such as trace IDs or the current tenant, are therefore available
+// when values are encoded and decoded. The context passed to the logger
+// also carries any deadline set by the statement's timeout directive.
+type DB struct {
+	// decodes and decodeNanos count the rows decoded into output targets
+	// and the time taken to do so. They are updated atomically, and are
+	// first in the struct so that they are aligned on 32-bit platforms.
+	decodes     uint64
+	decodeNanos int64
+
+	// nextReplica counts the queries routed to replicas,
+	// selecting each replica in turn. It is updated atomically.
+	nextReplica uint64
+
+	dbConfig
+}
+
+// dbConfig holds the connections and configuration of a DB,
+// which are shared by the copies of it returned by DB.With.
+type dbConfig struct {
+	// conn is the connection used for every statement that is not
+	// routed to a replica. When there are replicas, it is the primary.
+	conn Conn
+
+	// replicas holds the connections to read-only replicas of the
+	// database, to which statements that only read are routed.
+	replicas []Conn
+
+	// cache, if not nil, holds the results of read-only queries.
+	cache *ResultCache
+
+	// dialect describes the SQL accepted by the database.
+	dialect Dialect
+
+	// logger, if not nil, receives an entry
+	// for each statement executed; see Logger.
+	logger Logger
+
+	// session, if not nil, records the tables written by the
+	// statements run, so that later queries reading them are
+	// run on the primary; see Session.
+	session *Session
+
+	// commenter, if not nil, returns labels appended to
+	// the SQL of each statement run; see Commenter.
+	commenter Commenter
+
+	// backend describes the database to which the DB
+	// is connected, if it has been probed; see DB.Probe.
+	backend Backend
+
+	// pool is the connection pool opened for the DB by Open
+	// or OpenConnector. It is nil if the connection was
+	// supplied to NewDB, in which case the DB does not own it.
+	pool *sql.DB
+
+	// init holds the statements executed on every new connection
+	// opened by Open or OpenConnector; see WithInit.
+	init []string
+
+	// pinned is true if the DB runs statements on a single connection
+	// pinned by RunPinned. Its results are not cached, but the statements
+	// it runs still invalidate those cached.
+	pinned bool
+}
+
+// NewDB returns a reference to a new DB that executes statements
+// using the input connection, configured by the input options.
+func NewDB(conn Conn, options ...DBOption) *DB {
+	db := &DB{dbConfig: dbConfig{conn: conn}}
+	return db.With(options...)
+}
+
+// NewReplicatedDB returns a reference to a new DB that executes statements
+// using the input primary connection, except for queries that only read,
+// which are shared between the input replica connections in turn.
+// Statements are classified by the keyword with which they begin, so a
+// SELECT is routed to a replica, while INSERT, UPDATE, DELETE, DDL and any
+// statement that can not be classified are executed on the primary, as is
+// every statement run with Exec. The DB is configured by the input options.
+func NewReplicatedDB(primary Conn, replicas []Conn, options ...DBOption) *DB {
+	db := &DB{dbConfig: dbConfig{conn: primary, replicas: replicas}}
+	return db.With(options...)
+}
+
+// connFor returns the connection with which to run the input statement:
+// a replica if it only reads, otherwise the primary. A statement reading
+// a table written in the DB's session, if it has one, is run on the primary.
+func (db *DB) connFor(s *Statement) Conn {
+	if len(db.replicas) == 0 || parse.Classify(s.expression) != parse.KindQuery {
+		return db.conn
+	}
+	if db.session != nil && db.session.readsWritten(s) {
+		return db.conn
+	}
+	n := atomic.AddUint64(&db.nextReplica, 1) - 1
+	return db.replicas[n%uint64(len(db.replicas))]
+}
+
+// Query returns a Query for running the input statement, with
+// parameters sourced from the input objects. The statement is not
+// executed until results are requested from the Query.
+func (db *DB) Query(ctx context.Context, s *Statement, inputs ...any) *Query {
+	return &Query{
+		ctx:    ctx,
+		db:     db,
+		stmt:   s,
+		inputs: inputs,
+	}
+}
+
+// Exec executes the input statement without returning any rows,
+// with parameters sourced from the input objects.
+//
+// For an INSERT statement with inputs of a type that has a field tagged
+// "autoincrement", such as `db:"id,autoincrement"`, the key generated by the
+// database is assigned to that field of the input, which must be supplied by
+// reference. The key is read with a RETURNING clause, added to the statement,
+// if the DB's dialect has ReturningKeys, and from the result's LastInsertId
+// otherwise. An error assigning the key is returned along with the result.
+func (db *DB) Exec(ctx context.Context, s *Statement, inputs ...any) (sql.Result, error) {
+	s.recordUse()
+	key, hasKey := s.generatedKeyFor(inputs)
+	args, err := s.bindInputs(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	return db.execArgs(ctx, s, args, key, hasKey)
+}
+
+// execArgs executes the input statement with the input arguments, bound
+// from its inputs, assigning the key generated by the database to the
+// input key if hasKey is true. The execution is logged and commented as
+// for Exec.
+func (db *DB) execArgs(ctx context.Context, s *Statement, args []any, key generatedKey, hasKey bool) (sql.Result, error) {
+	query, params, err := db.sqlFor(s, args)
+	if err != nil {
+		return nil, s.redactError(err, args)
+	}
+
+	ctx, cancel := s.executionContext(ctx)
+	defer cancel()
+	start := time.Now()
+	comment := db.comment(ctx, s)
+	var result sql.Result
+	if hasKey && db.dialect.ReturningKeys && !hasReturning(s.expression) {
+		result, err = db.execReturningKey(ctx, query, comment, params, key)
+	} else {
+		result, err = db.conn.ExecContext(ctx, withComment(query, comment), params...)
+		if err == nil && hasKey {
+			err = setLastInsertID(result, key)
+		}
+	}
+	if result != nil {
+		db.ran(s)
+	}
+	return result, db.logExecution(ctx, s, args, start, err)
+}
+
+// DBStats holds statistics describing the use of a DB.
+type DBStats struct {
+	// DBStats holds the statistics of the DB's connection pool, which is
+	// the primary for a replicated DB. They are zero if the connection
+	// is not a *sql.DB.
+	sql.DBStats
+
+	// TypesCached is the number of types for which reflection information
+	// is held by the cache shared by every DB, and by the statements not
+	// prepared WithCache.
+	TypesCached int
+
+	// Decodes is the number of result rows
+	// decoded into output targets by the DB.
+	Decodes uint64
+
+	// DecodeTime is the total time taken to decode those rows.
+	DecodeTime time.Duration
+}
+
+// AverageDecodeTime returns the mean time taken to decode
+// a result row, or zero if no rows have been decoded.
+func (s DBStats) AverageDecodeTime() time.Duration {
+	if s.Decodes == 0 {
+		return 0
+	}
+	return s.DecodeTime / time.Duration(s.Decodes)
+}
+
+// Stats returns statistics describing the use of the DB and its connection.
+func (db *DB) Stats() DBStats {
+	stats := DBStats{
+		TypesCached: sqlairreflect.Cache().Stats().Size,
+		Decodes:     atomic.LoadUint64(&db.decodes),
+		DecodeTime:  time.Duration(atomic.LoadInt64(&db.decodeNanos)),
+	}
+	if pool, ok := db.conn.(interface{ Stats() sql.DBStats }); ok {
+		stats.DBStats = pool.Stats()
+	}
+	return stats
+}
+
+// recordDecode adds a decoded row, taking the input duration, to the
+// DB's statistics.
+func (db *DB) recordDecode(d time.Duration) {
+	atomic.AddUint64(&db.decodes, 1)
+	atomic.AddInt64(&db.decodeNanos, int64(d))
+}
+
+// QueryRows executes the statement using the input DB, with parameters
+// sourced from the input objects, and returns the result rows for the
+// caller to scan. It is an escape hatch for results that can not be
+// decoded into output targets; the statement's output bindings are not
+// used. The caller must close the rows. The rows are not cached, but if
+// the statement modifies the database, it invalidates cached results
+// as it would if run with Exec.
+func (s *Statement) QueryRows(ctx context.Context, db *DB, inputs ...any) (*sql.Rows, error) {
+	s.recordUse()
+	args, err := s.bindInputs(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	query, params, err := db.sqlFor(s, args)
+	if err != nil {
+		return nil, s.redactError(err, args)
+	}
+
+	// The rows outlive this call, so the context for
+	// the statement's timeout is released when it expires.
+	ctx, cancel := s.executionContext(ctx)
+	query = withComment(query, db.comment(ctx, s))
+	start := time.Now()
+	rows, err := db.connFor(s).QueryContext(ctx, query, params...)
+	if err = db.logExecution(ctx, s, args, start, err); err != nil {
+		cancel()
+		return nil, err
+	}
+	if s.timeout > 0 {
+		time.AfterFunc(s.timeout, cancel)
+	}
+	db.ran(s)
+	return rows, nil
+}
+
+// ran updates the DB's result cache and session, if it has them, after
+// running the input statement, which may have modified the database.
+func (db *DB) ran(s *Statement) {
+	if db.cache != nil {
+		db.cache.invalidateFor(s)
+	}
+	if db.session != nil {
+		db.session.recordWrites(s)
+	}
+}
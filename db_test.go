@@ -0,0 +1,135 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ Conn = (*sql.DB)(nil)
+	_ Conn = (*sql.Conn)(nil)
+	_ Conn = (*sql.Tx)(nil)
+)
+
+func TestExec(t *testing.T) {
+	db := setupPersonDB(t)
+
+	stmt, err := prepareExpression(
+		expressionForStatement("UPDATE person SET name = $Person.name WHERE id = $Person.id"),
+		[]any{sqlairtesting.Person{}},
+	)
+	assert.Nil(t, err)
+
+	result, err := NewDB(db).Exec(context.Background(), stmt, sqlairtesting.Person{ID: "3", Name: "Fiddler"})
+	assert.Nil(t, err)
+
+	affected, err := result.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	var name string
+	err = db.QueryRow("SELECT name FROM person WHERE id = '3'").Scan(&name)
+	assert.Nil(t, err)
+	assert.Equal(t, "Fiddler", name)
+}
+
+func TestStatementQueryRows(t *testing.T) {
+	db := setupPersonDB(t)
+
+	stmt, err := Prepare("SELECT &Person.name, length(name) FROM person WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	rows, err := stmt.QueryRows(context.Background(), NewDB(db), sqlairtesting.Person{ID: "2"})
+	assert.Nil(t, err)
+	defer rows.Close()
+
+	assert.True(t, rows.Next())
+	var name string
+	var length int
+	assert.Nil(t, rows.Scan(&name, &length))
+	assert.Equal(t, "Onos", name)
+	assert.Equal(t, 4, length)
+	assert.False(t, rows.Next())
+	assert.Nil(t, rows.Err())
+
+	_, err = stmt.QueryRows(context.Background(), NewDB(db))
+	assert.EqualError(t, err, `no input of type "Person" supplied for statement`)
+}
+
+func TestDBStats(t *testing.T) {
+	conn := setupPersonDB(t)
+	db := NewDB(conn)
+
+	stats := db.Stats()
+	assert.Equal(t, uint64(0), stats.Decodes)
+	assert.Equal(t, time.Duration(0), stats.AverageDecodeTime())
+	assert.Equal(t, 1, stats.MaxOpenConnections)
+
+	stmt, err := Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	var people []sqlairtesting.Person
+	err = db.Query(context.Background(), stmt).GetAll(&people)
+	assert.Nil(t, err)
+
+	stats = db.Stats()
+	assert.Equal(t, uint64(3), stats.Decodes)
+	assert.Equal(t, stats.DecodeTime/3, stats.AverageDecodeTime())
+	assert.Greater(t, stats.TypesCached, 0)
+	assert.Equal(t, 1, stats.OpenConnections)
+
+	// Connections other than pools have no pool statistics.
+	tx, err := conn.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+	assert.Equal(t, sql.DBStats{}, NewDB(tx).Stats().DBStats)
+}
+
+func TestReplicatedDBRouting(t *testing.T) {
+	// Each database names the same person differently,
+	// so that the database answering a query can be told.
+	conns := make([]*sql.DB, 3)
+	for i, name := range []string{"primary", "replica1", "replica2"} {
+		conns[i] = setupPersonDB(t)
+		_, err := conns[i].Exec("UPDATE person SET name = ? WHERE id = '1'", name)
+		assert.Nil(t, err)
+	}
+	db := NewReplicatedDB(conns[0], []Conn{conns[1], conns[2]})
+	ctx := context.Background()
+
+	query, err := Prepare("SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	var names []string
+	for i := 0; i < 3; i++ {
+		var p sqlairtesting.Person
+		err = db.Query(ctx, query, sqlairtesting.Person{ID: "1"}).Get(&p)
+		assert.Nil(t, err)
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"replica1", "replica2", "replica1"}, names)
+
+	// Statements that modify the database run on the primary.
+	update, err := Prepare("UPDATE person SET name = 'Fred' WHERE id = $Person.id RETURNING &Person.*", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	var p sqlairtesting.Person
+	err = db.Query(ctx, update, sqlairtesting.Person{ID: "1"}).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, "Fred", p.Name)
+
+	var name string
+	err = conns[0].QueryRow("SELECT name FROM person WHERE id = '1'").Scan(&name)
+	assert.Nil(t, err)
+	assert.Equal(t, "Fred", name)
+
+	// Exec always uses the primary, regardless of the statement.
+	_, err = db.Exec(ctx, query, sqlairtesting.Person{ID: "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), db.nextReplica)
+}
@@ -0,0 +1,203 @@
+package sqlair
+
+import (
+	"reflect"
+
+	"github.com/canonical/sqlair/internal/parse"
+	"github.com/pkg/errors"
+)
+
+// derivation holds the parts of a statement
+// being derived from another by Statement.Derive.
+type derivation struct {
+	// children are the top-level expressions of the derived statement.
+	children []parse.Expression
+
+	// argTypes holds the reflection info for types
+	// used in the derived statement.
+	argTypes typeMap
+
+	// lenient indicates whether the derived statement ignores
+	// objects of unused types rather than returning an error.
+	lenient bool
+}
+
+// DeriveOption describes a change to a statement
+// being derived from another by Statement.Derive.
+// WithSuperfluousTypes, a PrepareOption, is also a DeriveOption.
+type DeriveOption interface {
+	derive(d *derivation) error
+}
+
+// deriveFunc is a DeriveOption that applies itself to the derivation.
+type deriveFunc func(*derivation) error
+
+// derive implements DeriveOption.
+func (f deriveFunc) derive(d *derivation) error {
+	return f(d)
+}
+
+// derive implements DeriveOption. The derived statement is not parsed
+// again, so the only PrepareOption that applies to it is
+// WithSuperfluousTypes; any other is an error.
+func (o PrepareOption) derive(d *derivation) error {
+	opts := prepareOptions{allowUnused: d.lenient}
+	o(&opts)
+	if !reflect.DeepEqual(opts, prepareOptions{allowUnused: opts.allowUnused}) {
+		return errors.New("option does not apply to a derived statement")
+	}
+	d.lenient = opts.allowUnused
+	return nil
+}
+
+// WithLimit returns an option that replaces the LIMIT clause of the
+// statement with the input DSL clause, such as "LIMIT $Page.size",
+// adding it if there is none. Type information for any input sources
+// in the clause is inferred from the input objects.
+func WithLimit(clause string, args ...any) DeriveOption {
+	return deriveFunc(func(d *derivation) error {
+		limit, err := d.parseClause(clause, args)
+		if err != nil {
+			return err
+		}
+		if _, ok := limit.(*parse.LimitExpression); !ok {
+			return errors.Errorf("%q is not a LIMIT clause", clause)
+		}
+
+		d.replaceOrInsert(limit, isLimit, isLimit)
+		return nil
+	})
+}
+
+// WithOrderBy returns an option that replaces the ORDER BY clause of the
+// statement with the input DSL clause, such as "ORDER BY name DESC",
+// adding it if there is none. Type information for any input sources
+// in the clause is inferred from the input objects.
+func WithOrderBy(clause string, args ...any) DeriveOption {
+	return deriveFunc(func(d *derivation) error {
+		orderBy, err := d.parseClause(clause, args)
+		if err != nil {
+			return err
+		}
+		if _, ok := orderBy.(*parse.OrderByExpression); !ok {
+			return errors.Errorf("%q is not an ORDER BY clause", clause)
+		}
+
+		// An added ORDER BY clause precedes any LIMIT clause.
+		d.replaceOrInsert(orderBy, isOrderBy, isLimit)
+		return nil
+	})
+}
+
+// Derive returns a new Statement derived from this one with the input
+// options applied. The expression tree of this statement is reused rather
+// than parsed again; only the clauses supplied to the options are parsed.
+// The statement must not be a compound query; this statement is not
+// modified.
+func (s *Statement) Derive(options ...DeriveOption) (*Statement, error) {
+	children := s.expression.Expressions()
+	for _, child := range children {
+		if _, ok := child.(*parse.CompoundExpression); ok {
+			return nil, errors.Errorf("can not derive from compound statement %q", s.expression.String())
+		}
+	}
+
+	d := &derivation{
+		children: append([]parse.Expression(nil), children...),
+		argTypes: s.argTypes,
+		lenient:  s.lenient,
+	}
+	for _, option := range options {
+		if err := option.derive(d); err != nil {
+			return nil, err
+		}
+	}
+
+	exp := &parse.SQLExpression{}
+	for _, child := range d.children {
+		exp.AppendExpression(child)
+	}
+
+	stmt, err := s.recompile(exp, d.argTypes)
+	if err != nil {
+		return nil, err
+	}
+	stmt.lenient = d.lenient
+	return stmt, nil
+}
+
+// replaceOrInsert replaces the first top-level expression matched by the
+// input replace predicate with the input expression. If there is none, the
+// expression is inserted before the first expression matched by the input
+// before predicate, or else at the end, preceding any final semicolon.
+func (d *derivation) replaceOrInsert(exp parse.Expression, replace, before func(parse.Expression) bool) {
+	for i, child := range d.children {
+		if replace(child) {
+			d.children[i] = exp
+			return
+		}
+	}
+
+	i := len(d.children)
+	if i > 0 && d.children[i-1].String() == ";" {
+		i--
+	}
+	for j, child := range d.children {
+		if before(child) {
+			i = j
+			break
+		}
+	}
+
+	d.children = append(d.children[:i], append([]parse.Expression{exp}, d.children[i:]...)...)
+}
+
+// parseClause parses the input DSL clause, which must comprise
+// a single expression, and merges the type information for the
+// input objects into that of the derivation.
+func (d *derivation) parseClause(clause string, args []any) (parse.Expression, error) {
+	exp, argTypes, err := parseClause(clause, args)
+	if err != nil {
+		return nil, err
+	}
+	if d.argTypes, err = mergeTypes(d.argTypes, argTypes); err != nil {
+		return nil, err
+	}
+	return exp, nil
+}
+
+// parseClause parses the input DSL clause, which must comprise a single
+// expression, and returns it with the type information for the input
+// objects, against which its input sources are validated.
+func parseClause(clause string, args []any) (parse.Expression, typeMap, error) {
+	exp, err := parse.NewParser(parse.NewLexer(clause)).Run()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	children := exp.Expressions()
+	if len(children) != 1 {
+		return nil, nil, errors.Errorf("%q is not a single clause", clause)
+	}
+
+	argTypes, err := typesForStatement(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := interpret(exp, argTypes); err != nil {
+		return nil, nil, err
+	}
+	return children[0], argTypes, nil
+}
+
+// isLimit returns true if the input expression is a LIMIT clause.
+func isLimit(exp parse.Expression) bool {
+	_, ok := exp.(*parse.LimitExpression)
+	return ok
+}
+
+// isOrderBy returns true if the input expression is an ORDER BY clause.
+func isOrderBy(exp parse.Expression) bool {
+	_, ok := exp.(*parse.OrderByExpression)
+	return ok
+}
@@ -0,0 +1,89 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeriveReplacesClauses(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM person ORDER BY id LIMIT 1;", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	derived, err := stmt.Derive(WithOrderBy("ORDER BY name DESC"), WithLimit("LIMIT 2"))
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person ORDER BY name DESC LIMIT 2 ;", derived.sql)
+
+	// The original statement is unchanged.
+	assert.Equal(t, "SELECT id, name FROM person ORDER BY id LIMIT 1 ;", stmt.sql)
+}
+
+func TestDeriveAddsClauses(t *testing.T) {
+	type Page struct {
+		Size int `db:"size"`
+	}
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id <> $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	derived, err := stmt.Derive(WithLimit("LIMIT $Page.size", Page{}), WithOrderBy("ORDER BY name"))
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person WHERE id <> ? ORDER BY name LIMIT ?", derived.sql)
+
+	var people []sqlairtesting.Person
+	err = NewDB(setupPersonDB(t)).Query(
+		context.Background(), derived, sqlairtesting.Person{ID: "3"}, Page{Size: 1}).GetAll(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, []sqlairtesting.Person{{ID: "1", Name: "Lorn"}}, people)
+}
+
+func TestDeriveNotStrict(t *testing.T) {
+	type Unused struct{}
+	db := NewDB(setupPersonDB(t))
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	var p sqlairtesting.Person
+	err = db.Query(context.Background(), stmt, sqlairtesting.Person{ID: "1"}, Unused{}).Get(&p)
+	assert.Equal(t, NewErrSuperfluousType("Unused"), err)
+
+	lenient, err := stmt.Derive(WithSuperfluousTypes(true))
+	assert.Nil(t, err)
+
+	err = db.Query(context.Background(), lenient, sqlairtesting.Person{ID: "1"}, Unused{}).Get(&p, &Unused{})
+	assert.Nil(t, err)
+	assert.Equal(t, sqlairtesting.Person{ID: "1", Name: "Lorn"}, p)
+
+	strict, err := lenient.Derive(WithSuperfluousTypes(false))
+	assert.Nil(t, err)
+
+	err = db.Query(context.Background(), strict, sqlairtesting.Person{ID: "1"}, Unused{}).Get(&p)
+	assert.Equal(t, NewErrSuperfluousType("Unused"), err)
+}
+
+func TestDeriveErrors(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	_, err = stmt.Derive(WithLimit("ORDER BY id"))
+	assert.EqualError(t, err, `"ORDER BY id" is not a LIMIT clause`)
+
+	_, err = stmt.Derive(WithOrderBy("ORDER BY id LIMIT 1"))
+	assert.EqualError(t, err, `"ORDER BY id LIMIT 1" is not a single clause`)
+
+	_, err = stmt.Derive(WithLimit("LIMIT $Page.size"))
+	assert.Equal(t, NewErrTypeInfoNotPresent("Page"), err)
+
+	_, err = stmt.Derive(WithStrictness(true))
+	assert.EqualError(t, err, "option does not apply to a derived statement")
+
+	compound, err := Prepare("SELECT &Person.* FROM person UNION SELECT id, name FROM manager", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	_, err = compound.Derive(WithLimit("LIMIT 1"))
+	assert.EqualError(t, err,
+		`can not derive from compound statement "SELECT &Person.* FROM person UNION SELECT id , name FROM manager"`)
+}
@@ -0,0 +1,106 @@
+package sqlair
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Description reports how a prepared statement is run, without running
+// it: the SQL generated for each dialect, the sources of its parameters,
+// the destinations of its result columns, and anything about the
+// statement that may not be intended.
+type Description struct {
+	// SQL holds the SQL generated for the default
+	// dialect, followed by that for other dialects.
+	SQL []DialectSQL `json:"sql"`
+
+	// Outputs holds, in column order, the destination of each result
+	// column that is decoded into an output target.
+	Outputs []OutputBinding `json:"outputs"`
+
+	// Warnings holds the statement's warnings; see Statement.Warnings.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// DialectSQL is the SQL generated for a statement in a single dialect.
+type DialectSQL struct {
+	// Dialect is the dialect for which the SQL is generated.
+	Dialect Dialect `json:"dialect"`
+
+	// SQL is the statement passed to the database. The counts of LIMIT and
+	// OFFSET clauses written into it when it is executed are shown as the
+	// input sources from which they are taken, such as "$Page.size", as
+	// are slices expanded into lists, such as "id IN ($Filter.ids)".
+	// Identifier templates are shown as in the DSL, such as "[[table]]".
+	SQL string `json:"sql"`
+
+	// Inputs holds, in placeholder order, the source of each parameter.
+	Inputs []InputBinding `json:"inputs"`
+}
+
+// dialects holds the dialects for which SQL is described,
+// the default dialect being first.
+var dialects = []Dialect{{}, {InlineLimits: true}, {NativeArrays: true}}
+
+// Describe returns a report of how the statement is run, for checking
+// statements without a database. Each of the dialects is described,
+// even where the SQL generated for it is the same as for the default.
+func (s *Statement) Describe() Description {
+	plan := s.BindingPlan()
+	d := Description{Outputs: plan.Outputs}
+
+	var sql strings.Builder
+	last := 0
+	for _, t := range s.templates {
+		sql.WriteString(s.sql[last:t.offset])
+		sql.WriteString("[[" + t.name + "]]")
+		last = t.offset
+	}
+	sql.WriteString(s.sql[last:])
+
+	source := func(i int) string {
+		return "$" + plan.Inputs[i].TypeName + "." + plan.Inputs[i].Column
+	}
+	for _, dialect := range dialects {
+		rewrites := make(map[int]paramText)
+		if dialect.InlineLimits {
+			for _, i := range s.limits {
+				rewrites[i] = paramText{text: source(i)}
+			}
+		}
+		if !dialect.NativeArrays {
+			for _, l := range s.lists {
+				if l.in != "" {
+					rewrites[l.param] = paramText{replaces: len(l.comparison), text: l.in + source(l.param)}
+				}
+			}
+		}
+
+		var inputs []InputBinding
+		for i, in := range plan.Inputs {
+			if _, ok := rewrites[i]; !ok {
+				inputs = append(inputs, in)
+			}
+		}
+		d.SQL = append(d.SQL, DialectSQL{Dialect: dialect, SQL: inlineParams(sql.String(), rewrites, s.operators, s.backslashEscapes, dialect.NumberedPlaceholders), Inputs: inputs})
+	}
+
+	d.Warnings = s.Warnings()
+	return d
+}
+
+// String returns a description of the report, with the SQL for each
+// dialect followed by its parameters, the bindings of the result
+// columns, and a line for each warning.
+func (d Description) String() string {
+	var b strings.Builder
+	for _, sql := range d.SQL {
+		fmt.Fprintf(&b, "%s: %s\n", sql.Dialect, sql.SQL)
+		b.WriteString(BindingPlan{Inputs: sql.Inputs}.String())
+	}
+	b.WriteString(BindingPlan{Outputs: d.Outputs}.String())
+	for _, w := range d.Warnings {
+		fmt.Fprintf(&b, "warning: %s\n", w)
+	}
+	return b.String()
+}
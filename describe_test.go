@@ -0,0 +1,82 @@
+package sqlair
+
+import (
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE name <> $PageSpec.name LIMIT $PageSpec.size OFFSET $PageSpec.skip",
+		sqlairtesting.Person{}, PageSpec{})
+	assert.Nil(t, err)
+
+	d := stmt.Describe()
+	assert.Equal(t, []DialectSQL{{
+		Dialect: Dialect{},
+		SQL:     "SELECT id, name FROM person WHERE name <> ? LIMIT ? OFFSET ?",
+		Inputs: []InputBinding{
+			{TypeName: "PageSpec", Column: "name", Field: "Name"},
+			{TypeName: "PageSpec", Column: "size", Field: "Size"},
+			{TypeName: "PageSpec", Column: "skip", Field: "Skip"},
+		},
+	}, {
+		Dialect: Dialect{InlineLimits: true},
+		SQL:     "SELECT id, name FROM person WHERE name <> ? LIMIT $PageSpec.size OFFSET $PageSpec.skip",
+		Inputs: []InputBinding{
+			{TypeName: "PageSpec", Column: "name", Field: "Name"},
+		},
+	}, {
+		Dialect: Dialect{NativeArrays: true},
+		SQL:     "SELECT id, name FROM person WHERE name <> ? LIMIT ? OFFSET ?",
+		Inputs: []InputBinding{
+			{TypeName: "PageSpec", Column: "name", Field: "Name"},
+			{TypeName: "PageSpec", Column: "size", Field: "Size"},
+			{TypeName: "PageSpec", Column: "skip", Field: "Skip"},
+		},
+	}}, d.SQL)
+	assert.Equal(t, stmt.BindingPlan().Outputs, d.Outputs)
+	assert.Empty(t, d.Warnings)
+
+	expected := "default: SELECT id, name FROM person WHERE name <> ? LIMIT ? OFFSET ?\n" +
+		"$1 <- PageSpec.Name\n$2 <- PageSpec.Size\n$3 <- PageSpec.Skip\n" +
+		"InlineLimits: SELECT id, name FROM person WHERE name <> ? LIMIT $PageSpec.size OFFSET $PageSpec.skip\n" +
+		"$1 <- PageSpec.Name\n" +
+		"NativeArrays: SELECT id, name FROM person WHERE name <> ? LIMIT ? OFFSET ?\n" +
+		"$1 <- PageSpec.Name\n$2 <- PageSpec.Size\n$3 <- PageSpec.Skip\n" +
+		"id -> Person.ID\nname -> Person.Name\n"
+	assert.Equal(t, expected, d.String())
+}
+
+func TestDescribeLists(t *testing.T) {
+	type Filter struct {
+		IDs []string `db:"ids"`
+	}
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id = ANY($Filter.ids)", sqlairtesting.Person{}, Filter{})
+	assert.Nil(t, err)
+
+	d := stmt.Describe()
+	if assert.Len(t, d.SQL, 3) {
+		assert.Equal(t, "SELECT id, name FROM person WHERE id IN ($Filter.ids)", d.SQL[0].SQL)
+		assert.Empty(t, d.SQL[0].Inputs)
+		assert.Equal(t, "SELECT id, name FROM person WHERE id = ANY(?)", d.SQL[2].SQL)
+		assert.Len(t, d.SQL[2].Inputs, 1)
+	}
+}
+
+func TestDescribeWarnings(t *testing.T) {
+	stmt, err := Prepare("SELECT name AS &Person.name FROM [[table]]", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	stmt, err = stmt.Derive(WithSuperfluousTypes(true))
+	assert.Nil(t, err)
+
+	d := stmt.Describe()
+	assert.Equal(t, `SELECT name AS "Person.name" FROM [[table]]`, d.SQL[0].SQL)
+	assert.Equal(t, []string{
+		`identifier template "table" must be substituted before execution`,
+		`column "id" of output type "Person" is not decoded`,
+		"objects of types not used by the statement are ignored, rather than being an error",
+	}, d.Warnings)
+}
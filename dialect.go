@@ -0,0 +1,370 @@
+package sqlair
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Dialect describes the ways in which the SQL accepted by a database
+// differs from that which Sqlair generates by default, which suits SQLite
+// and PostgreSQL. The zero Dialect is the default.
+type Dialect struct {
+	// InlineLimits is true if the database does not accept parameters for
+	// the counts of LIMIT and OFFSET clauses. Their values, once validated,
+	// are written into the SQL in place of the parameter placeholders.
+	InlineLimits bool
+
+	// NativeArrays is true if the database accepts a slice as the
+	// parameter of a quantified comparison, as in "id = ANY($Filter.ids)".
+	// Otherwise, the comparison is written as "id IN (?, ?, ...)", with
+	// a parameter for each value, or as "NOT IN" for "<> ALL". Other
+	// comparisons with slices can not be written without native arrays.
+	NativeArrays bool
+
+	// ReturningKeys is true if the keys generated by the database for
+	// inserted rows are read with a RETURNING clause, as for PostgreSQL,
+	// rather than from the LastInsertId of the statement's result; see
+	// DB.Exec.
+	ReturningKeys bool
+
+	// NumberedPlaceholders is true if the database's driver accepts
+	// parameters written "$1", "$2" and so on, as do those for
+	// PostgreSQL, rather than "?".
+	NumberedPlaceholders bool
+
+	// Explain is the syntax with which the plan for a statement is
+	// read by Statement.Explain.
+	Explain ExplainSyntax
+
+	// BacktickQuotes is true if the database quotes identifiers with
+	// backticks, as MySQL does unless its ANSI_QUOTES mode is set, rather
+	// than with double quotes. Only the identifiers that Sqlair writes,
+	// such as the column names derived from struct tags and those
+	// substituted for identifier templates, are quoted in this way.
+	BacktickQuotes bool
+
+	// ReservedWords are the words that are quoted where Sqlair writes them
+	// as identifiers: the column names derived from struct tags, and the
+	// table and column names of generated statements, such as those of
+	// CRUD. If nil, the keywords of SQLite are quoted.
+	ReservedWords *ReservedWords
+}
+
+// String returns the names of the options of the dialect that are set,
+// joined by "+", such as "InlineLimits+NativeArrays", or "default" for
+// the default dialect.
+func (d Dialect) String() string {
+	var options []string
+	if d.InlineLimits {
+		options = append(options, "InlineLimits")
+	}
+	if d.NativeArrays {
+		options = append(options, "NativeArrays")
+	}
+	if d.ReturningKeys {
+		options = append(options, "ReturningKeys")
+	}
+	if d.NumberedPlaceholders {
+		options = append(options, "NumberedPlaceholders")
+	}
+	if d.Explain != ExplainQueryPlan {
+		options = append(options, d.Explain.String())
+	}
+	if d.BacktickQuotes {
+		options = append(options, "BacktickQuotes")
+	}
+	if d.ReservedWords != nil {
+		options = append(options, "ReservedWords")
+	}
+	if len(options) == 0 {
+		return "default"
+	}
+	return strings.Join(options, "+")
+}
+
+// WithDialect returns an option with which a DB generates SQL for the
+// input dialect.
+//
+// Whatever the dialect, the counts of LIMIT and OFFSET clauses sourced from
+// inputs, such as "LIMIT $Page.size", must be integers that are not
+// negative, otherwise the statement is not executed.
+func WithDialect(d Dialect) DBOption {
+	return func(db *DB) {
+		db.dialect = d
+	}
+}
+
+// identifier returns the input identifier as it is written by Sqlair
+// into SQL for the dialect: quoted if quoted is true or if it is one of
+// the dialect's reserved words.
+func (d Dialect) identifier(id string, quoted bool) string {
+	reserved := d.ReservedWords
+	if reserved == nil {
+		reserved = sqliteReservedWords
+	}
+	if quoted || reserved.has(id) {
+		return d.quoteIdentifier(id)
+	}
+	return id
+}
+
+// quoteIdentifier returns the input identifier quoted for the dialect,
+// with backticks if it has BacktickQuotes and otherwise as by the
+// package's quoteIdentifier.
+func (d Dialect) quoteIdentifier(id string) string {
+	if d.BacktickQuotes {
+		return "`" + strings.ReplaceAll(id, "`", "``") + "`"
+	}
+	return quoteIdentifier(id)
+}
+
+// sqlFor returns the SQL and parameters with which to execute the input
+// statement, given the parameters bound from its inputs followed by any
+// others required by its SQL. The counts of LIMIT and OFFSET clauses among
+// them are validated, and are written into the SQL if the DB's dialect does
+// not accept them as parameters. Slices compared with quantifiers are
+// expanded into lists of parameters if the dialect has no native arrays.
+// The placeholders are numbered if the dialect has NumberedPlaceholders,
+// which statements with operators containing question marks require.
+func (db *DB) sqlFor(s *Statement, args []any) (string, []any, error) {
+	if len(s.operators) > 0 && !db.dialect.NumberedPlaceholders {
+		return "", nil, errors.New("operators containing \"?\" require a dialect with numbered placeholders")
+	}
+
+	rewrites := make(map[int]paramText, len(s.limits)+len(s.lists))
+	for _, i := range s.limits {
+		count, err := limitCount(args[i])
+		if err != nil {
+			return "", nil, err
+		}
+		if db.dialect.InlineLimits {
+			rewrites[i] = paramText{text: count}
+		}
+	}
+
+	values := make(map[int][]any, len(s.lists))
+	if !db.dialect.NativeArrays {
+		for _, l := range s.lists {
+			if l.in == "" {
+				return "", nil, listComparisonError(l)
+			}
+			values[l.param] = listValues(args[l.param])
+			rewrites[l.param] = paramText{
+				replaces: len(l.comparison),
+				text:     l.in + listPlaceholders(len(values[l.param])),
+			}
+		}
+	}
+	query := s.sqlForDialect(db.dialect)
+	if len(rewrites) == 0 && !db.dialect.NumberedPlaceholders {
+		return query, args, nil
+	}
+
+	params := make([]any, 0, len(args))
+	for i, arg := range args {
+		if list, ok := values[i]; ok {
+			params = append(params, list...)
+		} else if _, ok := rewrites[i]; !ok {
+			params = append(params, arg)
+		}
+	}
+	return inlineParams(query, rewrites, s.operators, s.backslashEscapes, db.dialect.NumberedPlaceholders), params, nil
+}
+
+// sqlForDialect returns the statement's SQL with the identifiers written
+// by Sqlair, such as the column names derived from struct tags, written
+// for the input dialect rather than the default.
+func (s *Statement) sqlForDialect(d Dialect) string {
+	if d.ReservedWords == nil && !d.BacktickQuotes {
+		return s.sql
+	}
+	var sql strings.Builder
+	last := 0
+	for _, id := range s.identifiers {
+		sql.WriteString(s.sql[last:id.offset])
+		sql.WriteString(d.identifier(id.name, id.quoted))
+		last = id.offset + len(Dialect{}.identifier(id.name, id.quoted))
+	}
+	sql.WriteString(s.sql[last:])
+	return sql.String()
+}
+
+// paramText is the text replacing the placeholder of a parameter,
+// along with the input number of bytes immediately preceding it.
+type paramText struct {
+	replaces int
+	text     string
+}
+
+// inlineParams returns the input SQL with the placeholders of the
+// parameters with the input indexes replaced by the corresponding text.
+// The question marks with the input indexes, in order, are operators
+// rather than placeholders, and are left as they are, as are those within
+// quoted strings, quoted identifiers and comments. If escapes is true, a
+// backslash within a string literal escapes the character following it;
+// see WithBackslashEscapes. If numbered is true, the placeholders that
+// remain, including those in the replacement text, are numbered from "$1".
+func inlineParams(query string, text map[int]paramText, operators []int, escapes, numbered bool) string {
+	var sql strings.Builder
+	mark, param, last, number := 0, 0, 0, 0
+	placeholders := func(text string) string {
+		if !numbered {
+			return text
+		}
+		return numberPlaceholders(text, &number)
+	}
+	for offset := 0; offset < len(query); offset++ {
+		switch query[offset] {
+		case '\'', '"', '`':
+			// Skip quoted strings and identifiers, which may contain
+			// question marks. Doubled quotes within them are skipped
+			// as the end of one quoted run and the start of another.
+			offset = quotedEnd(query, offset, escapes && query[offset] == '\'')
+		case '-', '/':
+			// Skip comments, which may contain question marks and quotes.
+			offset = commentEnd(query, offset)
+		case '?':
+			if len(operators) > 0 && operators[0] == mark {
+				operators = operators[1:]
+				mark++
+				continue
+			}
+			mark++
+			if t, ok := text[param]; ok {
+				sql.WriteString(query[last : offset-t.replaces])
+				sql.WriteString(placeholders(t.text))
+				last = offset + 1
+			} else if numbered {
+				sql.WriteString(query[last:offset])
+				sql.WriteString(placeholders("?"))
+				last = offset + 1
+			}
+			param++
+		}
+	}
+	sql.WriteString(query[last:])
+	return sql.String()
+}
+
+// numberPlaceholders returns the input text, which holds no quoted strings,
+// with each "?" replaced by "$" followed by the next number after that
+// input, which is updated.
+func numberPlaceholders(text string, number *int) string {
+	if !strings.Contains(text, "?") {
+		return text
+	}
+	var sb strings.Builder
+	for _, r := range text {
+		if r != '?' {
+			sb.WriteRune(r)
+			continue
+		}
+		*number++
+		sb.WriteString("$" + strconv.Itoa(*number))
+	}
+	return sb.String()
+}
+
+// quotedEnd returns the offset in the input SQL of the quote closing
+// the quoted string or identifier opened at the input offset, or the input
+// offset if it is not closed. If escapes is true, a backslash escapes the
+// character following it.
+func quotedEnd(query string, open int, escapes bool) int {
+	for offset := open + 1; offset < len(query); offset++ {
+		switch query[offset] {
+		case '\\':
+			if escapes {
+				offset++
+			}
+		case query[open]:
+			return offset
+		}
+	}
+	return open
+}
+
+// commentEnd returns the offset in the input SQL of the last byte of the
+// comment opened at the input offset, by "--" or "/*", or the input offset
+// if no comment is opened there. A comment opened by "--" ends with the
+// line, and one opened by "/*" at the following "*/", or else with the SQL.
+func commentEnd(query string, open int) int {
+	if open+1 >= len(query) {
+		return open
+	}
+	switch query[open : open+2] {
+	case "--":
+		if end := strings.IndexByte(query[open:], '\n'); end >= 0 {
+			return open + end
+		}
+	case "/*":
+		if end := strings.Index(query[open+2:], "*/"); end >= 0 {
+			return open + 2 + end + 1
+		}
+	default:
+		return open
+	}
+	return len(query) - 1
+}
+
+// listValues returns the elements of the input
+// parameter, which is a slice or an array.
+func listValues(arg any) []any {
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil
+	}
+
+	values := make([]any, v.Len())
+	for i := range values {
+		values[i] = v.Index(i).Interface()
+	}
+	return values
+}
+
+// listPlaceholders returns the placeholders for a list with the input
+// number of parameters. An empty list is written as a subquery returning
+// no rows, since not every database accepts "IN ()".
+func listPlaceholders(n int) string {
+	if n == 0 {
+		return "SELECT NULL WHERE 0 = 1"
+	}
+	return strings.Repeat("?, ", n-1) + "?"
+}
+
+// listComparisonError returns the error for a quantified
+// comparison of a slice that can not be written with a list.
+func listComparisonError(l listBinding) error {
+	comparison := strings.TrimSpace(strings.TrimSuffix(l.comparison, "("))
+	return errors.Errorf("comparison %q of a slice requires a dialect with native arrays", comparison)
+}
+
+// limitCount returns the decimal representation of the input parameter,
+// which must be an integer in the range of int64 that is not negative,
+// for use as the count of a LIMIT or OFFSET clause.
+func limitCount(arg any) (string, error) {
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() >= 0 {
+			return strconv.FormatInt(v.Int(), 10), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() <= math.MaxInt64 {
+			return strconv.FormatUint(v.Uint(), 10), nil
+		}
+	default:
+		return "", errors.Errorf("LIMIT or OFFSET count must be an integer, got %T", arg)
+	}
+	return "", errors.Errorf("LIMIT or OFFSET count %v is out of range", v.Interface())
+}
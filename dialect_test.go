@@ -0,0 +1,217 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+type PageSpec struct {
+	Size int    `db:"size"`
+	Skip uint16 `db:"skip"`
+	Name string `db:"name"`
+}
+
+type IDList struct {
+	IDs  []string `db:"ids"`
+	Data []byte   `db:"data"`
+}
+
+func TestSQLForInlineLimits(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE name <> $PageSpec.name AND id <> '?' LIMIT $PageSpec.size OFFSET $PageSpec.skip",
+		sqlairtesting.Person{}, PageSpec{})
+	assert.Nil(t, err)
+
+	args, err := stmt.bindInputs(context.Background(), []any{PageSpec{Size: 10, Skip: 20, Name: "Fred"}})
+	assert.Nil(t, err)
+
+	query, params, err := NewDB(nil).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, stmt.sql, query)
+	assert.Equal(t, []any{"Fred", 10, uint16(20)}, params)
+
+	query, params, err = NewDB(nil).With(WithDialect(Dialect{InlineLimits: true})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person WHERE name <> ? AND id <> '?' LIMIT 10 OFFSET 20", query)
+	assert.Equal(t, []any{"Fred"}, params)
+}
+
+func TestLimitCountErrors(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM person LIMIT $PageSpec.size", sqlairtesting.Person{}, PageSpec{})
+	assert.Nil(t, err)
+
+	db := NewDB(setupPersonDB(t))
+	var people []sqlairtesting.Person
+	err = db.Query(context.Background(), stmt, PageSpec{Size: -1}).GetAll(&people)
+	assert.EqualError(t, err, "LIMIT or OFFSET count -1 is out of range")
+
+	_, err = limitCount("10")
+	assert.EqualError(t, err, "LIMIT or OFFSET count must be an integer, got string")
+
+	_, err = limitCount(uint64(1 << 63))
+	assert.EqualError(t, err, "LIMIT or OFFSET count 9223372036854775808 is out of range")
+
+	size := 2
+	count, err := limitCount(&size)
+	assert.Nil(t, err)
+	assert.Equal(t, "2", count)
+}
+
+func TestQueryInlineLimits(t *testing.T) {
+	db := NewDB(setupPersonDB(t)).With(WithDialect(Dialect{InlineLimits: true}))
+
+	stmt, err := Prepare("SELECT &Person.* FROM person ORDER BY id LIMIT $PageSpec.size OFFSET $PageSpec.skip",
+		sqlairtesting.Person{}, PageSpec{})
+	assert.Nil(t, err)
+
+	var people []sqlairtesting.Person
+	err = db.Query(context.Background(), stmt, PageSpec{Size: 1, Skip: 1}).GetAll(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, []sqlairtesting.Person{{ID: "2", Name: "Onos"}}, people)
+
+	// The counts of a paginator's LIMIT clause are also inlined.
+	stmt, err = Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	p, err := NewOffsetPaginator(stmt, "id", 2)
+	assert.Nil(t, err)
+
+	people = nil
+	page, err := db.Paginate(context.Background(), p, Cursor{}).GetPage(&people)
+	assert.Nil(t, err)
+	page, err = db.Paginate(context.Background(), p, page.Next).GetPage(&people)
+	assert.Nil(t, err)
+	assert.True(t, page.Last)
+	assert.Equal(t, samplePeople(), people)
+}
+
+func TestSQLForLists(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id = ANY($IDList.ids) AND name <> ALL($IDList.ids) AND name = $Person.name",
+		sqlairtesting.Person{}, IDList{})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person WHERE id = ANY(?) AND name <> ALL(?) AND name = ?", stmt.sql)
+
+	args, err := stmt.bindInputs(context.Background(), []any{IDList{IDs: []string{"1", "2"}}, sqlairtesting.Person{Name: "Fred"}})
+	assert.Nil(t, err)
+
+	query, params, err := NewDB(nil).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person WHERE id IN (?, ?) AND name NOT IN (?, ?) AND name = ?", query)
+	assert.Equal(t, []any{"1", "2", "1", "2", "Fred"}, params)
+
+	query, params, err = NewDB(nil).With(WithDialect(Dialect{NativeArrays: true})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, stmt.sql, query)
+	assert.Equal(t, []any{[]string{"1", "2"}, []string{"1", "2"}, "Fred"}, params)
+
+	// An empty list is written as a subquery returning no rows.
+	args, err = stmt.bindInputs(context.Background(), []any{IDList{}, sqlairtesting.Person{Name: "Fred"}})
+	assert.Nil(t, err)
+	query, params, err = NewDB(nil).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person WHERE id IN (SELECT NULL WHERE 0 = 1) AND name NOT IN (SELECT NULL WHERE 0 = 1) AND name = ?", query)
+	assert.Equal(t, []any{"Fred"}, params)
+}
+
+func TestSQLForListErrors(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id > ANY($IDList.ids)", sqlairtesting.Person{}, IDList{})
+	assert.Nil(t, err)
+
+	args, err := stmt.bindInputs(context.Background(), []any{IDList{IDs: []string{"1"}}})
+	assert.Nil(t, err)
+	_, _, err = NewDB(nil).sqlFor(stmt, args)
+	assert.EqualError(t, err, `comparison "> ANY" of a slice requires a dialect with native arrays`)
+
+	_, _, err = NewDB(nil).With(WithDialect(Dialect{NativeArrays: true})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+
+	// Byte slices are single values, rather than lists.
+	stmt, err = Prepare("SELECT &Person.* FROM person WHERE id > ANY($IDList.data)", sqlairtesting.Person{}, IDList{})
+	assert.Nil(t, err)
+	assert.Empty(t, stmt.lists)
+}
+
+func TestQueryLists(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id = ANY($IDList.ids) ORDER BY id", sqlairtesting.Person{}, IDList{})
+	assert.Nil(t, err)
+
+	var people []sqlairtesting.Person
+	err = db.Query(context.Background(), stmt, IDList{IDs: []string{"1", "3"}}).GetAll(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, []sqlairtesting.Person{{ID: "1", Name: "Lorn"}, {ID: "3", Name: "Fred"}}, people)
+
+	stmt, err = Prepare("SELECT &Person.* FROM person WHERE id <> ALL($IDList.ids) ORDER BY id", sqlairtesting.Person{}, IDList{})
+	assert.Nil(t, err)
+
+	people = nil
+	err = db.Query(context.Background(), stmt, IDList{}).GetAll(&people)
+	assert.Nil(t, err)
+	assert.Len(t, people, 3)
+}
+
+func TestDialectString(t *testing.T) {
+	assert.Equal(t, "default", Dialect{}.String())
+	assert.Equal(t, "InlineLimits+ReturningKeys", Dialect{InlineLimits: true, ReturningKeys: true}.String())
+	assert.Equal(t, "ReturningKeys+NumberedPlaceholders", Dialect{ReturningKeys: true, NumberedPlaceholders: true}.String())
+	assert.Equal(t, "NumberedPlaceholders+ExplainText", Dialect{NumberedPlaceholders: true, Explain: ExplainText}.String())
+	assert.Equal(t, "ExplainTree+BacktickQuotes+ReservedWords", Dialect{Explain: ExplainTree, BacktickQuotes: true, ReservedWords: mysqlReservedWords}.String())
+}
+
+func TestSQLForNumberedPlaceholders(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id = ANY($IDList.ids) AND name <> '?' AND name = $Person.name LIMIT $PageSpec.size",
+		sqlairtesting.Person{}, IDList{}, PageSpec{})
+	assert.Nil(t, err)
+
+	args, err := stmt.bindInputs(context.Background(), []any{IDList{IDs: []string{"1", "2"}}, sqlairtesting.Person{Name: "Fred"}, PageSpec{Size: 5}})
+	assert.Nil(t, err)
+
+	query, params, err := NewDB(nil, WithDialect(Dialect{NumberedPlaceholders: true})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person WHERE id IN ($1, $2) AND name <> '?' AND name = $3 LIMIT $4", query)
+	assert.Equal(t, []any{"1", "2", "Fred", 5}, params)
+
+	query, params, err = NewDB(nil, WithDialect(Dialect{NumberedPlaceholders: true, InlineLimits: true, NativeArrays: true})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person WHERE id = ANY($1) AND name <> '?' AND name = $2 LIMIT 5", query)
+	assert.Equal(t, []any{[]string{"1", "2"}, "Fred"}, params)
+}
+
+func TestSQLForPlaceholdersAfterComments(t *testing.T) {
+	stmt, err := Prepare(`SELECT &Person.* FROM person -- is it?
+WHERE id = $Person.id /* don't ? */ AND name = $Person.name LIMIT $PageSpec.size`, sqlairtesting.Person{}, PageSpec{})
+	assert.Nil(t, err)
+
+	args, err := stmt.bindInputs(context.Background(), []any{sqlairtesting.Person{ID: "1", Name: "Fred"}, PageSpec{Size: 5}})
+	assert.Nil(t, err)
+
+	query, params, err := NewDB(nil, WithDialect(Dialect{NumberedPlaceholders: true})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person /* is it? */ WHERE id = $1 AND name = $2 /* don't ? */ LIMIT $3", query)
+	assert.Equal(t, []any{"1", "Fred", 5}, params)
+
+	query, params, err = NewDB(nil, WithDialect(Dialect{InlineLimits: true})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person /* is it? */ WHERE id = ? AND name = ? /* don't ? */ LIMIT 5", query)
+	assert.Equal(t, []any{"1", "Fred"}, params)
+}
+
+func TestCommentEnd(t *testing.T) {
+	assert.Equal(t, 8, commentEnd("a -- b ?\nc", 2))
+	assert.Equal(t, 9, commentEnd("a -- b ? c", 2))
+	assert.Equal(t, 8, commentEnd("a /* ? */ b", 2))
+	assert.Equal(t, 9, commentEnd("a /* ? * b", 2))
+	assert.Equal(t, 2, commentEnd("a - b", 2))
+	assert.Equal(t, 2, commentEnd("a / b", 2))
+	assert.Equal(t, 2, commentEnd("a -", 2))
+}
+
+func TestInlineParamsSkipsComments(t *testing.T) {
+	query := inlineParams("SELECT a /* don't ? */ FROM t -- it's?\nWHERE b = ? AND c = ?", nil, nil, false, true)
+	assert.Equal(t, "SELECT a /* don't ? */ FROM t -- it's?\nWHERE b = $1 AND c = $2", query)
+
+	query = inlineParams("SELECT a FROM t /* ? */ WHERE b = ? LIMIT ?", map[int]paramText{1: {text: "5"}}, nil, false, false)
+	assert.Equal(t, "SELECT a FROM t /* ? */ WHERE b = ? LIMIT 5", query)
+}
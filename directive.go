@@ -0,0 +1,44 @@
+package sqlair
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// applyDirectives sets the options of the statement given by directive
+// comments preceding its DSL, such as "-- sqlair:timeout=5s".
+// The only directive recognised is timeout, with a value in the form
+// accepted by time.ParseDuration.
+func (s *Statement) applyDirectives(directives map[string]string) error {
+	for name, value := range directives {
+		switch name {
+		case "timeout":
+			timeout, err := time.ParseDuration(value)
+			if err != nil || timeout <= 0 {
+				return errors.Errorf("timeout directive %q is not a positive duration", value)
+			}
+			s.timeout = timeout
+		default:
+			return errors.Errorf("unknown directive %q", name)
+		}
+	}
+	return nil
+}
+
+// Timeout returns the time limit for executing the statement,
+// given by its timeout directive, or zero if it has none.
+func (s *Statement) Timeout() time.Duration {
+	return s.timeout
+}
+
+// executionContext returns a context for executing the statement, derived
+// from the input context and limited by the statement's timeout, if it has
+// one. The returned function must be called when execution is complete.
+func (s *Statement) executionContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.timeout)
+}
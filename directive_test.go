@@ -0,0 +1,65 @@
+package sqlair
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareTimeoutDirective(t *testing.T) {
+	stmt, err := Prepare(`
+-- sqlair:timeout=5s
+SELECT &Person.* FROM person`, sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Second, stmt.Timeout())
+	assert.Equal(t, "SELECT id, name FROM person", stmt.sql)
+
+	stmt, err = PrepareReader(strings.NewReader("-- sqlair:timeout=250ms\nSELECT &Person.* FROM person"), sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, 250*time.Millisecond, stmt.Timeout())
+
+	// Derived and serialized statements keep the timeout.
+	derived, err := stmt.Derive(WithLimit("LIMIT 1"))
+	assert.Nil(t, err)
+	assert.Equal(t, 250*time.Millisecond, derived.Timeout())
+
+	data, err := stmt.MarshalJSON()
+	assert.Nil(t, err)
+	loaded, err := LoadStatement(data, sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, 250*time.Millisecond, loaded.Timeout())
+
+	stmt, err = Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), stmt.Timeout())
+}
+
+func TestPrepareDirectiveErrors(t *testing.T) {
+	_, err := Prepare("-- sqlair:timeout=soon\nSELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.EqualError(t, err, `timeout directive "soon" is not a positive duration`)
+
+	_, err = Prepare("-- sqlair:retries=3\nSELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.EqualError(t, err, `unknown directive "retries"`)
+}
+
+func TestQueryTimeoutDirective(t *testing.T) {
+	type Count struct {
+		N int `db:"n"`
+	}
+	db := NewDB(setupPersonDB(t))
+
+	// The recursive query never ends unless interrupted.
+	stmt, err := Prepare(`
+-- sqlair:timeout=50ms
+WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c)
+SELECT count(*) AS &Count.n FROM c`, Count{})
+	assert.Nil(t, err)
+
+	var c Count
+	err = db.Query(context.Background(), stmt).Get(&c)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,184 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// dqlitePackage is the import path of the packages of go-dqlite,
+// which declare the errors reported by dqlite nodes.
+const dqlitePackage = "github.com/canonical/go-dqlite"
+
+// The extended error codes with which a dqlite node reports that it is not
+// the leader, which it reports before running a statement, or that it has
+// lost leadership, which it may report after the statement has been run.
+// Versions before 3.32.1+replication4 reported different codes.
+const (
+	dqliteNotLeader            = 10 | 40<<8
+	dqliteLeadershipLost       = 10 | 41<<8
+	dqliteNotLeaderLegacy      = 10 | 32<<8
+	dqliteLeadershipLostLegacy = 10 | 33<<8
+)
+
+// dqliteNoLeader is the message of the error with which go-dqlite
+// reports that no node of the cluster could be found to be the leader.
+const dqliteNoLeader = "no available dqlite leader server found"
+
+// IsNotLeader returns true if the input error, or one that it wraps, is
+// from a dqlite node reporting that it is not the leader of its cluster,
+// or from finding no leader, such as during an election. The statement
+// was not run, so it can be run again once a leader is elected.
+func IsNotLeader(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if code, ok := dqliteErrorCode(err); ok {
+			return code == dqliteNotLeader || code == dqliteNotLeaderLegacy
+		}
+		if msg := err.Error(); msg == dqliteNoLeader || msg == "not leader" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLeadershipLost returns true if the input error, or one that it wraps,
+// is from a dqlite node reporting that it lost leadership of its cluster
+// while running a statement. Whether a statement that modifies the
+// database was applied is not known.
+func IsLeadershipLost(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if code, ok := dqliteErrorCode(err); ok {
+			return code == dqliteLeadershipLost || code == dqliteLeadershipLostLegacy
+		}
+		if err.Error() == "leadership lost" {
+			return true
+		}
+	}
+	return false
+}
+
+// dqliteErrorCode returns the code of the input error, and true, if it is
+// an error reported by a dqlite node: a struct declared by go-dqlite with an
+// integer Code field. Its type is matched by reflection, so that Sqlair
+// does not depend on go-dqlite.
+func dqliteErrorCode(err error) (int, bool) {
+	v := reflect.Indirect(reflect.ValueOf(err))
+	if v.Kind() != reflect.Struct || !strings.HasPrefix(v.Type().PkgPath(), dqlitePackage) {
+		return 0, false
+	}
+	code := v.FieldByName("Code")
+	switch code.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(code.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(code.Uint()), true
+	}
+	return 0, false
+}
+
+// NewDqliteConn returns a Conn that executes statements using the input
+// pool of connections to a dqlite database, such as that opened by
+// go-dqlite's app.App.Open. A statement refused because the node
+// connected to is not the leader, or because there is no leader, is run
+// again, on a connection that the pool opens to the new leader, until it
+// is accepted or the statement's context is done; see IsNotLeader.
+// Statements interrupted by the loss of leadership are not run again,
+// since they may have been applied; see IsLeadershipLost.
+//
+// Example:
+//
+//     app, err := app.New(dir, app.WithAddress(address), app.WithCluster(cluster))
+//     if err != nil {
+//         return err
+//     }
+//     pool, err := app.Open(ctx, "juju")
+//     if err != nil {
+//         return err
+//     }
+//     conn := sqlair.NewDqliteConn(pool)
+//
+func NewDqliteConn(pool *sql.DB) Conn {
+	return &dqliteConn{DB: pool, backoff: 10 * time.Millisecond, maxBackoff: time.Second}
+}
+
+// NewDqliteDB returns a reference to a new DB that executes statements
+// using the input pool of connections to a dqlite database, through the
+// Conn returned by NewDqliteConn, configured by the input options.
+// Statements run on a connection pinned by RunPinned, or in a transaction,
+// are not run again. The DB generates SQL for SQLite, which dqlite embeds,
+// and Probe identifies the database as SQLite.
+func NewDqliteDB(pool *sql.DB, options ...DBOption) *DB {
+	return NewDB(NewDqliteConn(pool), append([]DBOption{WithDialect(Dialect{})}, options...)...)
+}
+
+// dqliteConn is a pool of connections to a dqlite database, which runs
+// statements again that are refused because there is no leader to run
+// them; see NewDqliteConn. The methods of the pool that Sqlair uses
+// other than to run statements, such as Conn and Driver, are promoted.
+type dqliteConn struct {
+	*sql.DB
+
+	// backoff is the time waited before running a statement again
+	// for the first time. It is doubled for each time thereafter, up
+	// to maxBackoff.
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// PrepareContext implements Conn.
+func (c *dqliteConn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	var stmt *sql.Stmt
+	err := c.retry(ctx, func() (err error) {
+		stmt, err = c.DB.PrepareContext(ctx, query)
+		return err
+	})
+	return stmt, err
+}
+
+// QueryContext implements Conn.
+func (c *dqliteConn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := c.retry(ctx, func() (err error) {
+		rows, err = c.DB.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// ExecContext implements Conn.
+func (c *dqliteConn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	var result sql.Result
+	err := c.retry(ctx, func() (err error) {
+		result, err = c.DB.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// retry calls the input function until it returns an error other than
+// one for which IsNotLeader is true, or the input context is done, in
+// which case the last error from the function is returned.
+func (c *dqliteConn) retry(ctx context.Context, fn func() error) error {
+	backoff := c.backoff
+	for {
+		err := fn()
+		if !IsNotLeader(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// electionConnector opens connections to a database that refuses
+// statements with its errors, in turn, before accepting them.
+type electionConnector struct {
+	errs []error
+	runs int
+}
+
+func (c *electionConnector) Connect(context.Context) (driver.Conn, error) {
+	return electionConn{c}, nil
+}
+
+func (c *electionConnector) Driver() driver.Driver {
+	return nil
+}
+
+type electionConn struct {
+	c *electionConnector
+}
+
+func (electionConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (electionConn) Close() error {
+	return nil
+}
+
+func (electionConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (e electionConn) run() error {
+	e.c.runs++
+	if len(e.c.errs) == 0 {
+		return nil
+	}
+	err := e.c.errs[0]
+	e.c.errs = e.c.errs[1:]
+	return err
+}
+
+func (e electionConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	if err := e.run(); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (e electionConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	if err := e.run(); err != nil {
+		return nil, err
+	}
+	return electionRows{}, nil
+}
+
+// electionRows are the rows, of which there are none,
+// returned by the queries accepted by an electionConn.
+type electionRows struct{}
+
+func (electionRows) Columns() []string {
+	return []string{"id"}
+}
+
+func (electionRows) Close() error {
+	return nil
+}
+
+func (electionRows) Next([]driver.Value) error {
+	return io.EOF
+}
+
+func newElectionConn(errs ...error) (Conn, *electionConnector) {
+	c := &electionConnector{errs: errs}
+	conn := NewDqliteConn(sql.OpenDB(c))
+	conn.(*dqliteConn).backoff = time.Millisecond
+	return conn, c
+}
+
+func TestIsNotLeader(t *testing.T) {
+	assert.True(t, IsNotLeader(errors.New("not leader")))
+	assert.True(t, IsNotLeader(errors.Wrap(errors.New(dqliteNoLeader), "running statement")))
+	assert.True(t, IsNotLeader(fmt.Errorf("running statement: %w", errors.New("not leader"))))
+	assert.False(t, IsNotLeader(errors.New("leadership lost")))
+	assert.False(t, IsNotLeader(errors.New("database is locked")))
+	assert.False(t, IsNotLeader(nil))
+
+	assert.True(t, IsLeadershipLost(errors.Wrap(errors.New("leadership lost"), "running statement")))
+	assert.False(t, IsLeadershipLost(errors.New("not leader")))
+}
+
+func TestDqliteConnRetriesNotLeader(t *testing.T) {
+	conn, c := newElectionConn(errors.New("not leader"), errors.New(dqliteNoLeader))
+	_, err := conn.ExecContext(context.Background(), "DELETE FROM person")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, c.runs)
+
+	conn, c = newElectionConn(errors.New("not leader"))
+	rows, err := conn.QueryContext(context.Background(), "SELECT id FROM address")
+	if assert.Nil(t, err) {
+		assert.False(t, rows.Next())
+		assert.Nil(t, rows.Close())
+	}
+	assert.Equal(t, 2, c.runs)
+}
+
+func TestDqliteConnLeadershipLost(t *testing.T) {
+	// A statement that may have been applied is not run again.
+	conn, c := newElectionConn(errors.New("leadership lost"))
+	_, err := conn.ExecContext(context.Background(), "DELETE FROM person")
+	assert.True(t, IsLeadershipLost(err))
+	assert.Equal(t, 1, c.runs)
+}
+
+func TestDqliteConnContextDone(t *testing.T) {
+	errs := make([]error, 1000)
+	for i := range errs {
+		errs[i] = errors.New("not leader")
+	}
+	conn, _ := newElectionConn(errs...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := conn.ExecContext(ctx, "DELETE FROM person")
+	assert.True(t, IsNotLeader(err))
+}
+
+func TestDqliteDB(t *testing.T) {
+	c := &electionConnector{errs: []error{errors.New("not leader")}}
+	db := NewDqliteDB(sql.OpenDB(c))
+	db.conn.(*dqliteConn).backoff = time.Millisecond
+	assert.Equal(t, Dialect{}, db.dialect)
+
+	stmt, err := Prepare("SELECT &Item.id FROM item", Item{})
+	assert.Nil(t, err)
+	var items []Item
+	err = db.Query(context.Background(), stmt).GetAll(&items)
+	assert.Nil(t, err)
+	assert.Len(t, items, 0)
+	assert.Equal(t, 2, c.runs)
+}
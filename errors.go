@@ -1,6 +1,10 @@
 package sqlair
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
 
 // ErrTypeNameNotUnique is an error indicating that the objects
 // passed as arguments to statement preparation do not constitute
@@ -57,3 +61,123 @@ func NewErrSuperfluousType(name string) error {
 func (e *ErrSuperfluousType) Error() string {
 	return fmt.Sprintf("type with name %q was supplied, but is not used in the statement", e.name)
 }
+
+// ErrFieldNotPresent is an error indicating that a field referenced by
+// an input or output target in a DSL statement does not correspond to
+// a tagged field of the named type.
+type ErrFieldNotPresent struct {
+	typeName string
+	field    string
+}
+
+// NewErrFieldNotPresent returns a new error
+// for the input type and missing field.
+func NewErrFieldNotPresent(typeName, field string) error {
+	return &ErrFieldNotPresent{typeName: typeName, field: field}
+}
+
+// Error implements error, returning a message
+// indicating the type and missing field.
+func (e *ErrFieldNotPresent) Error() string {
+	return fmt.Sprintf("type %q has no field with tag %q", e.typeName, e.field)
+}
+
+// ErrInvalidStatements is an error indicating that statements
+// registered with a Registry are not valid.
+type ErrInvalidStatements struct {
+	errs map[string]error
+}
+
+// NewErrInvalidStatements returns a new error
+// for the input errors, indexed by statement name.
+func NewErrInvalidStatements(errs map[string]error) error {
+	return &ErrInvalidStatements{errs: errs}
+}
+
+// Error implements error, returning a message
+// indicating each invalid statement, by name.
+func (e *ErrInvalidStatements) Error() string {
+	names := make([]string, 0, len(e.errs))
+	for name := range e.errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%q: %v", name, e.errs[name])
+	}
+	return "invalid statements: " + strings.Join(msgs, "; ")
+}
+
+// Errors returns the error for each invalid statement, by name.
+func (e *ErrInvalidStatements) Errors() map[string]error {
+	errs := make(map[string]error, len(e.errs))
+	for name, err := range e.errs {
+		errs[name] = err
+	}
+	return errs
+}
+
+// ErrInvalidInput is an error indicating that an input
+// to a statement that modifies data failed validation.
+type ErrInvalidInput struct {
+	name string
+	err  error
+}
+
+// NewErrInvalidInput returns a new error for the
+// input type name and its validation error.
+func NewErrInvalidInput(name string, err error) error {
+	return &ErrInvalidInput{name: name, err: err}
+}
+
+// Error implements error, returning a message
+// indicating the invalid input and why.
+func (e *ErrInvalidInput) Error() string {
+	return fmt.Sprintf("input %q is invalid: %v", e.name, e.err)
+}
+
+// Cause returns the error returned by the input's Validate method.
+func (e *ErrInvalidInput) Cause() error {
+	return e.err
+}
+
+// Unwrap returns the error returned by the input's Validate method.
+func (e *ErrInvalidInput) Unwrap() error {
+	return e.err
+}
+
+// ErrParseLimitExceeded is an error indicating that a statement
+// was refused for exceeding one of the ParseLimits passed to Prepare.
+type ErrParseLimitExceeded struct {
+	limit string
+	err   error
+}
+
+// NewErrParseLimitExceeded returns a new error for the input name
+// of the exceeded limit, such as "MaxTokens", and the parse error.
+func NewErrParseLimitExceeded(limit string, err error) error {
+	return &ErrParseLimitExceeded{limit: limit, err: err}
+}
+
+// Error implements error, returning the message of the parse error,
+// which indicates the limit and where it is exceeded.
+func (e *ErrParseLimitExceeded) Error() string {
+	return e.err.Error()
+}
+
+// Limit returns the name of the field of ParseLimits that is exceeded.
+func (e *ErrParseLimitExceeded) Limit() string {
+	return e.limit
+}
+
+// Cause returns the error from parsing the statement.
+func (e *ErrParseLimitExceeded) Cause() error {
+	return e.err
+}
+
+// Unwrap returns the error from parsing the statement.
+func (e *ErrParseLimitExceeded) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,30 @@
+package sqlair
+
+import "github.com/canonical/sqlair/internal/parse"
+
+// WithBackslashEscapes returns an option determining whether a backslash
+// within a string literal escapes the character following it, as in MySQL's
+// default SQL mode, so that 'it\'s' is a single literal. By default, as in
+// standard SQL, a backslash is an ordinary character, and quotes within
+// string literals are escaped only by doubling them, as in 'it''s'.
+//
+// Example:
+//
+//     stmt, err := sqlair.Prepare(`
+//     SELECT &Person.*
+//       FROM person
+//      WHERE name <> 'O\'Brien'`, sqlair.WithBackslashEscapes(true), Person{})
+//
+func WithBackslashEscapes(escapes bool) PrepareOption {
+	return func(o *prepareOptions) {
+		o.escapes = escapes
+	}
+}
+
+// newLexer returns a reference to a new Lexer for the input statement,
+// reading string literals with backslash escapes if escapes is true.
+func newLexer(stmt string, escapes bool) *parse.Lexer {
+	lex := parse.NewLexer(stmt)
+	lex.SetBackslashEscapes(escapes)
+	return lex
+}
@@ -0,0 +1,44 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareBackslashEscapes(t *testing.T) {
+	stmt, err := Prepare(`SELECT &Person.* FROM person WHERE name <> 'it\'s ?' LIMIT $PageSpec.size`,
+		WithBackslashEscapes(true), WithStrictness(true), sqlairtesting.Person{}, PageSpec{})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, `SELECT id, name FROM person WHERE name <> 'it\'s ?' LIMIT ?`, stmt.sql)
+
+	args, err := stmt.bindInputs(context.Background(), []any{PageSpec{Size: 10}})
+	assert.Nil(t, err)
+
+	// The question mark within the literal is not a placeholder.
+	query, _, err := NewDB(nil).With(WithDialect(Dialect{InlineLimits: true})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT id, name FROM person WHERE name <> 'it\'s ?' LIMIT 10`, query)
+
+	// Without backslash escapes, the literal is not terminated.
+	_, err = Prepare(`SELECT &Person.* FROM person WHERE name <> 'it\'s'`, WithStrictness(true), sqlairtesting.Person{})
+	assert.EqualError(t, err, "unterminated string literal at line 1, column 50")
+}
+
+func TestConditionBackslashEscapes(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	cond, err := PrepareCondition(`name <> 'O\'Brien'`, WithBackslashEscapes(true))
+	assert.Nil(t, err)
+
+	filtered, err := stmt.Where(cond)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT id, name FROM person WHERE (name <> 'O\'Brien')`, filtered.sql)
+	assert.True(t, filtered.backslashEscapes)
+	assert.False(t, stmt.backslashEscapes)
+}
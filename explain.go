@@ -0,0 +1,259 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ExplainSyntax is the syntax with which a database is asked for the plan
+// by which it would run a statement, and in which the plan is reported.
+type ExplainSyntax int
+
+const (
+	// ExplainQueryPlan, the default, prefixes statements with "EXPLAIN
+	// QUERY PLAN", as does SQLite, which reports a row for each step of
+	// the plan, identifying the step of which it is a part.
+	ExplainQueryPlan ExplainSyntax = iota
+
+	// ExplainText prefixes statements with "EXPLAIN", as does PostgreSQL,
+	// which reports the plan as indented lines of text, each step other
+	// than the first following "->".
+	ExplainText
+
+	// ExplainTree prefixes statements with "EXPLAIN FORMAT=TREE", as does
+	// MySQL, which reports the plan as indented lines of text, each step
+	// following "->".
+	ExplainTree
+)
+
+// explainPrefixes holds the keywords with which each ExplainSyntax
+// prefixes statements.
+var explainPrefixes = map[ExplainSyntax]string{
+	ExplainQueryPlan: "EXPLAIN QUERY PLAN",
+	ExplainText:      "EXPLAIN",
+	ExplainTree:      "EXPLAIN FORMAT=TREE",
+}
+
+// QueryPlan is the plan by which a database would run a statement, as
+// reported by Statement.Explain.
+type QueryPlan struct {
+	// Steps are the steps of the plan that are not part of another.
+	Steps []*PlanStep `json:"steps"`
+}
+
+// PlanStep is a step of a QueryPlan, such as a scan of a table.
+type PlanStep struct {
+	// Detail describes the step as reported by the database,
+	// such as "SCAN person" or "Seq Scan on person".
+	Detail string `json:"detail"`
+
+	// Properties holds any further lines reported for the step,
+	// such as PostgreSQL's "Filter: (name <> 'Fred'::text)".
+	Properties []string `json:"properties,omitempty"`
+
+	// Steps are the steps that are part of this one.
+	Steps []*PlanStep `json:"steps,omitempty"`
+}
+
+// String returns the plan's steps on separate lines, each indented
+// beneath that of which it is a part, followed by their properties.
+func (p *QueryPlan) String() string {
+	var sb strings.Builder
+	var write func(steps []*PlanStep, depth int)
+	write = func(steps []*PlanStep, depth int) {
+		indent := strings.Repeat("  ", depth)
+		for _, step := range steps {
+			sb.WriteString(indent + step.Detail + "\n")
+			for _, property := range step.Properties {
+				sb.WriteString(indent + "  " + property + "\n")
+			}
+			write(step.Steps, depth+1)
+		}
+	}
+	write(p.Steps, 0)
+	return sb.String()
+}
+
+// Explain returns the plan by which the database of the input DB would run
+// the statement, with parameters sourced from the input objects. The
+// statement's SQL, as compiled for the DB's dialect, is prefixed with the
+// dialect's Explain syntax, so the statement itself is not run. It suits
+// checking, from tests, that a statement uses the indexes intended for it.
+//
+// Example:
+//
+//     plan, err := stmt.Explain(ctx, db, Person{ID: id})
+//     if err != nil {
+//         return err
+//     }
+//     if !strings.Contains(plan.String(), "USING INDEX") {
+//         t.Errorf("statement does not use an index:\n%s", plan)
+//     }
+//
+func (s *Statement) Explain(ctx context.Context, db *DB, inputs ...any) (*QueryPlan, error) {
+	args, err := s.bindInputs(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	query, params, err := db.sqlFor(s, args)
+	if err != nil {
+		return nil, s.redactError(err, args)
+	}
+
+	ctx, cancel := s.executionContext(ctx)
+	defer cancel()
+	rows, err := db.connFor(s).QueryContext(ctx, explainPrefixes[db.dialect.Explain]+" "+query, params...)
+	if err != nil {
+		return nil, errors.Wrap(s.redactError(err, args), "explaining statement")
+	}
+	defer func() { _ = rows.Close() }()
+
+	var plan *QueryPlan
+	if db.dialect.Explain == ExplainQueryPlan {
+		plan, err = readQueryPlan(rows)
+	} else {
+		plan, err = readTextPlan(rows)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading query plan")
+	}
+	return plan, rows.Close()
+}
+
+// readQueryPlan returns the plan reported by the input rows of an
+// "EXPLAIN QUERY PLAN" statement, which have the columns "id", "parent"
+// and "detail", among others. Each step is identified by its id, and is
+// part of the step identified by its parent, if it is not zero.
+func readQueryPlan(rows *sql.Rows) (*QueryPlan, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	id, parent, detail := -1, -1, -1
+	for i, column := range columns {
+		switch strings.ToLower(column) {
+		case "id":
+			id = i
+		case "parent":
+			parent = i
+		case "detail":
+			detail = i
+		}
+	}
+	if id < 0 || parent < 0 || detail < 0 {
+		return nil, errors.Errorf("expected columns \"id\", \"parent\" and \"detail\", got %q", columns)
+	}
+
+	plan := &QueryPlan{}
+	steps := make(map[int64]*PlanStep)
+	var stepID, parentID int64
+	var text string
+	ptrs := make([]any, len(columns))
+	for i := range ptrs {
+		ptrs[i] = new(any)
+	}
+	ptrs[id], ptrs[parent], ptrs[detail] = &stepID, &parentID, &text
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		step := &PlanStep{Detail: text}
+		steps[stepID] = step
+		if p, ok := steps[parentID]; ok && parentID != 0 {
+			p.Steps = append(p.Steps, step)
+		} else {
+			plan.Steps = append(plan.Steps, step)
+		}
+	}
+	return plan, rows.Err()
+}
+
+// readTextPlan returns the plan reported by the input rows as indented
+// lines of text, in their first column. A line following "->" begins a
+// step, which is part of the closest preceding step that is indented less.
+// The first line begins a step whether or not it follows "->". Other lines
+// are properties of the step preceding them.
+func readTextPlan(rows *sql.Rows) (*QueryPlan, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("expected a column of text")
+	}
+
+	var lines []string
+	var text string
+	ptrs := make([]any, len(columns))
+	for i := range ptrs {
+		ptrs[i] = new(any)
+	}
+	ptrs[0] = &text
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		lines = append(lines, strings.Split(text, "\n")...)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return parseTextPlan(lines), nil
+}
+
+// parseTextPlan returns the plan described by the input lines of text;
+// see readTextPlan.
+func parseTextPlan(lines []string) *QueryPlan {
+	type indented struct {
+		step   *PlanStep
+		indent int
+	}
+
+	plan := &QueryPlan{}
+	var stack []indented
+	for _, line := range lines {
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		detail := strings.TrimPrefix(text, "->")
+		if detail == text && len(stack) > 0 {
+			top := stack[len(stack)-1].step
+			top.Properties = append(top.Properties, text)
+			continue
+		}
+
+		step := &PlanStep{Detail: strings.TrimSpace(detail)}
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1].step
+			parent.Steps = append(parent.Steps, step)
+		} else {
+			plan.Steps = append(plan.Steps, step)
+		}
+		stack = append(stack, indented{step: step, indent: indent})
+	}
+	return plan
+}
+
+// String returns the name of the syntax, such as "ExplainText".
+func (e ExplainSyntax) String() string {
+	switch e {
+	case ExplainQueryPlan:
+		return "ExplainQueryPlan"
+	case ExplainText:
+		return "ExplainText"
+	case ExplainTree:
+		return "ExplainTree"
+	}
+	return fmt.Sprintf("ExplainSyntax(%d)", int(e))
+}
@@ -0,0 +1,88 @@
+package sqlair
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	conn := setupPersonDB(t)
+	_, err := conn.Exec("CREATE INDEX person_name ON person (name)")
+	assert.Nil(t, err)
+	db := NewDB(conn)
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE name = $Person.name", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	plan, err := stmt.Explain(context.Background(), db, sqlairtesting.Person{Name: "Fred"})
+	assert.Nil(t, err)
+	if assert.Len(t, plan.Steps, 1) {
+		assert.Contains(t, plan.Steps[0].Detail, "USING INDEX person_name")
+	}
+
+	// Steps that are part of others are nested within them.
+	stmt, err = Prepare(`
+SELECT &Person.* FROM person
+ WHERE id IN (SELECT id FROM person WHERE name <> $Person.name)
+ UNION
+SELECT &Person.* FROM person WHERE id = '1'`, sqlairtesting.Person{})
+	assert.Nil(t, err)
+	plan, err = stmt.Explain(context.Background(), db, sqlairtesting.Person{Name: "Fred"})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(plan.String(), "COMPOUND QUERY\n  LEFT-MOST SUBQUERY\n"), plan.String())
+
+	// The statement is not run.
+	stmt, err = Prepare("DELETE FROM person")
+	assert.Nil(t, err)
+	_, err = stmt.Explain(context.Background(), db)
+	assert.Nil(t, err)
+	var people []sqlairtesting.Person
+	selectAll, err := Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	err = db.Query(context.Background(), selectAll).GetAll(&people)
+	assert.Nil(t, err)
+	assert.Len(t, people, 3)
+}
+
+func TestParseTextPlan(t *testing.T) {
+	// As reported by PostgreSQL.
+	plan := parseTextPlan(strings.Split(`Hash Join  (cost=1.07..2.15 rows=3 width=64)
+  Hash Cond: (p.id = a.person_id)
+  ->  Seq Scan on person p  (cost=0.00..1.03 rows=3 width=64)
+        Filter: (name <> 'Fred'::text)
+  ->  Hash  (cost=1.03..1.03 rows=3 width=32)
+        ->  Seq Scan on address a  (cost=0.00..1.03 rows=3 width=32)`, "\n"))
+	assert.Equal(t, &QueryPlan{Steps: []*PlanStep{{
+		Detail:     "Hash Join  (cost=1.07..2.15 rows=3 width=64)",
+		Properties: []string{"Hash Cond: (p.id = a.person_id)"},
+		Steps: []*PlanStep{{
+			Detail:     "Seq Scan on person p  (cost=0.00..1.03 rows=3 width=64)",
+			Properties: []string{"Filter: (name <> 'Fred'::text)"},
+		}, {
+			Detail: "Hash  (cost=1.03..1.03 rows=3 width=32)",
+			Steps: []*PlanStep{{
+				Detail: "Seq Scan on address a  (cost=0.00..1.03 rows=3 width=32)",
+			}},
+		}},
+	}}}, plan)
+
+	// As reported by MySQL.
+	plan = parseTextPlan(strings.Split(`-> Filter: (person.name <> 'Fred')  (cost=0.55 rows=2)
+    -> Table scan on person  (cost=0.55 rows=3)
+`, "\n"))
+	assert.Equal(t, &QueryPlan{Steps: []*PlanStep{{
+		Detail: "Filter: (person.name <> 'Fred')  (cost=0.55 rows=2)",
+		Steps:  []*PlanStep{{Detail: "Table scan on person  (cost=0.55 rows=3)"}},
+	}}}, plan)
+	assert.Equal(t, "Filter: (person.name <> 'Fred')  (cost=0.55 rows=2)\n  Table scan on person  (cost=0.55 rows=3)\n", plan.String())
+}
+
+func TestExplainSyntaxString(t *testing.T) {
+	assert.Equal(t, "ExplainQueryPlan", ExplainQueryPlan.String())
+	assert.Equal(t, "ExplainTree", ExplainTree.String())
+	assert.Equal(t, "ExplainSyntax(7)", ExplainSyntax(7).String())
+}
@@ -0,0 +1,143 @@
+package sqlair
+
+import (
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ExportCSV executes the query and writes its result rows to the input
+// writer as CSV, preceded by a header. The columns are those decoded into
+// output targets, in the order selected, each headed by the "db" tag of the
+// field into which it is decoded, qualified by the name of the field's type,
+// as in "Person.id", if the query has outputs of more than one type. Rows
+// are decoded before they are written, with transformers and AfterSelect
+// hooks. Fields implementing driver.Valuer, such as sql.NullString, are
+// written as their values, and NULL values as empty values.
+//
+// Example:
+//
+//     w.Header().Set("Content-Type", "text/csv")
+//     err := db.Query(ctx, stmt, filter).ExportCSV(w)
+//
+func (q *Query) ExportCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	record := make([]string, 0, len(q.stmt.outputs))
+	err := q.export(func(header []string) error {
+		return cw.Write(header)
+	}, func(values []any) error {
+		record = record[:0]
+		for _, value := range values {
+			record = append(record, csvValue(value))
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ExportNDJSON executes the query and writes its result rows to the input
+// writer as newline-delimited JSON: a JSON object on each line, with a key
+// for each column decoded into an output target, named as the column's
+// header is by ExportCSV.
+func (q *Query) ExportNDJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	var header []string
+	return q.export(func(h []string) error {
+		header = h
+		return nil
+	}, func(values []any) error {
+		object := make(map[string]any, len(values))
+		for i, value := range values {
+			object[header[i]] = value
+		}
+		return enc.Encode(object)
+	})
+}
+
+// export executes the query, passing the header of its output columns to
+// the input header function, and then the values decoded for those columns
+// from each result row to the input row function.
+func (q *Query) export(header func([]string) error, row func([]any) error) error {
+	var names []string
+	outputs := make(map[string]reflect.Value)
+	for _, out := range q.stmt.outputs {
+		if _, ok := outputs[out.typeName]; !ok {
+			outputs[out.typeName] = reflect.New(q.stmt.argTypes[out.typeName].Type())
+			names = append(names, out.typeName)
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("can not export query without output targets")
+	}
+
+	columns := make([]string, len(q.stmt.outputs))
+	for i, out := range q.stmt.outputs {
+		columns[i] = out.tag
+		if len(names) > 1 {
+			columns[i] = out.typeName + "." + out.tag
+		}
+	}
+	if err := header(columns); err != nil {
+		return err
+	}
+
+	targets := make([]any, len(names))
+	for i, name := range names {
+		targets[i] = Alias(name, outputs[name].Interface())
+	}
+	values := make([]any, len(q.stmt.outputs))
+	return q.ForEach(func() error {
+		for i, out := range q.stmt.outputs {
+			value, err := exportValue(outputs[out.typeName].Elem().Field(out.field.Index).Interface())
+			if err != nil {
+				return errors.Wrapf(err, "exporting column %q", out.column)
+			}
+			values[i] = value
+		}
+		return row(values)
+	}, targets...)
+}
+
+// exportValue returns the input field value as it is exported: the value
+// of a driver.Valuer, such as sql.NullString, or otherwise the value itself.
+func exportValue(value any) (any, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr && v.IsNil() {
+			return nil, nil
+		}
+		return valuer.Value()
+	}
+	return value, nil
+}
+
+// csvValue returns the text of the input field value in a CSV record.
+func csvValue(value any) string {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprint(v.Interface())
+}
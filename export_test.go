@@ -0,0 +1,61 @@
+package sqlair
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportCSV(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+	ctx := context.Background()
+
+	stmt, err := Prepare("SELECT &Person.* FROM person ORDER BY id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	err = db.Query(ctx, stmt).ExportCSV(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "id,name\n1,Lorn\n2,Onos\n3,Fred\n", buf.String())
+
+	// Columns of more than one type are qualified by the type's name.
+	stmt, err = Prepare(`
+SELECT p.name AS &Person.name, m.name AS &Manager.name
+  FROM person AS p
+  JOIN person AS m ON m.id = '1'
+ WHERE p.id = '2'`, sqlairtesting.Person{}, Alias("Manager", sqlairtesting.Person{}))
+	assert.Nil(t, err)
+
+	buf.Reset()
+	err = db.Query(ctx, stmt).ExportCSV(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "Person.name,Manager.name\nOnos,Lorn\n", buf.String())
+
+	err = db.Query(ctx, MustPrepare("SELECT 1")).ExportCSV(&buf)
+	assert.EqualError(t, err, "can not export query without output targets")
+}
+
+func TestExportNDJSON(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id < '3' ORDER BY id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+	err = db.Query(context.Background(), stmt).ExportNDJSON(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"id\":\"1\",\"name\":\"Lorn\"}\n{\"id\":\"2\",\"name\":\"Onos\"}\n", buf.String())
+}
+
+func TestCSVValue(t *testing.T) {
+	name := "Fred"
+	var none *string
+	assert.Equal(t, "Fred", csvValue(&name))
+	assert.Equal(t, "", csvValue(none))
+	assert.Equal(t, "", csvValue(nil))
+	assert.Equal(t, "42", csvValue(42))
+	assert.Equal(t, "raw", csvValue([]byte("raw")))
+}
@@ -0,0 +1,134 @@
+// Package fragments provides reusable WHERE and ORDER BY fragments of
+// Sqlair statements. Each fragment is parsed, and its input sources
+// validated against its own type objects, once, when it is created, and can
+// then be embedded into any number of statements without being parsed again.
+package fragments
+
+import (
+	"github.com/canonical/sqlair"
+	"github.com/pkg/errors"
+)
+
+// Fragment is a reusable part of a statement,
+// created by NewWhere or NewOrderBy.
+type Fragment interface {
+	// String returns the DSL of the fragment.
+	String() string
+
+	// fragment prevents types from other packages
+	// from implementing Fragment.
+	fragment()
+}
+
+// Where is a predicate that is ANDed into the WHERE clause
+// of the statements into which it is embedded.
+type Where struct {
+	source    string
+	condition *sqlair.Condition
+}
+
+// NewWhere returns a Where for the input DSL predicate, such as
+// "person.tenant_id = $Tenant.id". Type information for its input sources
+// is inferred from the input objects, as for sqlair.PrepareCondition.
+func NewWhere(predicate string, args ...any) (*Where, error) {
+	cond, err := sqlair.PrepareCondition(predicate, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "preparing WHERE fragment %q", predicate)
+	}
+	return &Where{source: predicate, condition: cond}, nil
+}
+
+// MustWhere is like NewWhere, but panics if the fragment can not be
+// prepared. It simplifies the initialisation of package-level variables.
+func MustWhere(predicate string, args ...any) *Where {
+	w, err := NewWhere(predicate, args...)
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
+
+// String returns the DSL of the predicate.
+func (w *Where) String() string {
+	return w.source
+}
+
+func (*Where) fragment() {}
+
+// OrderBy is an ORDER BY clause that replaces that of
+// the statements into which it is embedded.
+type OrderBy struct {
+	source string
+	clause *sqlair.Clause
+}
+
+// NewOrderBy returns an OrderBy for the input DSL clause, such as
+// "ORDER BY name DESC". Type information for any input sources in the
+// clause is inferred from the input objects, as for sqlair.PrepareClause.
+func NewOrderBy(clause string, args ...any) (*OrderBy, error) {
+	c, err := sqlair.PrepareClause(clause, args...)
+	if err == nil && !c.IsOrderBy() {
+		err = errors.New("not an ORDER BY clause")
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "preparing ORDER BY fragment %q", clause)
+	}
+	return &OrderBy{source: clause, clause: c}, nil
+}
+
+// MustOrderBy is like NewOrderBy, but panics if the fragment can not be
+// prepared. It simplifies the initialisation of package-level variables.
+func MustOrderBy(clause string, args ...any) *OrderBy {
+	o, err := NewOrderBy(clause, args...)
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
+// String returns the DSL of the clause.
+func (o *OrderBy) String() string {
+	return o.source
+}
+
+func (*OrderBy) fragment() {}
+
+// Embed returns a new Statement in which the input fragments are embedded
+// into the input statement, which must be a single SELECT, UPDATE or
+// DELETE; the statement is not modified. The predicates of Where fragments
+// are ANDed into its WHERE clause, and an OrderBy fragment replaces its
+// ORDER BY clause. At most one OrderBy fragment can be embedded.
+//
+// Example:
+//
+//     var (
+//         byTenant = fragments.MustWhere("person.tenant_id = $Tenant.id", Tenant{})
+//         byName   = fragments.MustOrderBy("ORDER BY person.name")
+//     )
+//
+//     stmt, err := fragments.Embed(listPeople, byTenant, byName)
+//
+func Embed(stmt *sqlair.Statement, fragments ...Fragment) (*sqlair.Statement, error) {
+	var conditions []*sqlair.Condition
+	var orderBy *OrderBy
+	for _, f := range fragments {
+		switch f := f.(type) {
+		case *Where:
+			conditions = append(conditions, f.condition)
+		case *OrderBy:
+			if orderBy != nil {
+				return nil, errors.Errorf("ORDER BY fragments %q and %q can not both be embedded", orderBy, f)
+			}
+			orderBy = f
+		}
+	}
+
+	stmt, err := stmt.Where(conditions...)
+	if err != nil {
+		return nil, err
+	}
+	if orderBy != nil {
+		return stmt.Derive(sqlair.WithClause(orderBy.clause))
+	}
+	return stmt, nil
+}
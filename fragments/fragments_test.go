@@ -0,0 +1,47 @@
+package fragments
+
+import (
+	"testing"
+
+	"github.com/canonical/sqlair"
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+type Tenant struct {
+	ID string `db:"id"`
+}
+
+func TestEmbed(t *testing.T) {
+	byTenant := MustWhere("tenant_id = $Tenant.id", Tenant{})
+	named := MustWhere("name <> ''")
+	byName := MustOrderBy("ORDER BY name DESC")
+
+	list := sqlair.MustPrepare("SELECT &Person.* FROM person ORDER BY id LIMIT 10", sqlairtesting.Person{})
+	update := sqlair.MustPrepare("UPDATE person SET name = $Person.name WHERE id = $Person.id", sqlairtesting.Person{})
+
+	// The same fragments are embedded into statements with other types.
+	stmt, err := Embed(list, byTenant, named, byName)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT id, name FROM person WHERE (tenant_id = ?) AND (name <> '') ORDER BY name DESC LIMIT 10`, stmt.Describe().SQL[0].SQL)
+
+	stmt, err = Embed(update, byTenant)
+	assert.Nil(t, err)
+	assert.Equal(t, `UPDATE person SET name = ? WHERE (id = ?) AND (tenant_id = ?)`, stmt.Describe().SQL[0].SQL)
+
+	// The statements are not modified.
+	assert.Equal(t, `SELECT id, name FROM person ORDER BY id LIMIT 10`, list.Describe().SQL[0].SQL)
+
+	_, err = Embed(list, byName, MustOrderBy("ORDER BY id"))
+	assert.EqualError(t, err, `ORDER BY fragments "ORDER BY name DESC" and "ORDER BY id" can not both be embedded`)
+}
+
+func TestFragmentErrors(t *testing.T) {
+	_, err := NewWhere("tenant_id = $Tenant.id")
+	assert.EqualError(t, err, `preparing WHERE fragment "tenant_id = $Tenant.id": identity "Tenant" has no associated object from which to derive type information`)
+
+	_, err = NewOrderBy("LIMIT 1")
+	assert.EqualError(t, err, `preparing ORDER BY fragment "LIMIT 1": not an ORDER BY clause`)
+
+	assert.Panics(t, func() { MustOrderBy("ORDER BY $Tenant.id") })
+}
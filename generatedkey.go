@@ -0,0 +1,127 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/canonical/sqlair/internal/parse"
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// generatedKey is the field of an input to an INSERT statement
+// that receives the key generated by the database.
+type generatedKey struct {
+	typeName string
+	column   string
+
+	// dest is the field, settable since the input is supplied by reference.
+	dest reflect.Value
+}
+
+// generatedKeyFor returns the field to receive the key generated by the
+// statement, and true, if it is an INSERT statement with an input source
+// of a type with a field tagged "autoincrement", and the input of that
+// type is supplied by reference. Where there is more than one such type,
+// that of the first parameter is used.
+func (s *Statement) generatedKeyFor(inputs []any) (generatedKey, bool) {
+	if !isInsert(s.expression) {
+		return generatedKey{}, false
+	}
+
+	for _, in := range s.inputs {
+		info, ok := s.argTypes[in.typeName].(sqlairreflect.Struct)
+		if !ok {
+			continue
+		}
+		for _, tag := range info.Tags() {
+			field := info.Fields[tag]
+			if !field.AutoIncrement {
+				continue
+			}
+			for _, input := range inputs {
+				name, v := objectName(input)
+				if name != in.typeName || v.Kind() != reflect.Ptr || v.IsNil() {
+					continue
+				}
+				return generatedKey{
+					typeName: in.typeName,
+					column:   tag,
+					dest:     v.Elem().Field(field.Index),
+				}, true
+			}
+			return generatedKey{}, false
+		}
+	}
+	return generatedKey{}, false
+}
+
+// execReturningKey executes the input INSERT statement, with the input
+// SQL and parameters, with a RETURNING clause for the column of the input
+// key, which is assigned the value returned for the last row inserted.
+func (db *DB) execReturningKey(ctx context.Context, query, comment string, params []any, key generatedKey) (sql.Result, error) {
+	query = withComment(query+" RETURNING "+db.dialect.identifier(key.column, !QuoteInvalidIdentifiers.isBare(key.column)), comment)
+	rows, err := db.conn.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result returningResult
+	var value any
+	for rows.Next() {
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		result.rowsAffected++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if result.rowsAffected > 0 {
+		if err := assignValue(key.dest.Addr().Interface(), value); err != nil {
+			return result, errors.Wrapf(err, "assigning generated key to %s.%s", key.typeName, key.column)
+		}
+	}
+	return result, rows.Close()
+}
+
+// setLastInsertID assigns the ID of the last row
+// inserted, from the input result, to the input key.
+func setLastInsertID(result sql.Result, key generatedKey) error {
+	id, err := result.LastInsertId()
+	if err == nil {
+		err = assignValue(key.dest.Addr().Interface(), id)
+	}
+	return errors.Wrapf(err, "assigning generated key to %s.%s", key.typeName, key.column)
+}
+
+// returningResult is the result of an INSERT
+// statement run with a RETURNING clause.
+type returningResult struct {
+	rowsAffected int64
+}
+
+// LastInsertId implements sql.Result. The generated key
+// is assigned to the input field rather than returned.
+func (r returningResult) LastInsertId() (int64, error) {
+	return 0, errors.New("LastInsertId is not supported for keys read with RETURNING")
+}
+
+// RowsAffected implements sql.Result,
+// returning the number of rows inserted.
+func (r returningResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// hasReturning returns true if the input statement
+// expression tree has a top-level RETURNING clause.
+func hasReturning(exp parse.Expression) bool {
+	for _, child := range exp.Expressions() {
+		if isOneOfKeywords(child, "RETURNING") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Note struct {
+	ID   int64  `db:"id,autoincrement"`
+	Text string `db:"text"`
+}
+
+func setupNoteDB(t *testing.T) *sql.DB {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE note (id INTEGER PRIMARY KEY AUTOINCREMENT, text TEXT)")
+		return err
+	})
+	return db
+}
+
+func TestExecGeneratedKey(t *testing.T) {
+	ctx := context.Background()
+	insert, err := Prepare("INSERT INTO note (text) VALUES ($Note.text)", Note{})
+	assert.Nil(t, err)
+
+	for _, dialect := range []Dialect{{}, {ReturningKeys: true}} {
+		db := NewDB(setupNoteDB(t)).With(WithDialect(dialect))
+
+		first := &Note{Text: "first"}
+		result, err := db.Exec(ctx, insert, first)
+		assert.Nil(t, err, dialect)
+		assert.Equal(t, int64(1), first.ID, dialect)
+
+		n, err := result.RowsAffected()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), n)
+
+		second := &Note{Text: "second"}
+		_, err = db.Exec(ctx, insert, second)
+		assert.Nil(t, err, dialect)
+		assert.Equal(t, int64(2), second.ID, dialect)
+
+		// An input supplied by value is not populated.
+		_, err = db.Exec(ctx, insert, Note{Text: "third"})
+		assert.Nil(t, err, dialect)
+	}
+}
+
+func TestExecGeneratedKeyOnlyForInsert(t *testing.T) {
+	db := NewDB(setupNoteDB(t))
+	ctx := context.Background()
+
+	insert, err := Prepare("INSERT INTO note (text) VALUES ($Note.text)", Note{})
+	assert.Nil(t, err)
+	_, err = db.Exec(ctx, insert, &Note{Text: "first"})
+	assert.Nil(t, err)
+
+	update, err := Prepare("UPDATE note SET text = $Note.text WHERE id = $Note.id", Note{})
+	assert.Nil(t, err)
+	n := &Note{ID: 5, Text: "updated"}
+	_, err = db.Exec(ctx, update, n)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), n.ID)
+}
@@ -0,0 +1,67 @@
+package sqlair
+
+import (
+	"context"
+	"reflect"
+)
+
+// Select prepares the input DSL statement, runs it using the input DB with
+// parameters sourced from the input objects, and returns the result rows
+// decoded into values of type T. Type information for T is inferred, so
+// it need not be supplied; that of the inputs is taken from the inputs
+// themselves. It suits simple statements with a single output target type.
+// The statement is prepared on every call, so statements that are run
+// frequently are better prepared once with Prepare.
+//
+// Example:
+//
+//     people, err := sqlair.Select[Person](ctx, db, `
+//     SELECT &Person.*
+//       FROM person
+//      WHERE address_id = $Address.id`, Address{ID: id})
+//
+func Select[T any](ctx context.Context, db *DB, stmt string, inputs ...any) ([]T, error) {
+	s, err := prepareFor[T](stmt, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []T
+	if err := db.Query(ctx, s, inputs...).GetAll(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Get is like Select, but returns only the first result row.
+// If there are no rows, sql.ErrNoRows is returned.
+func Get[T any](ctx context.Context, db *DB, stmt string, inputs ...any) (T, error) {
+	var result T
+	s, err := prepareFor[T](stmt, inputs)
+	if err != nil {
+		return result, err
+	}
+
+	err = db.Query(ctx, s, inputs...).Get(&result)
+	return result, err
+}
+
+// prepareFor prepares the input DSL statement using type information from
+// the input objects and from type T, unless T is the type of one of them.
+func prepareFor[T any](stmt string, inputs []any) (*Statement, error) {
+	var zero T
+	outputType := reflect.TypeOf(zero)
+
+	args := make([]any, 0, len(inputs)+1)
+	for _, input := range inputs {
+		v := reflect.Indirect(reflect.ValueOf(input))
+		if _, ok := input.(Aliased); !ok && v.IsValid() && v.Type() == outputType {
+			outputType = nil
+		}
+		args = append(args, input)
+	}
+	if outputType != nil {
+		args = append(args, zero)
+	}
+	return Prepare(stmt, args...)
+}
@@ -0,0 +1,43 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+	ctx := context.Background()
+
+	people, err := Select[sqlairtesting.Person](ctx, db, "SELECT &Person.* FROM person ORDER BY id")
+	assert.Nil(t, err)
+	assert.Equal(t, samplePeople(), people)
+
+	people, err = Select[sqlairtesting.Person](ctx, db, "SELECT &Person.* FROM person WHERE id = $Employee.id", Employee{ID: "2"})
+	assert.Nil(t, err)
+	assert.Equal(t, []sqlairtesting.Person{{ID: "2", Name: "Onos"}}, people)
+
+	// The output type may also be that of an input.
+	people, err = Select[sqlairtesting.Person](ctx, db, "SELECT &Person.* FROM person WHERE name = $Person.name", &sqlairtesting.Person{Name: "Fred"})
+	assert.Nil(t, err)
+	assert.Equal(t, []sqlairtesting.Person{{ID: "3", Name: "Fred"}}, people)
+
+	_, err = Select[sqlairtesting.Person](ctx, db, "SELECT &Person.* FROM person WHERE id = $Employee.id")
+	assert.EqualError(t, err, `identity "Employee" has no associated object from which to derive type information`)
+}
+
+func TestGet(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+	ctx := context.Background()
+
+	p, err := Get[sqlairtesting.Person](ctx, db, "SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{ID: "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, sqlairtesting.Person{ID: "1", Name: "Lorn"}, p)
+
+	_, err = Get[sqlairtesting.Person](ctx, db, "SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{ID: "4"})
+	assert.Equal(t, sql.ErrNoRows, err)
+}
@@ -3,8 +3,10 @@ module github.com/canonical/sqlair
 go 1.18
 
 require (
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/tools v0.1.12
 )
 
 require (
@@ -0,0 +1,236 @@
+package sqlair
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/canonical/sqlair/internal/parse"
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+)
+
+// groupByEnds are the keywords that end the terms of a GROUP BY clause.
+var groupByEnds = map[string]bool{
+	"HAVING": true, "WINDOW": true, "LIMIT": true, "OFFSET": true, ";": true,
+}
+
+// groupedColumns holds the terms of a GROUP BY clause, in lower case.
+type groupedColumns struct {
+	// terms holds the terms as written.
+	terms map[string]bool
+
+	// names holds the names of the columns among the terms,
+	// whether qualified or not, and bare holds those of the
+	// columns that are not qualified.
+	names map[string]bool
+	bare  map[string]bool
+}
+
+// contains returns true if the column with the input
+// qualifier, which may be "", and name is grouped.
+func (g groupedColumns) contains(qualifier, name string) bool {
+	qualifier, name = strings.ToLower(qualifier), strings.ToLower(name)
+	if qualifier == "" {
+		return g.names[name]
+	}
+	return g.terms[qualifier+"."+name] || g.bare[name]
+}
+
+// groupByWarnings returns a warning for each column decoded into an output
+// target, by the query with the input top-level expressions, that is not
+// among the terms of the query's GROUP BY clause. Most databases refuse
+// such queries, while SQLite decodes the value of an arbitrary row of each
+// group. Only columns selected by name are checked, so those within
+// expressions, such as aggregate functions, are not reported.
+func (s *Statement) groupByWarnings(exps []parse.Expression) []string {
+	start := 0
+	for i, exp := range exps {
+		switch exp.(type) {
+		case *parse.CompoundExpression:
+			var warnings []string
+			for _, branch := range exp.Expressions() {
+				warnings = append(warnings, s.groupByWarnings(branch.Expressions())...)
+			}
+			return warnings
+		case *parse.WithExpression:
+			start = i + 1
+		}
+	}
+	if start >= len(exps) || !isKeywordIdentity(exps[start], "SELECT") {
+		return nil
+	}
+
+	end := len(exps)
+	group := -1
+	for i := start; i < len(exps); i++ {
+		if isKeywordIdentity(exps[i], "FROM") && end == len(exps) {
+			end = i
+		}
+		if isKeywordIdentity(exps[i], "GROUP") && i+1 < len(exps) && isKeywordIdentity(exps[i+1], "BY") {
+			group = i + 2
+			break
+		}
+	}
+	if group < 0 {
+		return nil
+	}
+	grouped, ok := groupByTerms(exps[group:])
+	if !ok {
+		return nil
+	}
+
+	var warnings []string
+	check := func(qualifier, column, typeName, tag string) {
+		if !grouped.contains(qualifier, column) {
+			if qualifier != "" {
+				column = qualifier + "." + column
+			}
+			warnings = append(warnings, fmt.Sprintf("column %q decoded into %s.%s is neither in the GROUP BY clause nor aggregated", column, typeName, tag))
+		}
+	}
+
+	for i := start + 1; i < end; i++ {
+		var source parse.Expression
+		if i >= 2 && isKeywordIdentity(exps[i-1], "AS") {
+			source = exps[i-2]
+		}
+
+		switch target := exps[i].(type) {
+		case *parse.OutputTargetExpression:
+			s.checkGroupedTarget(source, target, check)
+		case *parse.GroupedColumnsExpression:
+			sources, ok := source.(*parse.GroupedColumnsExpression)
+			if !ok {
+				continue
+			}
+			targets := target.Expressions()
+			for j, column := range sources.Expressions() {
+				if j < len(targets) {
+					if t, ok := targets[j].(*parse.OutputTargetExpression); ok {
+						s.checkGroupedTarget(column, t, check)
+					}
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// checkGroupedTarget calls the input function for each column, selected
+// by name, that is decoded into the input output target from the input
+// source expression, or from the columns named by the target's tags if
+// the source is nil, with the column's qualifier, name and destination.
+func (s *Statement) checkGroupedTarget(source parse.Expression, target *parse.OutputTargetExpression, check func(qualifier, column, typeName, tag string)) {
+	typeName := target.TypeName().String()
+	member := target.Field().String()
+
+	var tags []string
+	if member == "*" {
+		info, ok := s.argTypes[typeName].(sqlairreflect.Struct)
+		if !ok {
+			return
+		}
+		tags = info.Tags()
+	} else {
+		tags = []string{member}
+	}
+
+	switch source := source.(type) {
+	case nil:
+		for _, tag := range tags {
+			qualifier, column := "", tag
+			if alias, ok := s.aliases[typeName+"."+tag]; ok {
+				column = alias
+				if i := strings.LastIndexByte(alias, '.'); i >= 0 {
+					qualifier, column = alias[:i], alias[i+1:]
+				}
+			}
+			check(qualifier, column, typeName, tag)
+		}
+	case *parse.GroupedColumnsExpression:
+		for _, column := range source.Expressions() {
+			if qualifier, name, ok := columnReference(column); ok {
+				check(qualifier, name, typeName, name)
+			}
+		}
+	default:
+		if isWildcard(source) {
+			qualifier := ""
+			if q, ok := source.(*parse.QualifiedIdentityExpression); ok {
+				qualifier = q.Qualifier().String()
+			}
+			for _, tag := range tags {
+				check(qualifier, tag, typeName, tag)
+			}
+		} else if qualifier, name, ok := columnReference(source); ok && len(tags) == 1 {
+			check(qualifier, name, typeName, tags[0])
+		}
+	}
+}
+
+// groupByTerms returns the terms of the GROUP BY clause beginning the
+// input expressions. False is returned if they can not be matched with
+// the columns of output targets, as when they are positions in the select
+// list rather than expressions.
+func groupByTerms(exps []parse.Expression) (groupedColumns, bool) {
+	grouped := groupedColumns{
+		terms: make(map[string]bool),
+		names: make(map[string]bool),
+		bare:  make(map[string]bool),
+	}
+	add := func(exp parse.Expression) {
+		qualifier, name, ok := columnReference(exp)
+		if !ok {
+			return
+		}
+		name = strings.ToLower(name)
+		grouped.names[name] = true
+		if qualifier == "" {
+			grouped.bare[name] = true
+		}
+	}
+
+	for _, exp := range exps {
+		switch exp := exp.(type) {
+		case *parse.OrderByExpression, *parse.LimitExpression:
+			return grouped, true
+		case *parse.IdentityExpression:
+			if groupByEnds[strings.ToUpper(exp.String())] {
+				return grouped, true
+			}
+			if exp.String() == "," {
+				continue
+			}
+			if strings.Trim(exp.String(), "0123456789") == "" {
+				return grouped, false
+			}
+		case *parse.FunctionCallExpression:
+			// The columns of ROLLUP and CUBE are grouped.
+			if name := strings.ToUpper(exp.Name().String()); name == "ROLLUP" || name == "CUBE" {
+				for _, arg := range exp.Arguments() {
+					add(arg)
+				}
+			}
+		}
+		grouped.terms[strings.ToLower(exp.String())] = true
+		add(exp)
+	}
+	return grouped, true
+}
+
+// columnReference returns the qualifier, which may be "",
+// and name of the column to which the input expression
+// refers, or false if it is not a column name.
+func columnReference(exp parse.Expression) (string, string, bool) {
+	switch exp := exp.(type) {
+	case *parse.QualifiedIdentityExpression:
+		if name := exp.Name().String(); name != "*" {
+			return exp.Qualifier().String(), name, true
+		}
+	case *parse.IdentityExpression:
+		tokens, err := parse.Tokens(exp.String())
+		if err == nil && len(tokens) == 1 && tokens[0].Type == parse.IDENT {
+			return "", exp.String(), true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,54 @@
+package sqlair
+
+import (
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+type NameCount struct {
+	Name  string `db:"name"`
+	Count int    `db:"count"`
+}
+
+func TestGroupByWarnings(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		warnings []string
+	}{
+		{"SELECT name AS &NameCount.name, count(*) AS &NameCount.count FROM person GROUP BY name", nil},
+		{"SELECT p.name AS &NameCount.name, count(*) AS &NameCount.count FROM person AS p GROUP BY name", nil},
+		{"SELECT name AS &NameCount.name, count(*) AS &NameCount.count FROM person AS p GROUP BY p.name HAVING count(*) > 1", nil},
+		{"SELECT (name, count) AS (&NameCount.name, &NameCount.count) FROM person GROUP BY ROLLUP(name, count)", nil},
+		{"SELECT &NameCount.* FROM person GROUP BY 1, 2", nil},
+		{"SELECT lower(name) AS &NameCount.name, count(*) AS &NameCount.count FROM person GROUP BY lower(name)", nil},
+		{"SELECT &NameCount.* FROM person GROUP BY name", []string{
+			`column "count" decoded into NameCount.count is neither in the GROUP BY clause nor aggregated`,
+		}},
+		{"SELECT p.* AS &NameCount.* FROM person AS p GROUP BY p.name ORDER BY p.name", []string{
+			`column "p.count" decoded into NameCount.count is neither in the GROUP BY clause nor aggregated`,
+		}},
+		{"SELECT (id, name) AS (&NameCount.count, &NameCount.name) FROM person AS p GROUP BY m.name", []string{
+			`column "id" decoded into NameCount.count is neither in the GROUP BY clause nor aggregated`,
+		}},
+		{"SELECT name AS &NameCount.name, id AS &NameCount.count FROM person AS p GROUP BY name UNION SELECT name AS &NameCount.name, count AS &NameCount.count FROM person GROUP BY count", []string{
+			`column "id" decoded into NameCount.count is neither in the GROUP BY clause nor aggregated`,
+			`column "name" decoded into NameCount.name is neither in the GROUP BY clause nor aggregated`,
+		}},
+	}
+
+	for _, test := range tests {
+		stmt, err := Prepare(test.stmt, NameCount{})
+		if assert.Nil(t, err, test.stmt) {
+			assert.Equal(t, test.warnings, stmt.groupByWarnings(stmt.expression.Expressions()), test.stmt)
+		}
+	}
+
+	// Aliased columns are checked.
+	stmt, err := Prepare("SELECT &Person.* FROM person AS p GROUP BY p.id", sqlairtesting.Person{}, WithAlias("p.full_name", "Person.name"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		`column "p.full_name" decoded into Person.name is neither in the GROUP BY clause nor aggregated`,
+	}, stmt.Warnings())
+}
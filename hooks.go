@@ -0,0 +1,101 @@
+package sqlair
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/canonical/sqlair/internal/parse"
+	"github.com/pkg/errors"
+)
+
+// BeforeInsertHook is implemented by input types that prepare their values
+// for insertion, such as by populating a CreatedAt field. The BeforeInsert
+// method of each input supplying parameters to an INSERT statement is
+// called, with the context in which the statement is run, before it is
+// validated and its parameters are bound. An input supplied by value is
+// modified in a copy, from which its parameters are bound.
+type BeforeInsertHook interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+// BeforeUpdateHook is implemented by input types that prepare their values
+// for an update, such as by populating an UpdatedAt field. It is called
+// for the inputs of UPDATE statements as BeforeInsertHook is for INSERT.
+type BeforeUpdateHook interface {
+	BeforeUpdate(ctx context.Context) error
+}
+
+// AfterSelectHook is implemented by output types that process their
+// values once decoded, such as by deriving fields from those read. The
+// AfterSelect method of each output is called, with the context in which
+// the statement was run, after a result row is decoded into it.
+type AfterSelectHook interface {
+	AfterSelect(ctx context.Context) error
+}
+
+// inputHook is a hook called for the inputs of a statement.
+type inputHook struct {
+	name  string
+	iface reflect.Type
+	call  func(ctx context.Context, input any) error
+}
+
+var (
+	beforeInsertHook = &inputHook{
+		name:  "BeforeInsert",
+		iface: reflect.TypeOf((*BeforeInsertHook)(nil)).Elem(),
+		call: func(ctx context.Context, input any) error {
+			return input.(BeforeInsertHook).BeforeInsert(ctx)
+		},
+	}
+	beforeUpdateHook = &inputHook{
+		name:  "BeforeUpdate",
+		iface: reflect.TypeOf((*BeforeUpdateHook)(nil)).Elem(),
+		call: func(ctx context.Context, input any) error {
+			return input.(BeforeUpdateHook).BeforeUpdate(ctx)
+		},
+	}
+
+	afterSelectType = reflect.TypeOf((*AfterSelectHook)(nil)).Elem()
+)
+
+// inputHookFor returns the hook called for the inputs of the
+// input statement expression tree, or nil if it has none.
+func inputHookFor(exp parse.Expression) *inputHook {
+	switch {
+	case isInsert(exp):
+		return beforeInsertHook
+	case isUpdate(exp):
+		return beforeUpdateHook
+	}
+	return nil
+}
+
+// prepareInput returns the input struct value, of the named type, from
+// which the parameters of a statement that modifies data are to be bound.
+// The input hook, if any, is called first if the value implements it,
+// followed by its Validate method.
+func prepareInput(ctx context.Context, hook *inputHook, name string, v reflect.Value) (reflect.Value, error) {
+	if hook != nil && reflect.PtrTo(v.Type()).Implements(hook.iface) {
+		v = addressable(v)
+		if err := hook.call(ctx, v.Addr().Interface()); err != nil {
+			return v, errors.Wrapf(err, "%s hook for input %q", hook.name, name)
+		}
+	}
+	if err := validate(v); err != nil {
+		return v, NewErrInvalidInput(name, err)
+	}
+	return v, nil
+}
+
+// afterSelect calls the AfterSelect method of the input output struct
+// value, of the named type, if it implements AfterSelectHook.
+func afterSelect(ctx context.Context, name string, v reflect.Value) error {
+	if !reflect.PtrTo(v.Type()).Implements(afterSelectType) {
+		return nil
+	}
+	if err := v.Addr().Interface().(AfterSelectHook).AfterSelect(ctx); err != nil {
+		return errors.Wrapf(err, "AfterSelect hook for output %q", name)
+	}
+	return nil
+}
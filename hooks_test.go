@@ -0,0 +1,102 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type hookKey struct{}
+
+type Article struct {
+	ID      string `db:"id"`
+	Title   string `db:"title"`
+	Version string `db:"version"`
+	Slug    string
+}
+
+func (a *Article) BeforeInsert(ctx context.Context) error {
+	a.Version = ctx.Value(hookKey{}).(string) + "-inserted"
+	return nil
+}
+
+func (a *Article) BeforeUpdate(ctx context.Context) error {
+	if a.Title == "" {
+		return errors.New("no title")
+	}
+	a.Version = ctx.Value(hookKey{}).(string) + "-updated"
+	return nil
+}
+
+func (a *Article) AfterSelect(ctx context.Context) error {
+	a.Slug = strings.ToLower(strings.ReplaceAll(a.Title, " ", "-"))
+	return nil
+}
+
+func (a Article) Validate() error {
+	if a.Version == "" {
+		return errors.New("no version")
+	}
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE article (id TEXT, title TEXT, version TEXT)")
+		return err
+	})
+	sqlairDB := NewDB(db)
+	ctx := context.WithValue(context.Background(), hookKey{}, "v1")
+
+	// The hook runs before validation, and populates the
+	// parameters even for an input supplied by value.
+	insert, err := Prepare("INSERT INTO article (id, title, version) VALUES ($Article.id, $Article.title, $Article.version)", Article{})
+	assert.Nil(t, err)
+	_, err = sqlairDB.Exec(ctx, insert, Article{ID: "1", Title: "Hello World"})
+	assert.Nil(t, err)
+
+	var version string
+	assert.Nil(t, db.QueryRow("SELECT version FROM article").Scan(&version))
+	assert.Equal(t, "v1-inserted", version)
+
+	// An input supplied by reference is modified.
+	a := &Article{ID: "1", Title: "Goodbye World"}
+	update, err := Prepare("UPDATE article SET title = $Article.title, version = $Article.version WHERE id = $Article.id", Article{})
+	assert.Nil(t, err)
+	_, err = sqlairDB.Exec(ctx, update, a)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1-updated", a.Version)
+
+	_, err = sqlairDB.Exec(ctx, update, Article{ID: "1"})
+	assert.EqualError(t, err, `BeforeUpdate hook for input "Article": no title`)
+
+	selectStmt, err := Prepare("SELECT &Article.* FROM article WHERE id = $Article.id", Article{})
+	assert.Nil(t, err)
+
+	var got Article
+	err = sqlairDB.Query(ctx, selectStmt, Article{ID: "1"}).Get(&got)
+	assert.Nil(t, err)
+	assert.Equal(t, Article{ID: "1", Title: "Goodbye World", Version: "v1-updated", Slug: "goodbye-world"}, got)
+}
+
+func TestHooksNotCalledForQueryInputs(t *testing.T) {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE article (id TEXT, title TEXT, version TEXT)")
+		return err
+	})
+
+	stmt, err := Prepare("SELECT &Article.* FROM article WHERE id = $Article.id", Article{})
+	assert.Nil(t, err)
+
+	// The hooks would panic without a value in the context.
+	var articles []Article
+	err = NewDB(db).Query(context.Background(), stmt, &Article{ID: "1"}).GetAll(&articles)
+	assert.Nil(t, err)
+	assert.Empty(t, articles)
+}
@@ -0,0 +1,90 @@
+package sqlair
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IdentifierAllowlist maps the names of identifier templates in a
+// statement, such as "table" in "[[table]]", to the identifiers that
+// may be substituted for them.
+type IdentifierAllowlist map[string][]string
+
+// AllowIdentifiers returns a copy of the statement in which the identifiers
+// of the input allowlist may be substituted for its templates. Every
+// template in the statement must have an entry in the allowlist.
+func (s *Statement) AllowIdentifiers(allowed IdentifierAllowlist) (*Statement, error) {
+	for _, t := range s.templates {
+		if len(allowed[t.name]) == 0 {
+			return nil, errors.Errorf("no identifiers allowed for template %q", t.name)
+		}
+	}
+
+	stmt := *s
+	stmt.allowed = allowed
+	return &stmt, nil
+}
+
+// WithIdentifiers returns a copy of the statement in which the input
+// identifiers, keyed by template name, are substituted for its templates.
+// Each identifier must be allowed for its template by AllowIdentifiers,
+// and is quoted in the SQL passed to the database as for the dialect of
+// the DB executing it; see Dialect.
+func (s *Statement) WithIdentifiers(identifiers map[string]string) (*Statement, error) {
+	var sql strings.Builder
+	var bindings []identifierBinding
+	var last, shift int
+	existing := s.identifiers
+	for _, t := range s.templates {
+		id, ok := identifiers[t.name]
+		if !ok {
+			return nil, errors.Errorf("no identifier supplied for template %q", t.name)
+		}
+		if !s.isAllowed(t.name, id) {
+			return nil, errors.Errorf("identifier %q is not allowed for template %q", id, t.name)
+		}
+
+		for len(existing) > 0 && existing[0].offset < t.offset {
+			b := existing[0]
+			b.offset += shift
+			bindings = append(bindings, b)
+			existing = existing[1:]
+		}
+		sql.WriteString(s.sql[last:t.offset])
+		bindings = append(bindings, identifierBinding{offset: sql.Len(), name: id, quoted: true})
+		sql.WriteString(quoteIdentifier(id))
+		shift = sql.Len() - t.offset
+		last = t.offset
+	}
+	sql.WriteString(s.sql[last:])
+	for _, b := range existing {
+		b.offset += shift
+		bindings = append(bindings, b)
+	}
+
+	stmt := *s
+	stmt.sql = sql.String()
+	stmt.identifiers = bindings
+	stmt.templates = nil
+	return &stmt, nil
+}
+
+// isAllowed returns true if the input identifier
+// may be substituted for the named template.
+func (s *Statement) isAllowed(template, id string) bool {
+	for _, allowed := range s.allowed[template] {
+		if id == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteIdentifier returns the input identifier quoted with double quotes,
+// as is standard SQL and supported by SQLite and PostgreSQL. Any double
+// quotes within the identifier are escaped by doubling them. It is how
+// identifiers are quoted for the default dialect; see Dialect.
+func quoteIdentifier(id string) string {
+	return `"` + strings.ReplaceAll(id, `"`, `""`) + `"`
+}
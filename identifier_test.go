@@ -0,0 +1,121 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithIdentifiers(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM [[table]] WHERE id = $Person.id ORDER BY [[column]]", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	stmt, err = stmt.AllowIdentifiers(IdentifierAllowlist{
+		"table":  {"person", "manager"},
+		"column": {"id", "name"},
+	})
+	assert.Nil(t, err)
+
+	resolved, err := stmt.WithIdentifiers(map[string]string{"table": "person", "column": "name"})
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT id, name FROM "person" WHERE id = ? ORDER BY "name"`, resolved.sql)
+
+	var p sqlairtesting.Person
+	err = NewDB(setupPersonDB(t)).Query(context.Background(), resolved, sqlairtesting.Person{ID: "3"}).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, sqlairtesting.Person{ID: "3", Name: "Fred"}, p)
+}
+
+func TestWithIdentifiersMySQLDialect(t *testing.T) {
+	type Purchase struct {
+		ID    string `db:"id"`
+		Order string `db:"order"`
+		Key   string `db:"key"`
+	}
+	stmt, err := Prepare("SELECT &Purchase.*, p.name AS &Person.name FROM [[table]] JOIN person AS p WHERE p.name = $Person.name ORDER BY [[column]] LIMIT $PageSpec.size",
+		Purchase{}, sqlairtesting.Person{}, PageSpec{})
+	assert.Nil(t, err)
+	stmt, err = stmt.AllowIdentifiers(IdentifierAllowlist{"table": {"purchase"}, "column": {"odd`name"}})
+	assert.Nil(t, err)
+	stmt, err = stmt.WithIdentifiers(map[string]string{"table": "purchase", "column": "odd`name"})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, \"order\", \"key\" , p.name AS \"Person.name\" FROM \"purchase\" JOIN person AS p WHERE p.name = ? ORDER BY \"odd`name\" LIMIT ?", stmt.sql)
+
+	args, err := stmt.bindInputs(context.Background(), []any{Purchase{}, sqlairtesting.Person{Name: "Fred"}, PageSpec{Size: 5}})
+	assert.Nil(t, err)
+	mysql := Dialect{InlineLimits: true, BacktickQuotes: true, ReservedWords: mysqlReservedWords}
+	query, params, err := NewDB(nil, WithDialect(mysql)).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, `order`, `key` , p.name AS `Person.name` FROM `purchase` JOIN person AS p WHERE p.name = ? ORDER BY `odd``name` LIMIT 5", query)
+	assert.Equal(t, []any{"Fred"}, params)
+}
+
+func TestGeneratedStatementsMySQLDialect(t *testing.T) {
+	type Purchase struct {
+		ID    int64  `db:"id,pk"`
+		Order string `db:"order"`
+		Rank  int    `db:"rank"`
+	}
+	purchases, err := CRUD(Purchase{}, "purchase")
+	if !assert.Nil(t, err) {
+		return
+	}
+	args, err := purchases.Update.bindInputs(context.Background(), []any{Purchase{ID: 1}})
+	assert.Nil(t, err)
+	mysql := NewDB(nil, WithDialect(Dialect{BacktickQuotes: true, ReservedWords: mysqlReservedWords}))
+	query, _, err := mysql.sqlFor(purchases.Update, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "UPDATE purchase SET `order` = ? , `rank` = ? WHERE id = ?", query)
+
+	stmt, err := Prepare("SELECT &Purchase.* FROM purchase", Purchase{})
+	assert.Nil(t, err)
+	p, err := NewKeysetPaginator(stmt, "id", 10)
+	assert.Nil(t, err)
+	args, err = p.first.bindInputs(context.Background(), []any{pageBounds{Limit: 10}})
+	assert.Nil(t, err)
+	query, _, err = mysql.sqlFor(p.first, args)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM (SELECT id, `order`, `rank` FROM purchase) AS page ORDER BY `id` LIMIT ?", query)
+}
+
+func TestWithIdentifiersNotAllowedError(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM [[table]]", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	_, err = stmt.WithIdentifiers(map[string]string{"table": "person"})
+	assert.EqualError(t, err, `identifier "person" is not allowed for template "table"`)
+
+	stmt, err = stmt.AllowIdentifiers(IdentifierAllowlist{"table": {"person"}})
+	assert.Nil(t, err)
+
+	_, err = stmt.WithIdentifiers(map[string]string{"table": `person"; DROP TABLE person; --`})
+	assert.EqualError(t, err, `identifier "person\"; DROP TABLE person; --" is not allowed for template "table"`)
+
+	_, err = stmt.WithIdentifiers(nil)
+	assert.EqualError(t, err, `no identifier supplied for template "table"`)
+}
+
+func TestAllowIdentifiersMissingTemplateError(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM [[table]]", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	_, err = stmt.AllowIdentifiers(IdentifierAllowlist{"column": {"id"}})
+	assert.EqualError(t, err, `no identifiers allowed for template "table"`)
+}
+
+func TestUnsubstitutedTemplateError(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.* FROM [[table]]", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	var p sqlairtesting.Person
+	err = NewDB(setupPersonDB(t)).Query(context.Background(), stmt).Get(&p)
+	assert.EqualError(t, err, `no identifier substituted for template "table"`)
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	assert.Equal(t, `"person"`, quoteIdentifier("person"))
+	assert.Equal(t, `"odd""name"`, quoteIdentifier(`odd"name`))
+	assert.Equal(t, "`odd``name`", Dialect{BacktickQuotes: true}.quoteIdentifier("odd`name"))
+}
@@ -0,0 +1,183 @@
+package sqlair
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"unicode"
+
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// importBatchRows is the number of rows decoded
+// by an import before they are loaded.
+const importBatchRows = 1000
+
+// ImportCSV reads CSV records from the input reader, decodes each into a
+// struct of the loader's type, and loads them using the input DB, in
+// batches, returning the number loaded. The first record is a header
+// naming, for each column, the "db" tag of the field into which it is
+// decoded; a column with no such field is an error. Values are converted
+// to the types of the fields as if read from the database, except that an
+// empty value leaves its field's zero value. Batches loaded before an
+// error remain loaded, unless the DB's connection is a transaction that
+// is rolled back.
+//
+// Example:
+//
+//     loader, err := sqlair.NewBulkLoader(Person{}, "person")
+//
+//     f, err := os.Open("people.csv")
+//     n, err := loader.ImportCSV(ctx, db, f)
+//
+func (l *BulkLoader) ImportCSV(ctx context.Context, db *DB, r io.Reader) (int64, error) {
+	info := l.rowType()
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.Wrap(err, "reading CSV header")
+	}
+	fields := make([]sqlairreflect.Field, len(header))
+	for i, column := range header {
+		field, ok := info.Fields[column]
+		if !ok {
+			return 0, errors.Errorf("CSV column %q has no field in type %q", column, info.Name())
+		}
+		fields[i] = field
+	}
+
+	record := 1
+	return l.importRows(ctx, db, func(v reflect.Value) (bool, error) {
+		values, err := cr.Read()
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, errors.Wrap(err, "reading CSV")
+		}
+		record++
+
+		for i, value := range values {
+			if value == "" {
+				continue
+			}
+			if err := assignValue(v.Field(fields[i].Index).Addr().Interface(), value); err != nil {
+				return false, errors.Wrapf(err, "decoding column %q of CSV record %d", header[i], record)
+			}
+		}
+		return true, nil
+	})
+}
+
+// ImportJSON reads JSON objects from the input reader, decodes each into a
+// struct of the loader's type, and loads them using the input DB, in
+// batches, returning the number loaded. The objects are either the
+// elements of a single array, or a sequence of objects, such as one on
+// each line. The key of each value is the "db" tag of the field into which
+// it is decoded; a key with no such field is an error. As for ImportCSV,
+// batches loaded before an error remain loaded.
+func (l *BulkLoader) ImportJSON(ctx context.Context, db *DB, r io.Reader) (int64, error) {
+	info := l.rowType()
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+
+	first, err := firstNonSpace(br)
+	if err == io.EOF {
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.Wrap(err, "reading JSON")
+	}
+	inArray := first == '['
+	if inArray {
+		if _, err := dec.Token(); err != nil {
+			return 0, errors.Wrap(err, "reading JSON")
+		}
+	}
+
+	object := 0
+	return l.importRows(ctx, db, func(v reflect.Value) (bool, error) {
+		if inArray && !dec.More() {
+			return false, nil
+		}
+		var values map[string]json.RawMessage
+		if err := dec.Decode(&values); err == io.EOF && !inArray {
+			return false, nil
+		} else if err != nil {
+			return false, errors.Wrapf(err, "reading JSON object %d", object+1)
+		}
+		object++
+
+		for key, raw := range values {
+			field, ok := info.Fields[key]
+			if !ok {
+				return false, errors.Errorf("key %q of JSON object %d has no field in type %q", key, object, info.Name())
+			}
+			if err := json.Unmarshal(raw, v.Field(field.Index).Addr().Interface()); err != nil {
+				return false, errors.Wrapf(err, "decoding key %q of JSON object %d", key, object)
+			}
+		}
+		return true, nil
+	})
+}
+
+// rowType returns reflection information for the
+// struct type of the rows loaded by the loader.
+func (l *BulkLoader) rowType() sqlairreflect.Struct {
+	var info sqlairreflect.Struct
+	for _, t := range l.insert.argTypes {
+		info = t.(sqlairreflect.Struct)
+	}
+	return info
+}
+
+// importRows loads, in batches, the rows decoded by the input function
+// into new struct values of the loader's type, until it returns false,
+// returning the number loaded.
+func (l *BulkLoader) importRows(ctx context.Context, db *DB, decode func(reflect.Value) (bool, error)) (int64, error) {
+	t := l.rowType().Type()
+	batch := reflect.MakeSlice(reflect.SliceOf(t), 0, importBatchRows)
+
+	var n int64
+	for {
+		row := reflect.New(t).Elem()
+		ok, err := decode(row)
+		if err != nil {
+			return n, err
+		}
+		if ok {
+			batch = reflect.Append(batch, row)
+		}
+		if batch.Len() == importBatchRows || !ok && batch.Len() > 0 {
+			loaded, err := l.Load(ctx, db, batch.Interface())
+			n += loaded
+			if err != nil {
+				return n, err
+			}
+			batch = batch.Slice(0, 0)
+		}
+		if !ok {
+			return n, nil
+		}
+	}
+}
+
+// firstNonSpace returns the first character of the input
+// reader that is not white space, without consuming it.
+func firstNonSpace(r *bufio.Reader) (rune, error) {
+	for {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(c) {
+			return c, r.UnreadRune()
+		}
+	}
+}
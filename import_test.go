@@ -0,0 +1,63 @@
+package sqlair
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportCSV(t *testing.T) {
+	conn := setupPersonDB(t)
+	db := NewDB(conn)
+	ctx := context.Background()
+
+	loader, err := NewBulkLoader(sqlairtesting.Person{}, "person")
+	assert.Nil(t, err)
+
+	n, err := loader.ImportCSV(ctx, db, strings.NewReader("name,id\nRalph,4\n\"Jane, Jr\",5\n,6\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+
+	var people []sqlairtesting.Person
+	err = db.Query(ctx, MustPrepare("SELECT &Person.* FROM person WHERE id > '3' ORDER BY id", sqlairtesting.Person{})).GetAll(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, []sqlairtesting.Person{{ID: "4", Name: "Ralph"}, {ID: "5", Name: "Jane, Jr"}, {ID: "6"}}, people)
+
+	_, err = loader.ImportCSV(ctx, db, strings.NewReader("id,age\n7,30\n"))
+	assert.EqualError(t, err, `CSV column "age" has no field in type "Person"`)
+
+	n, err = loader.ImportCSV(ctx, db, strings.NewReader(""))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestImportJSON(t *testing.T) {
+	conn := setupPersonDB(t)
+	db := NewDB(conn)
+	ctx := context.Background()
+
+	loader, err := NewBulkLoader(sqlairtesting.Person{}, "person")
+	assert.Nil(t, err)
+
+	n, err := loader.ImportJSON(ctx, db, strings.NewReader(` [{"id": "4", "name": "Ralph"}, {"id": "5"}]`))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	n, err = loader.ImportJSON(ctx, db, strings.NewReader("{\"id\": \"6\", \"name\": \"Jane\"}\n{\"id\": \"7\", \"name\": \"Mo\"}\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	var count int
+	err = conn.QueryRow("SELECT count(*) FROM person").Scan(&count)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, count)
+
+	_, err = loader.ImportJSON(ctx, db, strings.NewReader(`[{"id": "8", "age": 30}]`))
+	assert.EqualError(t, err, `key "age" of JSON object 1 has no field in type "Person"`)
+
+	_, err = loader.ImportJSON(ctx, db, strings.NewReader(`[{"id": 9}]`))
+	assert.NotNil(t, err)
+}
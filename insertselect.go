@@ -0,0 +1,142 @@
+package sqlair
+
+import (
+	"github.com/canonical/sqlair/internal/parse"
+	"github.com/pkg/errors"
+)
+
+// insertSelect describes an INSERT statement whose column list is
+// expanded from the output targets of the SELECT providing its rows,
+// as in "INSERT INTO archive (*) SELECT &Archive.* FROM person".
+type insertSelect struct {
+	// wildcard is the column list "(*)" to be expanded.
+	wildcard *parse.GroupedColumnsExpression
+
+	// columns holds the columns of the output targets,
+	// into which the selected values are inserted.
+	columns []string
+}
+
+// insertSelectFor returns the insertSelect for the input statement
+// expression tree, or nil if it is not such a statement. The select list
+// must comprise only output targets, each of which may be preceded by the
+// columns decoded into it, as in "p.* AS &Archive.*". The columns of the
+// targets are inserted, so the same struct drives the columns on both
+// sides of the statement.
+func (c *compiler) insertSelectFor(exp parse.Expression) (*insertSelect, error) {
+	if !isInsert(exp) {
+		return nil, nil
+	}
+
+	children := exp.Expressions()
+	var wildcard *parse.GroupedColumnsExpression
+	start := -1
+	for i, child := range children {
+		if g, ok := child.(*parse.GroupedColumnsExpression); ok && wildcard == nil && g.String() == "(*)" {
+			wildcard = g
+			continue
+		}
+		if wildcard != nil && isKeywordIdentity(child, "SELECT") {
+			start = i + 1
+			break
+		}
+	}
+	if wildcard == nil || start < 0 {
+		return nil, nil
+	}
+	if start < len(children) && (isKeywordIdentity(children[start], "DISTINCT") || isKeywordIdentity(children[start], "ALL")) {
+		start++
+	}
+
+	// Each item of the select list is an output target,
+	// or the columns decoded into one followed by it.
+	var items [][]parse.Expression
+	item := []parse.Expression{}
+	for _, child := range children[start:] {
+		if isKeywordIdentity(child, "FROM") {
+			break
+		}
+		if isKeywordIdentity(child, ",") {
+			items = append(items, item)
+			item = []parse.Expression{}
+			continue
+		}
+		item = append(item, child)
+	}
+	items = append(items, item)
+
+	var targets []*parse.OutputTargetExpression
+	for _, item := range items {
+		valid := len(item) == 1 || len(item) == 3 && isKeywordIdentity(item[1], "AS")
+		if !valid || !isOutputTarget(item[len(item)-1]) {
+			return nil, insertSelectError(exp)
+		}
+		switch e := item[len(item)-1].(type) {
+		case *parse.OutputTargetExpression:
+			targets = append(targets, e)
+		case *parse.GroupedColumnsExpression:
+			grouped, _ := outputTargets(e)
+			targets = append(targets, grouped...)
+		}
+	}
+
+	s := &insertSelect{wildcard: wildcard}
+	for _, target := range targets {
+		info, err := c.structInfo(target)
+		if err != nil {
+			return nil, err
+		}
+		columns, err := targetColumns(info, target.Field().String())
+		if err != nil {
+			return nil, err
+		}
+		s.columns = append(s.columns, columns...)
+	}
+	return s, nil
+}
+
+// outputTargets returns the output targets comprising the
+// input parenthesised list, or false if it has anything else.
+func outputTargets(e *parse.GroupedColumnsExpression) ([]*parse.OutputTargetExpression, bool) {
+	var targets []*parse.OutputTargetExpression
+	for _, child := range e.Expressions() {
+		target, ok := child.(*parse.OutputTargetExpression)
+		if !ok {
+			return nil, false
+		}
+		targets = append(targets, target)
+	}
+	return targets, len(targets) > 0
+}
+
+// isOutputTarget returns true if the input expression is an output
+// target, or a parenthesised list of them.
+func isOutputTarget(exp parse.Expression) bool {
+	switch e := exp.(type) {
+	case *parse.OutputTargetExpression:
+		return true
+	case *parse.GroupedColumnsExpression:
+		_, ok := outputTargets(e)
+		return ok
+	}
+	return false
+}
+
+// insertSelectError returns the error for an INSERT statement whose
+// columns "(*)" can not be expanded from the select list of its SELECT.
+func insertSelectError(exp parse.Expression) error {
+	return errors.Errorf("columns (*) of %q can only be expanded from a select list of output targets", exp.String())
+}
+
+// compileInsertColumns writes the column list of an INSERT
+// statement expanded from the output targets of its SELECT.
+func (c *compiler) compileInsertColumns() {
+	c.sql.WriteByte('(')
+	for i, column := range c.insert.columns {
+		if i > 0 {
+			c.sql.WriteString(", ")
+		}
+		c.writeIdentifier(column, false)
+	}
+	c.sql.WriteByte(')')
+}
@@ -0,0 +1,64 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+type Archive struct {
+	ID   string `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestInsertSelect(t *testing.T) {
+	conn := setupPersonDB(t)
+	_, err := conn.Exec("CREATE TABLE archive (id TEXT, name TEXT)")
+	assert.Nil(t, err)
+	db := NewDB(conn)
+	ctx := context.Background()
+
+	tests := []struct {
+		stmt string
+		sql  string
+	}{{
+		stmt: "INSERT INTO archive (*) SELECT &Archive.* FROM person WHERE id = $Person.id",
+		sql:  "INSERT INTO archive (id, name) SELECT id, name FROM person WHERE id = ?",
+	}, {
+		stmt: "INSERT INTO archive (*) SELECT p.* AS &Archive.* FROM person AS p WHERE p.id = $Person.id",
+		sql:  `INSERT INTO archive (id, name) SELECT p.id AS p_id, p.name AS p_name FROM person AS p WHERE p.id = ?`,
+	}}
+	for _, test := range tests {
+		stmt, err := Prepare(test.stmt, Archive{}, sqlairtesting.Person{})
+		if !assert.Nil(t, err, test.stmt) {
+			continue
+		}
+		assert.Equal(t, test.sql, stmt.sql)
+		assert.Empty(t, stmt.outputs)
+	}
+
+	stmt, err := Prepare(tests[0].stmt, Archive{}, sqlairtesting.Person{})
+	assert.Nil(t, err)
+	_, err = db.Exec(ctx, stmt, sqlairtesting.Person{ID: "2"})
+	assert.Nil(t, err)
+
+	var archived []Archive
+	query, err := Prepare("SELECT &Archive.* FROM archive", Archive{})
+	assert.Nil(t, err)
+	err = db.Query(ctx, query).GetAll(&archived)
+	assert.Nil(t, err)
+	assert.Equal(t, []Archive{{ID: "2", Name: "Onos"}}, archived)
+
+	// Output targets of a RETURNING clause are bound to result columns.
+	returning, err := Prepare("INSERT INTO archive (*) SELECT &Archive.* FROM person WHERE id = $Person.id RETURNING &Archive.name", Archive{}, sqlairtesting.Person{})
+	assert.Nil(t, err)
+	var a Archive
+	err = db.Query(ctx, returning, sqlairtesting.Person{ID: "3"}).Get(&a)
+	assert.Nil(t, err)
+	assert.Equal(t, Archive{Name: "Fred"}, a)
+
+	_, err = Prepare("INSERT INTO archive (*) SELECT &Archive.*, now() FROM person", Archive{})
+	assert.EqualError(t, err, `columns (*) of "INSERT INTO archive (*) SELECT &Archive.* , now() FROM person" can only be expanded from a select list of output targets`)
+}
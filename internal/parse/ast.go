@@ -30,6 +30,10 @@ type TypeMappingExpression interface {
 	// TypeName returns the type name used in this expression,
 	// such as "Person" in "&Person.*" or "$Person.id".
 	TypeName() Expression
+
+	// Field returns the field used in this expression,
+	// such as "*" in "&Person.*" or "id" in "$Person.id".
+	Field() Expression
 }
 
 // parentExpressionBase implements base functionality for working
@@ -66,6 +70,13 @@ func (e *parentExpressionBase) AppendExpression(child Expression) {
 	e.children = append(e.children, child)
 }
 
+// setExpressions replaces this parent's children with the input
+// expressions, which it retains, so that a parent whose children are
+// already collected in a slice is built without appending each of them.
+func (e *parentExpressionBase) setExpressions(children []Expression) {
+	e.children = children
+}
+
 // SQLExpression is a parent expression representing
 // a full structured query language query.
 type SQLExpression struct {
@@ -177,6 +188,10 @@ func (e *OutputTargetExpression) TypeName() Expression {
 	return e.name
 }
 
+func (e *OutputTargetExpression) Field() Expression {
+	return e.field
+}
+
 // InputSourceExpression is an expression representing a type
 // from which parameters of a statement are to be sourced.
 // Example:
@@ -222,6 +237,10 @@ func (e *InputSourceExpression) TypeName() Expression {
 	return e.name
 }
 
+func (e *InputSourceExpression) Field() Expression {
+	return e.field
+}
+
 // IdentityExpression is an expression that identifies a single entity.
 type IdentityExpression struct {
 	token Token
@@ -254,31 +273,963 @@ func (e *IdentityExpression) String() string {
 	return e.token.Literal
 }
 
+// QualifiedIdentityExpression is an expression that identifies
+// an entity by name within a qualifying entity.
+// Example:
+// "p.name" in "SELECT p.name FROM person AS p;"
+type QualifiedIdentityExpression struct {
+	qualifier Expression
+	name      *IdentityExpression
+}
+
+// NewQualifiedIdentityExpression returns a reference to a new
+// QualifiedIdentityExpression based on the input arguments.
+func NewQualifiedIdentityExpression(
+	qualifier Expression, name *IdentityExpression,
+) *QualifiedIdentityExpression {
+	return &QualifiedIdentityExpression{
+		qualifier: qualifier,
+		name:      name,
+	}
+}
+
+// Expressions implements Expression by returning the child Expressions.
+func (e *QualifiedIdentityExpression) Expressions() []Expression {
+	return []Expression{e.qualifier, e.name}
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *QualifiedIdentityExpression) Begin() Position {
+	return e.qualifier.Begin()
+}
+
+func (e *QualifiedIdentityExpression) End() Position {
+	return e.name.End()
+}
+
+func (e *QualifiedIdentityExpression) String() string {
+	return e.qualifier.String() + "." + e.name.String()
+}
+
+// Qualifier returns the qualifying entity, such as "p" in "p.name".
+func (e *QualifiedIdentityExpression) Qualifier() Expression {
+	return e.qualifier
+}
+
+// Name returns the qualified name, such as "name" in "p.name".
+func (e *QualifiedIdentityExpression) Name() *IdentityExpression {
+	return e.name
+}
+
+// PrefixExpression is an expression representing a unary operator
+// applied to the expression following it.
+// Example:
+// "NOT deleted" in "SELECT * FROM person WHERE NOT deleted;"
+type PrefixExpression struct {
+	operator Token
+	right    Expression
+}
+
+// NewPrefixExpression returns a reference to a new
+// PrefixExpression based on the input arguments.
+func NewPrefixExpression(operator Token, right Expression) *PrefixExpression {
+	return &PrefixExpression{
+		operator: operator,
+		right:    right,
+	}
+}
+
+// Expressions implements Expression by returning the child Expressions.
+func (e *PrefixExpression) Expressions() []Expression {
+	return []Expression{e.right}
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *PrefixExpression) Begin() Position {
+	return e.operator.Pos
+}
+
+func (e *PrefixExpression) End() Position {
+	return e.right.End()
+}
+
+func (e *PrefixExpression) String() string {
+	return e.Operator() + e.right.String()
+}
+
+// Operator returns the operator of this expression, followed by a space
+// if it is a keyword such as "NOT" rather than a symbol such as "-".
+func (e *PrefixExpression) Operator() string {
+	if e.operator.Type == KEYWORD {
+		return e.operator.Literal + " "
+	}
+	return e.operator.Literal
+}
+
+// Right returns the operand of this expression.
+func (e *PrefixExpression) Right() Expression {
+	return e.right
+}
+
+// InfixExpression is an expression representing a binary operator applied
+// to the expressions either side of it. Operators such as "IS NOT" and
+// "NOT IN" are composed of more than one token.
+// Example:
+// "a + b * c" in "SELECT a + b * c AS total FROM t;"
+type InfixExpression struct {
+	left     Expression
+	operator []Token
+	right    Expression
+}
+
+// NewInfixExpression returns a reference to a new
+// InfixExpression based on the input arguments.
+func NewInfixExpression(left Expression, operator []Token, right Expression) *InfixExpression {
+	return &InfixExpression{
+		left:     left,
+		operator: operator,
+		right:    right,
+	}
+}
+
+// Expressions implements Expression by returning the child Expressions.
+func (e *InfixExpression) Expressions() []Expression {
+	return []Expression{e.left, e.right}
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *InfixExpression) Begin() Position {
+	return e.left.Begin()
+}
+
+func (e *InfixExpression) End() Position {
+	return e.right.End()
+}
+
+func (e *InfixExpression) String() string {
+	return e.left.String() + e.Spacing() + e.Operator() + e.Spacing() + e.right.String()
+}
+
+// Spacing returns the space written either side of the operator: none
+// for a cast such as "id::TEXT", and a single space for any other.
+func (e *InfixExpression) Spacing() string {
+	if len(e.operator) == 1 && e.operator[0].Type == CAST {
+		return ""
+	}
+	return " "
+}
+
+// Operator returns the operator of this expression,
+// with the literals of multi-token operators separated by a space.
+func (e *InfixExpression) Operator() string {
+	if len(e.operator) == 1 {
+		return e.operator[0].Literal
+	}
+
+	literals := make([]string, len(e.operator))
+	for i, tok := range e.operator {
+		literals[i] = tok.Literal
+	}
+	return strings.Join(literals, " ")
+}
+
+// Left returns the left operand of this expression.
+func (e *InfixExpression) Left() Expression {
+	return e.left
+}
+
+// Right returns the right operand of this expression.
+func (e *InfixExpression) Right() Expression {
+	return e.right
+}
+
+// FunctionCallExpression is an expression representing
+// the invocation of a function with a list of arguments.
+// Example:
+// "coalesce(name, 'unknown')" in "SELECT coalesce(name, 'unknown') FROM person;"
+type FunctionCallExpression struct {
+	name   *IdentityExpression
+	args   []Expression
+	rparen Token
+}
+
+// NewFunctionCallExpression returns a reference to a new
+// FunctionCallExpression based on the input arguments.
+func NewFunctionCallExpression(
+	name *IdentityExpression, args []Expression, rparen Token,
+) *FunctionCallExpression {
+	return &FunctionCallExpression{
+		name:   name,
+		args:   args,
+		rparen: rparen,
+	}
+}
+
+// Expressions implements Expression by returning the
+// function name followed by the function arguments.
+func (e *FunctionCallExpression) Expressions() []Expression {
+	return append([]Expression{e.name}, e.args...)
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *FunctionCallExpression) Begin() Position {
+	return e.name.Begin()
+}
+
+func (e *FunctionCallExpression) End() Position {
+	return Position{
+		Offset: e.rparen.Pos.Offset + len(e.rparen.Literal),
+	}
+}
+
+func (e *FunctionCallExpression) String() string {
+	args := make([]string, len(e.args))
+	for i, arg := range e.args {
+		args[i] = arg.String()
+	}
+	return e.name.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Name returns the name of the called function.
+func (e *FunctionCallExpression) Name() Expression {
+	return e.name
+}
+
+// Arguments returns the arguments passed to the function.
+func (e *FunctionCallExpression) Arguments() []Expression {
+	return e.args
+}
+
+// QuantifiedExpression is an expression representing a parenthesised
+// operand preceded by a quantifier: ANY, SOME or ALL, applying the
+// comparison of which it is the right-hand operand to the values of the
+// operand, or EXISTS, testing whether a subquery returns any rows.
+// The operand of ANY, SOME or ALL may be a single input source,
+// which supplies a list of values.
+// Example:
+// "ANY($Filter.ids)" in "SELECT * FROM person WHERE id = ANY($Filter.ids);"
+type QuantifiedExpression struct {
+	quantifier Token
+	operand    Expression
+}
+
+// NewQuantifiedExpression returns a reference to a new
+// QuantifiedExpression based on the input arguments.
+func NewQuantifiedExpression(quantifier Token, operand Expression) *QuantifiedExpression {
+	return &QuantifiedExpression{
+		quantifier: quantifier,
+		operand:    operand,
+	}
+}
+
+// Expressions implements Expression by returning the child Expressions.
+func (e *QuantifiedExpression) Expressions() []Expression {
+	return []Expression{e.operand}
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *QuantifiedExpression) Begin() Position {
+	return e.quantifier.Pos
+}
+
+func (e *QuantifiedExpression) End() Position {
+	return e.operand.End()
+}
+
+func (e *QuantifiedExpression) String() string {
+	return e.quantifier.Literal + e.operand.String()
+}
+
+// Quantifier returns the quantifier, such as "ANY", as written.
+func (e *QuantifiedExpression) Quantifier() string {
+	return e.quantifier.Literal
+}
+
+// Operand returns the parenthesised operand: a subquery,
+// or a list of expressions such as "($Filter.ids)".
+func (e *QuantifiedExpression) Operand() Expression {
+	return e.operand
+}
+
+// InputSource returns the input source that is the sole
+// item of the operand, or nil if the operand is not one.
+func (e *QuantifiedExpression) InputSource() *InputSourceExpression {
+	list, ok := e.operand.(*GroupedColumnsExpression)
+	if !ok || len(list.Expressions()) != 1 {
+		return nil
+	}
+	source, _ := list.Expressions()[0].(*InputSourceExpression)
+	return source
+}
+
+// SubqueryExpression is a parent expression representing
+// a parenthesised statement nested within another.
+// Example:
+// "(SELECT id FROM manager)" in "SELECT * FROM person WHERE id IN (SELECT id FROM manager);"
+type SubqueryExpression struct {
+	parentExpressionBase
+}
+
+func (e *SubqueryExpression) String() string {
+	var sb strings.Builder
+	sb.WriteByte('(')
+	for i, exp := range e.Expressions() {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(exp.String())
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
+
+// CompoundExpression is a parent expression representing queries
+// combined with set operators. Its children are the combined queries.
+// Example:
+// "SELECT id FROM person UNION ALL SELECT id FROM manager"
+type CompoundExpression struct {
+	parentExpressionBase
+
+	// operators holds, for each query after the first,
+	// the set operator combining it with those preceding it.
+	operators [][]Token
+}
+
+// AppendBranch appends the input query to the compound, combined with
+// the queries preceding it by the input set operator tokens, such as
+// UNION followed by ALL. The operator is ignored for the first query.
+func (e *CompoundExpression) AppendBranch(operator []Token, branch Expression) {
+	if len(e.children) > 0 {
+		e.operators = append(e.operators, operator)
+	}
+	e.AppendExpression(branch)
+}
+
+// Operator returns the set operator combining the query at the input index
+// with those preceding it. An empty string is returned for the first query.
+func (e *CompoundExpression) Operator(i int) string {
+	if i < 1 || i > len(e.operators) {
+		return ""
+	}
+
+	operator := e.operators[i-1]
+	literals := make([]string, len(operator))
+	for j, tok := range operator {
+		literals[j] = tok.Literal
+	}
+	return strings.Join(literals, " ")
+}
+
+func (e *CompoundExpression) String() string {
+	var sb strings.Builder
+	for i, exp := range e.Expressions() {
+		if i > 0 {
+			sb.WriteString(" " + e.Operator(i) + " ")
+		}
+		sb.WriteString(exp.String())
+	}
+	return sb.String()
+}
+
+// WithExpression is a parent expression representing a WITH clause.
+// Its children are the common table expressions that it declares.
+// Example:
+// "WITH m AS (SELECT * FROM manager)" in "WITH m AS (SELECT * FROM manager) SELECT * FROM m;"
+type WithExpression struct {
+	parentExpressionBase
+	keywords []Token
+}
+
+// NewWithExpression returns a reference to a new WithExpression
+// for the input keywords, being WITH and optionally RECURSIVE.
+func NewWithExpression(keywords []Token) *WithExpression {
+	return &WithExpression{keywords: keywords}
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *WithExpression) Begin() Position {
+	return e.keywords[0].Pos
+}
+
+func (e *WithExpression) String() string {
+	var sb strings.Builder
+	sb.WriteString(e.Keyword())
+	for i, exp := range e.Expressions() {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(exp.String())
+	}
+	return sb.String()
+}
+
+// Keyword returns the keywords introducing the clause,
+// being "WITH" or "WITH RECURSIVE".
+func (e *WithExpression) Keyword() string {
+	literals := make([]string, len(e.keywords))
+	for i, tok := range e.keywords {
+		literals[i] = tok.Literal
+	}
+	return strings.Join(literals, " ")
+}
+
+// Recursive returns true if the common
+// table expressions may be recursive.
+func (e *WithExpression) Recursive() bool {
+	return len(e.keywords) > 1
+}
+
+// CommonTableExpression is an expression representing
+// a named query declared by a WITH clause.
+// Example:
+// "m (id) AS (SELECT id FROM manager)" in "WITH m (id) AS (SELECT id FROM manager) SELECT * FROM m;"
+type CommonTableExpression struct {
+	name    *IdentityExpression
+	columns *GroupedColumnsExpression
+	as      []Token
+	query   *SubqueryExpression
+}
+
+// NewCommonTableExpression returns a reference to a new
+// CommonTableExpression based on the input arguments.
+// The columns are nil if the expression does not name them.
+// The input "as" tokens are AS and any materialisation hint following it.
+func NewCommonTableExpression(
+	name *IdentityExpression, columns *GroupedColumnsExpression, as []Token, query *SubqueryExpression,
+) *CommonTableExpression {
+	return &CommonTableExpression{
+		name:    name,
+		columns: columns,
+		as:      as,
+		query:   query,
+	}
+}
+
+// Expressions implements Expression by returning the child Expressions.
+func (e *CommonTableExpression) Expressions() []Expression {
+	if e.columns == nil {
+		return []Expression{e.name, e.query}
+	}
+	return []Expression{e.name, e.columns, e.query}
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *CommonTableExpression) Begin() Position {
+	return e.name.Begin()
+}
+
+func (e *CommonTableExpression) End() Position {
+	return e.query.End()
+}
+
+func (e *CommonTableExpression) String() string {
+	var sb strings.Builder
+	sb.WriteString(e.name.String())
+	if e.columns != nil {
+		sb.WriteByte(' ')
+		sb.WriteString(e.columns.String())
+	}
+	sb.WriteByte(' ')
+	sb.WriteString(e.As())
+	sb.WriteByte(' ')
+	sb.WriteString(e.query.String())
+	return sb.String()
+}
+
+// Name returns the name of the common table expression.
+func (e *CommonTableExpression) Name() Expression {
+	return e.name
+}
+
+// Columns returns the column names declared for the
+// common table expression, or nil if there are none.
+func (e *CommonTableExpression) Columns() *GroupedColumnsExpression {
+	return e.columns
+}
+
+// As returns the AS keyword along with any materialisation hint,
+// such as "AS NOT MATERIALIZED".
+func (e *CommonTableExpression) As() string {
+	literals := make([]string, len(e.as))
+	for i, tok := range e.as {
+		literals[i] = tok.Literal
+	}
+	return strings.Join(literals, " ")
+}
+
+// Query returns the statement defining the common table expression.
+func (e *CommonTableExpression) Query() *SubqueryExpression {
+	return e.query
+}
+
+// OrderByExpression is a parent expression representing an ORDER BY
+// clause. Its children are the terms by which results are ordered.
+// Example:
+// "ORDER BY name DESC, id" in "SELECT * FROM person ORDER BY name DESC, id;"
+type OrderByExpression struct {
+	parentExpressionBase
+	keywords []Token
+}
+
+// NewOrderByExpression returns a reference to a
+// new OrderByExpression for the input keywords.
+func NewOrderByExpression(keywords []Token) *OrderByExpression {
+	return &OrderByExpression{keywords: keywords}
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *OrderByExpression) Begin() Position {
+	return e.keywords[0].Pos
+}
+
+func (e *OrderByExpression) String() string {
+	var sb strings.Builder
+	sb.WriteString(e.Keyword())
+	for i, exp := range e.Expressions() {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(exp.String())
+	}
+	return sb.String()
+}
+
+// Keyword returns the keywords introducing the clause.
+func (e *OrderByExpression) Keyword() string {
+	return e.keywords[0].Literal + " " + e.keywords[1].Literal
+}
+
+// OrderingTermExpression is an expression representing an expression
+// by which results are ordered, and the manner of the ordering.
+// Example:
+// "name COLLATE NOCASE DESC" in "SELECT * FROM person ORDER BY name COLLATE NOCASE DESC;"
+type OrderingTermExpression struct {
+	exp       Expression
+	modifiers []Token
+}
+
+// NewOrderingTermExpression returns a reference to a new
+// OrderingTermExpression for the input expression, and tokens
+// for its collation, direction and null ordering.
+func NewOrderingTermExpression(exp Expression, modifiers []Token) *OrderingTermExpression {
+	return &OrderingTermExpression{
+		exp:       exp,
+		modifiers: modifiers,
+	}
+}
+
+// Expressions implements Expression by returning the child Expressions.
+func (e *OrderingTermExpression) Expressions() []Expression {
+	return []Expression{e.exp}
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *OrderingTermExpression) Begin() Position {
+	return e.exp.Begin()
+}
+
+func (e *OrderingTermExpression) End() Position {
+	if l := len(e.modifiers); l > 0 {
+		last := e.modifiers[l-1]
+		return Position{
+			Offset: last.Pos.Offset + len(last.Literal),
+		}
+	}
+	return e.exp.End()
+}
+
+func (e *OrderingTermExpression) String() string {
+	if len(e.modifiers) == 0 {
+		return e.exp.String()
+	}
+	return e.exp.String() + " " + e.Modifiers()
+}
+
+// Expression returns the expression by which results are ordered.
+func (e *OrderingTermExpression) Expression() Expression {
+	return e.exp
+}
+
+// Modifiers returns the collation, direction and null
+// ordering of the term, such as "COLLATE NOCASE DESC".
+func (e *OrderingTermExpression) Modifiers() string {
+	literals := make([]string, len(e.modifiers))
+	for i, tok := range e.modifiers {
+		literals[i] = tok.Literal
+	}
+	return strings.Join(literals, " ")
+}
+
+// ValuesExpression is a parent expression representing a VALUES clause.
+// Its children are the GroupedColumnsExpressions of its rows, each of which
+// has the same number of values.
+// Example:
+// "VALUES ($Person.id, $Person.name), (2, 'Onos')" in "INSERT INTO person (id, name) VALUES ($Person.id, $Person.name), (2, 'Onos');"
+type ValuesExpression struct {
+	parentExpressionBase
+	keyword Token
+}
+
+// NewValuesExpression returns a reference to a
+// new ValuesExpression for the input keyword.
+func NewValuesExpression(keyword Token) *ValuesExpression {
+	return &ValuesExpression{keyword: keyword}
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *ValuesExpression) Begin() Position {
+	return e.keyword.Pos
+}
+
+func (e *ValuesExpression) String() string {
+	var sb strings.Builder
+	sb.WriteString(e.Keyword())
+	for i, exp := range e.Expressions() {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(exp.String())
+	}
+	return sb.String()
+}
+
+// Keyword returns the keyword introducing the clause.
+func (e *ValuesExpression) Keyword() string {
+	return e.keyword.Literal
+}
+
+// Rows returns the rows of values in the order that they are written.
+func (e *ValuesExpression) Rows() []*GroupedColumnsExpression {
+	rows := make([]*GroupedColumnsExpression, 0, len(e.children))
+	for _, child := range e.children {
+		if row, ok := child.(*GroupedColumnsExpression); ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// Arity returns the number of values in each row,
+// or zero if the clause has no rows.
+func (e *ValuesExpression) Arity() int {
+	if len(e.children) == 0 {
+		return 0
+	}
+	return len(e.children[0].Expressions())
+}
+
+// Repeat returns a new VALUES clause with the rows of
+// this one, repeated in turn the input number of times.
+func (e *ValuesExpression) Repeat(n int) *ValuesExpression {
+	exp := NewValuesExpression(e.keyword)
+	for i := 0; i < n; i++ {
+		for _, row := range e.children {
+			exp.AppendExpression(row)
+		}
+	}
+	return exp
+}
+
+// LimitExpression is an expression representing a LIMIT clause,
+// with an optional offset. The offset may be written after the count,
+// as in "LIMIT 10 OFFSET 20", or before it, as in "LIMIT 20, 10".
+// Example:
+// "LIMIT $Page.size OFFSET $Page.offset" in "SELECT * FROM person LIMIT $Page.size OFFSET $Page.offset;"
+type LimitExpression struct {
+	keyword   Token
+	first     Expression
+	separator Token
+	second    Expression
+}
+
+// NewLimitExpression returns a reference to a new LimitExpression.
+// The input expressions are in the order that they are written, separated
+// by either the OFFSET keyword or a comma. For a LIMIT clause without an
+// offset, the separator is the zero Token and the second expression is nil.
+func NewLimitExpression(keyword Token, first Expression, separator Token, second Expression) *LimitExpression {
+	return &LimitExpression{
+		keyword:   keyword,
+		first:     first,
+		separator: separator,
+		second:    second,
+	}
+}
+
+// Expressions implements Expression by returning the
+// child Expressions in the order that they are written.
+func (e *LimitExpression) Expressions() []Expression {
+	if e.second == nil {
+		return []Expression{e.first}
+	}
+	return []Expression{e.first, e.second}
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *LimitExpression) Begin() Position {
+	return e.keyword.Pos
+}
+
+func (e *LimitExpression) End() Position {
+	if e.second == nil {
+		return e.first.End()
+	}
+	return e.second.End()
+}
+
+func (e *LimitExpression) String() string {
+	s := e.keyword.Literal + " " + e.first.String()
+	if e.second != nil {
+		s += e.Separator() + e.second.String()
+	}
+	return s
+}
+
+// Keyword returns the keyword introducing the clause.
+func (e *LimitExpression) Keyword() string {
+	return e.keyword.Literal
+}
+
+// Separator returns the text separating the expressions of a clause
+// with an offset, being either ", " or " OFFSET ".
+// An empty string is returned for a clause without an offset.
+func (e *LimitExpression) Separator() string {
+	switch {
+	case e.second == nil:
+		return ""
+	case e.separator.Type == COMMA:
+		return ", "
+	}
+	return " " + e.separator.Literal + " "
+}
+
+// Count returns the expression for the maximum number of rows.
+func (e *LimitExpression) Count() Expression {
+	if e.separator.Type == COMMA {
+		return e.second
+	}
+	return e.first
+}
+
+// Offset returns the expression for the number of rows
+// to skip, or nil if the clause has no offset.
+func (e *LimitExpression) Offset() Expression {
+	if e.second == nil {
+		return nil
+	}
+	if e.separator.Type == COMMA {
+		return e.first
+	}
+	return e.second
+}
+
+// TemplateExpression is an expression representing a placeholder for an
+// identifier, such as a table name, that is substituted after parsing.
+// Example:
+// "[[table]]" in "SELECT &Person.* FROM [[table]];"
+type TemplateExpression struct {
+	open  Token
+	name  *IdentityExpression
+	close Token
+}
+
+// NewTemplateExpression returns a reference to a new TemplateExpression
+// for the input name, enclosed by the input opening and closing brackets.
+func NewTemplateExpression(open Token, name *IdentityExpression, close Token) *TemplateExpression {
+	return &TemplateExpression{
+		open:  open,
+		name:  name,
+		close: close,
+	}
+}
+
+// Expressions implements Expression by returning the child Expressions.
+func (e *TemplateExpression) Expressions() []Expression {
+	return []Expression{e.name}
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *TemplateExpression) Begin() Position {
+	return e.open.Pos
+}
+
+func (e *TemplateExpression) End() Position {
+	return Position{
+		Offset: e.close.Pos.Offset + len(e.close.Literal),
+	}
+}
+
+func (e *TemplateExpression) String() string {
+	return "[[" + e.name.String() + "]]"
+}
+
+// Name returns the name of the template.
+func (e *TemplateExpression) Name() Expression {
+	return e.name
+}
+
 // PassThroughExpression is an expression representing a chunk of SQL, DML
-// or SQL that Sqlair will effectively ignore and pass to the DB as is.
+// or SQL that Sqlair will effectively ignore and pass to the DB as is,
+// such as syntax parsed by a registered ParseFunc.
 type PassThroughExpression struct {
 	parentExpressionBase
 }
 
 func (e *PassThroughExpression) String() string {
 	var sb strings.Builder
-	for _, exp := range e.Expressions() {
+	for i, exp := range e.Expressions() {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
 		sb.WriteString(exp.String())
 	}
 	return sb.String()
 }
 
+// CommentExpression is an expression representing a comment, which is
+// attached to the statement containing it, following the expression that
+// precedes it.
+// Example:
+// "-- By name." in "SELECT * FROM person -- By name."
+type CommentExpression struct {
+	token Token
+}
+
+// NewCommentExpression returns a reference to a new
+// CommentExpression based on the input COMMENT Token.
+func NewCommentExpression(token Token) *CommentExpression {
+	return &CommentExpression{token: token}
+}
+
+// Expressions implements Expression by returning the child Expressions.
+func (e *CommentExpression) Expressions() []Expression {
+	return nil
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *CommentExpression) Begin() Position {
+	return e.token.Pos
+}
+
+func (e *CommentExpression) End() Position {
+	return Position{
+		Offset: e.token.Pos.Offset + len(e.token.Literal),
+	}
+}
+
+// String returns the comment as written, including its delimiters.
+func (e *CommentExpression) String() string {
+	return e.token.Literal
+}
+
+// Block returns the comment as a block comment, enclosed by "/*" and "*/",
+// so that it does not extend to the end of the line when written within
+// one. Any "*/" within the text of a line comment is broken by a space.
+func (e *CommentExpression) Block() string {
+	lit := e.token.Literal
+	if !strings.HasPrefix(lit, "--") {
+		return lit
+	}
+	text := strings.ReplaceAll(strings.TrimPrefix(lit, "--"), "*/", "* /")
+	return "/*" + text + " */"
+}
+
+// HintExpression is an expression representing an optimizer hint, a block
+// comment beginning "/*+" that the database interprets. It is attached to
+// the statement in the same way as a comment, and written to the database
+// verbatim.
+// Example:
+// "/*+ INDEX(person idx_name) */" in "SELECT /*+ INDEX(person idx_name) */ * FROM person;"
+type HintExpression struct {
+	token Token
+}
+
+// NewHintExpression returns a reference to a new
+// HintExpression based on the input HINT Token.
+func NewHintExpression(token Token) *HintExpression {
+	return &HintExpression{token: token}
+}
+
+// Expressions implements Expression by returning the child Expressions.
+func (e *HintExpression) Expressions() []Expression {
+	return nil
+}
+
+// Begin implements Expression by returning the
+// Position of this Expression's first Token.
+func (e *HintExpression) Begin() Position {
+	return e.token.Pos
+}
+
+func (e *HintExpression) End() Position {
+	return Position{
+		Offset: e.token.Pos.Offset + len(e.token.Literal),
+	}
+}
+
+// String returns the hint as written, including its delimiters.
+func (e *HintExpression) String() string {
+	return e.token.Literal
+}
+
+// Hint returns the text of the hint, without its delimiters,
+// such as "INDEX(person idx_name)".
+func (e *HintExpression) Hint() string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(e.token.Literal, "/*+"), "*/"))
+}
+
+// Adjacent returns true if the input expression is plain SQL, such as an
+// identifier or a sequence of operands and operators, whose first token
+// immediately follows the one preceding it in the statement from which
+// it was parsed, as does "]" in "[c]"; see Token.Adjacent.
+func Adjacent(exp Expression) bool {
+	switch e := exp.(type) {
+	case *IdentityExpression:
+		return e.token.Adjacent
+	case *QualifiedIdentityExpression:
+		return Adjacent(e.qualifier)
+	case *InfixExpression:
+		return Adjacent(e.left)
+	case *FunctionCallExpression:
+		return Adjacent(e.name)
+	}
+	return false
+}
+
 // Walk recursively iterates depth-first over the input expression tree,
 // calling the input function for each visited expression.
 // If it returns an error, the iteration terminates.
 func Walk(parent Expression, visit func(Expression) error) error {
-	if err := visit(parent); err != nil {
-		return err
-	}
-	for _, child := range parent.Expressions() {
-		if err := Walk(child, visit); err != nil {
+	// The expressions yet to be visited are held on a stack, rather than
+	// by recursion, with the children of each pushed in reverse order.
+	stack := make([]Expression, 1, 64)
+	stack[0] = parent
+	for len(stack) > 0 {
+		exp := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if err := visit(exp); err != nil {
 			return err
 		}
+
+		children := exp.Expressions()
+		for i := len(children) - 1; i >= 0; i-- {
+			stack = append(stack, children[i])
+		}
 	}
 	return nil
 }
@@ -42,6 +42,7 @@ func TestOutputTargetExpression(t *testing.T) {
 
 	assert.Equal(t, literal, exp.String())
 	assert.Equal(t, "Person", exp.TypeName().String())
+	assert.Equal(t, "*", exp.Field().String())
 }
 
 var _ parse.TypeMappingExpression = (*parse.InputSourceExpression)(nil)
@@ -54,6 +55,74 @@ func TestInputSourceExpression(t *testing.T) {
 
 	assert.Equal(t, literal, exp.String())
 	assert.Equal(t, "Address", exp.TypeName().String())
+	assert.Equal(t, "id", exp.Field().String())
+}
+
+var _ parse.Expression = (*parse.QualifiedIdentityExpression)(nil)
+
+func TestQualifiedIdentityExpression(t *testing.T) {
+	tokens := tokensForStatement("p.name")
+	exp := parse.NewQualifiedIdentityExpression(
+		parse.NewIdentityExpression(tokens[0]), parse.NewIdentityExpression(tokens[2]))
+
+	assert.Equal(t, "p.name", exp.String())
+	assert.Len(t, exp.Expressions(), 2)
+}
+
+var _ parse.Expression = (*parse.PrefixExpression)(nil)
+
+func TestPrefixExpression(t *testing.T) {
+	tokens := tokensForStatement("NOT deleted - 1")
+
+	exp := parse.NewPrefixExpression(tokens[0], parse.NewIdentityExpression(tokens[1]))
+	assert.Equal(t, "NOT deleted", exp.String())
+
+	exp = parse.NewPrefixExpression(tokens[2], parse.NewIdentityExpression(tokens[3]))
+	assert.Equal(t, "-1", exp.String())
+}
+
+var _ parse.Expression = (*parse.InfixExpression)(nil)
+
+func TestInfixExpression(t *testing.T) {
+	tokens := tokensForStatement("name IS NOT NULL")
+	exp := parse.NewInfixExpression(
+		parse.NewIdentityExpression(tokens[0]), tokens[1:3], parse.NewIdentityExpression(tokens[3]))
+
+	assert.Equal(t, "name IS NOT NULL", exp.String())
+	assert.Equal(t, "IS NOT", exp.Operator())
+	assert.Equal(t, "name", exp.Left().String())
+	assert.Equal(t, "NULL", exp.Right().String())
+}
+
+var _ parse.Expression = (*parse.FunctionCallExpression)(nil)
+
+func TestFunctionCallExpression(t *testing.T) {
+	tokens := tokensForStatement("coalesce(name, 'unknown')")
+	exp := parse.NewFunctionCallExpression(
+		parse.NewIdentityExpression(tokens[0]),
+		[]parse.Expression{parse.NewIdentityExpression(tokens[2]), parse.NewIdentityExpression(tokens[4])},
+		tokens[5],
+	)
+
+	assert.Equal(t, "coalesce(name, 'unknown')", exp.String())
+	assert.Equal(t, "coalesce", exp.Name().String())
+	assert.Len(t, exp.Arguments(), 2)
+	assert.Len(t, exp.Expressions(), 3)
+}
+
+var _ parse.Expression = (*parse.QuantifiedExpression)(nil)
+
+func TestQuantifiedExpression(t *testing.T) {
+	tokens := tokensForStatement("ANY(ids)")
+	list := &parse.GroupedColumnsExpression{}
+	list.AppendExpression(parse.NewIdentityExpression(tokens[2]))
+	exp := parse.NewQuantifiedExpression(tokens[0], list)
+
+	assert.Equal(t, "ANY(ids)", exp.String())
+	assert.Equal(t, "ANY", exp.Quantifier())
+	assert.Equal(t, list, exp.Operand())
+	assert.Nil(t, exp.InputSource())
+	assert.Len(t, exp.Expressions(), 1)
 }
 
 func TestWalk(t *testing.T) {
@@ -92,3 +161,31 @@ func tokensForStatement(stmt string) []parse.Token {
 
 	return tokens
 }
+
+func TestEqual(t *testing.T) {
+	run := func(stmt string) parse.Expression {
+		exp, err := parse.NewParser(parse.NewLexer(stmt)).Run()
+		assert.Nil(t, err)
+		return exp
+	}
+
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"SELECT &Person.* FROM person", "SELECT  &Person.*\n\tFROM person", true},
+		{"SELECT a FROM t WHERE a = $P.a", "SELECT a\nFROM t\nWHERE a=$P.a", true},
+		{"SELECT a FROM t", "SELECT b FROM t", false},
+		{"SELECT a FROM t", "SELECT a FROM t WHERE a = 1", false},
+		{"SELECT a FROM t WHERE a < 1", "SELECT a FROM t WHERE a > 1", false},
+		{"SELECT a FROM t UNION SELECT a FROM u", "SELECT a FROM t UNION ALL SELECT a FROM u", false},
+		{"SELECT &P.a FROM t", "SELECT $P.a FROM t", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.equal, parse.Equal(run(test.a), run(test.b)), "%q vs %q", test.a, test.b)
+	}
+
+	assert.True(t, parse.Equal(nil, nil))
+	assert.False(t, parse.Equal(run("SELECT a"), nil))
+}
@@ -0,0 +1,92 @@
+package parse
+
+import "strings"
+
+// Audit returns an error for the first token in the input expression tree
+// that the lexer could not identify, or that begins a string literal or
+// quoted identifier that is not terminated. Either may indicate that a statement has been assembled
+// from untrusted input, so that its text does not mean what was intended.
+// If backslashEscapes is true, string literals are as read by a Lexer with
+// backslash escapes; see Lexer.SetBackslashEscapes.
+func Audit(exp Expression, backslashEscapes bool) error {
+	return Walk(exp, func(exp Expression) error {
+		id, ok := exp.(*IdentityExpression)
+		if !ok {
+			return nil
+		}
+
+		switch tok := id.token; {
+		case tok.Type == UNKNOWN:
+			return errorAt(tok, "unrecognised character %q", tok.Literal)
+		case tok.Type == STRING && !isTerminatedString(tok.Literal, backslashEscapes):
+			return errorAt(tok, "unterminated string literal")
+		case tok.Type == QUOTEDIDENT && !isTerminatedString(tok.Literal, false):
+			return errorAt(tok, "unterminated quoted identifier")
+		}
+		return nil
+	})
+}
+
+// Literals returns the string and number literal tokens
+// in the input expression tree, in the order visited by Walk.
+func Literals(exp Expression) []Token {
+	var literals []Token
+	_ = Walk(exp, func(exp Expression) error {
+		if id, ok := exp.(*IdentityExpression); ok {
+			if id.token.Type == STRING || id.token.Type == NUM {
+				literals = append(literals, id.token)
+			}
+		}
+		return nil
+	})
+	return literals
+}
+
+// comparisonOperators are the operators, in upper case,
+// by which ComparedLiterals finds a literal compared with a column.
+var comparisonOperators = map[string]bool{
+	"=": true, "==": true, "<>": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	"LIKE": true, "NOT LIKE": true, "GLOB": true, "IS": true, "IS NOT": true,
+}
+
+// ComparedLiterals returns the string and number literal tokens in the
+// input expression tree that are compared directly with a column, as in
+// "name = 'Fred'", in the order visited by Walk. Such a literal is often a
+// value concatenated into the statement in place of an input source.
+func ComparedLiterals(exp Expression) []Token {
+	var literals []Token
+	_ = Walk(exp, func(exp Expression) error {
+		e, ok := exp.(*InfixExpression)
+		if !ok || !comparisonOperators[strings.ToUpper(e.Operator())] {
+			return nil
+		}
+		if tok, ok := literalToken(e.right); ok && isColumn(e.left) {
+			literals = append(literals, tok)
+		} else if tok, ok := literalToken(e.left); ok && isColumn(e.right) {
+			literals = append(literals, tok)
+		}
+		return nil
+	})
+	return literals
+}
+
+// literalToken returns the token of the input expression
+// and true if it is a string or number literal.
+func literalToken(exp Expression) (Token, bool) {
+	if id, ok := exp.(*IdentityExpression); ok && (id.token.Type == STRING || id.token.Type == NUM) {
+		return id.token, true
+	}
+	return Token{}, false
+}
+
+// isColumn returns true if the input expression names a column,
+// either alone or qualified by the name of its table.
+func isColumn(exp Expression) bool {
+	switch e := exp.(type) {
+	case *IdentityExpression:
+		return e.token.Type == IDENT || e.token.Type == QUOTEDIDENT
+	case *QualifiedIdentityExpression:
+		return isColumn(e.Name())
+	}
+	return false
+}
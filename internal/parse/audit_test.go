@@ -0,0 +1,58 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	tests := []struct {
+		stmt string
+		err  string
+	}{
+		{"SELECT &Person.* FROM person WHERE name = 'it''s'", ""},
+		{"SELECT * FROM person WHERE name = 'Fred", "unterminated string literal at line 1, column 35"},
+		{"SELECT * FROM person WHERE \"order\" = 'Fred'", ""},
+		{"SELECT * FROM person WHERE name = \"Fred", "unterminated quoted identifier at line 1, column 35"},
+		{"SELECT * FROM person WHERE name = #Fred", "unrecognised character \"#\" at line 1, column 35"},
+		{"SELECT * FROM person\nWHERE id IN (SELECT id FROM t WHERE a ~ b)", "unrecognised character \"~\" at line 2, column 39"},
+	}
+
+	for _, test := range tests {
+		exp, err := NewParser(NewLexer(test.stmt)).Run()
+		if !assert.Nil(t, err, test.stmt) {
+			continue
+		}
+
+		err = Audit(exp, false)
+		if test.err == "" {
+			assert.Nil(t, err, test.stmt)
+		} else {
+			assert.EqualError(t, err, test.err, test.stmt)
+		}
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	exp, err := NewParser(NewLexer("SELECT 'a', 1 FROM t WHERE x = $P.x AND y IN (SELECT 2.5) LIMIT 10")).Run()
+	assert.Nil(t, err)
+
+	var literals []string
+	for _, tok := range Literals(exp) {
+		literals = append(literals, tok.Literal)
+	}
+	assert.Equal(t, []string{"'a'", "1", "2.5", "10"}, literals)
+}
+
+func TestComparedLiterals(t *testing.T) {
+	stmt := "SELECT 'a' AS x FROM t WHERE name = 'Fred' AND 42 < p.id AND y = $P.y AND z + 1 > 2 AND w LIKE 'F%' LIMIT 10"
+	exp, err := NewParser(NewLexer(stmt)).Run()
+	assert.Nil(t, err)
+
+	var literals []string
+	for _, tok := range ComparedLiterals(exp) {
+		literals = append(literals, tok.Literal)
+	}
+	assert.Equal(t, []string{"'Fred'", "42", "'F%'"}, literals)
+}
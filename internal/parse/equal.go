@@ -0,0 +1,56 @@
+package parse
+
+// Equal returns true if the input expression trees have the same structure
+// and tokens. Token positions are ignored, so the same statement parsed from
+// differently formatted input yields equal trees.
+func Equal(a, b Expression) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	ja, err := toJSON(a)
+	if err != nil {
+		return false
+	}
+	jb, err := toJSON(b)
+	if err != nil {
+		return false
+	}
+	return equalJSON(ja, jb)
+}
+
+// equalJSON returns true if the input JSON representations
+// are equal when disregarding token positions.
+func equalJSON(a, b *jsonExpression) bool {
+	if a.Type != b.Type ||
+		!equalTokens(a.Tokens, b.Tokens) ||
+		len(a.Operators) != len(b.Operators) ||
+		len(a.Children) != len(b.Children) {
+		return false
+	}
+	for i := range a.Operators {
+		if !equalTokens(a.Operators[i], b.Operators[i]) {
+			return false
+		}
+	}
+	for i := range a.Children {
+		if !equalJSON(a.Children[i], b.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalTokens returns true if the input token slices
+// have the same types and literals, in the same order.
+func equalTokens(a, b []Token) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type != b[i].Type || a[i].Literal != b[i].Literal {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,271 @@
+package parse
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ParseFunc parses dialect-specific syntax, such as a JSON operator and its
+// operand, that the parser does not otherwise understand. It is called with
+// the cursor at the token for which it is registered, and returns with the
+// cursor at the last token of the syntax. The syntax is parsed as a
+// PassThroughExpression, which is written to the database as it is, with
+// the tokens separated by spaces.
+type ParseFunc func(c *Cursor) error
+
+// Cursor is the position of a ParseFunc within the tokens of the
+// statement being parsed. The tokens over which it advances are
+// passed through to the database.
+type Cursor struct {
+	p *Parser
+
+	// precedence is that of the syntax
+	// for which the ParseFunc is registered.
+	precedence int
+
+	// exps holds the expressions passed through so far.
+	exps []Expression
+}
+
+// Token returns the current token.
+func (c *Cursor) Token() Token {
+	return c.p.cur()
+}
+
+// Peek returns the token following the current one.
+func (c *Cursor) Peek() Token {
+	return c.p.peek()
+}
+
+// Next advances the cursor to the token following the current one.
+// An error is returned at the end of the statement.
+func (c *Cursor) Next() error {
+	if tok := c.p.peek(); tok.Type == EOF {
+		return errorAt(tok, "unexpected end of statement")
+	}
+	c.p.next()
+	c.exps = append(c.exps, NewIdentityExpression(c.p.cur()))
+	return nil
+}
+
+// Operand parses the expression beginning with the token following the
+// current one as an operand of the syntax, advancing the cursor to its
+// last token. Operators within it that bind more tightly than the syntax
+// are part of the operand. Unlike the tokens over which Next advances,
+// the operand may contain input sources and output targets.
+func (c *Cursor) Operand() error {
+	c.p.next()
+	exp, err := c.p.parseExpression(c.precedence)
+	if err != nil {
+		return err
+	}
+	c.exps = append(c.exps, exp)
+	return nil
+}
+
+// extensionSet holds the registered parse functions. It is not modified
+// once in use; registration replaces it with an updated copy.
+type extensionSet struct {
+	// prefix and infix hold the parse functions by the literal of the token
+	// for which they are registered, in upper case for names.
+	prefix map[string]ParseFunc
+	infix  map[string]ParseFunc
+
+	// operators holds the literals of the registered operators,
+	// which the lexer reads as OPERATOR tokens.
+	operators map[string]bool
+}
+
+// maxOperatorLen is the maximum length of a registered operator.
+const maxOperatorLen = 3
+
+// operatorChars are the characters of which registered operators consist.
+const operatorChars = "!#%*+-/:<=>?@^|~"
+
+// extensions holds the parse functions registered for all parsers.
+var extensions = struct {
+	sync.RWMutex
+	set *extensionSet
+}{set: &extensionSet{}}
+
+// currentExtensions returns the parse functions registered so far.
+func currentExtensions() *extensionSet {
+	extensions.RLock()
+	defer extensions.RUnlock()
+
+	return extensions.set
+}
+
+// RegisterPrefix registers the input function for parsing syntax that
+// begins with the input literal, which is either a name that is not a
+// keyword, such as "INTERVAL", or an operator of up to three of the
+// characters "!#%*+-/:<=>?@^|~" that is not already understood, such as
+// "@@". If the function is nil, the syntax is that of a prefix operator
+// followed by its operand. Parsers created before the function is
+// registered do not use it.
+func RegisterPrefix(literal string, fn ParseFunc) error {
+	return register(literal, fn, false)
+}
+
+// RegisterInfix registers the input function for parsing syntax that
+// follows an expression, its left-hand operand, beginning with the input
+// literal, which is as for RegisterPrefix. If the function is nil, the
+// syntax is that of an infix operator followed by its right-hand operand.
+// Registered infix operators bind more tightly than comparisons and less
+// tightly than arithmetic, as PostgreSQL's do.
+func RegisterInfix(literal string, fn ParseFunc) error {
+	return register(literal, fn, true)
+}
+
+// register registers the input function for the input literal,
+// as for RegisterPrefix or RegisterInfix.
+func register(literal string, fn ParseFunc, infix bool) error {
+	key, operator, err := extensionLiteral(literal)
+	if err != nil {
+		return err
+	}
+
+	extensions.Lock()
+	defer extensions.Unlock()
+
+	old := extensions.set
+	fns := old.prefix
+	if infix {
+		fns = old.infix
+	}
+	if _, ok := fns[key]; ok {
+		return errors.Errorf("parse function already registered for %q", literal)
+	}
+
+	set := &extensionSet{
+		prefix:    copyParseFuncs(old.prefix),
+		infix:     copyParseFuncs(old.infix),
+		operators: make(map[string]bool, len(old.operators)+1),
+	}
+	for op := range old.operators {
+		set.operators[op] = true
+	}
+	if operator {
+		set.operators[key] = true
+	}
+	if infix {
+		set.infix[key] = fn
+	} else {
+		set.prefix[key] = fn
+	}
+	extensions.set = set
+	return nil
+}
+
+// copyParseFuncs returns a copy of the input map, with room for one more.
+func copyParseFuncs(fns map[string]ParseFunc) map[string]ParseFunc {
+	c := make(map[string]ParseFunc, len(fns)+1)
+	for k, fn := range fns {
+		c[k] = fn
+	}
+	return c
+}
+
+// extensionLiteral returns the key under which syntax beginning with the
+// input literal is registered, and true if it is an operator rather than
+// a name, or an error if parse functions can not be registered for it.
+func extensionLiteral(literal string) (string, bool, error) {
+	switch {
+	case literal == "":
+		return "", false, errors.New("can not register parse function for empty literal")
+	case strings.Trim(literal, operatorChars) == "":
+		if len(literal) > maxOperatorLen {
+			return "", false, errors.Errorf("operator %q is longer than %d characters", literal, maxOperatorLen)
+		}
+		if strings.Contains(literal, "--") || strings.Contains(literal, "/*") {
+			return "", false, errors.Errorf("operator %q contains the start of a comment", literal)
+		}
+		if _, ok := knownOperatorTokens[literal]; ok || jsonOperators[literal] || len(literal) == 1 && knownRuneTokens[rune(literal[0])] != 0 {
+			return "", false, errors.Errorf("operator %q is already parsed", literal)
+		}
+		return literal, true, nil
+	}
+
+	tokens, err := Tokens(literal)
+	if err != nil || len(tokens) != 1 || tokens[0].Type != IDENT || tokens[0].Literal != literal {
+		if len(tokens) == 1 && tokens[0].Type == KEYWORD {
+			return "", false, errors.Errorf("keyword %q is already parsed", literal)
+		}
+		return "", false, errors.Errorf("literal %q is neither a name nor an operator", literal)
+	}
+	return strings.ToUpper(literal), false, nil
+}
+
+// extensionKey returns the key under which syntax beginning with
+// the input token is registered, or false if it can be registered
+// for no syntax.
+func extensionKey(tok Token) (string, bool) {
+	switch tok.Type {
+	case OPERATOR:
+		return tok.Literal, true
+	case IDENT:
+		return strings.ToUpper(tok.Literal), true
+	}
+	return "", false
+}
+
+// prefixExtension returns the parse function registered
+// for syntax beginning with the input token, if there is one.
+func (s *extensionSet) prefixExtension(tok Token) (ParseFunc, bool) {
+	if len(s.prefix) == 0 {
+		return nil, false
+	}
+	key, ok := extensionKey(tok)
+	if !ok {
+		return nil, false
+	}
+	fn, ok := s.prefix[key]
+	return fn, ok
+}
+
+// infixExtension returns the parse function registered for syntax
+// that follows an expression beginning with the input token,
+// if there is one.
+func (s *extensionSet) infixExtension(tok Token) (ParseFunc, bool) {
+	if len(s.infix) == 0 {
+		return nil, false
+	}
+	key, ok := extensionKey(tok)
+	if !ok {
+		return nil, false
+	}
+	fn, ok := s.infix[key]
+	return fn, ok
+}
+
+// parseExtension parses the syntax beginning with the current token using
+// the input registered function, or as an operator and its operand if it
+// is nil. The left-hand operand of infix syntax, if any, is the first of
+// the expressions passed through.
+func (p *Parser) parseExtension(fn ParseFunc, left Expression, precedence int) (Expression, error) {
+	start := p.cur()
+	c := &Cursor{p: p, precedence: precedence}
+	if left != nil {
+		c.exps = append(c.exps, left)
+	}
+	c.exps = append(c.exps, NewIdentityExpression(start))
+
+	var err error
+	if fn == nil {
+		err = c.Operand()
+	} else {
+		err = fn(c)
+	}
+	if err != nil {
+		if _, ok := err.(*Error); !ok {
+			err = errorAt(start, "%s", err.Error())
+		}
+		return nil, err
+	}
+
+	exp := &PassThroughExpression{}
+	exp.setExpressions(c.exps)
+	return exp, nil
+}
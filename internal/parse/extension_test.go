@@ -0,0 +1,96 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	mustRegister(RegisterInfix("#>", nil))
+	mustRegister(RegisterInfix("#>>", nil))
+	mustRegister(RegisterPrefix("@@", nil))
+
+	// "INTERVAL '1 day'" is passed through as a single unit.
+	mustRegister(RegisterPrefix("interval", func(c *Cursor) error {
+		if c.Peek().Type != STRING {
+			return errors.New("expected string after INTERVAL")
+		}
+		return c.Next()
+	}))
+}
+
+func mustRegister(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestParseRegisteredSyntax(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected string
+	}{
+		{"data #> '{a,b}'", "{data #> '{a,b}'}"},
+		{"data#>>$Path.keys", "{data #>> $Path.keys}"},
+		{"data #> a || b", "{data #> (a || b)}"},
+		{"data #> a = b", "({data #> a} = b)"},
+		{"data #> a + b", "{data #> (a + b)}"},
+		{"a #> b #> c", "{{a #> b} #> c}"},
+		{"@@ q AND x", "({@@ q} AND x)"},
+		{"created > now() - INTERVAL '1 day'", "(created > (now() - {INTERVAL '1 day'}))"},
+	}
+
+	for _, test := range tests {
+		exp, err := NewParser(NewLexer(test.stmt)).Run()
+		if !assert.Nil(t, err, test.stmt) {
+			continue
+		}
+
+		children := exp.Expressions()
+		if assert.Len(t, children, 1, test.stmt) {
+			assert.Equal(t, test.expected, nestedString(children[0]), test.stmt)
+		}
+	}
+}
+
+func TestParseRegisteredSyntaxErrors(t *testing.T) {
+	_, err := NewParser(NewLexer("SELECT interval 5")).Run()
+	assert.EqualError(t, err, "expected string after INTERVAL at line 1, column 8")
+
+	_, err = NewParser(NewLexer("SELECT data #>")).Run()
+	assert.EqualError(t, err, "unexpected end of statement at line 1, column 14")
+}
+
+func TestRegisterErrors(t *testing.T) {
+	tests := []struct {
+		literal  string
+		expected string
+	}{
+		{"", "can not register parse function for empty literal"},
+		{"#>", `parse function already registered for "#>"`},
+		{"||", `operator "||" is already parsed`},
+		{"<", `operator "<" is already parsed`},
+		{"#>>>", `operator "#>>>" is longer than 3 characters`},
+		{"!--", `operator "!--" contains the start of a comment`},
+		{"select", `keyword "select" is already parsed`},
+		{"a.b", `literal "a.b" is neither a name nor an operator`},
+	}
+
+	for _, test := range tests {
+		assert.EqualError(t, RegisterInfix(test.literal, nil), test.expected, test.literal)
+	}
+}
+
+func TestLexRegisteredOperators(t *testing.T) {
+	tokens, err := Tokens("a#>>b #> c @@ d # e")
+	assert.Nil(t, err)
+
+	var types []TokenType
+	for _, tok := range tokens {
+		types = append(types, tok.Type)
+	}
+	assert.Equal(t, []TokenType{IDENT, OPERATOR, IDENT, OPERATOR, IDENT, OPERATOR, IDENT, UNKNOWN, IDENT}, types)
+	assert.Equal(t, "#>>", tokens[1].Literal)
+}
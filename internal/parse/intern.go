@@ -0,0 +1,102 @@
+package parse
+
+import "sync"
+
+// maxInterned is the number of distinct literals that are interned,
+// bounding the memory held by the interner whatever the input.
+const maxInterned = 1 << 16
+
+// interner holds a single copy of each of the literals of keywords,
+// identifiers and operators read by parsers, so that the expression
+// trees of many statements share them. It is safe for concurrent use.
+type interner struct {
+	mutex    sync.RWMutex
+	literals map[string]string
+}
+
+// literals interns the literals of every parsed statement.
+var literals = &interner{literals: make(map[string]string)}
+
+// intern replaces the input literals with their interned copies. Any
+// that are not yet interned are copied and, unless the interner is full,
+// interned. The interner is locked once for reading, and at most once
+// more for writing, however many literals there are.
+func (in *interner) intern(literals []*string) {
+	var missing []*string
+	in.mutex.RLock()
+	for _, s := range literals {
+		if interned, ok := in.literals[*s]; ok {
+			*s = interned
+		} else {
+			missing = append(missing, s)
+		}
+	}
+	in.mutex.RUnlock()
+	if len(missing) == 0 {
+		return
+	}
+
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+	for _, s := range missing {
+		if interned, ok := in.literals[*s]; ok {
+			*s = interned
+			continue
+		}
+		*s = string([]byte(*s))
+		if len(in.literals) < maxInterned {
+			in.literals[*s] = *s
+		}
+	}
+}
+
+// interned returns true if the literals of tokens of the
+// input type are interned, rather than copied, when detached.
+func interned(t TokenType) bool {
+	switch t {
+	case NUM, STRING, DIRECTIVE, COMMENT, HINT:
+		return false
+	}
+	return true
+}
+
+// detachLiterals replaces the literal of each of the input tokens, which
+// are slices of the lexer's input, so that expression trees built from them
+// do not keep the input from being freed. Literals of keywords, identifiers
+// and operators are interned. The others are copied into a single string.
+func detachLiterals(tokens ...[]Token) {
+	size, count := 0, 0
+	for _, toks := range tokens {
+		for _, tok := range toks {
+			if interned(tok.Type) {
+				count++
+			} else {
+				size += len(tok.Literal)
+			}
+		}
+	}
+
+	buf := make([]byte, 0, size)
+	for _, toks := range tokens {
+		for _, tok := range toks {
+			if !interned(tok.Type) {
+				buf = append(buf, tok.Literal...)
+			}
+		}
+	}
+	copied := string(buf)
+
+	names := make([]*string, 0, count)
+	offset := 0
+	for _, toks := range tokens {
+		for i, tok := range toks {
+			if interned(tok.Type) {
+				names = append(names, &toks[i].Literal)
+				continue
+			}
+			toks[i].Literal = copied[offset : offset+len(tok.Literal)]
+			offset += len(tok.Literal)
+		}
+	}
+	literals.intern(names)
+}
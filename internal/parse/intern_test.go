@@ -0,0 +1,63 @@
+package parse
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stringData returns the address of the bytes of the input string.
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+// within returns true if the bytes of the input literal
+// lie within those of the input string.
+func within(literal, s string) bool {
+	start := stringData(s)
+	return stringData(literal) >= start && stringData(literal) < start+uintptr(len(s))
+}
+
+func TestParseInternsLiterals(t *testing.T) {
+	first := "SELECT name FROM person WHERE id = 1 -- note"
+	second := strings.Replace(first, "1", "2", 1)
+
+	a, err := NewParser(NewLexer(first)).Run()
+	assert.Nil(t, err)
+	b, err := NewParser(NewLexer(second)).Run()
+	assert.Nil(t, err)
+
+	// Keywords and identifiers are shared by the statements' trees.
+	for i := 0; i < 3; i++ {
+		litA, litB := a.Expressions()[i].String(), b.Expressions()[i].String()
+		assert.Equal(t, litA, litB)
+		assert.Equal(t, stringData(litA), stringData(litB), litA)
+	}
+
+	// No literal is a slice of the input.
+	_ = Walk(a, func(exp Expression) error {
+		switch e := exp.(type) {
+		case *IdentityExpression:
+			assert.False(t, within(e.token.Literal, first), e.token.Literal)
+		case *CommentExpression:
+			assert.False(t, within(e.token.Literal, first), e.token.Literal)
+		}
+		return nil
+	})
+}
+
+func TestInternerBound(t *testing.T) {
+	in := &interner{literals: make(map[string]string)}
+	for i := 0; i < maxInterned; i++ {
+		in.literals[strconv.Itoa(i)] = ""
+	}
+
+	s := "beyond"
+	in.intern([]*string{&s})
+	assert.Equal(t, "beyond", s)
+	assert.Len(t, in.literals, maxInterned)
+}
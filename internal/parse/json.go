@@ -0,0 +1,353 @@
+package parse
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// jsonExpression is the JSON representation of an Expression.
+// Every expression type is represented by its name, the tokens that it
+// holds other than via its child expressions, and its child expressions.
+type jsonExpression struct {
+	Type     string            `json:"type"`
+	Tokens   []Token           `json:"tokens,omitempty"`
+	Children []*jsonExpression `json:"children,omitempty"`
+
+	// Operators holds the set operators of a CompoundExpression.
+	Operators [][]Token `json:"operators,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (e *SQLExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *DMLExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *DDLExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *GroupedColumnsExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *OutputTargetExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *InputSourceExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *IdentityExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *QualifiedIdentityExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *PrefixExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *InfixExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *FunctionCallExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *QuantifiedExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *SubqueryExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *CompoundExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *WithExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *CommonTableExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *OrderByExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *OrderingTermExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *ValuesExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *LimitExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *TemplateExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *PassThroughExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *CommentExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// MarshalJSON implements json.Marshaler.
+func (e *HintExpression) MarshalJSON() ([]byte, error) { return marshalExpression(e) }
+
+// marshalExpression returns the JSON encoding of the input expression tree.
+func marshalExpression(exp Expression) ([]byte, error) {
+	j, err := toJSON(exp)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalExpression returns the expression tree
+// for the input JSON, as encoded by MarshalJSON.
+func UnmarshalExpression(data []byte) (Expression, error) {
+	var j jsonExpression
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling expression")
+	}
+	return fromJSON(&j)
+}
+
+// toJSON returns the JSON representation of the input expression tree.
+func toJSON(exp Expression) (*jsonExpression, error) {
+	var j *jsonExpression
+	switch e := exp.(type) {
+	case *SQLExpression:
+		j = &jsonExpression{Type: "SQLExpression"}
+	case *DMLExpression:
+		j = &jsonExpression{Type: "DMLExpression"}
+	case *DDLExpression:
+		j = &jsonExpression{Type: "DDLExpression"}
+	case *GroupedColumnsExpression:
+		j = &jsonExpression{Type: "GroupedColumnsExpression"}
+	case *SubqueryExpression:
+		j = &jsonExpression{Type: "SubqueryExpression"}
+	case *PassThroughExpression:
+		j = &jsonExpression{Type: "PassThroughExpression"}
+	case *QualifiedIdentityExpression:
+		j = &jsonExpression{Type: "QualifiedIdentityExpression"}
+	case *OutputTargetExpression:
+		j = &jsonExpression{Type: "OutputTargetExpression", Tokens: []Token{e.marker}}
+	case *InputSourceExpression:
+		j = &jsonExpression{Type: "InputSourceExpression", Tokens: []Token{e.marker}}
+	case *IdentityExpression:
+		j = &jsonExpression{Type: "IdentityExpression", Tokens: []Token{e.token}}
+	case *PrefixExpression:
+		j = &jsonExpression{Type: "PrefixExpression", Tokens: []Token{e.operator}}
+	case *InfixExpression:
+		j = &jsonExpression{Type: "InfixExpression", Tokens: e.operator}
+	case *FunctionCallExpression:
+		j = &jsonExpression{Type: "FunctionCallExpression", Tokens: []Token{e.rparen}}
+	case *QuantifiedExpression:
+		j = &jsonExpression{Type: "QuantifiedExpression", Tokens: []Token{e.quantifier}}
+	case *CompoundExpression:
+		j = &jsonExpression{Type: "CompoundExpression", Operators: e.operators}
+	case *WithExpression:
+		j = &jsonExpression{Type: "WithExpression", Tokens: e.keywords}
+	case *CommonTableExpression:
+		j = &jsonExpression{Type: "CommonTableExpression", Tokens: e.as}
+	case *OrderByExpression:
+		j = &jsonExpression{Type: "OrderByExpression", Tokens: e.keywords}
+	case *OrderingTermExpression:
+		j = &jsonExpression{Type: "OrderingTermExpression", Tokens: e.modifiers}
+	case *ValuesExpression:
+		j = &jsonExpression{Type: "ValuesExpression", Tokens: []Token{e.keyword}}
+	case *LimitExpression:
+		j = &jsonExpression{Type: "LimitExpression", Tokens: []Token{e.keyword}}
+		if e.second != nil {
+			j.Tokens = append(j.Tokens, e.separator)
+		}
+	case *TemplateExpression:
+		j = &jsonExpression{Type: "TemplateExpression", Tokens: []Token{e.open, e.close}}
+	case *CommentExpression:
+		j = &jsonExpression{Type: "CommentExpression", Tokens: []Token{e.token}}
+	case *HintExpression:
+		j = &jsonExpression{Type: "HintExpression", Tokens: []Token{e.token}}
+	default:
+		return nil, errors.Errorf("unable to marshal expression of type %T", exp)
+	}
+
+	for _, child := range exp.Expressions() {
+		cj, err := toJSON(child)
+		if err != nil {
+			return nil, err
+		}
+		j.Children = append(j.Children, cj)
+	}
+	return j, nil
+}
+
+// fromJSON returns the expression tree for the input JSON representation.
+func fromJSON(j *jsonExpression) (Expression, error) {
+	if j == nil {
+		return nil, errors.New("null expression")
+	}
+
+	children := make([]Expression, len(j.Children))
+	for i, cj := range j.Children {
+		child, err := fromJSON(cj)
+		if err != nil {
+			return nil, err
+		}
+		children[i] = child
+	}
+
+	switch j.Type {
+	case "SQLExpression":
+		return withChildren(&SQLExpression{}, children), nil
+	case "DMLExpression":
+		return withChildren(&DMLExpression{}, children), nil
+	case "DDLExpression":
+		return withChildren(&DDLExpression{}, children), nil
+	case "GroupedColumnsExpression":
+		return withChildren(&GroupedColumnsExpression{}, children), nil
+	case "SubqueryExpression":
+		return withChildren(&SubqueryExpression{}, children), nil
+	case "PassThroughExpression":
+		return withChildren(&PassThroughExpression{}, children), nil
+	case "WithExpression":
+		if len(j.Tokens) == 0 {
+			break
+		}
+		return withChildren(NewWithExpression(j.Tokens), children), nil
+	case "OrderByExpression":
+		if len(j.Tokens) != 2 {
+			break
+		}
+		return withChildren(NewOrderByExpression(j.Tokens), children), nil
+	case "ValuesExpression":
+		if len(j.Tokens) != 1 || len(children) == 0 {
+			break
+		}
+		exp := NewValuesExpression(j.Tokens[0])
+		if withChildren(exp, children); len(exp.Rows()) == len(children) {
+			return exp, nil
+		}
+	case "CompoundExpression":
+		if len(children) == 0 || len(j.Operators) != len(children)-1 {
+			break
+		}
+		exp := &CompoundExpression{}
+		for i, child := range children {
+			var operator []Token
+			if i > 0 {
+				operator = j.Operators[i-1]
+			}
+			exp.AppendBranch(operator, child)
+		}
+		return exp, nil
+	case "IdentityExpression":
+		if len(j.Tokens) != 1 || len(children) != 0 {
+			break
+		}
+		return NewIdentityExpression(j.Tokens[0]), nil
+	case "CommentExpression":
+		if len(j.Tokens) != 1 || len(children) != 0 {
+			break
+		}
+		return NewCommentExpression(j.Tokens[0]), nil
+	case "HintExpression":
+		if len(j.Tokens) != 1 || len(children) != 0 {
+			break
+		}
+		return NewHintExpression(j.Tokens[0]), nil
+	case "QualifiedIdentityExpression":
+		if len(children) != 2 {
+			break
+		}
+		if name, ok := children[1].(*IdentityExpression); ok {
+			return NewQualifiedIdentityExpression(children[0], name), nil
+		}
+	case "OutputTargetExpression", "InputSourceExpression":
+		if len(j.Tokens) != 1 || len(children) != 2 {
+			break
+		}
+		name, ok := children[0].(*IdentityExpression)
+		field, ok2 := children[1].(*IdentityExpression)
+		if !ok || !ok2 {
+			break
+		}
+		if j.Type == "OutputTargetExpression" {
+			return NewOutputTargetExpression(j.Tokens[0], name, field), nil
+		}
+		return NewInputSourceExpression(j.Tokens[0], name, field), nil
+	case "PrefixExpression":
+		if len(j.Tokens) != 1 || len(children) != 1 {
+			break
+		}
+		return NewPrefixExpression(j.Tokens[0], children[0]), nil
+	case "InfixExpression":
+		if len(j.Tokens) == 0 || len(children) != 2 {
+			break
+		}
+		return NewInfixExpression(children[0], j.Tokens, children[1]), nil
+	case "FunctionCallExpression":
+		if len(j.Tokens) != 1 || len(children) == 0 {
+			break
+		}
+		if name, ok := children[0].(*IdentityExpression); ok {
+			return NewFunctionCallExpression(name, children[1:], j.Tokens[0]), nil
+		}
+	case "QuantifiedExpression":
+		if len(j.Tokens) != 1 || len(children) != 1 {
+			break
+		}
+		return NewQuantifiedExpression(j.Tokens[0], children[0]), nil
+	case "CommonTableExpression":
+		if len(j.Tokens) == 0 || len(children) < 2 || len(children) > 3 {
+			break
+		}
+		name, ok := children[0].(*IdentityExpression)
+		query, ok2 := children[len(children)-1].(*SubqueryExpression)
+		if !ok || !ok2 {
+			break
+		}
+		var columns *GroupedColumnsExpression
+		if len(children) == 3 {
+			if columns, ok = children[1].(*GroupedColumnsExpression); !ok {
+				break
+			}
+		}
+		return NewCommonTableExpression(name, columns, j.Tokens, query), nil
+	case "OrderingTermExpression":
+		if len(children) != 1 {
+			break
+		}
+		return NewOrderingTermExpression(children[0], j.Tokens), nil
+	case "LimitExpression":
+		switch {
+		case len(j.Tokens) == 1 && len(children) == 1:
+			return NewLimitExpression(j.Tokens[0], children[0], Token{}, nil), nil
+		case len(j.Tokens) == 2 && len(children) == 2:
+			return NewLimitExpression(j.Tokens[0], children[0], j.Tokens[1], children[1]), nil
+		}
+	case "TemplateExpression":
+		if len(j.Tokens) != 2 || len(children) != 1 {
+			break
+		}
+		if name, ok := children[0].(*IdentityExpression); ok {
+			return NewTemplateExpression(j.Tokens[0], name, j.Tokens[1]), nil
+		}
+	default:
+		return nil, errors.Errorf("unknown expression type %q", j.Type)
+	}
+
+	return nil, errors.Errorf("malformed %s with %d tokens and %d children", j.Type, len(j.Tokens), len(children))
+}
+
+// parentExpression is an expression to which
+// child expressions can be appended.
+type parentExpression interface {
+	Expression
+	AppendExpression(Expression)
+}
+
+// withChildren appends the input children to the input
+// parent expression, before returning the parent.
+func withChildren(parent parentExpression, children []Expression) Expression {
+	for _, child := range children {
+		parent.AppendExpression(child)
+	}
+	return parent
+}
@@ -0,0 +1,71 @@
+package parse
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	stmts := append([]string{`
+WITH RECURSIVE m (id) AS NOT MATERIALIZED (SELECT id FROM person WHERE NOT id = -1)
+SELECT p.* AS &Person.*, count(*) FROM [[table]] AS p
+WHERE p.id IN (SELECT id FROM m) AND name IS NOT NULL
+UNION ALL
+SELECT &Person.* FROM person
+ORDER BY name COLLATE NOCASE DESC LIMIT $Page.offset, $Page.size;`,
+		"SELECT /*+ hint */ a /* note */ FROM t -- note",
+		"SELECT * FROM t WHERE id = ANY($Filter.ids) AND NOT EXISTS (SELECT 1 FROM u)",
+		"INSERT INTO person (id, name) VALUES ($Person.id, $Person.name), (2, 'Onos')",
+	}, jujuStatements...)
+
+	for _, stmt := range stmts {
+		exp, err := NewParser(NewLexer(stmt)).Run()
+		assert.Nil(t, err)
+
+		data, err := json.Marshal(exp)
+		assert.Nil(t, err)
+
+		loaded, err := UnmarshalExpression(data)
+		assert.Nil(t, err)
+		assert.Equal(t, exp, loaded)
+
+		reloaded, err := json.Marshal(loaded)
+		assert.Nil(t, err)
+		assert.Equal(t, string(data), string(reloaded))
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	exp, err := NewParser(NewLexer("-a")).Run()
+	assert.Nil(t, err)
+
+	data, err := json.Marshal(exp.Expressions()[0])
+	assert.Nil(t, err)
+
+	expected := fmt.Sprintf(`{"type":"PrefixExpression",`+
+		`"tokens":[{"type":%d,"literal":"-","pos":{"offset":0,"line":1,"column":1}}],`+
+		`"children":[{"type":"IdentityExpression",`+
+		`"tokens":[{"type":%d,"literal":"a","pos":{"offset":1,"line":1,"column":2},"adjacent":true}]}]}`, MINUS, IDENT)
+	assert.Equal(t, expected, string(data))
+}
+
+func TestUnmarshalExpressionErrors(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{`{"type":"BogusExpression"}`, `unknown expression type "BogusExpression"`},
+		{`{"type":"InfixExpression","children":[{"type":"SQLExpression"}]}`, "malformed InfixExpression with 0 tokens and 1 children"},
+		{`{"type":"SQLExpression","children":[null]}`, "null expression"},
+		{`{"type":"ValuesExpression","tokens":[{"literal":"VALUES"}],"children":[{"type":"SQLExpression"}]}`, "malformed ValuesExpression with 1 tokens and 1 children"},
+		{`[]`, "unmarshalling expression: json: cannot unmarshal array into Go value of type parse.jsonExpression"},
+	}
+
+	for _, test := range tests {
+		_, err := UnmarshalExpression([]byte(test.data))
+		assert.EqualError(t, err, test.expected, test.data)
+	}
+}
@@ -0,0 +1,87 @@
+package parse
+
+import "strings"
+
+// Kind classifies a statement by its effect on the database.
+type Kind int
+
+const (
+	// KindUnknown is the kind of a statement that can not be classified,
+	// such as a GRANT. It must be assumed to modify the database.
+	KindUnknown Kind = iota
+
+	// KindQuery is the kind of a statement that only reads, such as SELECT.
+	KindQuery
+
+	// KindDML is the kind of a statement that modifies data,
+	// such as INSERT, UPDATE or DELETE.
+	KindDML
+
+	// KindDDL is the kind of a statement that modifies the schema,
+	// such as CREATE TABLE.
+	KindDDL
+
+	// KindAdmin is the kind of an administrative statement that configures
+	// the connection or maintains the database, such as a PRAGMA, SET,
+	// VACUUM or ANALYZE. Its effect on the data is not known, so it too
+	// must be assumed to modify the database.
+	KindAdmin
+)
+
+// kindNames holds the name of each Kind.
+var kindNames = map[Kind]string{
+	KindUnknown: "unknown",
+	KindQuery:   "query",
+	KindDML:     "DML",
+	KindDDL:     "DDL",
+	KindAdmin:   "admin",
+}
+
+// String implements fmt.Stringer, returning the name of the kind.
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// keywordKinds maps the keywords that begin statements to their kinds.
+var keywordKinds = map[string]Kind{
+	"SELECT":  KindQuery,
+	"VALUES":  KindQuery,
+	"INSERT":  KindDML,
+	"REPLACE": KindDML,
+	"UPDATE":  KindDML,
+	"DELETE":  KindDML,
+	"CREATE":  KindDDL,
+	"ALTER":   KindDDL,
+	"DROP":    KindDDL,
+	"PRAGMA":  KindAdmin,
+	"SET":     KindAdmin,
+	"VACUUM":  KindAdmin,
+	"ANALYZE": KindAdmin,
+}
+
+// Classify returns the kind of the input statement expression tree,
+// as determined by the keyword with which it begins, following any WITH
+// clause. A compound query is classified by its first query.
+func Classify(exp Expression) Kind {
+	switch exp.(type) {
+	case *DMLExpression:
+		return KindDML
+	case *DDLExpression:
+		return KindDDL
+	}
+
+	for _, child := range exp.Expressions() {
+		switch e := child.(type) {
+		case *WithExpression:
+			continue
+		case *CompoundExpression:
+			return Classify(e.Expressions()[0])
+		case *ValuesExpression:
+			return KindQuery
+		case *IdentityExpression:
+			return keywordKinds[strings.ToUpper(e.String())]
+		}
+		return KindUnknown
+	}
+	return KindUnknown
+}
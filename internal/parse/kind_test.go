@@ -0,0 +1,41 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected Kind
+	}{
+		{"SELECT &Person.* FROM person", KindQuery},
+		{"select 1", KindQuery},
+		{"VALUES (1, 2)", KindQuery},
+		{"WITH m AS (SELECT 1) SELECT * FROM m", KindQuery},
+		{"SELECT 1 UNION SELECT 2", KindQuery},
+		{"INSERT INTO person (name) VALUES ($Person.name)", KindDML},
+		{"WITH m AS (SELECT 1) DELETE FROM person", KindDML},
+		{"UPDATE person SET name = 'Fred'", KindDML},
+		{"CREATE TABLE person (id TEXT)", KindDDL},
+		{"PRAGMA foreign_keys = ON", KindAdmin},
+		{"pragma table_info(person)", KindAdmin},
+		{"SET search_path = public", KindAdmin},
+		{"VACUUM", KindAdmin},
+		{"ANALYZE person", KindAdmin},
+		{"GRANT SELECT ON person TO reader", KindUnknown},
+		{"", KindUnknown},
+	}
+
+	for _, test := range tests {
+		exp, err := NewParser(NewLexer(test.stmt)).Run()
+		if assert.Nil(t, err, test.stmt) {
+			assert.Equal(t, test.expected, Classify(exp), test.stmt)
+		}
+	}
+
+	assert.Equal(t, KindDML, Classify(&DMLExpression{}))
+	assert.Equal(t, "query", KindQuery.String())
+}
@@ -1,6 +1,8 @@
 package parse
 
 import (
+	"bufio"
+	"io"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -19,32 +21,212 @@ type Lexer struct {
 	readOffset int
 	line       int
 	column     int
+
+	// start is the offset in input of the token being read.
+	start int
+
+	// reader, if not nil, is the source from which input is read as the
+	// lexer advances. Input preceding the current token is then discarded,
+	// and base is the offset of what remains within the whole statement.
+	reader *bufio.Reader
+	base   int
+	err    error
+
+	// started is true once the first token has been read.
+	started bool
+
+	// last is the type of the token most recently read, and spaced is
+	// true if whitespace or a comment has been read since.
+	last   TokenType
+	spaced bool
+
+	// directives holds the directive comments preceding the first token.
+	directives []Token
+
+	// comments holds the other comments read.
+	comments []Token
+
+	// operators holds the operators registered with parse functions.
+	operators map[string]bool
+
+	// backslashEscapes is true if a backslash within a string
+	// literal escapes the character following it.
+	backslashEscapes bool
 }
 
+// readChunkSize is the number of bytes requested from
+// a reader each time the lexer needs more input.
+const readChunkSize = 4096
+
+// directivePrefix begins the text of a directive comment,
+// such as "-- sqlair:timeout=5s".
+const directivePrefix = "sqlair:"
+
+// hintPrefix begins a block comment that is an optimizer hint,
+// such as "/*+ INDEX(person idx_name) */".
+const hintPrefix = "/*+"
+
+// byteOrderMark is the encoding of the Unicode byte order mark in UTF-8.
+// It is skipped if it begins the input.
+const byteOrderMark = "\uFEFF"
+
 // NewLexer creates a new Lexer from a given input and primes it
 // with the first non-whitespace character before returning.
 func NewLexer(input string) *Lexer {
 	l := &Lexer{
-		input:  strings.TrimSpace(input),
-		line:   1,
-		column: 1,
+		input:     strings.TrimSpace(strings.TrimPrefix(input, byteOrderMark)),
+		line:      1,
+		column:    1,
+		operators: currentExtensions().operators,
 	}
 	l.nextChar()
 	return l
 }
 
+// NewReaderLexer creates a new Lexer that reads its input incrementally
+// from the input reader, so the statement need not be held in memory as a
+// whole. Positions are reported as if by a Lexer for the entire statement.
+func NewReaderLexer(r io.Reader) *Lexer {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+
+	l := &Lexer{
+		reader:    br,
+		line:      1,
+		column:    1,
+		operators: currentExtensions().operators,
+	}
+	l.nextChar()
+	if l.currentChar() == byteOrderMark {
+		l.nextChar()
+	}
+
+	// Leading whitespace is not counted, as with
+	// the trimmed input of a string-based Lexer.
+	for l.skipWhitespace() {
+	}
+	l.start = l.offset
+	l.base = -l.offset
+	l.line = 1
+	l.column = 2
+	return l
+}
+
+// SetBackslashEscapes sets whether a backslash within a string literal
+// escapes the character following it, as in MySQL, so that "'it\'s'" is
+// a single literal. Otherwise, as in standard SQL, a backslash is an
+// ordinary character and quotes are escaped only by doubling them.
+// It must be called before the first token is read.
+func (l *Lexer) SetBackslashEscapes(escapes bool) {
+	l.backslashEscapes = escapes
+}
+
+// sizeHint returns the length in bytes of the lexer's input,
+// or zero if it is read incrementally and so not known.
+func (l *Lexer) sizeHint() int {
+	if l.reader != nil {
+		return 0
+	}
+	return len(l.input)
+}
+
+// Err returns the error, if any, encountered reading from the lexer's
+// reader or decoding its input, which must be valid UTF-8.
+// Once there has been an error, NextToken returns EOF.
+func (l *Lexer) Err() error {
+	if l.err == io.EOF {
+		return nil
+	}
+	return l.err
+}
+
+// Tokens returns every token in the input statement, in order and excluding
+// the final EOF token. An error is returned if the statement contains an
+// unterminated string literal or quoted identifier, or invalid UTF-8.
+// It allows the token stream to be consumed without constructing a Parser.
+func Tokens(stmt string) ([]Token, error) {
+	l := NewLexer(stmt)
+
+	var tokens []Token
+	for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+		if (tok.Type == STRING || tok.Type == QUOTEDIDENT) && !isTerminatedString(tok.Literal, false) {
+			// An invalid encoding ends the input early,
+			// so it is the underlying cause if there is one.
+			if err := l.Err(); err != nil {
+				return nil, err
+			}
+			return nil, errorAt(tok, "unterminated %s", quotedDescription(tok.Type))
+		}
+		tokens = append(tokens, tok)
+	}
+	if err := l.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
 // NextToken returns the next token based on
 // the current offset, ignoring whitespace.
 // The EOF token is returned if we have
 // reached the end of the input.
+// Token literals are slices of the input rather than copies,
+// so lexing a statement does not allocate.
 func (l *Lexer) NextToken() Token {
-	for l.skipWhitespace() {
+	tok := l.nextToken()
+	tok.Adjacent = l.started && !l.spaced
+	l.started = true
+	l.last = tok.Type
+	l.spaced = false
+	return tok
+}
+
+// nextToken returns the next token, without recording it as read.
+func (l *Lexer) nextToken() Token {
+	for l.skipWhitespace() || l.skipComment() {
+		l.spaced = true
 	}
+	l.start = l.offset
 
 	pos := l.position()
 
+	if len(l.operators) > 0 || strings.ContainsRune("?@<-", l.char) {
+		if lit := l.readOperator(); lit != "" {
+			for range lit {
+				l.nextChar()
+			}
+			return Token{
+				Type:    OPERATOR,
+				Literal: lit,
+				Pos:     pos,
+			}
+		}
+	}
+
+	if opType, isKnown := knownOperatorTokens[l.nextTwoChars()]; isKnown {
+		lit := l.nextTwoChars()
+		l.nextChar()
+		l.nextChar()
+		return Token{
+			Type:    opType,
+			Literal: lit,
+			Pos:     pos,
+		}
+	}
+
+	// A period before a digit begins a number, such as ".5", unless
+	// it qualifies the name or number immediately preceding it.
+	if l.char == '.' && isDigit(l.peek()) && !(l.started && !l.spaced && qualifiable(l.last)) {
+		return Token{
+			Type:    NUM,
+			Literal: l.readNumber(),
+			Pos:     pos,
+		}
+	}
+
 	if runeType, isKnown := knownRuneTokens[l.char]; isKnown {
-		lit := string(l.char)
+		lit := l.currentChar()
 		l.nextChar()
 		return Token{
 			Type:    runeType,
@@ -57,9 +239,25 @@ func (l *Lexer) NextToken() Token {
 	return l.readComplexToken(pos)
 }
 
+// readOperator returns the range of input occupied by the longest JSON or
+// registered operator beginning with the current character, if any.
+func (l *Lexer) readOperator() string {
+	l.fill()
+	for n := maxOperatorLen; n > 0; n-- {
+		end := l.offset + n
+		if end > len(l.input) {
+			continue
+		}
+		if op := l.input[l.offset:end]; jsonOperators[op] || l.operators[op] {
+			return op
+		}
+	}
+	return ""
+}
+
 func (l *Lexer) position() Position {
 	return Position{
-		Offset: l.offset,
+		Offset: l.base + l.offset,
 		Line:   l.line,
 		Column: l.column - 1,
 	}
@@ -76,6 +274,78 @@ func (l *Lexer) skipWhitespace() bool {
 	return true
 }
 
+// skipComment checks if the current character begins a comment and if so,
+// reads to its end before returning true. A line comment, beginning "--",
+// ends with the line, and a block comment is enclosed by "/*" and "*/".
+// A directive line comment preceding the first token is recorded as a
+// DIRECTIVE token, with the text following its prefix as the literal.
+// Every other comment is recorded as a COMMENT or HINT token.
+func (l *Lexer) skipComment() bool {
+	var line bool
+	switch l.nextTwoChars() {
+	case "--":
+		line = true
+	case "/*":
+	default:
+		return false
+	}
+
+	pos := l.position()
+	l.start = l.offset
+	if line {
+		for l.char != '\n' && l.char != 0 {
+			l.nextChar()
+		}
+	} else {
+		l.nextChar()
+		l.nextChar()
+		for l.char != 0 && l.nextTwoChars() != "*/" {
+			l.nextChar()
+		}
+		if l.char != 0 {
+			l.nextChar()
+			l.nextChar()
+		}
+	}
+	lit := l.input[l.start:l.offset]
+
+	if text := strings.TrimSpace(strings.TrimPrefix(lit, "--")); line && !l.started && strings.HasPrefix(text, directivePrefix) {
+		l.directives = append(l.directives, Token{
+			Type:    DIRECTIVE,
+			Literal: strings.TrimPrefix(text, directivePrefix),
+			Pos:     pos,
+		})
+		return true
+	}
+
+	tok := Token{
+		Type:    COMMENT,
+		Literal: strings.TrimRightFunc(lit, unicode.IsSpace),
+		Pos:     pos,
+	}
+	if strings.HasPrefix(lit, hintPrefix) {
+		tok.Type = HINT
+	}
+	l.comments = append(l.comments, tok)
+	return true
+}
+
+// Comments returns the comments, other than directives, read so far,
+// in order. Each is a COMMENT token, or a HINT token for a block comment
+// beginning "/*+", with the whole comment, including its delimiters, as
+// its literal.
+func (l *Lexer) Comments() []Token {
+	return l.comments
+}
+
+// Directives returns the directive comments, such as "-- sqlair:timeout=5s",
+// that precede the first token of the statement. Each is a DIRECTIVE token
+// with the text following "sqlair:" as its literal.
+// They are complete once the first token has been read.
+func (l *Lexer) Directives() []Token {
+	return l.directives
+}
+
 func (l *Lexer) readComplexToken(pos Position) Token {
 	tok := Token{Pos: pos}
 
@@ -89,47 +359,106 @@ func (l *Lexer) readComplexToken(pos Position) Token {
 		tok.Literal = l.readNumber()
 		return tok
 
+	case strings.ContainsRune("eEnNbBxX", l.char) && l.peek() == '\'':
+		// A prefixed string, such as E'\n' or X'1F'. That of
+		// PostgreSQL's escape string syntax has backslash escapes.
+		escapes := l.char == 'e' || l.char == 'E'
+		l.nextChar()
+		tok.Type = STRING
+		tok.Literal = l.readQuoted(l.char, escapes || l.backslashEscapes)
+		return tok
+
+	case l.char == '@' && (unicode.IsLetter(l.peek()) || l.peek() == '_' || l.peek() == '@'):
+		// A variable, such as MySQL's "@total" or "@@version".
+		l.nextChar()
+		if l.char == '@' {
+			l.nextChar()
+		}
+		tok.Type = IDENT
+		tok.Literal = l.readIdentifier()
+		return tok
+
 	case unicode.IsLetter(l.char) || l.char == '_':
 		tok.Type = IDENT
 		tok.Literal = l.readIdentifier()
+		if isKeywordLiteral(tok.Literal) {
+			tok.Type = KEYWORD
+		}
+		return tok
+
+	case l.char == '"' || l.char == '`':
+		// A quoted identifier, which may be a keyword, as in "order".
+		tok.Type = QUOTEDIDENT
+		tok.Literal = l.readQuoted(l.char, false)
 		return tok
 
 	case l.char == '\'':
 		tok.Type = STRING
-		tok.Literal = l.readString(l.char)
+		tok.Literal = l.readQuoted(l.char, l.backslashEscapes)
 		return tok
 	}
 
 	tok.Type = UNKNOWN
-	tok.Literal = string(l.char)
+	tok.Literal = l.currentChar()
 	l.nextChar()
 	return tok
 }
 
+// currentChar returns the range of input
+// occupied by the current character.
+func (l *Lexer) currentChar() string {
+	return l.input[l.offset:l.readOffset]
+}
+
+// nextTwoChars returns the range of input occupied by the current
+// character and the one following it. If the current character is
+// the last in the input, an empty string is returned.
+func (l *Lexer) nextTwoChars() string {
+	l.fill()
+	if l.readOffset >= len(l.input) {
+		return ""
+	}
+
+	_, size := utf8.DecodeRuneInString(l.input[l.readOffset:])
+	return l.input[l.offset : l.readOffset+size]
+}
+
 // readIdentifier calls nextChar until it detects the end of an identifier,
 // then returns the range of input from when we started reading.
+// Identifiers may contain letters and digits of any script, and the
+// combining marks that accompany them, such as the accent of "é" in
+// its decomposed form.
 func (l *Lexer) readIdentifier() string {
-	pos := l.offset
-
-	for unicode.IsLetter(l.char) || isDigit(l.char) || l.char == '_' {
+	for unicode.IsLetter(l.char) || isDigit(l.char) || l.char == '_' || l.char >= utf8.RuneSelf && unicode.IsMark(l.char) {
 		l.nextChar()
 	}
 
-	return l.input[pos:l.offset]
+	return l.input[l.start:l.offset]
 }
 
-// readString calls nextChar until it detects the end of a quoted string,
-// then returns the range of input from when we started reading.
-// The return includes the quotes.
-func (l *Lexer) readString(r rune) string {
-	pos := l.offset
-
+// readQuoted calls nextChar until it detects the end of a string quoted by
+// the input rune, then returns the range of input from when we started
+// reading. The return includes the quotes. If escapes is true, a backslash
+// within the string escapes the character following it.
+func (l *Lexer) readQuoted(r rune, escapes bool) string {
 	maybeCloser := true
 	for {
 		// Unterminated string. Will be handled downstream.
+		// Trailing whitespace is excluded as it would be
+		// from the trimmed input of a string-based Lexer.
 		if l.char == 0 {
 			l.nextChar()
-			break
+			return strings.TrimRightFunc(l.input[l.start:l.offset], unicode.IsSpace)
+		}
+
+		if l.char == '\\' && escapes {
+			// The escaped character does not end
+			// the string, even if it is a quote.
+			l.nextChar()
+			if l.char != 0 {
+				l.nextChar()
+			}
+			continue
 		}
 
 		if l.char == r {
@@ -148,36 +477,68 @@ func (l *Lexer) readString(r rune) string {
 		l.nextChar()
 	}
 
-	return l.input[pos:l.offset]
+	return l.input[l.start:l.offset]
 }
 
 // readNumber calls nextChar until it detects the end of a number,
 // then returns the range of input from when we started reading.
+// Besides decimal numbers such as "12", "1.5" and ".5", a number may be
+// hexadecimal, octal or binary, as in "0x1F", "0o17" and "0b101", and
+// may have an exponent, as in "1e3" or "2.5E-3". Digits may be separated
+// by underscores, as in "1_000".
 func (l *Lexer) readNumber() string {
-	pos := l.offset
-
-	var oneDecimal bool
-	for isDigit(l.char) || l.char == '.' {
-		if l.char == '.' {
-			oneDecimal = true
-		}
-
-		// If we've already seen a decimal point and there is one ahead,
-		// just finish the token accumulation. Syntax error goes downstream.
-		if oneDecimal && l.peek() == '.' {
+	if l.char == '0' && strings.ContainsRune("xXoObB", l.peek()) {
+		l.nextChar()
+		l.nextChar()
+		for isHexDigit(l.char) || l.char == '_' {
 			l.nextChar()
-			break
 		}
+		return l.input[l.start:l.offset]
+	}
 
+	l.readDigits()
+	if l.char == '.' && l.peek() != '.' {
 		l.nextChar()
+		l.readDigits()
+	}
+	if l.char == 'e' || l.char == 'E' {
+		l.readExponent()
 	}
+	return l.input[l.start:l.offset]
+}
+
+// readDigits calls nextChar until the current character is
+// neither a digit nor an underscore separating digits.
+func (l *Lexer) readDigits() {
+	for isDigit(l.char) || l.char == '_' && isDigit(l.peek()) {
+		l.nextChar()
+	}
+}
 
-	return l.input[pos:l.offset]
+// readExponent reads the exponent of a number, beginning with the
+// current character "e" or "E", if it is followed by a digit or a
+// signed digit. Otherwise it is left to begin the next token.
+func (l *Lexer) readExponent() {
+	l.fill()
+	rest := l.input[l.readOffset:]
+	if len(rest) > 0 && (rest[0] == '+' || rest[0] == '-') {
+		rest = rest[1:]
+	}
+	if len(rest) == 0 || rest[0] < '0' || rest[0] > '9' {
+		return
+	}
+
+	l.nextChar()
+	if l.char == '+' || l.char == '-' {
+		l.nextChar()
+	}
+	l.readDigits()
 }
 
 // nextChar reads the next character from the
 // input and increments the read offset.
 func (l *Lexer) nextChar() {
+	l.fill()
 	if l.readOffset >= len(l.input) {
 		l.char = 0
 		l.offset = l.readOffset
@@ -193,10 +554,20 @@ func (l *Lexer) nextChar() {
 	l.column++
 	l.offset = l.readOffset
 	l.readOffset += size
+
+	// Rather than lexing the replacement character for an invalid
+	// encoding, we stop at it as if it were the end of the input.
+	if l.char == utf8.RuneError && size == 1 {
+		l.err = &Error{Pos: l.position(), msg: "invalid UTF-8 encoding"}
+		l.char = 0
+		l.input = l.input[:l.offset]
+		l.readOffset = l.offset
+	}
 }
 
 // peek returns the next rune to be read without moving the lexer forward.
 func (l *Lexer) peek() rune {
+	l.fill()
 	if l.readOffset >= len(l.input) {
 		return 0
 	}
@@ -205,6 +576,92 @@ func (l *Lexer) peek() rune {
 	return peek
 }
 
+// fill reads from the lexer's reader, if it has one, until at least two
+// characters' worth of bytes follow the read offset or the reader is
+// exhausted. Input preceding the current token is discarded.
+func (l *Lexer) fill() {
+	if l.reader == nil {
+		return
+	}
+
+	for l.err == nil && len(l.input)-l.readOffset < 2*utf8.UTFMax {
+		buf := make([]byte, readChunkSize)
+		n, err := l.reader.Read(buf)
+		l.err = err
+
+		l.input = l.input[l.start:] + string(buf[:n])
+		l.offset -= l.start
+		l.readOffset -= l.start
+		l.base += l.start
+		l.start = 0
+	}
+}
+
+// qualifiable returns true if a token of the input type may be
+// qualified by a period immediately following it, as in "p.id".
+func qualifiable(t TokenType) bool {
+	switch t {
+	case IDENT, QUOTEDIDENT, NUM, RPAREN, RBRACKET:
+		return true
+	}
+	return false
+}
+
+func isHexDigit(char rune) bool {
+	return '0' <= char && char <= '9' || 'a' <= char && char <= 'f' || 'A' <= char && char <= 'F'
+}
+
 func isDigit(char rune) bool {
 	return '0' <= char && char <= '9' || char >= utf8.RuneSelf && unicode.IsDigit(char)
 }
+
+// quotedDescription describes a token of the input type,
+// a string literal or quoted identifier, in errors.
+func quotedDescription(t TokenType) string {
+	if t == QUOTEDIDENT {
+		return "quoted identifier"
+	}
+	return "string literal"
+}
+
+// Unquote returns the input identifier without its quotes, with any
+// doubled quotes within it undoubled, or as it is if it is not quoted.
+func Unquote(id string) string {
+	if len(id) < 2 || id[0] != id[len(id)-1] || (id[0] != '"' && id[0] != '`') {
+		return id
+	}
+	q := id[:1]
+	return strings.ReplaceAll(id[1:len(id)-1], q+q, q)
+}
+
+// isTerminatedString returns true if the input string literal is closed.
+// If backslashEscapes is true, a backslash within it escapes the character
+// following it, which does not close it.
+func isTerminatedString(lit string, backslashEscapes bool) bool {
+	// The prefix of a string such as E'\n' precedes its quote.
+	// That of an escape string gives it backslash escapes.
+	if i := strings.IndexByte(lit, '\''); i > 0 {
+		backslashEscapes = backslashEscapes || lit[0] == 'e' || lit[0] == 'E'
+		lit = lit[i:]
+	}
+	if len(lit) < 2 || lit[len(lit)-1] != lit[0] {
+		return false
+	}
+	if !backslashEscapes {
+		return strings.Count(lit, lit[:1])%2 == 0
+	}
+
+	for i := 1; i < len(lit); i++ {
+		switch lit[i] {
+		case '\\':
+			i++
+		case lit[0]:
+			if i == len(lit)-1 {
+				return true
+			}
+			// A doubled quote is also an escape.
+			i++
+		}
+	}
+	return false
+}
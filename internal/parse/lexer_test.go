@@ -1,7 +1,12 @@
 package parse
 
 import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -22,6 +27,18 @@ WHERE address_id = $Address.id;
 	assert.Equal(t, expected, stringsFromTokens(tokensForStatement(stmt)))
 }
 
+func TestLexerKeywords(t *testing.T) {
+	tokens := tokensForStatement("select Name FROM person wHeRe materialized_view IS NOT null")
+
+	var types []TokenType
+	for _, token := range tokens {
+		types = append(types, token.Type)
+	}
+
+	expected := []TokenType{KEYWORD, IDENT, KEYWORD, IDENT, KEYWORD, IDENT, KEYWORD, KEYWORD, KEYWORD}
+	assert.Equal(t, expected, types)
+}
+
 func TestLexerSimpleCorrectNumbers(t *testing.T) {
 	stmt := `
 SELECT * AS &Person.* 
@@ -50,6 +67,62 @@ WHERE  salary = 100000.5.6;`
 	assert.Equal(t, expected, stringsFromTokens(tokensForStatement(stmt)))
 }
 
+func TestLexerNumberForms(t *testing.T) {
+	stmt := `0x1F 0o17 0b101 1e3 2.5E-3 1e+2 .5 1_000 a.b 2*.5 1end`
+
+	expected := []string{
+		"0x1F", "0o17", "0b101", "1e3", "2.5E-3", "1e+2", ".5", "1_000",
+		"a", ".", "b", "2", "*", ".5", "1", "end",
+	}
+	for _, lex := range []*Lexer{NewLexer(stmt), NewReaderLexer(iotest.OneByteReader(strings.NewReader(stmt)))} {
+		tokens := tokensFromLexer(lex)
+		assert.Equal(t, expected, stringsFromTokens(tokens))
+		assert.Equal(t, NUM, tokens[6].Type)
+		assert.Equal(t, NUM, tokens[13].Type)
+	}
+}
+
+func TestLexerAdjacentTokens(t *testing.T) {
+	tokens := tokensForStatement("SELECT [c],  -- note\nx")
+
+	var adjacent []bool
+	for _, tok := range tokens {
+		adjacent = append(adjacent, tok.Adjacent)
+	}
+	assert.Equal(t, []bool{false, false, true, true, true, false}, adjacent)
+}
+
+func TestLexerPrefixedStringsAndVariables(t *testing.T) {
+	stmt := `SELECT e'it\'s\n', N'x', X'1F', @total, @_row, a@>b`
+
+	expected := []string{
+		"SELECT", `e'it\'s\n'`, ",", "N'x'", ",", "X'1F'", ",",
+		"@total", ",", "@_row", ",", "a", "@>", "b",
+	}
+	tokens := tokensForStatement(stmt)
+	assert.Equal(t, expected, stringsFromTokens(tokens))
+	assert.Equal(t, STRING, tokens[1].Type)
+	assert.Equal(t, IDENT, tokens[7].Type)
+
+	_, err := Tokens(stmt)
+	assert.Nil(t, err)
+}
+
+func TestLexerQuotedIdentifiers(t *testing.T) {
+	stmt := "SELECT \"order\", `a b`, \"x\"\"y\".id FROM t"
+
+	expected := []string{"SELECT", `"order"`, ",", "`a b`", ",", `"x""y"`, ".", "id", "FROM", "t"}
+	tokens := tokensForStatement(stmt)
+	assert.Equal(t, expected, stringsFromTokens(tokens))
+	assert.Equal(t, QUOTEDIDENT, tokens[1].Type)
+	assert.Equal(t, QUOTEDIDENT, tokens[3].Type)
+	assert.Equal(t, `x"y`, Unquote(tokens[5].Literal))
+	assert.Equal(t, "id", Unquote("id"))
+
+	_, err := Tokens(`SELECT "order`)
+	assert.EqualError(t, err, "unterminated quoted identifier at line 1, column 8")
+}
+
 func TestLexerSimpleCorrectQuotedString(t *testing.T) {
 	stmt := `
 SELECT * AS &Person.* 
@@ -93,6 +166,26 @@ func TestLexerUnterminatedString(t *testing.T) {
 	assert.Equal(t, 8, tokens[1].Pos.Column)
 }
 
+func TestLexerOperators(t *testing.T) {
+	stmt := `a+b-c*d/e%f<g>h<=i>=j<>k!=l||m==n::o`
+
+	tokens := tokensForStatement(stmt)
+
+	var types []TokenType
+	for _, token := range tokens {
+		if token.Type != IDENT {
+			types = append(types, token.Type)
+		}
+	}
+
+	expected := []TokenType{
+		PLUS, MINUS, ASTERISK, SLASH, PERCENT, LT, GT, LTEQ, GTEQ, NOTEQ, NOTEQ, CONCAT, EQUAL, CAST,
+	}
+	assert.Equal(t, expected, types)
+	assert.Equal(t, "<=", tokens[15].Literal)
+	assert.Len(t, tokens, 29)
+}
+
 func TestLexerUnknownToken(t *testing.T) {
 	stmt := `SELECT #a AS badtoken FROM t`
 
@@ -106,6 +199,176 @@ func TestLexerUnknownToken(t *testing.T) {
 	assert.Equal(t, UNKNOWN, tokens[1].Type)
 }
 
+func TestTokens(t *testing.T) {
+	stmt := `SELECT 'it''s' AS &Quote.text FROM t`
+
+	tokens, err := Tokens(stmt)
+	assert.Nil(t, err)
+	assert.Equal(t, tokensForStatement(stmt), tokens)
+	assert.Equal(t, "'it''s'", tokens[1].Literal)
+
+	for _, stmt := range []string{`SELECT 's`, `SELECT 'it''`, `SELECT '`} {
+		_, err = Tokens(stmt)
+		assert.EqualError(t, err, "unterminated string literal at line 1, column 8", stmt)
+	}
+}
+
+func TestLexJSONOperators(t *testing.T) {
+	stmt := "d->>'a'->'b' @> x<@y ?| z ?& w ? v <- u"
+	expected := []string{
+		"d", "->>", "'a'", "->", "'b'", "@>", "x", "<@", "y",
+		"?|", "z", "?&", "w", "?", "v", "<", "-", "u",
+	}
+
+	tokens := tokensForStatement(stmt)
+
+	assert.Equal(t, expected, stringsFromTokens(tokens))
+	assert.Equal(t, OPERATOR, tokens[1].Type)
+	assert.Equal(t, OPERATOR, tokens[13].Type)
+	assert.Equal(t, LT, tokens[15].Type)
+}
+
+func TestLexBackslashEscapes(t *testing.T) {
+	stmt := `SELECT 'it\'s', 'a\\', 'b''c\'' FROM t`
+
+	expected := []string{"SELECT", `'it\'s'`, ",", `'a\\'`, ",", `'b''c\''`, "FROM", "t"}
+	for _, lex := range []*Lexer{NewLexer(stmt), NewReaderLexer(iotest.OneByteReader(strings.NewReader(stmt)))} {
+		lex.SetBackslashEscapes(true)
+		tokens := tokensFromLexer(lex)
+		assert.Equal(t, expected, stringsFromTokens(tokens))
+	}
+
+	// Without backslash escapes, the first backslash is an ordinary
+	// character, and the first quote following it closes the literal.
+	tokens := tokensForStatement(stmt)
+	assert.Equal(t, `'it\'`, tokens[1].Literal)
+
+	for lit, terminated := range map[string]bool{
+		`'it\'s'`: true, `'a\\'`: true, `'a''b'`: true,
+		`'a\'`: false, `'a\\\'`: false, `'`: false,
+	} {
+		assert.Equal(t, terminated, isTerminatedString(lit, true), lit)
+	}
+}
+
+func TestLexUnicodeIdentifiers(t *testing.T) {
+	// The first "é" is precomposed, and the second
+	// is an "e" followed by a combining acute accent.
+	stmt := "SELECT \u00e9t\u00e9, e\u0301te\u0301, 名前, _x1 FROM t"
+	expected := []string{"SELECT", "\u00e9t\u00e9", ",", "e\u0301te\u0301", ",", "名前", ",", "_x1", "FROM", "t"}
+
+	tokens := tokensForStatement(stmt)
+
+	assert.Equal(t, expected, stringsFromTokens(tokens))
+	for _, i := range []int{1, 3, 5, 7} {
+		assert.Equal(t, IDENT, tokens[i].Type, tokens[i].Literal)
+	}
+}
+
+func TestReaderLexer(t *testing.T) {
+	stmts := append([]string{benchmarkStatement, "\n\t  SELECT 'a\nb'  \n", `SELECT 'é'`, ""}, jujuStatements...)
+
+	for _, stmt := range stmts {
+		expected := tokensFromLexer(NewLexer(stmt))
+
+		// A reader returning a byte at a time splits
+		// every token and multi-byte character.
+		readers := []io.Reader{strings.NewReader(stmt), iotest.OneByteReader(strings.NewReader(stmt))}
+		for _, r := range readers {
+			lex := NewReaderLexer(r)
+			assert.Equal(t, expected, tokensFromLexer(lex), stmt)
+			assert.Nil(t, lex.Err())
+		}
+	}
+}
+
+func TestReaderLexerError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("SELECT a"), iotest.ErrReader(errors.New("boom")))
+	lex := NewReaderLexer(r)
+
+	// Tokens read before the error are returned, followed by EOF.
+	assert.Equal(t, []string{"SELECT", "a"}, stringsFromTokens(tokensFromLexer(lex)))
+	assert.EqualError(t, lex.Err(), "boom")
+
+	_, err := NewParser(NewReaderLexer(iotest.ErrReader(errors.New("boom")))).Run()
+	assert.EqualError(t, err, "reading statement: boom")
+}
+
+func TestLexerByteOrderMark(t *testing.T) {
+	stmt := "\uFEFF SELECT a"
+	expected := tokensForStatement("SELECT a")
+
+	assert.Equal(t, expected, tokensForStatement(stmt))
+	assert.Equal(t, expected, tokensFromLexer(NewReaderLexer(strings.NewReader(stmt))))
+}
+
+func TestLexerInvalidUTF8(t *testing.T) {
+	stmt := "SELECT a\nFROM t\xff\xfe WHERE b = 1"
+
+	for _, lex := range []*Lexer{NewLexer(stmt), NewReaderLexer(iotest.OneByteReader(strings.NewReader(stmt)))} {
+		// Lexing stops at the invalid encoding.
+		assert.Equal(t, []string{"SELECT", "a", "FROM", "t"}, stringsFromTokens(tokensFromLexer(lex)))
+		assert.EqualError(t, lex.Err(), "invalid UTF-8 encoding at line 2, column 7")
+	}
+
+	_, err := Tokens(stmt)
+	assert.EqualError(t, err, "invalid UTF-8 encoding at line 2, column 7")
+
+	_, err = NewParser(NewLexer(stmt)).Run()
+	assert.EqualError(t, err, "invalid UTF-8 encoding at line 2, column 7")
+
+	// A multi-byte character truncated by the end of the input is invalid.
+	_, err = Tokens("SELECT '\xc3")
+	assert.EqualError(t, err, "invalid UTF-8 encoding at line 1, column 9")
+}
+
+func TestLexerComments(t *testing.T) {
+	stmt := `-- sqlair:timeout=5s
+  --sqlair: retries=3
+-- An ordinary comment.
+SELECT a -- sqlair:ignored=true
+  FROM t --`
+
+	for _, lex := range []*Lexer{NewLexer(stmt), NewReaderLexer(iotest.OneByteReader(strings.NewReader(stmt)))} {
+		tokens := tokensFromLexer(lex)
+		assert.Equal(t, []string{"SELECT", "a", "FROM", "t"}, stringsFromTokens(tokens))
+		assert.Equal(t, Position{Offset: 67, Line: 4, Column: 1}, tokens[0].Pos)
+
+		assert.Equal(t, []Token{
+			{Type: DIRECTIVE, Literal: "timeout=5s", Pos: Position{Offset: 0, Line: 1, Column: 1}},
+			{Type: DIRECTIVE, Literal: " retries=3", Pos: Position{Offset: 23, Line: 2, Column: 3}},
+		}, lex.Directives())
+
+		assert.Equal(t, []Token{
+			{Type: COMMENT, Literal: "-- An ordinary comment.", Pos: Position{Offset: 43, Line: 3, Column: 1}},
+			{Type: COMMENT, Literal: "-- sqlair:ignored=true", Pos: Position{Offset: 76, Line: 4, Column: 10}},
+			{Type: COMMENT, Literal: "--", Pos: Position{Offset: 108, Line: 5, Column: 10}},
+		}, lex.Comments())
+	}
+}
+
+func TestLexerBlockComments(t *testing.T) {
+	stmt := "SELECT /*+ INDEX(t i) */ a/**/FROM /* one\n two */ t /* unterminated"
+
+	for _, lex := range []*Lexer{NewLexer(stmt), NewReaderLexer(iotest.OneByteReader(strings.NewReader(stmt)))} {
+		tokens := tokensFromLexer(lex)
+		assert.Equal(t, []string{"SELECT", "a", "FROM", "t"}, stringsFromTokens(tokens))
+
+		var comments []string
+		for _, tok := range lex.Comments() {
+			comments = append(comments, tok.Type.String()+" "+tok.Literal)
+		}
+		assert.Equal(t, []string{"HINT /*+ INDEX(t i) */", "COMMENT /**/", "COMMENT /* one\n two */", "COMMENT /* unterminated"}, comments)
+	}
+}
+
+func TestTokenTypeString(t *testing.T) {
+	assert.Equal(t, "IDENT", IDENT.String())
+	assert.Equal(t, "NOTEQ", NOTEQ.String())
+	assert.Equal(t, "UNKNOWN", UNKNOWN.String())
+	assert.Equal(t, "TokenType(100)", TokenType(100).String())
+}
+
 func tokensForStatement(stmt string) []Token {
 	lex := NewLexer(stmt)
 
@@ -117,6 +380,16 @@ func tokensForStatement(stmt string) []Token {
 	return tokens
 }
 
+// tokensFromLexer returns every token read from
+// the input lexer, excluding the final EOF token.
+func tokensFromLexer(lex *Lexer) []Token {
+	var tokens []Token
+	for token := lex.NextToken(); token.Type != EOF; token = lex.NextToken() {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
 func stringsFromTokens(tokens []Token) []string {
 	str := make([]string, len(tokens))
 	for i, t := range tokens {
@@ -124,3 +397,72 @@ func stringsFromTokens(tokens []Token) []string {
 	}
 	return str
 }
+
+func TestLexerDoesNotAllocate(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		lex := NewLexer(benchmarkStatement)
+		for token := lex.NextToken(); token.Type != EOF; token = lex.NextToken() {
+		}
+	})
+
+	// The lexer itself is the only allocation.
+	assert.LessOrEqual(t, allocs, float64(1))
+}
+
+func BenchmarkLexer(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lex := NewLexer(benchmarkStatement)
+		for token := lex.NextToken(); token.Type != EOF; token = lex.NextToken() {
+		}
+	}
+}
+
+const benchmarkStatement = `
+SELECT p.* AS &Person.*, a.* AS &Address.*
+FROM   person AS p
+JOIN   address AS a ON p.address_id = a.id
+WHERE  p.name IN ('Lorn', 'Onos T''oolan') AND a.district = $Address.district
+AND    p.salary = 100000.5;`
+
+func FuzzLexer(f *testing.F) {
+	for _, stmt := range jujuStatements {
+		f.Add(stmt)
+	}
+	f.Add(`SELECT 's`)
+	f.Add(`WHERE salary = 100000.5.6`)
+
+	f.Fuzz(func(t *testing.T, stmt string) {
+		lex := NewLexer(stmt)
+		input := lex.input
+
+		var count int
+		offset := -1
+		var tokens []Token
+		for token := lex.NextToken(); token.Type != EOF; token = lex.NextToken() {
+			tokens = append(tokens, token)
+
+			// Every token consumes at least one byte, so there can
+			// not be more tokens than there are bytes of input.
+			count++
+			if count > len(input) {
+				t.Fatalf("lexer did not terminate for %q", stmt)
+			}
+
+			if token.Pos.Offset <= offset {
+				t.Fatalf("token %q at offset %d does not follow offset %d", token.Literal, token.Pos.Offset, offset)
+			}
+			offset = token.Pos.Offset
+
+			end := token.Pos.Offset + len(token.Literal)
+			if token.Literal == "" || end > len(input) || input[token.Pos.Offset:end] != token.Literal {
+				t.Fatalf("token %q does not match input at offset %d", token.Literal, token.Pos.Offset)
+			}
+		}
+
+		fromReader := tokensFromLexer(NewReaderLexer(iotest.OneByteReader(strings.NewReader(stmt))))
+		if !reflect.DeepEqual(tokens, fromReader) {
+			t.Fatalf("reader lexer tokens %v differ from %v", fromReader, tokens)
+		}
+	})
+}
@@ -1,20 +1,1089 @@
 package parse
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Operator precedences, from the loosest binding to the tightest.
+const (
+	_ int = iota
+	precLowest
+	precOr      // OR
+	precAnd     // AND
+	precNot     // NOT x
+	precEquals  // =, <>, IS, IN, LIKE
+	precCompare // <, >, <=, >=
+	precOther   // JSON and registered operators, such as @> and #>
+	precSum     // +, -
+	precProduct // *, /, %
+	precConcat  // ||
+	precPrefix  // -x, +x
+	precCast    // x::type
+)
+
+// precedences maps operator tokens to their infix precedence.
+var precedences = map[TokenType]int{
+	EQUAL:    precEquals,
+	NOTEQ:    precEquals,
+	LT:       precCompare,
+	GT:       precCompare,
+	LTEQ:     precCompare,
+	GTEQ:     precCompare,
+	PLUS:     precSum,
+	MINUS:    precSum,
+	ASTERISK: precProduct,
+	SLASH:    precProduct,
+	PERCENT:  precProduct,
+	CONCAT:   precConcat,
+	CAST:     precCast,
+}
+
+// keywordPrecedences maps keyword operators to their infix precedence.
+// Keyword tokens are matched by literal, regardless of case.
+var keywordPrecedences = map[string]int{
+	"OR":   precOr,
+	"AND":  precAnd,
+	"IS":   precEquals,
+	"IN":   precEquals,
+	"LIKE": precEquals,
+}
+
+// reservedKeywords are the keywords that structure a statement rather than
+// act as operands. They are parsed as lone identities, so that a clause
+// such as "SELECT * FROM" is not mistaken for a multiplication.
+var reservedKeywords = map[string]bool{
+	"SELECT": true, "DISTINCT": true, "FROM": true, "WHERE": true, "AS": true,
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true,
+	"OUTER": true, "CROSS": true, "NATURAL": true, "ON": true, "USING": true,
+	"GROUP": true, "BY": true, "HAVING": true, "ORDER": true, "ASC": true,
+	"DESC": true, "LIMIT": true, "OFFSET": true, "UNION": true,
+	"INTERSECT": true, "EXCEPT": true, "WITH": true, "INSERT": true,
+	"INTO": true, "VALUES": true, "UPDATE": true, "SET": true, "DELETE": true,
+	"CREATE": true, "TABLE": true, "RETURNING": true, "CASE": true,
+	"WHEN": true, "THEN": true, "ELSE": true, "END": true, "BETWEEN": true,
+	"AND": true, "OR": true, "IS": true, "IN": true, "LIKE": true,
+}
+
+// tableKeywords are the keywords that precede a table name. A parenthesis
+// following the name opens a list of columns, not function arguments.
+var tableKeywords = map[string]bool{
+	"INTO": true, "TABLE": true, "REFERENCES": true,
+}
+
+// quantifiers are the words that, followed by a parenthesis,
+// quantify its contents rather than calling a function.
+var quantifiers = map[string]bool{
+	"ANY": true, "SOME": true, "ALL": true, "EXISTS": true,
+}
+
+// prefixParseFn parses an expression that begins with the current token.
+type prefixParseFn func() (Expression, error)
+
+// infixParseFn parses an expression for which the
+// input expression is the left-hand operand.
+type infixParseFn func(Expression) (Expression, error)
+
 // Parser is responsible for returning an Expression tree
 // for a Sqlair DSL statement represented by a Lexer.
+// It is a Pratt parser; operators are parsed by the functions registered
+// for their tokens, and nest according to their precedence.
 type Parser struct {
 	lex *Lexer
+
+	// tokens holds every token read from the lexer, ending with EOF.
+	tokens []Token
+
+	// pos is the index in tokens of the current token.
+	pos int
+
+	// directives holds the values of the statement's directives by name.
+	directives map[string]string
+
+	// comments holds every comment read from the lexer, and comment
+	// is the index in it of the first not yet attached to a statement.
+	comments []Token
+	comment  int
+
+	// limits bounds the statements that the parser accepts,
+	// and depth is the current nesting depth of expressions.
+	limits Limits
+	depth  int
+
+	prefixParseFns  map[TokenType]prefixParseFn
+	infixParseFns   map[TokenType]infixParseFn
+	keywordParseFns map[string]prefixParseFn
+
+	// extensions holds the parse functions registered
+	// when the parser was created.
+	extensions *extensionSet
+}
+
+// Limits bounds the size and complexity of the statements that a Parser
+// accepts, protecting long-running services from pathological input.
+// A limit of zero is no limit.
+type Limits struct {
+	// MaxLength is the maximum length of a statement in bytes.
+	MaxLength int
+
+	// MaxTokens is the maximum number of tokens in a statement,
+	// excluding comments.
+	MaxTokens int
+
+	// MaxDepth is the maximum depth to which expressions
+	// may be nested, as by parentheses or operators.
+	MaxDepth int
 }
 
 // NewParser returns a reference to a Parser based on the input Lexer.
 func NewParser(l *Lexer) *Parser {
-	return &Parser{
-		lex: l,
+	p := &Parser{
+		lex:        l,
+		extensions: currentExtensions(),
 	}
+
+	p.prefixParseFns = map[TokenType]prefixParseFn{
+		IDENT:       p.parseIdentity,
+		QUOTEDIDENT: p.parseIdentity,
+		KEYWORD:     p.parseIdentity,
+		BITAND:      p.parseOutputTarget,
+		DOLLAR:      p.parseInputSource,
+		LPAREN:      p.parseGroupedColumns,
+		LBRACKET:    p.parseTemplate,
+		PLUS:        p.parsePrefix,
+		MINUS:       p.parsePrefix,
+	}
+
+	p.infixParseFns = make(map[TokenType]infixParseFn, len(precedences)+1)
+	for tokenType := range precedences {
+		p.infixParseFns[tokenType] = p.parseInfix
+	}
+	p.infixParseFns[KEYWORD] = p.parseInfix
+	p.infixParseFns[OPERATOR] = p.parseInfix
+
+	p.keywordParseFns = map[string]prefixParseFn{
+		"WITH":   p.parseWith,
+		"ORDER":  p.parseOrderBy,
+		"LIMIT":  p.parseLimit,
+		"VALUES": p.parseValues,
+	}
+
+	return p
+}
+
+// SetLimits sets the limits of the statements that the parser accepts.
+// A statement exceeding one of them is refused with an Error naming it.
+func (p *Parser) SetLimits(limits Limits) {
+	p.limits = limits
 }
 
 // Run returns an Expression tree using its Lexer,
 // or an error for a malformed statement.
 func (p *Parser) Run() (Expression, error) {
-	return nil, nil
+	if err := p.readTokens(); err != nil {
+		return nil, err
+	}
+	if err := p.lex.Err(); err != nil {
+		if _, ok := err.(*Error); ok {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "reading statement")
+	}
+
+	// The literals of the tokens are detached from the input, so that
+	// the input is not retained by the expression tree or directives.
+	detachLiterals(p.tokens, p.lex.Comments(), p.lex.Directives())
+
+	directives, err := parseDirectives(p.lex.Directives())
+	if err != nil {
+		return nil, err
+	}
+	p.directives = directives
+
+	p.comments, p.comment = p.lex.Comments(), 0
+	for _, tok := range p.comments {
+		if strings.HasPrefix(tok.Literal, "/*") && (len(tok.Literal) < 4 || !strings.HasSuffix(tok.Literal, "*/")) {
+			return nil, errorAt(tok, "unterminated comment")
+		}
+	}
+
+	children, err := p.parseStatement(p.cur(), EOF)
+	if err != nil {
+		return nil, err
+	}
+
+	exp := &SQLExpression{}
+	exp.setExpressions(children)
+	return exp, nil
+}
+
+// Directives returns the values by name of the directives given by
+// comments, such as "-- sqlair:timeout=5s", preceding the statement.
+// It is nil until Run has returned without error.
+func (p *Parser) Directives() map[string]string {
+	return p.directives
+}
+
+// parseDirectives returns the values by name of the input directive tokens.
+// A directive holds one or more space-separated pairs of the form
+// "name=value", and each name may only be given once.
+func parseDirectives(tokens []Token) (map[string]string, error) {
+	var directives map[string]string
+	for _, tok := range tokens {
+		for _, pair := range strings.Fields(tok.Literal) {
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok || name == "" || value == "" {
+				return nil, errorAt(tok, "malformed directive %q; expected name=value", pair)
+			}
+			if _, ok := directives[name]; ok {
+				return nil, errorAt(tok, "directive %q given more than once", name)
+			}
+
+			if directives == nil {
+				directives = make(map[string]string)
+			}
+			directives[name] = value
+		}
+	}
+	return directives, nil
+}
+
+// parseStatement parses expressions until the current token is of the input
+// type, which closes the statement opened by the input token. A statement
+// composed of queries combined with set operators, such as UNION, is
+// returned as a single CompoundExpression.
+func (p *Parser) parseStatement(open Token, end TokenType) ([]Expression, error) {
+	var compound *CompoundExpression
+	var operator []Token
+
+	// Comments are attached to the statement from the token opening it,
+	// except at the top level, where they may precede the first token.
+	from := open.Pos.Offset
+	if end == EOF {
+		from = -1
+	}
+
+	branch := &SQLExpression{}
+	for p.cur().Type != end {
+		if p.cur().Type == EOF {
+			return nil, errorAt(open, "unclosed parenthesis")
+		}
+
+		if op := p.parseSetOperator(); op != nil {
+			if len(branch.Expressions()) == 0 {
+				return nil, errorAt(op[0], "expected query before %q", op[0].Literal)
+			}
+			p.attachComments(branch, from, op[0].Pos.Offset)
+			if compound == nil {
+				compound = &CompoundExpression{}
+			}
+			compound.AppendBranch(operator, branch)
+
+			operator = op
+			branch = &SQLExpression{}
+			p.next()
+			continue
+		}
+
+		p.attachComments(branch, from, p.cur().Pos.Offset)
+		child, err := p.parseExpression(precLowest)
+		if err != nil {
+			return nil, err
+		}
+		branch.AppendExpression(child)
+		if values, ok := child.(*ValuesExpression); ok {
+			if err := checkValuesColumns(branch.Expressions(), values); err != nil {
+				return nil, err
+			}
+		}
+		p.next()
+	}
+	p.attachComments(branch, from, p.cur().Pos.Offset)
+
+	if compound == nil {
+		return branch.Expressions(), nil
+	}
+
+	if len(branch.Expressions()) == 0 {
+		last := operator[len(operator)-1]
+		return nil, errorAt(last, "expected query after %q", last.Literal)
+	}
+	compound.AppendBranch(operator, branch)
+	return []Expression{compound}, nil
+}
+
+// attachComments appends to the input statement, as CommentExpressions
+// and HintExpressions, the comments not yet attached that lie between the input offsets.
+// Comments preceding the statement's first expression are held back until
+// it has been appended, so that the statement still begins with its keyword.
+func (p *Parser) attachComments(stmt *SQLExpression, from, to int) {
+	if len(stmt.Expressions()) == 0 {
+		return
+	}
+	for ; p.comment < len(p.comments); p.comment++ {
+		tok := p.comments[p.comment]
+		if tok.Pos.Offset <= from || tok.Pos.Offset >= to {
+			return
+		}
+		if tok.Type == HINT {
+			stmt.AppendExpression(NewHintExpression(tok))
+		} else {
+			stmt.AppendExpression(NewCommentExpression(tok))
+		}
+	}
+}
+
+// parseSetOperator returns the tokens of the set operator at the
+// current position, finishing with its last token as the current one.
+// If the current token is not a set operator, nil is returned.
+func (p *Parser) parseSetOperator() []Token {
+	switch {
+	case isKeyword(p.cur(), "UNION"):
+		if isKeyword(p.peek(), "ALL") {
+			operator := []Token{p.cur(), p.peek()}
+			p.next()
+			return operator
+		}
+		return []Token{p.cur()}
+	case isKeyword(p.cur(), "INTERSECT"), isKeyword(p.cur(), "EXCEPT"):
+		return []Token{p.cur()}
+	}
+	return nil
+}
+
+// bytesPerToken is the approximate number of bytes of input per token,
+// by which the capacity for a statement's tokens is estimated.
+const bytesPerToken = 4
+
+// readTokens reads every token from the lexer, so that
+// the parser can look beyond the token following the current one.
+// Reading stops at the first token beyond the parser's limits.
+func (p *Parser) readTokens() error {
+	if size := p.lex.sizeHint() / bytesPerToken; cap(p.tokens) < size {
+		p.tokens = make([]Token, 0, size)
+	}
+	p.tokens = p.tokens[:0]
+	p.pos = 0
+
+	for {
+		tok := p.lex.NextToken()
+		if max := p.limits.MaxLength; max > 0 && tok.Pos.Offset+len(tok.Literal) > max {
+			return limitErrorAt(tok, "MaxLength", "statement exceeds maximum length of %d bytes", max)
+		}
+		if tok.Type == EOF {
+			p.tokens = append(p.tokens, tok)
+			return nil
+		}
+		if max := p.limits.MaxTokens; max > 0 && len(p.tokens) >= max {
+			return limitErrorAt(tok, "MaxTokens", "statement exceeds maximum of %d tokens", max)
+		}
+		p.tokens = append(p.tokens, tok)
+	}
+}
+
+// cur returns the current token.
+func (p *Parser) cur() Token {
+	return p.peekN(0)
+}
+
+// peek returns the token following the current one.
+func (p *Parser) peek() Token {
+	return p.peekN(1)
+}
+
+// peekN returns the token n places after the current one,
+// or the final EOF token if there are fewer than n remaining.
+func (p *Parser) peekN(n int) Token {
+	if i := p.pos + n; i < len(p.tokens) {
+		return p.tokens[i]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+// next advances to the next token.
+// The parser never advances beyond EOF.
+func (p *Parser) next() {
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
+}
+
+// parseExpression parses the expression beginning with the current token,
+// consuming infix operators that bind more tightly than the input precedence.
+// On return, the current token is the last token of the expression.
+func (p *Parser) parseExpression(precedence int) (Expression, error) {
+	tok := p.cur()
+	if max := p.limits.MaxDepth; max > 0 && p.depth >= max {
+		return nil, limitErrorAt(tok, "MaxDepth", "statement exceeds maximum nesting depth of %d", max)
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+	switch tok.Type {
+	case EOF:
+		return nil, errorAt(tok, "unexpected end of statement")
+	case RPAREN:
+		return nil, errorAt(tok, "unexpected %q", tok.Literal)
+	}
+
+	if tok.Type == KEYWORD {
+		if parseKeyword, ok := p.keywordParseFns[strings.ToUpper(tok.Literal)]; ok {
+			return parseKeyword()
+		}
+	}
+
+	if isReservedKeyword(tok) {
+		return NewIdentityExpression(tok), nil
+	}
+
+	prefix, ok := p.prefixParseFns[tok.Type]
+	if fn, registered := p.extensions.prefixExtension(tok); registered {
+		prefix = func() (Expression, error) {
+			return p.parseExtension(fn, nil, precPrefix)
+		}
+	} else if !ok {
+		prefix = p.parseToken
+	}
+
+	left, err := prefix()
+	if err != nil {
+		return nil, err
+	}
+
+	for precedence < p.infixPrecedence() {
+		p.next()
+		if fn, ok := p.extensions.infixExtension(p.cur()); ok {
+			left, err = p.parseExtension(fn, left, precOther)
+		} else {
+			left, err = p.infixParseFns[p.cur().Type](left)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return left, nil
+}
+
+// infixPrecedence returns the precedence of the token following the
+// current one when it is used as an infix operator. Tokens that are not
+// infix operators have the lowest precedence.
+func (p *Parser) infixPrecedence() int {
+	tok := p.peek()
+	if _, ok := p.extensions.infixExtension(tok); ok {
+		return precOther
+	}
+	if tok.Type == OPERATOR {
+		if jsonOperators[tok.Literal] && (tok.Literal != "?" || beginsOperand(p.peekN(2))) {
+			return precOther
+		}
+		return precLowest
+	}
+	if tok.Type != KEYWORD {
+		if precedence, ok := precedences[tok.Type]; ok {
+			return precedence
+		}
+		return precLowest
+	}
+
+	keyword := strings.ToUpper(tok.Literal)
+	if keyword == "NOT" {
+		// NOT is only an infix operator when negating one, as in "NOT IN".
+		if next := p.peekN(2); next.Type == KEYWORD && keywordPrecedences[strings.ToUpper(next.Literal)] == precEquals {
+			return precEquals
+		}
+		return precLowest
+	}
+
+	if precedence, ok := keywordPrecedences[keyword]; ok {
+		return precedence
+	}
+	return precLowest
+}
+
+// parseToken returns an identity for the current token.
+// It is used for tokens that have no other parse function.
+func (p *Parser) parseToken() (Expression, error) {
+	return NewIdentityExpression(p.cur()), nil
+}
+
+// parseIdentity parses an identifier, which may be qualified by the names
+// preceding it, as in "p.name" or "p.*", or be the name of a function
+// call, as in "count(*)". The keyword NOT is parsed as a prefix operator.
+func (p *Parser) parseIdentity() (Expression, error) {
+	if isKeyword(p.cur(), "NOT") {
+		return p.parsePrefix()
+	}
+
+	if quantifiers[strings.ToUpper(p.cur().Literal)] && p.peek().Type == LPAREN {
+		return p.parseQuantified()
+	}
+
+	if p.isFunctionCall() {
+		return p.parseFunctionCall()
+	}
+
+	var exp Expression = NewIdentityExpression(p.cur())
+	for p.peek().Type == PERIOD {
+		if name := p.peekN(2); !isName(name) && name.Type != ASTERISK {
+			break
+		}
+		p.next()
+		p.next()
+		exp = NewQualifiedIdentityExpression(exp, NewIdentityExpression(p.cur()))
+	}
+	return exp, nil
+}
+
+// parsePrefix parses a unary operator and its operand.
+func (p *Parser) parsePrefix() (Expression, error) {
+	operator := p.cur()
+
+	precedence := precPrefix
+	if operator.Type == KEYWORD {
+		precedence = precNot
+	}
+
+	p.next()
+	right, err := p.parseExpression(precedence)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrefixExpression(operator, right), nil
+}
+
+// parseInfix parses a binary operator and its right-hand operand,
+// returning an expression combining it with the input left-hand operand.
+// The current token is the operator, or the first token of it for the
+// operators "IS NOT", "NOT IN" and "NOT LIKE".
+func (p *Parser) parseInfix(left Expression) (Expression, error) {
+	precedence := precEquals
+	if p.cur().Type == OPERATOR {
+		precedence = precOther
+	} else if p.cur().Type != KEYWORD {
+		precedence = precedences[p.cur().Type]
+	} else if keyword := strings.ToUpper(p.cur().Literal); keyword != "NOT" {
+		precedence = keywordPrecedences[keyword]
+	}
+
+	operator := []Token{p.cur()}
+	switch strings.ToUpper(p.cur().Literal) {
+	case "IS":
+		if isKeyword(p.peek(), "NOT") {
+			p.next()
+			operator = append(operator, p.cur())
+		}
+	case "NOT":
+		p.next()
+		operator = append(operator, p.cur())
+	}
+
+	p.next()
+	right, err := p.parseExpression(precedence)
+	if err != nil {
+		return nil, err
+	}
+	return NewInfixExpression(left, operator, right), nil
+}
+
+// parseOutputTarget parses an expression such as "&Person.*".
+func (p *Parser) parseOutputTarget() (Expression, error) {
+	marker, name, field, err := p.parseTypeMapping()
+	if err != nil {
+		return nil, err
+	}
+	return NewOutputTargetExpression(marker, name, field), nil
+}
+
+// parseInputSource parses an expression such as "$Person.id".
+func (p *Parser) parseInputSource() (Expression, error) {
+	marker, name, field, err := p.parseTypeMapping()
+	if err != nil {
+		return nil, err
+	}
+	return NewInputSourceExpression(marker, name, field), nil
+}
+
+// parseTypeMapping parses the marker, type name and field
+// of an input source or output target expression.
+// The type name may be that of an instantiated generic type,
+// with its type arguments in brackets, as in "&Page[Person].*".
+func (p *Parser) parseTypeMapping() (Token, *IdentityExpression, *IdentityExpression, error) {
+	marker := p.cur()
+
+	if !isName(p.peek()) {
+		return Token{}, nil, nil, errorAt(marker, "expected type name and field after %q", marker.Literal)
+	}
+	period := 2
+	if p.peekN(period).Type == LBRACKET {
+		period = p.typeArgumentsEnd(period)
+	}
+	if p.peekN(period).Type != PERIOD {
+		return Token{}, nil, nil, errorAt(marker, "expected type name and field after %q", marker.Literal)
+	}
+	if field := p.peekN(period + 1); !isName(field) && field.Type != ASTERISK {
+		return Token{}, nil, nil, errorAt(field, "expected field name or \"*\" after %q", marker.Literal)
+	}
+
+	nameTok := p.peek()
+	if period > 2 {
+		var lit strings.Builder
+		for i := 1; i < period; i++ {
+			lit.WriteString(p.peekN(i).Literal)
+		}
+		nameTok = Token{Type: IDENT, Literal: lit.String(), Pos: nameTok.Pos}
+	}
+
+	for i := 0; i < period; i++ {
+		p.next()
+	}
+	p.next()
+	name := NewIdentityExpression(nameTok)
+	field := NewIdentityExpression(p.cur())
+
+	return marker, name, field, nil
+}
+
+// typeArgumentsEnd returns the offset from the current token of the
+// token following the bracketed type arguments that open at the input
+// offset. If the brackets are not closed, the offset of EOF is returned.
+func (p *Parser) typeArgumentsEnd(open int) int {
+	depth := 0
+	for i := open; ; i++ {
+		switch p.peekN(i).Type {
+		case LBRACKET:
+			depth++
+		case RBRACKET:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case EOF:
+			return i
+		}
+	}
+}
+
+// parseTemplate parses an identifier template such as "[[table]]".
+// A bracket that does not open a template is parsed as an identity.
+func (p *Parser) parseTemplate() (Expression, error) {
+	if p.peek().Type != LBRACKET || !isName(p.peekN(2)) ||
+		p.peekN(3).Type != RBRACKET || p.peekN(4).Type != RBRACKET {
+		return p.parseToken()
+	}
+
+	open := p.cur()
+	p.next()
+	p.next()
+	name := NewIdentityExpression(p.cur())
+	p.next()
+	p.next()
+
+	return NewTemplateExpression(open, name, p.cur()), nil
+}
+
+// parseGroupedColumns parses a parenthesised, comma-separated list,
+// or a sub-query if the parenthesis is followed by SELECT or WITH.
+func (p *Parser) parseGroupedColumns() (Expression, error) {
+	if isKeyword(p.peek(), "SELECT") || isKeyword(p.peek(), "WITH") {
+		return p.parseSubquery()
+	}
+
+	items, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+
+	exp := &GroupedColumnsExpression{}
+	exp.setExpressions(items)
+	return exp, nil
+}
+
+// parseFunctionCall parses a function name and its parenthesised arguments.
+func (p *Parser) parseFunctionCall() (Expression, error) {
+	name := NewIdentityExpression(p.cur())
+
+	p.next()
+	args, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	return NewFunctionCallExpression(name, args, p.cur()), nil
+}
+
+// parseQuantified parses a quantifier, such as ANY or EXISTS, and its
+// parenthesised operand, which is a subquery or a list.
+func (p *Parser) parseQuantified() (Expression, error) {
+	quantifier := p.cur()
+
+	p.next()
+	operand, err := p.parseGroupedColumns()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := operand.(*SubqueryExpression); !ok && strings.EqualFold(quantifier.Literal, "EXISTS") {
+		return nil, errorAt(quantifier, "expected subquery after %q", quantifier.Literal)
+	}
+	return NewQuantifiedExpression(quantifier, operand), nil
+}
+
+// parseSubquery parses a parenthesised statement, beginning with the
+// current token as the opening parenthesis and finishing with the
+// closing parenthesis as the current token.
+func (p *Parser) parseSubquery() (*SubqueryExpression, error) {
+	open := p.cur()
+
+	p.next()
+	if p.cur().Type == RPAREN {
+		return nil, errorAt(p.cur(), "expected statement before %q", p.cur().Literal)
+	}
+
+	children, err := p.parseStatement(open, RPAREN)
+	if err != nil {
+		return nil, err
+	}
+
+	exp := &SubqueryExpression{}
+	exp.setExpressions(children)
+	return exp, nil
+}
+
+// parseWith parses a WITH clause and the
+// common table expressions that it declares.
+func (p *Parser) parseWith() (Expression, error) {
+	keywords := []Token{p.cur()}
+	if isKeyword(p.peek(), "RECURSIVE") {
+		p.next()
+		keywords = append(keywords, p.cur())
+	}
+
+	exp := NewWithExpression(keywords)
+	for {
+		p.next()
+		cte, err := p.parseCommonTableExpression()
+		if err != nil {
+			return nil, err
+		}
+		exp.AppendExpression(cte)
+
+		if p.peek().Type != COMMA {
+			return exp, nil
+		}
+		p.next()
+	}
+}
+
+// parseCommonTableExpression parses a single common table expression
+// declared by a WITH clause, such as "managers (id) AS (SELECT ...)".
+func (p *Parser) parseCommonTableExpression() (*CommonTableExpression, error) {
+	if !isName(p.cur()) || isReservedKeyword(p.cur()) {
+		return nil, errorAt(p.cur(), "expected common table expression name, got %q", p.cur().Literal)
+	}
+	name := NewIdentityExpression(p.cur())
+
+	var columns *GroupedColumnsExpression
+	if p.peek().Type == LPAREN {
+		p.next()
+		exp, err := p.parseGroupedColumns()
+		if err != nil {
+			return nil, err
+		}
+		if columns, _ = exp.(*GroupedColumnsExpression); columns == nil {
+			return nil, errorAt(p.cur(), "expected column names for %q", name.String())
+		}
+	}
+
+	if !isKeyword(p.peek(), "AS") {
+		return nil, errorAt(p.peek(), "expected \"AS\" after %q, got %q", name.String(), p.peek().Literal)
+	}
+	p.next()
+	as := []Token{p.cur()}
+
+	// Materialisation hints, as in "AS NOT MATERIALIZED (SELECT ...)".
+	for isKeyword(p.peek(), "NOT") || isKeyword(p.peek(), "MATERIALIZED") {
+		p.next()
+		as = append(as, p.cur())
+	}
+
+	if p.peek().Type != LPAREN {
+		return nil, errorAt(p.peek(), "expected \"(\" after %q, got %q", as[len(as)-1].Literal, p.peek().Literal)
+	}
+	p.next()
+
+	query, err := p.parseSubquery()
+	if err != nil {
+		return nil, err
+	}
+	return NewCommonTableExpression(name, columns, as, query), nil
+}
+
+// parseOrderBy parses an ORDER BY clause and its ordering terms.
+func (p *Parser) parseOrderBy() (Expression, error) {
+	if !isKeyword(p.peek(), "BY") {
+		return nil, errorAt(p.peek(), "expected \"BY\" after %q, got %q", p.cur().Literal, p.peek().Literal)
+	}
+	keywords := []Token{p.cur(), p.peek()}
+	p.next()
+
+	exp := NewOrderByExpression(keywords)
+	for {
+		p.next()
+		term, err := p.parseOrderingTerm()
+		if err != nil {
+			return nil, err
+		}
+		exp.AppendExpression(term)
+
+		if p.peek().Type != COMMA {
+			return exp, nil
+		}
+		p.next()
+	}
+}
+
+// parseOrderingTerm parses an expression by which results are ordered,
+// along with any collation, direction and null ordering following it.
+func (p *Parser) parseOrderingTerm() (*OrderingTermExpression, error) {
+	exp, err := p.parseExpression(precLowest)
+	if err != nil {
+		return nil, err
+	}
+
+	var modifiers []Token
+	for {
+		switch next := p.peek(); {
+		case isKeyword(next, "COLLATE"), isKeyword(next, "NULLS"):
+			if !isName(p.peekN(2)) {
+				return nil, errorAt(p.peekN(2), "expected identifier after %q, got %q", next.Literal, p.peekN(2).Literal)
+			}
+			modifiers = append(modifiers, next, p.peekN(2))
+			p.next()
+			p.next()
+		case isKeyword(next, "ASC"), isKeyword(next, "DESC"):
+			modifiers = append(modifiers, next)
+			p.next()
+		default:
+			return NewOrderingTermExpression(exp, modifiers), nil
+		}
+	}
+}
+
+// parseLimit parses a LIMIT clause, which may include an offset either as
+// "LIMIT count OFFSET offset" or in the form "LIMIT offset, count".
+func (p *Parser) parseLimit() (Expression, error) {
+	keyword := p.cur()
+
+	p.next()
+	first, err := p.parseExpression(precLowest)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().Type != COMMA && !isKeyword(p.peek(), "OFFSET") {
+		return NewLimitExpression(keyword, first, Token{}, nil), nil
+	}
+
+	p.next()
+	separator := p.cur()
+
+	p.next()
+	second, err := p.parseExpression(precLowest)
+	if err != nil {
+		return nil, err
+	}
+	return NewLimitExpression(keyword, first, separator, second), nil
+}
+
+// parseValues parses a VALUES clause and its parenthesised rows, each of
+// which must have the same number of values as the first. A VALUES keyword
+// that is not followed by a row, as in "DEFAULT VALUES", or that is the
+// operand of an operator, as in MySQL's "name = VALUES(name)", is parsed
+// as an identity.
+func (p *Parser) parseValues() (Expression, error) {
+	keyword := p.cur()
+	if p.peek().Type != LPAREN {
+		return NewIdentityExpression(keyword), nil
+	}
+	if p.pos > 0 {
+		if _, ok := precedences[p.tokens[p.pos-1].Type]; ok {
+			return NewIdentityExpression(keyword), nil
+		}
+	}
+
+	exp := NewValuesExpression(keyword)
+	for {
+		p.next()
+		open := p.cur()
+		items, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return nil, errorAt(open, "expected values in row")
+		}
+
+		row := &GroupedColumnsExpression{}
+		row.setExpressions(items)
+		if arity := exp.Arity(); arity > 0 && arity != len(items) {
+			return nil, errorAt(open, "expected %d values in row, got %d", arity, len(items))
+		}
+		exp.AppendExpression(row)
+
+		if p.peek().Type != COMMA || p.peekN(2).Type != LPAREN {
+			return exp, nil
+		}
+		p.next()
+	}
+}
+
+// checkValuesColumns returns an error if the input VALUES clause, the last
+// of the input statement expressions, follows the column list of an INSERT
+// statement, as in "INTO person (id, name) VALUES (...)", and its rows do
+// not have a value for each column.
+func checkValuesColumns(stmt []Expression, values *ValuesExpression) error {
+	stmt = withoutComments(stmt)
+	if len(stmt) < 4 {
+		return nil
+	}
+
+	columns, ok := stmt[len(stmt)-2].(*GroupedColumnsExpression)
+	if !ok || !isKeywordExpression(stmt[len(stmt)-4], map[string]bool{"INTO": true}) {
+		return nil
+	}
+	if _, ok := tableName(stmt[len(stmt)-3]); !ok {
+		return nil
+	}
+
+	if n := len(columns.Expressions()); n != values.Arity() {
+		return errorAt(values.keyword, "expected %d values for columns %s, got %d", n, columns.String(), values.Arity())
+	}
+	return nil
+}
+
+// parseList parses the items of a parenthesised, comma-separated list,
+// beginning with the current token as the opening parenthesis and
+// finishing with the closing parenthesis as the current token.
+// Each item in the list that comprises more than one expression,
+// such as a sub-query, is returned as a SQLExpression.
+func (p *Parser) parseList() ([]Expression, error) {
+	open := p.cur()
+
+	p.next()
+	if p.cur().Type == RPAREN {
+		return nil, nil
+	}
+
+	// Items are collected in a slice reused for each of them, since
+	// most comprise a single expression and need no SQLExpression.
+	var items, children []Expression
+	for {
+		children = children[:0]
+		for p.cur().Type != COMMA && p.cur().Type != RPAREN {
+			if p.cur().Type == EOF {
+				return nil, errorAt(open, "unclosed parenthesis")
+			}
+
+			child, err := p.parseExpression(precLowest)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+			p.next()
+		}
+
+		switch len(children) {
+		case 0:
+			return nil, errorAt(p.cur(), "expected expression before %q", p.cur().Literal)
+		case 1:
+			items = append(items, children[0])
+		default:
+			item := &SQLExpression{}
+			item.setExpressions(append([]Expression(nil), children...))
+			items = append(items, item)
+		}
+
+		if p.cur().Type == RPAREN {
+			return items, nil
+		}
+		p.next()
+	}
+}
+
+// isFunctionCall returns true if the current identifier is followed by
+// a parenthesis that opens the arguments of a function call, rather than
+// the column list of a table, as in "INSERT INTO person (id, name)".
+func (p *Parser) isFunctionCall() bool {
+	if p.peek().Type != LPAREN {
+		return false
+	}
+	if p.pos == 0 {
+		return true
+	}
+
+	prev := p.tokens[p.pos-1]
+	return prev.Type != KEYWORD || !tableKeywords[strings.ToUpper(prev.Literal)]
+}
+
+// isReservedKeyword returns true if the input
+// token is a keyword that can not be an operand.
+func isReservedKeyword(tok Token) bool {
+	return tok.Type == KEYWORD && reservedKeywords[strings.ToUpper(tok.Literal)]
+}
+
+// isKeyword returns true if the input token
+// is the input keyword, regardless of case.
+func isKeyword(tok Token, keyword string) bool {
+	return tok.Type == KEYWORD && strings.EqualFold(tok.Literal, keyword)
+}
+
+// isName returns true if the input token can name a type, field or
+// column. Keywords are included, as in "$Order.id" or "p.order", as are
+// quoted identifiers, as in "p.\"order\"".
+func isName(tok Token) bool {
+	return tok.Type == IDENT || tok.Type == KEYWORD || tok.Type == QUOTEDIDENT
+}
+
+// beginsOperand returns true if the input token may begin the operand
+// of an operator, distinguishing the operator "?", as in "data ? 'key'",
+// from a placeholder.
+func beginsOperand(tok Token) bool {
+	switch tok.Type {
+	case IDENT, QUOTEDIDENT, STRING, NUM, DOLLAR, LPAREN, LBRACKET:
+		return true
+	case KEYWORD:
+		return !isReservedKeyword(tok)
+	}
+	return false
+}
+
+// Error describes a malformed statement,
+// at the position of the offending token.
+type Error struct {
+	// Pos is the position within the statement at which the error occurs.
+	Pos Position
+
+	// Limit is the name of the field of Limits that is exceeded,
+	// such as "MaxTokens", or empty for other errors.
+	Limit string
+
+	msg string
+}
+
+// Error implements error, returning the message
+// annotated with the line and column of the error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at line %d, column %d", e.msg, e.Pos.Line, e.Pos.Column)
+}
+
+// errorAt returns an error with the input formatted message,
+// located at the position of the input token.
+func errorAt(tok Token, format string, args ...any) error {
+	return &Error{Pos: tok.Pos, msg: fmt.Sprintf(format, args...)}
+}
+
+// limitErrorAt returns an error for exceeding the named limit,
+// with the input formatted message, located at the input token.
+func limitErrorAt(tok Token, limit, format string, args ...any) error {
+	return &Error{Pos: tok.Pos, Limit: limit, msg: fmt.Sprintf(format, args...)}
 }
@@ -0,0 +1,597 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected string
+	}{
+		{"a + b * c", "(a + (b * c))"},
+		{"a * b + c", "((a * b) + c)"},
+		{"a - b - c", "((a - b) - c)"},
+		{"a / b % c", "((a / b) % c)"},
+		{"a || b = c", "((a || b) = c)"},
+		{"a + b < c * d", "((a + b) < (c * d))"},
+		{"a <= b AND c <> d", "((a <= b) AND (c <> d))"},
+		{"a = 1 AND b = 2 OR c = 3", "(((a = 1) AND (b = 2)) OR (c = 3))"},
+		{"a = 1 OR b = 2 and c = 3", "((a = 1) OR ((b = 2) and (c = 3)))"},
+		{"NOT a = b AND c", "((NOT (a = b)) AND c)"},
+		{"-a * b", "((-a) * b)"},
+		{"(a + b) * c", "([(a + b)] * c)"},
+		{"a IS NOT NULL", "(a IS NOT NULL)"},
+		{"a NOT IN (1, 2) AND b LIKE 'x%'", "((a NOT IN [1, 2]) AND (b LIKE 'x%'))"},
+		{"p.id = $Person.id", "(p.id = $Person.id)"},
+		{"-a::int * b", "((-(a :: int)) * b)"},
+		{"$Person.id::text || 'x'", "(($Person.id :: text) || 'x')"},
+		{"a::varchar(10)::text", "((a :: varchar(10)) :: text)"},
+		{"expires_at < $Now.time + 3600", "(expires_at < ($Now.time + 3600))"},
+		{"$Now.time*2+1 > 3600 - $Now.time", "((($Now.time * 2) + 1) > (3600 - $Now.time))"},
+		{"-$Now.time % $Now.period", "((-$Now.time) % $Now.period)"},
+		{"id = ANY($Filter.ids) OR id <> ALL ($Filter.ids)", "((id = ANY($Filter.ids)) OR (id <> ALL($Filter.ids)))"},
+		{"NOT EXISTS (SELECT 1 FROM t) AND x > SOME (SELECT y FROM z)", "((NOT EXISTS(SELECT 1 FROM t)) AND (x > SOME(SELECT y FROM z)))"},
+		{"data->'a'->>'b' = 'c'", "(((data -> 'a') ->> 'b') = 'c')"},
+		{"data @> $Doc.filter AND tags <@ x || y", "((data @> $Doc.filter) AND (tags <@ (x || y)))"},
+		{"data ? 'k' OR data ?| keys AND data ?& keys", "((data ? 'k') OR ((data ?| keys) AND (data ?& keys)))"},
+		{"id = ? AND data ? $Doc.key", "((id = ?) AND (data ? $Doc.key))"},
+	}
+
+	for _, test := range tests {
+		exp, err := NewParser(NewLexer(test.stmt)).Run()
+		assert.Nil(t, err, test.stmt)
+
+		children := exp.Expressions()
+		if assert.Len(t, children, 1, test.stmt) {
+			assert.Equal(t, test.expected, nestedString(children[0]), test.stmt)
+		}
+	}
+}
+
+func TestParseStatement(t *testing.T) {
+	stmt := "SELECT p.* AS &Person.* FROM person AS p WHERE p.id = $Person.id AND p.name <> 'Fred';"
+
+	exp, err := NewParser(NewLexer(stmt)).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	assert.Len(t, children, 11)
+	assert.IsType(t, &QualifiedIdentityExpression{}, children[1])
+	assert.IsType(t, &OutputTargetExpression{}, children[3])
+	assert.IsType(t, &InfixExpression{}, children[9])
+	assert.Equal(t, "p.id = $Person.id AND p.name <> 'Fred'", children[9].String())
+	assert.Equal(t, "AND", children[9].(*InfixExpression).Operator())
+
+	assert.Equal(t, "SELECT p.* AS &Person.* FROM person AS p WHERE p.id = $Person.id AND p.name <> 'Fred' ;", exp.String())
+}
+
+func TestParseKeywordsAreNotOperands(t *testing.T) {
+	exp, err := NewParser(NewLexer("SELECT * FROM person")).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	assert.Len(t, children, 4)
+	for _, child := range children {
+		assert.IsType(t, &IdentityExpression{}, child)
+	}
+}
+
+func TestParseKeywordsAsNames(t *testing.T) {
+	stmt := "SELECT o.order AS &Order.order FROM [[table]] AS o WHERE o.set = $Order.set"
+
+	exp, err := NewParser(NewLexer(stmt)).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	assert.IsType(t, &QualifiedIdentityExpression{}, children[1])
+	assert.IsType(t, &OutputTargetExpression{}, children[3])
+	assert.IsType(t, &TemplateExpression{}, children[5])
+	assert.Equal(t, "Order", children[9].(*InfixExpression).Right().(*InputSourceExpression).TypeName().String())
+	assert.Equal(t, stmt, exp.String())
+}
+
+func TestParseGenericTypeMapping(t *testing.T) {
+	stmt := "SELECT &Page[Person].* FROM person WHERE id = $Pair[*Person,[]int].id"
+
+	exp, err := NewParser(NewLexer(stmt)).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	assert.Equal(t, "Page[Person]", children[1].(*OutputTargetExpression).TypeName().String())
+	assert.Equal(t, "Pair[*Person,[]int]", children[5].(*InfixExpression).Right().(*InputSourceExpression).TypeName().String())
+	assert.Equal(t, stmt, exp.String())
+
+	_, err = NewParser(NewLexer("SELECT &Page[Person.*")).Run()
+	assert.EqualError(t, err, `expected type name and field after "&" at line 1, column 8`)
+}
+
+func TestParseFunctionCall(t *testing.T) {
+	exp, err := NewParser(NewLexer("SELECT count(*), coalesce(name, 'x' || $Person.name) FROM person")).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	assert.Len(t, children, 6)
+
+	count, ok := children[1].(*FunctionCallExpression)
+	assert.True(t, ok)
+	assert.Equal(t, "count", count.Name().String())
+	assert.Len(t, count.Arguments(), 1)
+
+	coalesce, ok := children[3].(*FunctionCallExpression)
+	assert.True(t, ok)
+	assert.Equal(t, "coalesce(name, 'x' || $Person.name)", coalesce.String())
+	if assert.Len(t, coalesce.Arguments(), 2) {
+		assert.IsType(t, &InfixExpression{}, coalesce.Arguments()[1])
+	}
+}
+
+func TestParseQuantified(t *testing.T) {
+	exp, err := NewParser(NewLexer("SELECT * FROM person WHERE id = any($Filter.ids) AND EXISTS (SELECT 1 FROM manager)")).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	assert.Len(t, children, 6)
+
+	and := children[5].(*InfixExpression)
+	anyIDs, ok := and.Left().(*InfixExpression).Right().(*QuantifiedExpression)
+	if assert.True(t, ok) {
+		assert.Equal(t, "any", anyIDs.Quantifier())
+		assert.IsType(t, &GroupedColumnsExpression{}, anyIDs.Operand())
+		assert.Equal(t, "$Filter.ids", anyIDs.InputSource().String())
+	}
+
+	exists, ok := and.Right().(*QuantifiedExpression)
+	if assert.True(t, ok) {
+		assert.Equal(t, "EXISTS(SELECT 1 FROM manager)", exists.String())
+		assert.IsType(t, &SubqueryExpression{}, exists.Operand())
+		assert.Nil(t, exists.InputSource())
+	}
+}
+
+func TestParseTableColumnsAreNotFunctionCall(t *testing.T) {
+	exp, err := NewParser(NewLexer("INSERT INTO person(id, name) VALUES ($Person.id, lower($Person.name))")).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	assert.Len(t, children, 5)
+	assert.IsType(t, &IdentityExpression{}, children[2])
+	assert.IsType(t, &GroupedColumnsExpression{}, children[3])
+
+	values := children[4].Expressions()[0].Expressions()
+	assert.IsType(t, &InputSourceExpression{}, values[0])
+	assert.IsType(t, &FunctionCallExpression{}, values[1])
+}
+
+func TestParseValues(t *testing.T) {
+	exp, err := NewParser(NewLexer("INSERT INTO person (id, name) VALUES ($Person.id, $Person.name), (2, 'Onos'),(3, lower('Fred'))")).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	assert.Len(t, children, 5)
+
+	values, ok := children[4].(*ValuesExpression)
+	if assert.True(t, ok) {
+		assert.Equal(t, "VALUES ($Person.id, $Person.name), (2, 'Onos'), (3, lower('Fred'))", values.String())
+		assert.Equal(t, 2, values.Arity())
+		assert.Len(t, values.Rows(), 3)
+		assert.IsType(t, &InputSourceExpression{}, values.Rows()[0].Expressions()[0])
+		assert.IsType(t, &FunctionCallExpression{}, values.Rows()[2].Expressions()[1])
+
+		repeated := values.Repeat(2)
+		assert.Len(t, repeated.Rows(), 6)
+		assert.Equal(t, 2, repeated.Arity())
+		assert.Equal(t, values.String()+", "+strings.TrimPrefix(values.String(), "VALUES "), repeated.String())
+	}
+
+	// VALUES without rows, or as the operand of an operator, is an identity.
+	for _, stmt := range []string{
+		"INSERT INTO person DEFAULT VALUES",
+		"INSERT INTO person (id) VALUES (1) ON DUPLICATE KEY UPDATE id = VALUES(id)",
+	} {
+		exp, err := NewParser(NewLexer(stmt)).Run()
+		assert.Nil(t, err)
+
+		var identities int
+		_ = Walk(exp, func(exp Expression) error {
+			if id, ok := exp.(*IdentityExpression); ok && id.String() == "VALUES" {
+				identities++
+			}
+			return nil
+		})
+		assert.Equal(t, 1, identities, stmt)
+	}
+}
+
+func TestParseCommonTableExpressions(t *testing.T) {
+	stmt := `
+WITH RECURSIVE m (id) AS (SELECT id, name FROM person WHERE id = $Person.id),
+     n AS NOT MATERIALIZED (SELECT 1)
+SELECT &Person.* FROM person WHERE id IN (SELECT id FROM m)`
+
+	exp, err := NewParser(NewLexer(stmt)).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	assert.Len(t, children, 7)
+
+	with, ok := children[0].(*WithExpression)
+	assert.True(t, ok)
+	assert.True(t, with.Recursive())
+
+	ctes := with.Expressions()
+	if assert.Len(t, ctes, 2) {
+		m := ctes[0].(*CommonTableExpression)
+		assert.Equal(t, "m", m.Name().String())
+		assert.Equal(t, "(id)", m.Columns().String())
+		assert.Equal(t, "(SELECT id , name FROM person WHERE id = $Person.id)", m.Query().String())
+
+		n := ctes[1].(*CommonTableExpression)
+		assert.Nil(t, n.Columns())
+		assert.Equal(t, "AS NOT MATERIALIZED", n.As())
+	}
+
+	in := children[6].(*InfixExpression)
+	assert.IsType(t, &SubqueryExpression{}, in.Right())
+	assert.Equal(t, "id IN (SELECT id FROM m)", in.String())
+}
+
+func TestParseCompoundQuery(t *testing.T) {
+	stmt := "SELECT &Person.* FROM person UNION ALL SELECT id, name FROM manager EXCEPT SELECT * FROM (SELECT 1 INTERSECT SELECT 2);"
+
+	exp, err := NewParser(NewLexer(stmt)).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	assert.Len(t, children, 1)
+
+	compound, ok := children[0].(*CompoundExpression)
+	assert.True(t, ok)
+
+	branches := compound.Expressions()
+	assert.Len(t, branches, 3)
+	assert.Equal(t, "", compound.Operator(0))
+	assert.Equal(t, "UNION ALL", compound.Operator(1))
+	assert.Equal(t, "EXCEPT", compound.Operator(2))
+
+	sub := branches[2].Expressions()[3].(*SubqueryExpression)
+	assert.IsType(t, &CompoundExpression{}, sub.Expressions()[0])
+	assert.Equal(t, "(SELECT 1 INTERSECT SELECT 2)", sub.String())
+}
+
+func TestParseOrderByLimitOffset(t *testing.T) {
+	stmt := "SELECT * FROM person ORDER BY name COLLATE NOCASE DESC NULLS LAST, id LIMIT $Page.size OFFSET $Page.offset"
+
+	exp, err := NewParser(NewLexer(stmt)).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	assert.Len(t, children, 6)
+
+	orderBy, ok := children[4].(*OrderByExpression)
+	assert.True(t, ok)
+	terms := orderBy.Expressions()
+	if assert.Len(t, terms, 2) {
+		assert.Equal(t, "COLLATE NOCASE DESC NULLS LAST", terms[0].(*OrderingTermExpression).Modifiers())
+		assert.Equal(t, "id", terms[1].String())
+	}
+
+	limit, ok := children[5].(*LimitExpression)
+	assert.True(t, ok)
+	assert.Equal(t, "$Page.size", limit.Count().String())
+	assert.Equal(t, "$Page.offset", limit.Offset().String())
+	assert.Equal(t, stmt, exp.String())
+}
+
+func TestParseLimitWithLeadingOffset(t *testing.T) {
+	exp, err := NewParser(NewLexer("SELECT * FROM person LIMIT $Page.offset, $Page.size + 1")).Run()
+	assert.Nil(t, err)
+
+	limit, ok := exp.Expressions()[4].(*LimitExpression)
+	assert.True(t, ok)
+	assert.Equal(t, "$Page.size + 1", limit.Count().String())
+	assert.Equal(t, "$Page.offset", limit.Offset().String())
+	assert.Equal(t, "LIMIT $Page.offset, $Page.size + 1", limit.String())
+
+	exp, err = NewParser(NewLexer("SELECT * FROM person LIMIT 10")).Run()
+	assert.Nil(t, err)
+
+	limit = exp.Expressions()[4].(*LimitExpression)
+	assert.Equal(t, "10", limit.Count().String())
+	assert.Nil(t, limit.Offset())
+}
+
+func TestParseTemplate(t *testing.T) {
+	exp, err := NewParser(NewLexer("SELECT [[column]] FROM [[table]] WHERE [id] = 1")).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	assert.Len(t, children, 8)
+
+	column, ok := children[1].(*TemplateExpression)
+	assert.True(t, ok)
+	assert.Equal(t, "column", column.Name().String())
+	assert.Equal(t, "[[table]]", children[3].String())
+
+	// Single brackets are not templates.
+	assert.IsType(t, &IdentityExpression{}, children[5])
+}
+
+func TestParseDirectives(t *testing.T) {
+	p := NewParser(NewLexer("-- sqlair:timeout=5s retries=3\n-- sqlair:mode=ro\nSELECT 1"))
+	exp, err := p.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT 1", exp.String())
+	assert.Equal(t, map[string]string{"timeout": "5s", "retries": "3", "mode": "ro"}, p.Directives())
+
+	p = NewParser(NewLexer("SELECT 1"))
+	_, err = p.Run()
+	assert.Nil(t, err)
+	assert.Nil(t, p.Directives())
+}
+
+func TestParseComments(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected string
+	}{
+		{"SELECT /*+ INDEX(t i) */ a FROM t", "SELECT /*+ INDEX(t i) */ a FROM t"},
+		{"-- Leading.\nSELECT a FROM t -- Trailing.", "SELECT -- Leading. a FROM t -- Trailing."},
+		{"SELECT a FROM t WHERE b IN (SELECT /* inner */ b FROM u) /* outer */", "SELECT a FROM t WHERE b IN (SELECT /* inner */ b FROM u) /* outer */"},
+		{"SELECT a /* one */ UNION /* two */ SELECT b", "SELECT a /* one */ UNION SELECT /* two */ b"},
+		{"-- sqlair:timeout=5s\nSELECT 1", "SELECT 1"},
+		{"-- Only a comment.", ""},
+	}
+
+	for _, test := range tests {
+		exp, err := NewParser(NewLexer(test.stmt)).Run()
+		if assert.Nil(t, err, test.stmt) {
+			assert.Equal(t, test.expected, exp.String(), test.stmt)
+		}
+	}
+
+	exp, err := NewParser(NewLexer("SELECT /* note */ a FROM t")).Run()
+	assert.Nil(t, err)
+	comment, ok := exp.Expressions()[1].(*CommentExpression)
+	if assert.True(t, ok) {
+		assert.Equal(t, Position{Offset: 7, Line: 1, Column: 8}, comment.Begin())
+	}
+}
+
+func TestParseHints(t *testing.T) {
+	exp, err := NewParser(NewLexer("SELECT /*+ INDEX(person idx_name) */ /* +not a hint */ a FROM person --+ nor this")).Run()
+	assert.Nil(t, err)
+
+	children := exp.Expressions()
+	if assert.Len(t, children, 7) {
+		hint, ok := children[1].(*HintExpression)
+		if assert.True(t, ok) {
+			assert.Equal(t, "/*+ INDEX(person idx_name) */", hint.String())
+			assert.Equal(t, "INDEX(person idx_name)", hint.Hint())
+		}
+		assert.IsType(t, &CommentExpression{}, children[2])
+		assert.IsType(t, &CommentExpression{}, children[6])
+	}
+
+	_, err = NewParser(NewLexer("SELECT /*+ INDEX(person idx_name) a FROM person")).Run()
+	assert.EqualError(t, err, "unterminated comment at line 1, column 8")
+}
+
+func TestCommentExpressionBlock(t *testing.T) {
+	assert.Equal(t, "/*+ hint */", NewCommentExpression(Token{Type: COMMENT, Literal: "/*+ hint */"}).Block())
+	assert.Equal(t, "/* a * / b */", NewCommentExpression(Token{Type: COMMENT, Literal: "-- a */ b"}).Block())
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected string
+	}{
+		{"SELECT (a, b", "unclosed parenthesis at line 1, column 8"},
+		{"SELECT a FROM t /* note", "unterminated comment at line 1, column 17"},
+		{"SELECT a)", `unexpected ")" at line 1, column 9`},
+		{"WHERE a =", "unexpected end of statement at line 1, column 9"},
+		{"SELECT (a, ) FROM t", `expected expression before ")" at line 1, column 12`},
+		{"SELECT &Person FROM t", `expected type name and field after "&" at line 1, column 8`},
+		{"WHERE id = $Person.'id'", `expected field name or "*" after "$" at line 1, column 20`},
+		{"WITH (SELECT 1) SELECT 1", `expected common table expression name, got "(" at line 1, column 6`},
+		{"WITH m (SELECT 1) SELECT 1", `expected column names for "m" at line 1, column 17`},
+		{"WITH m SELECT 1", `expected "AS" after "m", got "SELECT" at line 1, column 8`},
+		{"WITH m AS SELECT 1", `expected "(" after "AS", got "SELECT" at line 1, column 11`},
+		{"SELECT * FROM (SELECT 1", "unclosed parenthesis at line 1, column 15"},
+		{"SELECT * FROM t ORDER name", `expected "BY" after "ORDER", got "name" at line 1, column 23`},
+		{"SELECT * FROM t ORDER BY name COLLATE 'x'", `expected identifier after "COLLATE", got "'x'" at line 1, column 39`},
+		{"SELECT * FROM t LIMIT", "unexpected end of statement at line 1, column 21"},
+		{"UNION SELECT 1", `expected query before "UNION" at line 1, column 1`},
+		{"SELECT 1 UNION ALL", `expected query after "ALL" at line 1, column 16`},
+		{"SELECT * FROM t WHERE EXISTS (1, 2)", `expected subquery after "EXISTS" at line 1, column 23`},
+		{"VALUES (1, 2), (3)", `expected 2 values in row, got 1 at line 1, column 16`},
+		{"VALUES ()", `expected values in row at line 1, column 8`},
+		{"INSERT INTO t (a, b) VALUES (1, 2, 3)", `expected 2 values for columns (a, b), got 3 at line 1, column 22`},
+		{"-- sqlair:timeout\nSELECT 1", `malformed directive "timeout"; expected name=value at line 1, column 1`},
+		{"-- sqlair:a=1\n-- sqlair:a=2\nSELECT 1", `directive "a" given more than once at line 2, column 1`},
+	}
+
+	for _, test := range tests {
+		_, err := NewParser(NewLexer(test.stmt)).Run()
+		assert.EqualError(t, err, test.expected, test.stmt)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		limits   Limits
+		limit    string
+		expected string
+	}{
+		{"SELECT name FROM person", Limits{MaxLength: 20}, "MaxLength", "statement exceeds maximum length of 20 bytes at line 1, column 18"},
+		{"SELECT name FROM person", Limits{MaxTokens: 3}, "MaxTokens", "statement exceeds maximum of 3 tokens at line 1, column 18"},
+		{"SELECT ((1 + (2)))", Limits{MaxDepth: 3}, "MaxDepth", "statement exceeds maximum nesting depth of 3 at line 1, column 14"},
+	}
+
+	for _, test := range tests {
+		p := NewParser(NewLexer(test.stmt))
+		p.SetLimits(test.limits)
+		_, err := p.Run()
+		assert.EqualError(t, err, test.expected, test.stmt)
+
+		var parseErr *Error
+		if assert.ErrorAs(t, err, &parseErr) {
+			assert.Equal(t, test.limit, parseErr.Limit)
+		}
+	}
+
+	// Statements within the limits are accepted.
+	p := NewParser(NewLexer("SELECT ((1 + (2))) FROM person"))
+	p.SetLimits(Limits{MaxLength: 30, MaxTokens: 12, MaxDepth: 5})
+	_, err := p.Run()
+	assert.Nil(t, err)
+}
+
+func TestParseJujuStatements(t *testing.T) {
+	for _, stmt := range jujuStatements {
+		exp, err := NewParser(NewLexer(stmt)).Run()
+		assert.Nil(t, err, stmt)
+		assert.NotEmpty(t, exp.Expressions(), stmt)
+	}
+}
+
+// nestedString returns the string for the input expression,
+// with the operands of each operator parenthesised to show
+// how they nest. Grouped columns are shown in square brackets.
+func nestedString(exp Expression) string {
+	switch e := exp.(type) {
+	case *InfixExpression:
+		return "(" + nestedString(e.Left()) + " " + e.Operator() + " " + nestedString(e.Right()) + ")"
+	case *PrefixExpression:
+		return "(" + e.Operator() + nestedString(e.Right()) + ")"
+	case *GroupedColumnsExpression:
+		items := make([]string, len(e.Expressions()))
+		for i, child := range e.Expressions() {
+			items[i] = nestedString(child)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *PassThroughExpression:
+		items := make([]string, len(e.Expressions()))
+		for i, child := range e.Expressions() {
+			items[i] = nestedString(child)
+		}
+		return "{" + strings.Join(items, " ") + "}"
+	}
+	return exp.String()
+}
+
+func TestRunReturnsExpressionOrError(t *testing.T) {
+	for _, stmt := range []string{"", "SELECT 1", "SELECT &Person.* FROM person"} {
+		exp, err := NewParser(NewLexer(stmt)).Run()
+		if err == nil && exp == nil {
+			t.Errorf("no expression or error returned for %q", stmt)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, stmt := range jujuStatements {
+		f.Add(stmt)
+	}
+
+	f.Fuzz(func(t *testing.T, stmt string) {
+		exp, err := NewParser(NewLexer(stmt)).Run()
+		if err == nil && exp == nil {
+			t.Fatalf("no expression or error returned for %q", stmt)
+		}
+	})
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, stmt := range jujuStatements {
+			if _, err := NewParser(NewLexer(stmt)).Run(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkParseLarge(b *testing.B) {
+	stmt := largeStatement()
+	b.SetBytes(int64(len(stmt)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewParser(NewLexer(stmt)).Run(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWalkLarge(b *testing.B) {
+	exp, err := NewParser(NewLexer(largeStatement())).Run()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = Walk(exp, func(Expression) error { return nil })
+	}
+}
+
+// largeStatement returns a statement of about 10,000 tokens, inserting
+// rows of values with nested expressions and input sources.
+func largeStatement() string {
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO person (id, name, score) VALUES ")
+	for i := 0; i < 500; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "(%d, lower($Person.name), (%d + 1) * 2)", i, i)
+	}
+	return sb.String()
+}
+
+// jujuStatements are representative of the statements
+// issued by Juju against its controller database.
+var jujuStatements = []string{
+	`
+SELECT &Application.*, &Unit.*
+FROM   application AS a
+JOIN   unit AS u ON a.uuid = u.application_uuid
+WHERE  a.name = $Application.name;`,
+
+	`
+SELECT (uuid, name, holder, expiry) AS &Lease.*
+FROM   lease
+WHERE  model_uuid = $Model.uuid
+AND    name IN ('controller', 'singular');`,
+
+	`
+INSERT INTO lease (uuid, lease_type_id, model_uuid, name, holder, start, expiry)
+VALUES ($Lease.uuid, $Lease.type_id, $Lease.model_uuid, $Lease.name, $Lease.holder, $Lease.start, $Lease.expiry);`,
+
+	`
+UPDATE lease
+SET    holder = $Lease.holder, expiry = $Lease.expiry
+WHERE  uuid = $Lease.uuid;`,
+
+	`
+DELETE FROM lease_pin
+WHERE  lease_uuid = $Lease.uuid
+AND    entity_id = 'machine-0';`,
+
+	`
+CREATE TABLE lease (
+    uuid         TEXT PRIMARY KEY,
+    model_uuid   TEXT NOT NULL,
+    name         TEXT,
+    expiry       DATETIME
+);`,
+}
@@ -0,0 +1,77 @@
+package parse
+
+import "strings"
+
+// predicateConnectives are the keywords that the parser may leave between
+// the operands of a predicate, rather than combining them into a single
+// expression, as it does for the "BETWEEN" of "a BETWEEN 1 AND 2".
+var predicateConnectives = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IS": true, "IN": true,
+	"LIKE": true, "BETWEEN": true, "ESCAPE": true,
+}
+
+// PredicateEnd returns the index, within the input sequence of sibling
+// expressions, following the last expression of the predicate that begins
+// at the input index, such as that of a WHERE clause. The predicate ends
+// at the first expression, other than a comment, that neither is a
+// connective nor follows one, such as the "FOR" of "FOR UPDATE" or an
+// ORDER BY clause, so clauses that follow it are recognised whatever
+// their keywords.
+func PredicateEnd(exps []Expression, start int) int {
+	end, operand := start, true
+	for i := start; i < len(exps); i++ {
+		switch exp := exps[i].(type) {
+		case *CommentExpression, *HintExpression:
+			continue
+		case *OrderByExpression, *LimitExpression, *CompoundExpression, *WithExpression:
+			return end
+		default:
+			if isConnective(exp) {
+				operand = true
+				continue
+			}
+		}
+		if !operand {
+			return end
+		}
+		operand = false
+		end = i + 1
+	}
+	return end
+}
+
+// IsClauseKeyword returns true if the expression at the input index,
+// within the input sequence of sibling expressions, is one of the input
+// keywords beginning a clause. It must be lexed as a KEYWORD and follow an
+// operand, such as the table of a FROM clause, rather than a keyword, comma
+// or operator, so that a column named by a keyword, as in "SELECT id,
+// offset FROM t" or "SET offset = 1", is not mistaken for a clause.
+func IsClauseKeyword(exps []Expression, i int, keywords map[string]bool) bool {
+	if !isKeywordExpression(exps[i], keywords) {
+		return false
+	}
+	for j := i - 1; j >= 0; j-- {
+		switch exp := exps[j].(type) {
+		case *CommentExpression, *HintExpression:
+			continue
+		case *IdentityExpression:
+			switch exp.token.Type {
+			case IDENT, QUOTEDIDENT, NUM, STRING:
+				return true
+			}
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// isConnective returns true if the input expression is one of the
+// predicateConnectives, or one negated, as in "NOT BETWEEN".
+func isConnective(exp Expression) bool {
+	if p, ok := exp.(*PrefixExpression); ok && strings.EqualFold(p.operator.Literal, "NOT") {
+		exp = p.Right()
+	}
+	id, ok := exp.(*IdentityExpression)
+	return ok && (id.token.Type == KEYWORD || id.token.Type == IDENT) && predicateConnectives[strings.ToUpper(id.token.Literal)]
+}
@@ -0,0 +1,79 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredicateEnd(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected string
+	}{
+		{"SELECT * FROM t WHERE a = 1", "a = 1"},
+		{"SELECT * FROM t WHERE a = 1 FOR UPDATE", "a = 1"},
+		{"SELECT * FROM t WHERE NOT EXISTS (SELECT 1) FOR NO KEY UPDATE", "NOT EXISTS(SELECT 1)"},
+		{"SELECT * FROM t WHERE a = 1 AND b BETWEEN 1 AND 2 AND c = 3 FOR SHARE", "a = 1 AND b BETWEEN 1 AND 2 AND c = 3"},
+		{"SELECT * FROM t WHERE a NOT BETWEEN 1 AND 2 OR c = 3 GROUP BY a", "a NOT BETWEEN 1 AND 2 OR c = 3"},
+		{"SELECT * FROM t WHERE a LIKE 'x' ESCAPE 'y' LIMIT 1", "a LIKE 'x' ESCAPE 'y'"},
+		{"SELECT x FROM t WHERE a = 1 WINDOW w AS (PARTITION BY x)", "a = 1"},
+		{"SELECT x FROM t WHERE a = 1 /* c */ OFFSET 5", "a = 1"},
+		{"SELECT x FROM t WHERE a = 1 FETCH FIRST 5 ROWS ONLY", "a = 1"},
+		{"SELECT x FROM t WHERE a = 1 ORDER BY x", "a = 1"},
+		{"DELETE FROM t WHERE a = 1 RETURNING id;", "a = 1"},
+	}
+
+	for _, test := range tests {
+		exp, err := NewParser(NewLexer(test.stmt)).Run()
+		if !assert.Nil(t, err, test.stmt) {
+			continue
+		}
+
+		children := exp.Expressions()
+		start := 0
+		for i, child := range children {
+			if child.String() == "WHERE" {
+				start = i + 1
+			}
+		}
+
+		var predicate []string
+		for _, child := range children[start:PredicateEnd(children, start)] {
+			predicate = append(predicate, child.String())
+		}
+		assert.Equal(t, test.expected, strings.Join(predicate, " "), test.stmt)
+	}
+}
+
+func TestIsClauseKeyword(t *testing.T) {
+	keywords := map[string]bool{"OFFSET": true, "FOR": true, "RETURNING": true}
+	tests := []struct {
+		stmt     string
+		expected string
+	}{
+		{"SELECT a FROM t FOR UPDATE", "FOR"},
+		{"SELECT a FROM t /* c */ OFFSET 5", "OFFSET"},
+		{"SELECT a, offset FROM t", ""},
+		{"SELECT offset FROM t AS offset", ""},
+		{"UPDATE t SET offset = 1 RETURNING offset", "RETURNING"},
+		{`SELECT a FROM t WHERE "offset" = 1`, ""},
+	}
+
+	for _, test := range tests {
+		exp, err := NewParser(NewLexer(test.stmt)).Run()
+		if !assert.Nil(t, err, test.stmt) {
+			continue
+		}
+
+		var found []string
+		children := exp.Expressions()
+		for i, child := range children {
+			if IsClauseKeyword(children, i, keywords) {
+				found = append(found, child.String())
+			}
+		}
+		assert.Equal(t, test.expected, strings.Join(found, " "), test.stmt)
+	}
+}
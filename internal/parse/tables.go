@@ -0,0 +1,224 @@
+package parse
+
+import "strings"
+
+// tableClauseKeywords are the keywords that are followed by
+// the name of a table that a statement reads or writes.
+var tableClauseKeywords = map[string]bool{
+	"FROM": true, "JOIN": true, "INTO": true, "UPDATE": true, "TABLE": true,
+	"USING": true,
+}
+
+// Tables returns the names, in lower case and without duplicates, of the
+// tables named in the input statement expression tree, including those in
+// subqueries, which follow those of the enclosing statement.
+// A table is recognised by the keyword preceding it, such as FROM or INTO;
+// a comma-separated list of tables following FROM or USING, as in
+// PostgreSQL's DELETE ... USING, is recognised in full, and the ONLY
+// preceding a table in PostgreSQL is skipped.
+// The names of common table expressions are included where the statement
+// reads from them.
+func Tables(exp Expression) []string {
+	read, written := TableAccess(exp)
+	return appendUnique(written, read...)
+}
+
+// TableAccess returns the names of the tables read and written by the
+// input statement expression tree, as for Tables. A statement that is not a
+// query writes to the first table named after its WITH clause, such as that
+// following INSERT INTO, UPDATE, DELETE FROM or CREATE TABLE. A MySQL
+// DELETE that lists the aliases of its targets before FROM, as in
+// "DELETE p, m FROM person AS p JOIN manager AS m ON ...", writes to each
+// of the tables listed. Every other table named is read, which may include
+// the written tables.
+func TableAccess(exp Expression) (read, written []string) {
+	refs := TableReferences(exp)
+	if kind := Classify(exp); len(refs) > 0 && (kind == KindDML || kind == KindDDL) {
+		targets := deleteTargets(exp)
+		if len(targets) == 0 {
+			written = []string{refs[0].Name}
+			refs = refs[1:]
+		}
+
+		var rest []TableReference
+		for _, ref := range refs {
+			if !ref.Nested && targets[strings.ToLower(ref.Alias)] {
+				written = appendUnique(written, ref.Name)
+				continue
+			}
+			rest = append(rest, ref)
+		}
+		refs = rest
+	}
+
+	var names []string
+	for _, ref := range refs {
+		names = append(names, ref.Name)
+	}
+	return appendUnique(nil, names...), written
+}
+
+// deleteTargets returns the lower-cased aliases of the tables listed
+// between DELETE and FROM in the input statement expression tree,
+// or nil if it is not a DELETE statement listing its targets.
+func deleteTargets(exp Expression) map[string]bool {
+	children := withoutComments(exp.Expressions())
+	for len(children) > 0 {
+		if _, ok := children[0].(*WithExpression); !ok {
+			break
+		}
+		children = children[1:]
+	}
+	if len(children) == 0 || !isKeywordExpression(children[0], map[string]bool{"DELETE": true}) {
+		return nil
+	}
+
+	var targets map[string]bool
+	for _, child := range children[1:] {
+		if isKeywordExpression(child, map[string]bool{"FROM": true}) {
+			return targets
+		}
+		if name, ok := tableName(child); ok {
+			if targets == nil {
+				targets = make(map[string]bool)
+			}
+			targets[name] = true
+		}
+	}
+	return nil
+}
+
+// TableReference is a reference to a table by a statement.
+type TableReference struct {
+	// Name is the name of the table, without any schema
+	// qualifier, in lower case unless it is quoted.
+	Name string
+
+	// Alias is the name by which the statement refers to the table's
+	// columns: its alias if it has one, otherwise its name as written.
+	Alias string
+
+	// Nested is true if the reference is within a subquery or common table
+	// expression, rather than at the top level of the statement.
+	Nested bool
+}
+
+// TableReferences returns every reference to a table in the input statement
+// expression tree, as recognised by Tables, in order of a walk of the tree.
+func TableReferences(exp Expression) []TableReference {
+	var references []TableReference
+	_ = Walk(exp, func(parent Expression) error {
+		children := withoutComments(parent.Expressions())
+		for i, child := range children {
+			if !isKeywordExpression(child, tableClauseKeywords) {
+				continue
+			}
+
+			for j := i + 1; j < len(children); {
+				if j+1 < len(children) && isOnly(children[j]) {
+					j++
+				}
+				name, ok := tableName(children[j])
+				if !ok {
+					break
+				}
+				ref := TableReference{Name: name, Alias: tableAlias(children[j]), Nested: parent != exp}
+
+				// Skip any alias, to find a comma preceding another table.
+				j++
+				if j+1 < len(children) && isKeywordExpression(children[j], map[string]bool{"AS": true}) {
+					ref.Alias = children[j+1].String()
+					j += 2
+				} else if j < len(children) {
+					if id, ok := children[j].(*IdentityExpression); ok && (id.token.Type == IDENT || id.token.Type == QUOTEDIDENT) {
+						ref.Alias = id.token.Literal
+						j++
+					}
+				}
+				references = append(references, ref)
+
+				if j >= len(children) || children[j].String() != "," {
+					break
+				}
+				j++
+			}
+		}
+		return nil
+	})
+	return references
+}
+
+// isOnly returns true if the input expression is PostgreSQL's ONLY,
+// which may precede a table to exclude the tables inheriting from it.
+func isOnly(exp Expression) bool {
+	id, ok := exp.(*IdentityExpression)
+	return ok && id.token.Type == IDENT && strings.EqualFold(id.token.Literal, "ONLY")
+}
+
+// tableAlias returns the name by which columns of the table named by the
+// input expression are qualified when it has no alias: the table name as
+// written, without any schema qualifier.
+func tableAlias(exp Expression) string {
+	if q, ok := exp.(*QualifiedIdentityExpression); ok {
+		return q.Name().String()
+	}
+	return exp.String()
+}
+
+// appendUnique appends to the input slice
+// each of the input names that it lacks.
+func appendUnique(names []string, more ...string) []string {
+	for _, name := range more {
+		found := false
+		for _, n := range names {
+			if n == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// isKeywordExpression returns true if the input expression
+// is an identity for one of the input keywords.
+func isKeywordExpression(exp Expression, keywords map[string]bool) bool {
+	id, ok := exp.(*IdentityExpression)
+	return ok && id.token.Type == KEYWORD && keywords[strings.ToUpper(id.token.Literal)]
+}
+
+// tableName returns the lower-cased name of the table named by the input
+// expression, without any schema qualifier, and true, or false if the
+// expression does not name a table. A quoted name is returned unquoted,
+// in its own case.
+func tableName(exp Expression) (string, bool) {
+	switch e := exp.(type) {
+	case *IdentityExpression:
+		switch e.token.Type {
+		case IDENT:
+			return strings.ToLower(e.token.Literal), true
+		case QUOTEDIDENT:
+			return Unquote(e.token.Literal), true
+		}
+	case *QualifiedIdentityExpression:
+		return tableName(e.Name())
+	}
+	return "", false
+}
+
+// withoutComments returns the input expressions, omitting any comments
+// and hints, so that they can not hide a table reference.
+func withoutComments(exps []Expression) []Expression {
+	filtered := make([]Expression, 0, len(exps))
+	for _, exp := range exps {
+		switch exp.(type) {
+		case *CommentExpression, *HintExpression:
+			continue
+		}
+		filtered = append(filtered, exp)
+	}
+	return filtered
+}
@@ -0,0 +1,79 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTables(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		expected []string
+	}{
+		{"SELECT &Person.* FROM person WHERE id = $Person.id", []string{"person"}},
+		{"SELECT * FROM Person AS p, address a, main.phone JOIN email ON 1", []string{"person", "address", "phone", "email"}},
+		{"SELECT * FROM person WHERE id IN (SELECT person_id FROM address)", []string{"person", "address"}},
+		{"INSERT INTO person (id, name) VALUES ($Person.id, $Person.name)", []string{"person"}},
+		{"UPDATE person SET name = 'Fred'", []string{"person"}},
+		{"DELETE FROM person", []string{"person"}},
+		{"DELETE FROM /* note */ person", []string{"person"}},
+		{"DELETE FROM ONLY person USING manager m, team WHERE 1", []string{"person", "manager", "team"}},
+		{"SELECT * FROM person JOIN address USING (id)", []string{"person", "address"}},
+		{"CREATE TABLE person (id TEXT)", []string{"person"}},
+		{"WITH m AS (SELECT * FROM person) SELECT * FROM m JOIN person ON 1", []string{"m", "person"}},
+		{"SELECT 1", nil},
+	}
+
+	for _, test := range tests {
+		exp, err := NewParser(NewLexer(test.stmt)).Run()
+		if assert.Nil(t, err, test.stmt) {
+			assert.Equal(t, test.expected, Tables(exp), test.stmt)
+		}
+	}
+}
+
+func TestTableAccess(t *testing.T) {
+	tests := []struct {
+		stmt    string
+		read    []string
+		written []string
+	}{
+		{"SELECT * FROM person JOIN address ON 1", []string{"person", "address"}, nil},
+		{"INSERT INTO person (id) SELECT id FROM staff", []string{"staff"}, []string{"person"}},
+		{"INSERT INTO person (id) SELECT id FROM person", []string{"person"}, []string{"person"}},
+		{"WITH s AS (SELECT * FROM staff) UPDATE person SET name = (SELECT name FROM s)", []string{"staff", "s"}, []string{"person"}},
+		{"DELETE FROM person WHERE id = $Person.id", nil, []string{"person"}},
+		{"DELETE FROM person USING manager AS m WHERE person.id = m.id", []string{"manager"}, []string{"person"}},
+		{"DELETE FROM ONLY person USING manager WHERE 1", []string{"manager"}, []string{"person"}},
+		{"DELETE p FROM person AS p JOIN manager AS m ON p.id = m.id", []string{"manager"}, []string{"person"}},
+		{"DELETE p, m FROM person AS p JOIN manager AS m ON p.id = m.id", nil, []string{"person", "manager"}},
+		{"UPDATE ONLY person SET name = 'Fred'", nil, []string{"person"}},
+		{"UPDATE person AS p JOIN manager AS m ON p.id = m.id SET p.name = m.name", []string{"manager"}, []string{"person"}},
+		{"UPDATE person SET name = m.name FROM manager AS m WHERE person.id = m.id", []string{"manager"}, []string{"person"}},
+		{"CREATE TABLE person (id TEXT)", nil, []string{"person"}},
+		{"PRAGMA table_info(person)", nil, nil},
+	}
+
+	for _, test := range tests {
+		exp, err := NewParser(NewLexer(test.stmt)).Run()
+		if assert.Nil(t, err, test.stmt) {
+			read, written := TableAccess(exp)
+			assert.Equal(t, test.read, read, test.stmt)
+			assert.Equal(t, test.written, written, test.stmt)
+		}
+	}
+}
+
+func TestTableReferences(t *testing.T) {
+	stmt := "SELECT * FROM main.Person AS p, address a JOIN person ON 1 WHERE id IN (SELECT id FROM phone)"
+	exp, err := NewParser(NewLexer(stmt)).Run()
+	assert.Nil(t, err)
+
+	assert.Equal(t, []TableReference{
+		{Name: "person", Alias: "p"},
+		{Name: "address", Alias: "a"},
+		{Name: "person", Alias: "person"},
+		{Name: "phone", Alias: "phone", Nested: true},
+	}, TableReferences(exp))
+}
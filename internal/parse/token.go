@@ -1,5 +1,7 @@
 package parse
 
+import "fmt"
+
 // TokenType identifies the type of a token.
 type TokenType int
 
@@ -8,7 +10,8 @@ const (
 	EOF
 
 	IDENT
-	NUM // Number literal.
+	KEYWORD // Keyword such as SELECT, in any case.
+	NUM     // Number literal.
 	STRING
 
 	COMMA // ,
@@ -24,8 +27,113 @@ const (
 	DOLLAR    // $
 	EQUAL     // =
 	SEMICOLON // ;
+
+	PLUS    // +
+	MINUS   // -
+	SLASH   // /
+	PERCENT // %
+	LT      // <
+	GT      // >
+	LTEQ    // <=
+	GTEQ    // >=
+	NOTEQ   // <> or !=
+	CONCAT  // ||
+
+	DIRECTIVE // Directive comment, such as "-- sqlair:timeout=5s".
+	COMMENT   // Comment, such as "-- note" or "/* note */".
+	HINT      // Optimizer hint, such as "/*+ INDEX(person idx_name) */".
+
+	CAST // ::
+
+	OPERATOR // Operator such as "@>", or one registered with a parse function.
+
+	QUOTEDIDENT // Quoted identifier, such as "order" or `order`.
 )
 
+var tokenTypeNames = map[TokenType]string{
+	UNKNOWN:   "UNKNOWN",
+	EOF:       "EOF",
+	IDENT:     "IDENT",
+	KEYWORD:   "KEYWORD",
+	NUM:       "NUM",
+	STRING:    "STRING",
+	COMMA:     "COMMA",
+	LPAREN:    "LPAREN",
+	RPAREN:    "RPAREN",
+	LBRACKET:  "LBRACKET",
+	RBRACKET:  "RBRACKET",
+	BITAND:    "BITAND",
+	PERIOD:    "PERIOD",
+	ASTERISK:  "ASTERISK",
+	DOLLAR:    "DOLLAR",
+	EQUAL:     "EQUAL",
+	SEMICOLON: "SEMICOLON",
+	PLUS:      "PLUS",
+	MINUS:     "MINUS",
+	SLASH:     "SLASH",
+	PERCENT:   "PERCENT",
+	LT:        "LT",
+	GT:        "GT",
+	LTEQ:      "LTEQ",
+	GTEQ:      "GTEQ",
+	NOTEQ:     "NOTEQ",
+	CONCAT:    "CONCAT",
+	DIRECTIVE: "DIRECTIVE",
+	COMMENT:   "COMMENT",
+	HINT:      "HINT",
+	CAST:      "CAST",
+	OPERATOR:  "OPERATOR",
+
+	QUOTEDIDENT: "QUOTEDIDENT",
+}
+
+// String implements fmt.Stringer, returning the name of the token type.
+func (t TokenType) String() string {
+	if name, ok := tokenTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TokenType(%d)", int(t))
+}
+
+// keywords are the words lexed as KEYWORD rather than IDENT tokens,
+// indexed in upper case.
+var keywords = map[string]bool{
+	"ALL": true, "AND": true, "AS": true, "ASC": true, "BETWEEN": true,
+	"BY": true, "CASE": true, "COLLATE": true, "CREATE": true, "CROSS": true,
+	"DELETE": true, "DESC": true, "DISTINCT": true, "ELSE": true, "END": true,
+	"EXCEPT": true, "FETCH": true, "FOR": true, "FROM": true, "FULL": true,
+	"GROUP": true, "HAVING": true, "IN": true, "INNER": true, "INSERT": true,
+	"INTERSECT": true, "INTO": true, "IS": true, "JOIN": true, "LEFT": true,
+	"LIKE": true, "LIMIT": true, "LOCK": true, "MATERIALIZED": true, "NATURAL": true, "NOT": true, "NULL": true,
+	"NULLS": true, "OFFSET": true, "ON": true, "OR": true, "ORDER": true,
+	"OUTER": true, "RECURSIVE": true, "REFERENCES": true, "RETURNING": true,
+	"RIGHT": true, "SELECT": true, "SET": true, "TABLE": true, "THEN": true,
+	"UNION": true, "UPDATE": true, "USING": true, "VALUES": true, "WHEN": true,
+	"WHERE": true, "WINDOW": true, "WITH": true,
+}
+
+// maxKeywordLen is the length of the longest of the keywords.
+const maxKeywordLen = len("MATERIALIZED")
+
+// isKeywordLiteral returns true if the input
+// identifier is a keyword, regardless of case.
+// It does not allocate.
+func isKeywordLiteral(lit string) bool {
+	if len(lit) > maxKeywordLen {
+		return false
+	}
+
+	var upper [maxKeywordLen]byte
+	for i := 0; i < len(lit); i++ {
+		c := lit[i]
+		if 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper[i] = c
+	}
+	return keywords[string(upper[:len(lit)])]
+}
+
 var knownRuneTokens = map[rune]TokenType{
 	'(': LPAREN,
 	')': RPAREN,
@@ -38,30 +146,62 @@ var knownRuneTokens = map[rune]TokenType{
 	'$': DOLLAR,
 	'=': EQUAL,
 	';': SEMICOLON,
+	'+': PLUS,
+	'-': MINUS,
+	'/': SLASH,
+	'%': PERCENT,
+	'<': LT,
+	'>': GT,
+}
+
+// knownOperatorTokens maps operators that span
+// two characters to their token types.
+var knownOperatorTokens = map[string]TokenType{
+	"<=": LTEQ,
+	">=": GTEQ,
+	"<>": NOTEQ,
+	"!=": NOTEQ,
+	"==": EQUAL,
+	"||": CONCAT,
+	"::": CAST,
+}
+
+// jsonOperators are PostgreSQL's JSON and array operators. They are
+// lexed as OPERATOR tokens and parsed as infix operators, except that "?"
+// is only an operator between operands, being otherwise a placeholder.
+var jsonOperators = map[string]bool{
+	"?": true, "?|": true, "?&": true,
+	"@>": true, "<@": true, "->": true, "->>": true,
 }
 
 // Position holds the location of the token
 // within the statement containing it.
 type Position struct {
 	// Offset is the character offset within the containing statement.
-	Offset int
+	Offset int `json:"offset"`
 
 	// Line indicates the line on which the token occurs.
-	Line int
+	Line int `json:"line"`
 
 	// Column indicates the textual column on which the token occurs.
-	Column int
+	Column int `json:"column"`
 }
 
 // Token describes the smallest part of a larger DSL statement
 // that is able to reasoned about by the parser.
 type Token struct {
 	// Type is the type of this token.
-	Type TokenType
+	Type TokenType `json:"type"`
 
 	// Literal is the string value of the token.
-	Literal string
+	Literal string `json:"literal"`
 
 	// Pos is the offset of this token within a statement.
-	Pos Position
+	Pos Position `json:"pos"`
+
+	// Adjacent is true if the token immediately follows the one preceding
+	// it, without whitespace or comments between them, as do the tokens
+	// "c" and "]" of "[c]". The parts of such a sequence are written to
+	// the database without space between them.
+	Adjacent bool `json:"adjacent,omitempty"`
 }
@@ -1,6 +1,7 @@
 package reflect
 
 import (
+	"container/list"
 	"reflect"
 	"strings"
 	"sync"
@@ -8,34 +9,92 @@ import (
 	"github.com/pkg/errors"
 )
 
-// Cache is responsible for generating, caching and retrieving reflection
-// information for use in parsing and executing Sqlair DSL statements.
-type cache struct {
-	mutex sync.RWMutex
-	cache map[reflect.Type]Info
+// TypeCache is responsible for generating, caching and retrieving
+// reflection information for use in parsing and executing Sqlair DSL
+// statements. When it holds its maximum number of types, the least
+// recently reflected type is evicted to make room for another.
+type TypeCache struct {
+	mutex sync.Mutex
+	cache map[reflect.Type]*list.Element
+
+	// lru holds the cached Info, most recently reflected first.
+	lru *list.List
+
+	// maxSize is the maximum number of types held.
+	// Zero means that the size is unbounded.
+	maxSize int
+
+	stats Stats
+}
+
+// Stats holds counters describing the use of a cache.
+type Stats struct {
+	// Hits is the number of requests served from the cache.
+	Hits uint64
+
+	// Misses is the number of requests for which
+	// reflection information was generated.
+	Misses uint64
+
+	// Evictions is the number of types evicted from the cache.
+	Evictions uint64
+
+	// Size is the number of types currently held.
+	Size int
+}
+
+// NewCache returns a reference to a new TypeCache holding at most maxSize
+// types, or an unbounded number if maxSize is zero. It is independent of
+// the cache returned by Cache, so tests can use it in isolation.
+func NewCache(maxSize int) *TypeCache {
+	return &TypeCache{
+		cache:   make(map[reflect.Type]*list.Element),
+		lru:     list.New(),
+		maxSize: maxSize,
+	}
 }
 
 // Reflect will return the Info of a given type,
 // generating and caching as required.
-func (r *cache) Reflect(value any) (Info, error) {
+func (r *TypeCache) Reflect(value any) (Info, error) {
 	v := reflect.ValueOf(value)
 	v = reflect.Indirect(v)
 
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if rs, ok := r.cache[v.Type()]; ok {
-		return rs, nil
+	if elem, ok := r.cache[v.Type()]; ok {
+		r.stats.Hits++
+		r.lru.MoveToFront(elem)
+		return elem.Value.(Info), nil
 	}
 
+	r.stats.Misses++
 	ri, err := generate(v)
 	if err != nil {
 		return Struct{}, err
 	}
-	r.cache[v.Type()] = ri
+
+	if r.maxSize > 0 && r.lru.Len() >= r.maxSize {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.cache, oldest.Value.(Info).Type())
+		r.stats.Evictions++
+	}
+	r.cache[v.Type()] = r.lru.PushFront(ri)
 	return ri, nil
 }
 
+// Stats returns the counters for the cache's use to date.
+func (r *TypeCache) Stats() Stats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stats := r.stats
+	stats.Size = r.lru.Len()
+	return stats
+}
+
 // generate produces and returns reflection information for the input
 // reflect.Value that is specifically required for Sqlair operation.
 func generate(value reflect.Value) (Info, error) {
@@ -63,33 +122,43 @@ func generate(value reflect.Value) (Info, error) {
 			continue
 		}
 
-		tag, omitEmpty, err := parseTag(tag)
+		tag, f, err := parseTag(tag)
 		if err != nil {
 			return Value{}, err
 		}
 
-		info.Fields[tag] = Field{
-			Name:      field.Name,
-			OmitEmpty: omitEmpty,
-			value:     value.Field(i),
-		}
+		f.Name = field.Name
+		f.Index = i
+		info.Fields[tag] = f
 	}
 
 	return info, nil
 }
 
-// parseTag parses the input tag string and returns its
-// name and whether it contains the "omitempty" option.
-func parseTag(tag string) (string, bool, error) {
+// parseTag parses the input tag string and returns its name, along with
+// a field having the properties given by its options: "omitempty",
+// "redact", "autoincrement", "pk", and any other, which names the
+// transformer for its values.
+func parseTag(tag string) (string, Field, error) {
 	options := strings.Split(tag, ",")
 
-	var omitEmpty bool
-	if len(options) > 1 {
-		if strings.ToLower(options[1]) != "omitempty" {
-			return "", false, errors.Errorf("unexpected tag value %q", options[1])
+	var field Field
+	for _, option := range options[1:] {
+		switch {
+		case strings.ToLower(option) == "omitempty":
+			field.OmitEmpty = true
+		case strings.ToLower(option) == "redact":
+			field.Redact = true
+		case strings.ToLower(option) == "autoincrement":
+			field.AutoIncrement = true
+		case strings.ToLower(option) == "pk":
+			field.PrimaryKey = true
+		case field.Transform == "" && option != "":
+			field.Transform = option
+		default:
+			return "", Field{}, errors.Errorf("unexpected tag value %q", option)
 		}
-		omitEmpty = true
 	}
 
-	return options[0], omitEmpty, nil
+	return options[0], field, nil
 }
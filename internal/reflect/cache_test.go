@@ -62,11 +62,13 @@ func TestReflectStruct(t *testing.T) {
 	id, ok := st.Fields["id"]
 	assert.True(t, ok)
 	assert.Equal(t, "ID", id.Name)
+	assert.Equal(t, 0, id.Index)
 	assert.False(t, id.OmitEmpty)
 
 	name, ok := st.Fields["name"]
 	assert.True(t, ok)
 	assert.Equal(t, "Name", name.Name)
+	assert.Equal(t, 1, name.Index)
 	assert.True(t, name.OmitEmpty)
 }
 
@@ -79,4 +81,137 @@ func TestReflectBadTagError(t *testing.T) {
 
 	_, err := Cache().Reflect(s)
 	assert.Error(t, errors.New(`unexpected tag value "bad-juju"`), err)
+
+	type another struct {
+		ID int64 `db:"id,encrypted,hashed"`
+	}
+
+	_, err = Cache().Reflect(another{})
+	assert.EqualError(t, err, `unexpected tag value "hashed"`)
+}
+
+func TestReflectTransformTag(t *testing.T) {
+	type something struct {
+		SSN  string `db:"ssn,encrypted"`
+		Name string `db:"name,omitempty,upper"`
+		Pass string `db:"password,redact,encrypted"`
+		ID   int64  `db:"id,autoincrement"`
+	}
+
+	info, err := Cache().Reflect(something{})
+	assert.Nil(t, err)
+
+	fields := info.(Struct).Fields
+	assert.Equal(t, "encrypted", fields["ssn"].Transform)
+	assert.False(t, fields["ssn"].OmitEmpty)
+	assert.Equal(t, "upper", fields["name"].Transform)
+	assert.True(t, fields["name"].OmitEmpty)
+	assert.False(t, fields["name"].Redact)
+	assert.Equal(t, "encrypted", fields["password"].Transform)
+	assert.True(t, fields["password"].Redact)
+	assert.True(t, fields["id"].AutoIncrement)
+	assert.Equal(t, "", fields["id"].Transform)
+}
+
+func TestReflectStructTags(t *testing.T) {
+	type something struct {
+		Zed   string `db:"zed"`
+		Alpha string `db:"alpha"`
+		Skip  string
+		Mid   string `db:"mid"`
+	}
+
+	info, err := Cache().Reflect(something{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"zed", "alpha", "mid"}, info.(Struct).Tags())
+}
+
+func TestReflectPrimaryKey(t *testing.T) {
+	type membership struct {
+		Role    string `db:"role"`
+		GroupID string `db:"group_id,pk"`
+		UserID  string `db:"user_id,PK"`
+	}
+
+	info, err := Cache().Reflect(membership{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"group_id", "user_id"}, info.(Struct).PrimaryKey())
+	assert.Equal(t, "", info.(Struct).Fields["user_id"].Transform)
+}
+
+func TestReflectCacheStats(t *testing.T) {
+	c := NewCache(0)
+
+	_, err := c.Reflect(int64(1))
+	assert.Nil(t, err)
+	_, err = c.Reflect(int64(2))
+	assert.Nil(t, err)
+	_, err = c.Reflect("string")
+	assert.Nil(t, err)
+
+	assert.Equal(t, Stats{Hits: 1, Misses: 2, Size: 2}, c.Stats())
+}
+
+func TestReflectCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	type a struct{}
+	type b struct{}
+	type c struct{}
+
+	cache := NewCache(2)
+
+	for _, v := range []any{a{}, b{}, a{}, c{}} {
+		_, err := cache.Reflect(v)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, Stats{Hits: 1, Misses: 3, Evictions: 1, Size: 2}, cache.Stats())
+
+	// b was evicted, as a was reflected more recently.
+	_, err := cache.Reflect(a{})
+	assert.Nil(t, err)
+	_, err = cache.Reflect(b{})
+	assert.Nil(t, err)
+	assert.Equal(t, Stats{Hits: 2, Misses: 4, Evictions: 2, Size: 2}, cache.Stats())
+}
+
+func TestReflectCachesAreIsolated(t *testing.T) {
+	type isolated struct{}
+
+	c := NewCache(0)
+	_, err := c.Reflect(isolated{})
+	assert.Nil(t, err)
+
+	assert.Equal(t, Stats{Misses: 1, Size: 1}, c.Stats())
+	assert.Equal(t, Stats{Size: 0}, NewCache(0).Stats())
+	assert.NotSame(t, c, Cache())
+}
+
+type box[T any] struct {
+	Value T `db:"value"`
+}
+
+type pair[A, B any] struct{}
+
+func TestTypeName(t *testing.T) {
+	type person struct{}
+
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{person{}, "person"},
+		{box[person]{}, "box[person]"},
+		{box[box[person]]{}, "box[box[person]]"},
+		{pair[*person, []map[string]int]{}, "pair[*person,[]map[string]int]"},
+		{pair[int, errors.Frame]{}, "pair[int,Frame]"},
+		{struct{ A int }{}, ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, TypeName(reflect.TypeOf(test.value)))
+	}
+
+	info, err := Cache().Reflect(box[person]{})
+	assert.Nil(t, err)
+	assert.Equal(t, "box[person]", info.Name())
+	assert.Contains(t, info.(Struct).Fields, "value")
 }
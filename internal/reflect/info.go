@@ -2,12 +2,16 @@ package reflect
 
 import (
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 )
 
 // Info describes the ability to return reflection information.
 type Info interface {
 	Name() string
 	Kind() reflect.Kind
+	Type() reflect.Type
 }
 
 // Value represents reflection information for a simple type.
@@ -23,19 +27,43 @@ func (r Value) Kind() reflect.Kind {
 
 // Name returns the name of the Value's type.
 func (r Value) Name() string {
-	return r.value.Type().Name()
+	return TypeName(r.value.Type())
+}
+
+// Type returns the Value's reflect.Type.
+func (r Value) Type() reflect.Type {
+	return r.value.Type()
 }
 
 // Field represents a single field from a struct type.
 type Field struct {
-	value reflect.Value
-
 	// Name is the name of the struct field.
 	Name string
 
+	// Index is the index of the field within its struct,
+	// suitable for use with reflect.Value.Field.
+	Index int
+
 	// OmitEmpty is true when "omitempty" is
 	// a property of the field's "db" tag.
 	OmitEmpty bool
+
+	// Redact is true when "redact" is a property of the field's "db"
+	// tag, so that its values are not written to logs or error messages.
+	Redact bool
+
+	// AutoIncrement is true when "autoincrement" is a property of the
+	// field's "db" tag, so that it receives the key generated by the
+	// database when a row is inserted.
+	AutoIncrement bool
+
+	// PrimaryKey is true when "pk" is a property of the field's "db" tag.
+	// The primary key of a struct may comprise more than one field.
+	PrimaryKey bool
+
+	// Transform is the name of any other option of the field's "db" tag,
+	// such as "encrypted", naming the transformer for its values.
+	Transform string
 }
 
 // Struct represents reflected information about a struct type.
@@ -47,6 +75,31 @@ type Struct struct {
 	Fields map[string]Field
 }
 
+// Tags returns the "db" tags of the Struct's fields,
+// in the order in which the fields are declared.
+func (r Struct) Tags() []string {
+	tags := make([]string, 0, len(r.Fields))
+	for tag := range r.Fields {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return r.Fields[tags[i]].Index < r.Fields[tags[j]].Index
+	})
+	return tags
+}
+
+// PrimaryKey returns the "db" tags of the fields that comprise
+// the Struct's primary key, in the order in which they are declared.
+func (r Struct) PrimaryKey() []string {
+	var tags []string
+	for _, tag := range r.Tags() {
+		if r.Fields[tag].PrimaryKey {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
 // Kind returns the Struct's reflect.Kind.
 func (r Struct) Kind() reflect.Kind {
 	return r.value.Kind()
@@ -54,5 +107,34 @@ func (r Struct) Kind() reflect.Kind {
 
 // Name returns the name of the Struct's type.
 func (r Struct) Name() string {
-	return r.value.Type().Name()
+	return TypeName(r.value.Type())
+}
+
+// Type returns the Struct's reflect.Type.
+func (r Struct) Type() reflect.Type {
+	return r.value.Type()
+}
+
+// packageQualifier matches the package path qualifying
+// a type name, such as "github.com/org/pkg." in a type argument.
+var packageQualifier = regexp.MustCompile(`[^\[\]*,{};\s]+\.`)
+
+// localTypeSuffix matches the suffix distinguishing a type declared
+// in a function, such as "·1" in a type argument.
+var localTypeSuffix = regexp.MustCompile(`·\d+`)
+
+// TypeName returns the name by which the input type is referred to in
+// Sqlair DSL statements. This is the reflected name of the type, except
+// that package paths are removed from the arguments of instantiated
+// generic types, so that Page[pkg.Person] is named "Page[Person]".
+// The suffixes distinguishing types declared in functions are also removed.
+// Unnamed types, such as anonymous structs, have an empty name.
+func TypeName(t reflect.Type) string {
+	name := t.Name()
+	i := strings.IndexByte(name, '[')
+	if i < 0 {
+		return name
+	}
+	args := packageQualifier.ReplaceAllString(name[i:], "")
+	return name[:i] + localTypeSuffix.ReplaceAllString(args, "")
 }
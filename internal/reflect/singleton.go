@@ -1,22 +1,23 @@
 package reflect
 
 import (
-	"reflect"
 	"sync"
 )
 
+// DefaultCacheSize is the maximum number of
+// types held by the cache returned by Cache.
+const DefaultCacheSize = 1024
+
 var (
-	singleCache *cache
+	singleCache *TypeCache
 	once        sync.Once
 )
 
 // Cache enforces the singleton pattern,
-// ensuring access to a single instance of cache.
-func Cache() *cache {
+// ensuring access to a single instance of TypeCache.
+func Cache() *TypeCache {
 	once.Do(func() {
-		singleCache = &cache{
-			cache: make(map[reflect.Type]Info),
-		}
+		singleCache = NewCache(DefaultCacheSize)
 	})
 
 	return singleCache
@@ -0,0 +1,123 @@
+package sqlair
+
+import (
+	"strings"
+
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// KeyCondition returns a Condition matching the row identified by the
+// primary key of the input object's type, which comprises its fields with
+// the "pk" option in their "db" tags, such as `db:"id,pk"`. Where more than
+// one field is tagged, the condition compares each of them, in the order
+// in which they are declared. Columns that are reserved words are quoted.
+//
+// Example:
+//
+//     type Membership struct {
+//         GroupID string `db:"group_id,pk"`
+//         UserID  string `db:"user_id,pk"`
+//         Role    string `db:"role"`
+//     }
+//
+//     cond, err := sqlair.KeyCondition(Membership{})
+//     // group_id = $Membership.group_id AND user_id = $Membership.user_id
+//
+//     stmt, err = stmt.Where(cond)
+//
+func KeyCondition(obj any) (*Condition, error) {
+	argTypes, err := typesForStatement([]any{obj})
+	if err != nil {
+		return nil, err
+	}
+	name, _ := objectName(obj)
+
+	predicate, err := keyPredicate(name, argTypes[name])
+	if err != nil {
+		return nil, err
+	}
+	key := argTypes[name].(sqlairreflect.Struct).PrimaryKey()
+	return PrepareCondition(predicate, withGeneratedNames(key), obj)
+}
+
+// keyPredicate returns the DSL predicate comparing each of the primary
+// key columns of the input type information with the input source for
+// the same column of the named type.
+func keyPredicate(name string, info sqlairreflect.Info) (string, error) {
+	st, ok := info.(sqlairreflect.Struct)
+	if !ok {
+		return "", errors.Errorf("type %q is not a struct", name)
+	}
+
+	key := st.PrimaryKey()
+	if len(key) == 0 {
+		return "", errors.Errorf(`type %q has no field tagged "pk"`, name)
+	}
+
+	comparisons := make([]string, len(key))
+	for i, column := range key {
+		comparisons[i] = generatedName(column) + " = $" + name + "." + column
+	}
+	return strings.Join(comparisons, " AND "), nil
+}
+
+// generatedName returns the input column name as it is written in the
+// DSL of a generated statement: quoted if it is a reserved word of the
+// default dialect, such as "order", or can not be written as a bare
+// identifier. Names recorded by withGeneratedNames are written into the
+// SQL for the dialect of the DB executing the statement.
+func generatedName(name string) string {
+	return Dialect{}.identifier(name, !QuoteInvalidIdentifiers.isBare(name))
+}
+
+// generatedTable returns the input table name, which may be qualified
+// by a schema, as in "main.person", as it is written in the DSL of a
+// generated statement: with each part quoted if it is a reserved word of
+// the default dialect. An error is returned if a part is not a valid bare
+// identifier, so that SQL can not be written in place of a name.
+func generatedTable(table string) (string, error) {
+	parts, err := tableParts(table)
+	if err != nil {
+		return "", err
+	}
+	for i, part := range parts {
+		parts[i] = Dialect{}.identifier(part, false)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// generatedNames returns the names written by generatedName and
+// generatedTable into the statements generated for the input type
+// information and table: the type's tags and the parts of the table's
+// name, which has been validated.
+func generatedNames(info sqlairreflect.Struct, table string) []string {
+	parts, _ := tableParts(table)
+	return append(info.Tags(), parts...)
+}
+
+// withGeneratedNames returns an option recording the input names, written
+// into the DSL of a generated statement by generatedName and
+// generatedTable, so that they are written into its SQL for the dialect
+// of the DB executing it; see Dialect.ReservedWords.
+func withGeneratedNames(names []string) PrepareOption {
+	return func(o *prepareOptions) {
+		o.generated = append(o.generated, names...)
+	}
+}
+
+// tableParts returns the parts of the input table name: the schema, if
+// it is qualified by one, followed by the table. An error is returned if
+// there are more than two parts or a part is not a valid bare identifier.
+func tableParts(table string) ([]string, error) {
+	parts := strings.Split(table, ".")
+	if len(parts) > 2 {
+		return nil, errors.Errorf("invalid table name %q", table)
+	}
+	for _, part := range parts {
+		if !QuoteInvalidIdentifiers.isBare(part) {
+			return nil, errors.Errorf("invalid table name %q", table)
+		}
+	}
+	return parts, nil
+}
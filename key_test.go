@@ -0,0 +1,64 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+type Membership struct {
+	GroupID string `db:"group_id,pk"`
+	Role    string `db:"role"`
+	UserID  string `db:"user_id,pk"`
+}
+
+func TestKeyCondition(t *testing.T) {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE membership (group_id TEXT, user_id TEXT, role TEXT)"); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO membership VALUES ('a', '1', 'admin'), ('a', '2', 'user'), ('b', '1', 'user')`)
+		return err
+	})
+
+	cond, err := KeyCondition(Membership{})
+	assert.Nil(t, err)
+
+	stmt, err := Prepare("SELECT &Membership.* FROM membership", Membership{})
+	assert.Nil(t, err)
+	stmt, err = stmt.Where(cond)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT group_id, role, user_id FROM membership WHERE (group_id = ? AND user_id = ?)", stmt.sql)
+
+	var m Membership
+	err = NewDB(db).Query(context.Background(), stmt, Membership{GroupID: "b", UserID: "1"}).Get(&m)
+	assert.Nil(t, err)
+	assert.Equal(t, Membership{GroupID: "b", UserID: "1", Role: "user"}, m)
+}
+
+func TestKeyConditionReservedWord(t *testing.T) {
+	type Line struct {
+		Order int    `db:"order,pk"`
+		Text  string `db:"text"`
+	}
+	cond, err := KeyCondition(Line{})
+	assert.Nil(t, err)
+
+	stmt, err := Prepare(`SELECT &Line.* FROM line`, Line{})
+	assert.Nil(t, err)
+	stmt, err = stmt.Where(cond)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT "order", text FROM line WHERE ("order" = ?)`, stmt.sql)
+}
+
+func TestKeyConditionErrors(t *testing.T) {
+	_, err := KeyCondition(sqlairtesting.Person{})
+	assert.EqualError(t, err, `type "Person" has no field tagged "pk"`)
+
+	_, err = KeyCondition(1)
+	assert.EqualError(t, err, `type "int" is not a struct`)
+}
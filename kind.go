@@ -0,0 +1,76 @@
+package sqlair
+
+import "github.com/canonical/sqlair/internal/parse"
+
+// StatementKind identifies the type of a statement, as determined by the
+// keyword with which it begins, following any WITH clause.
+type StatementKind int
+
+const (
+	// KindOther is the kind of a statement that is not
+	// of one of the other kinds, such as a GRANT.
+	KindOther StatementKind = iota
+
+	// KindSelect is the kind of a query that only reads, such as a SELECT,
+	// a compound query such as a UNION, or a VALUES list.
+	KindSelect
+
+	// KindInsert is the kind of an INSERT, or REPLACE, statement.
+	KindInsert
+
+	// KindUpdate is the kind of an UPDATE statement.
+	KindUpdate
+
+	// KindDelete is the kind of a DELETE statement.
+	KindDelete
+
+	// KindDDL is the kind of a statement that modifies
+	// the schema, such as CREATE TABLE.
+	KindDDL
+
+	// KindAdmin is the kind of an administrative statement, such as
+	// a PRAGMA, SET, VACUUM or ANALYZE, which is prepared and run like
+	// any other, without needing type information.
+	KindAdmin
+)
+
+// statementKindNames holds the name of each StatementKind.
+var statementKindNames = map[StatementKind]string{
+	KindOther:  "other",
+	KindSelect: "select",
+	KindInsert: "insert",
+	KindUpdate: "update",
+	KindDelete: "delete",
+	KindDDL:    "DDL",
+	KindAdmin:  "admin",
+}
+
+// String implements fmt.Stringer, returning the name of the kind.
+func (k StatementKind) String() string {
+	return statementKindNames[k]
+}
+
+// Kind returns the kind of the statement, from its expression tree, so that
+// middleware such as that splitting reads from writes can branch on it.
+// A statement of kind KindOther or KindAdmin must be
+// assumed to modify the database.
+func (s *Statement) Kind() StatementKind {
+	switch parse.Classify(s.expression) {
+	case parse.KindQuery:
+		return KindSelect
+	case parse.KindDDL:
+		return KindDDL
+	case parse.KindAdmin:
+		return KindAdmin
+	case parse.KindDML:
+		switch {
+		case isInsert(s.expression):
+			return KindInsert
+		case isUpdate(s.expression):
+			return KindUpdate
+		case isStatement(s.expression, "DELETE"):
+			return KindDelete
+		}
+	}
+	return KindOther
+}
@@ -0,0 +1,50 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatementKind(t *testing.T) {
+	tests := []struct {
+		stmt string
+		kind StatementKind
+	}{
+		{"SELECT * FROM person", KindSelect},
+		{"WITH p AS (SELECT * FROM person) SELECT * FROM p", KindSelect},
+		{"SELECT id FROM a UNION SELECT id FROM b", KindSelect},
+		{"VALUES (1, 2), (3, 4)", KindSelect},
+		{"INSERT INTO person (id) VALUES (1)", KindInsert},
+		{"REPLACE INTO person (id) VALUES (1)", KindInsert},
+		{"WITH p AS (SELECT 1) UPDATE person SET name = 'Fred'", KindUpdate},
+		{"DELETE FROM person WHERE id = 1", KindDelete},
+		{"CREATE TABLE person (id TEXT)", KindDDL},
+		{"DROP TABLE person", KindDDL},
+		{"PRAGMA foreign_keys = ON", KindAdmin},
+		{"SET search_path = public", KindAdmin},
+		{"VACUUM", KindAdmin},
+		{"ANALYZE", KindAdmin},
+		{"GRANT SELECT ON person TO reader", KindOther},
+	}
+
+	for _, test := range tests {
+		stmt, err := Prepare(test.stmt)
+		if assert.Nil(t, err, test.stmt) {
+			assert.Equal(t, test.kind, stmt.Kind(), test.stmt)
+		}
+	}
+	assert.Equal(t, "delete", KindDelete.String())
+}
+
+func TestAdminStatementExec(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+	for _, sql := range []string{"PRAGMA foreign_keys = ON", "ANALYZE person", "VACUUM"} {
+		stmt, err := Prepare(sql)
+		if assert.Nil(t, err, sql) {
+			_, err = db.Exec(context.Background(), stmt)
+			assert.Nil(t, err, sql)
+		}
+	}
+}
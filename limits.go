@@ -0,0 +1,62 @@
+package sqlair
+
+import (
+	"github.com/canonical/sqlair/internal/parse"
+	"github.com/pkg/errors"
+)
+
+// ParseLimits bound the size and complexity of the statements that are
+// accepted, protecting long-running services that prepare statements from
+// untrusted or generated input. A statement exceeding one of the limits is
+// refused with an ErrParseLimitExceeded. A limit of zero is no limit. They
+// are set by WithParseLimits.
+type ParseLimits struct {
+	// MaxLength is the maximum length of a statement in bytes.
+	MaxLength int
+
+	// MaxTokens is the maximum number of tokens in a statement,
+	// excluding comments.
+	MaxTokens int
+
+	// MaxDepth is the maximum depth to which expressions
+	// may be nested, as by parentheses or operators.
+	MaxDepth int
+}
+
+// WithParseLimits returns an option bounding the statements
+// accepted by the input limits.
+//
+// Example:
+//
+//     limits := sqlair.ParseLimits{MaxLength: 64 << 10, MaxTokens: 10000, MaxDepth: 100}
+//
+//     stmt, err := sqlair.Prepare(query, sqlair.WithParseLimits(limits), Person{})
+//
+func WithParseLimits(limits ParseLimits) PrepareOption {
+	return func(o *prepareOptions) {
+		o.limits = limits
+	}
+}
+
+// newParser returns a reference to a new Parser for the
+// input lexer that accepts statements within the limits.
+func (l ParseLimits) newParser(lex *parse.Lexer) *parse.Parser {
+	p := parse.NewParser(lex)
+	p.SetLimits(parse.Limits{
+		MaxLength: l.MaxLength,
+		MaxTokens: l.MaxTokens,
+		MaxDepth:  l.MaxDepth,
+	})
+	return p
+}
+
+// limitError returns an ErrParseLimitExceeded for the input error if it is
+// from the parser refusing a statement that exceeds one of its limits.
+// Otherwise the input error is returned unchanged.
+func limitError(err error) error {
+	var parseErr *parse.Error
+	if !errors.As(err, &parseErr) || parseErr.Limit == "" {
+		return err
+	}
+	return NewErrParseLimitExceeded(parseErr.Limit, err)
+}
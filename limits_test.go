@@ -0,0 +1,41 @@
+package sqlair
+
+import (
+	"strings"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareParseLimits(t *testing.T) {
+	limits := ParseLimits{MaxLength: 64, MaxTokens: 16, MaxDepth: 8}
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id = $Person.id", WithParseLimits(limits), sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Len(t, stmt.argTypes, 1)
+
+	query := "SELECT &Person.* FROM person WHERE id IN (" + strings.Repeat("'1', ", 20) + "'1')"
+	_, err = Prepare(query, WithParseLimits(limits), sqlairtesting.Person{})
+	assert.EqualError(t, err, "statement exceeds maximum of 16 tokens at line 1, column 56")
+
+	var limitErr *ErrParseLimitExceeded
+	if assert.ErrorAs(t, err, &limitErr) {
+		assert.Equal(t, "MaxTokens", limitErr.Limit())
+	}
+
+	_, err = PrepareReader(strings.NewReader(query), WithParseLimits(ParseLimits{MaxLength: 64}), sqlairtesting.Person{})
+	if assert.ErrorAs(t, err, &limitErr) {
+		assert.Equal(t, "MaxLength", limitErr.Limit())
+	}
+
+	_, err = PrepareCondition("id = "+strings.Repeat("(", 10)+"1"+strings.Repeat(")", 10), WithParseLimits(ParseLimits{MaxDepth: 8}))
+	if assert.ErrorAs(t, err, &limitErr) {
+		assert.Equal(t, "MaxDepth", limitErr.Limit())
+	}
+
+	// The limits are exceeded at a position in the Go source.
+	_, err = Prepare(query, WithLocation(Location{File: "queries.go", Line: 10}), WithParseLimits(limits), sqlairtesting.Person{})
+	assert.EqualError(t, err, "queries.go:10: statement exceeds maximum of 16 tokens at line 1, column 56")
+	assert.ErrorAs(t, err, &limitErr)
+}
@@ -0,0 +1,59 @@
+package sqlair
+
+import (
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/canonical/sqlair/internal/parse"
+	"github.com/pkg/errors"
+)
+
+// Location is a position in Go source code: the line on which the literal
+// for a DSL statement begins. It is set by WithLocation.
+type Location struct {
+	File string
+	Line int
+}
+
+// Here returns the Location of the line on which it is called.
+// For it to locate a statement, the call must be on the line at which the
+// statement's literal begins.
+//
+// Example:
+//
+//     loc, query := sqlair.Here(), `
+//     SELECT &Person.*
+//       FROM person`
+//
+//     stmt, err := sqlair.Prepare(query, sqlair.WithLocation(loc), Person{})
+//
+func Here() Location {
+	_, file, line, _ := runtime.Caller(1)
+	return Location{File: file, Line: line}
+}
+
+// WithLocation returns an option giving the Location of the statement's
+// literal, so that errors parsing it report the file and line of the
+// Go source at which they occur.
+func WithLocation(loc Location) PrepareOption {
+	return func(o *prepareOptions) {
+		o.location = &loc
+	}
+}
+
+// locateError returns the input error annotated with the Go source position
+// corresponding to its position within the input statement, if it is an
+// error from the parser. Otherwise the input error is returned unchanged.
+func (l Location) locateError(stmt string, err error) error {
+	var parseErr *parse.Error
+	if !errors.As(err, &parseErr) {
+		return err
+	}
+
+	// The parser numbers lines from the first that is not blank.
+	leading := stmt[:len(stmt)-len(strings.TrimLeftFunc(stmt, unicode.IsSpace))]
+	line := l.Line + strings.Count(leading, "\n") + parseErr.Pos.Line - 1
+
+	return errors.Wrapf(err, "%s:%d", l.File, line)
+}
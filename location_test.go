@@ -0,0 +1,42 @@
+package sqlair
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/canonical/sqlair/internal/parse"
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareWithLocation(t *testing.T) {
+	_, file, line, _ := runtime.Caller(0)
+	loc, query := Here(), `
+SELECT &Person.*
+  FROM person
+ WHERE id = (1`
+
+	_, err := Prepare(query, WithLocation(loc), sqlairtesting.Person{})
+	assert.EqualError(t, err, fmt.Sprintf("%s:%d: unclosed parenthesis at line 3, column 13", file, line+4))
+
+	var parseErr *parse.Error
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 3, parseErr.Pos.Line)
+
+	// The location is not mistaken for a type object.
+	stmt, err := Prepare(query[:len(query)-3]+"1", WithLocation(loc), sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Len(t, stmt.argTypes, 1)
+}
+
+func TestPrepareConditionWithLocation(t *testing.T) {
+	loc := Location{File: "conditions.go", Line: 10}
+
+	_, err := PrepareCondition("id = (1", WithLocation(loc))
+	assert.EqualError(t, err, "conditions.go:10: unclosed parenthesis at line 1, column 6")
+
+	_, err = PrepareCondition("id = (1")
+	assert.EqualError(t, err, "unclosed parenthesis at line 1, column 6")
+}
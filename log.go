@@ -0,0 +1,175 @@
+package sqlair
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+)
+
+// Redacted replaces the values of fields with the "redact" option in their
+// "db" tags, such as `db:"password,redact"`, in log entries and in the
+// messages of errors returned when running statements.
+const Redacted = "***"
+
+// Logger receives an entry for each statement that a DB executes on the
+// database, once the database has responded, along with the context in
+// which the statement was run.
+type Logger func(ctx context.Context, entry LogEntry)
+
+// LogEntry describes the execution of a statement.
+type LogEntry struct {
+	// SQL is the statement's SQL as prepared, before it is rewritten
+	// for the DB's dialect, with a placeholder for each parameter.
+	SQL string
+
+	// Args holds the parameters bound from the inputs, in the order of the
+	// input bindings of the statement's BindingPlan. Those of redacted
+	// fields are replaced by Redacted.
+	Args []any
+
+	// Duration is the time taken by the database to execute the statement.
+	Duration time.Duration
+
+	// Err is any error returned by the database, redacted
+	// in the same way as the error returned to the caller.
+	Err error
+}
+
+// WithLogger returns an option with which a DB passes an entry for each
+// statement executed to the input logger. Results served from a result
+// cache are not logged.
+//
+// Example:
+//
+//     db = db.With(sqlair.WithLogger(func(ctx context.Context, e sqlair.LogEntry) {
+//         log.Printf("%s %v (%s): %v", e.SQL, e.Args, e.Duration, e.Err)
+//     }))
+//
+func WithLogger(l Logger) DBOption {
+	return func(db *DB) {
+		db.logger = l
+	}
+}
+
+// logExecution passes an entry for the execution of the input statement,
+// with the input parameters bound from its inputs, to the DB's logger,
+// if it has one. It returns the input error, redacted.
+func (db *DB) logExecution(ctx context.Context, s *Statement, args []any, start time.Time, err error) error {
+	err = s.redactError(err, args)
+	if db.logger != nil {
+		db.logger(ctx, LogEntry{
+			SQL:      s.sql,
+			Args:     s.redactArgs(args),
+			Duration: time.Since(start),
+			Err:      err,
+		})
+	}
+	return err
+}
+
+// redactArgs returns a copy of the input parameters, bound from the
+// statement's inputs, with those of redacted fields replaced.
+func (s *Statement) redactArgs(args []any) []any {
+	redacted := make([]any, len(args))
+	for i, arg := range args {
+		if i < len(s.inputs) && s.inputs[i].field.Redact {
+			arg = Redacted
+		}
+		redacted[i] = arg
+	}
+	return redacted
+}
+
+// redactError returns the input error with the parameters of redacted
+// fields, from among the input parameters bound from the statement's
+// inputs, replaced in its message.
+func (s *Statement) redactError(err error, args []any) error {
+	var secrets []any
+	for i, in := range s.inputs {
+		if in.field.Redact && i < len(args) {
+			secrets = append(secrets, args[i])
+		}
+	}
+	return redactError(err, secrets)
+}
+
+// redactFields returns the input error with the values of the redacted
+// fields of the input struct, described by the input information,
+// replaced in its message.
+func redactFields(err error, info sqlairreflect.Info, v reflect.Value) error {
+	st, ok := info.(sqlairreflect.Struct)
+	if !ok || err == nil {
+		return err
+	}
+
+	var secrets []any
+	for _, field := range st.Fields {
+		if field.Redact {
+			secrets = append(secrets, v.Field(field.Index).Interface())
+		}
+	}
+	return redactError(err, secrets)
+}
+
+// redactError returns the input error with the text of each of the input
+// values replaced in its message. The error is returned unchanged if its
+// message contains none of them.
+func redactError(err error, secrets []any) error {
+	if err == nil || len(secrets) == 0 {
+		return err
+	}
+
+	msg := err.Error()
+	for _, secret := range secrets {
+		if text := secretText(secret); text != "" {
+			msg = strings.ReplaceAll(msg, text, Redacted)
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{err: err, msg: msg}
+}
+
+// secretText returns the text of the input value as it
+// would be written in an error message.
+func secretText(secret any) string {
+	v := reflect.ValueOf(secret)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() == reflect.Ptr {
+		return ""
+	}
+	if b, ok := v.Interface().([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// redactedError is an error whose message has had secrets removed.
+type redactedError struct {
+	err error
+	msg string
+}
+
+// Error implements error.
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+// Cause returns the error with the original message,
+// for use with errors.Cause.
+func (e *redactedError) Cause() error {
+	return e.err
+}
+
+// Unwrap returns the error with the original
+// message, for use with errors.Is and errors.As.
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,96 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type Login struct {
+	Name     string `db:"name"`
+	Password string `db:"password,redact"`
+}
+
+func (a Login) Validate() error {
+	if len(a.Password) < 8 {
+		return errors.Errorf("password %q is too short", a.Password)
+	}
+	return nil
+}
+
+func TestLogger(t *testing.T) {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE login (name TEXT, password TEXT)")
+		return err
+	})
+
+	var entries []LogEntry
+	sqlairDB := NewDB(db).With(WithLogger(func(_ context.Context, e LogEntry) {
+		entries = append(entries, e)
+	}))
+	ctx := context.Background()
+
+	insert, err := Prepare("INSERT INTO login (name, password) VALUES ($Login.name, $Login.password)", Login{})
+	assert.Nil(t, err)
+	_, err = sqlairDB.Exec(ctx, insert, Login{Name: "fred", Password: "correct horse"})
+	assert.Nil(t, err)
+
+	// The logger is kept by DBs derived from this one.
+	selectStmt, err := Prepare("SELECT &Login.* FROM login WHERE password = $Login.password", Login{})
+	assert.Nil(t, err)
+	var got Login
+	err = sqlairDB.With(WithDialect(Dialect{})).Query(ctx, selectStmt, Login{Password: "correct horse"}).Get(&got)
+	assert.Nil(t, err)
+	assert.Equal(t, Login{Name: "fred", Password: "correct horse"}, got)
+
+	_, err = sqlairDB.Exec(ctx, insert, Login{Name: "fred", Password: "hunter2"})
+	assert.EqualError(t, err, `input "Login" is invalid: password "***" is too short`)
+
+	var invalid *ErrInvalidInput
+	assert.ErrorAs(t, err, &invalid)
+
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, insert.sql, entries[0].SQL)
+		assert.Equal(t, []any{"fred", "***"}, entries[0].Args)
+		assert.Nil(t, entries[0].Err)
+		assert.Equal(t, []any{"***"}, entries[1].Args)
+	}
+}
+
+func TestLoggerError(t *testing.T) {
+	db := setupDB(t)
+
+	var entries []LogEntry
+	var traces []any
+	sqlairDB := NewDB(db).With(WithLogger(func(ctx context.Context, e LogEntry) {
+		entries = append(entries, e)
+		traces = append(traces, ctx.Value(hookKey{}))
+	}))
+	ctx := context.WithValue(context.Background(), hookKey{}, "trace")
+
+	stmt, err := Prepare("INSERT INTO missing (name, password) VALUES ($Login.name, $Login.password)", Login{})
+	assert.Nil(t, err)
+	_, err = sqlairDB.Exec(ctx, stmt, Login{Name: "fred", Password: "correct horse"})
+	assert.EqualError(t, err, "no such table: missing")
+	assert.Equal(t, []any{"trace"}, traces)
+
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, err, entries[0].Err)
+		assert.Equal(t, []any{"fred", "***"}, entries[0].Args)
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	err := redactError(sql.ErrNoRows, []any{"rows", nil, []byte("no")})
+	assert.EqualError(t, err, "sql: *** *** in result set")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Equal(t, sql.ErrNoRows, errors.Cause(err))
+
+	// An error without secrets is returned unchanged.
+	err = redactError(sql.ErrNoRows, []any{"secret", ""})
+	assert.Equal(t, sql.ErrNoRows, err)
+}
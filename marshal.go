@@ -0,0 +1,215 @@
+package sqlair
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/canonical/sqlair/internal/parse"
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// jsonStatement is the JSON representation of a prepared Statement.
+// Type information can not be serialized, so types are recorded by name
+// and resolved against the objects supplied when the statement is loaded.
+type jsonStatement struct {
+	SQL         string              `json:"sql"`
+	Types       []string            `json:"types"`
+	Plan        BindingPlan         `json:"plan"`
+	Templates   []jsonTemplate      `json:"templates,omitempty"`
+	Identifiers []jsonIdentifier    `json:"identifiers,omitempty"`
+	Generated   []string            `json:"generated,omitempty"`
+	Limits      []int               `json:"limits,omitempty"`
+	Lists       []jsonList          `json:"lists,omitempty"`
+	Operators   []int               `json:"operators,omitempty"`
+	Allowed     IdentifierAllowlist `json:"allowed,omitempty"`
+	Lenient     bool                `json:"lenient,omitempty"`
+	Unsafe      bool                `json:"unsafe,omitempty"`
+	Unused      []string            `json:"unused,omitempty"`
+	Aliases     map[string]string   `json:"aliases,omitempty"`
+	Timeout     time.Duration       `json:"timeout,omitempty"`
+	Escapes     bool                `json:"backslashEscapes,omitempty"`
+	Quoting     IdentifierQuoting   `json:"quoting,omitempty"`
+	Expression  json.RawMessage     `json:"expression"`
+}
+
+// jsonTemplate is the JSON representation of a templateBinding.
+type jsonTemplate struct {
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+}
+
+// jsonIdentifier is the JSON representation of an identifierBinding.
+type jsonIdentifier struct {
+	Offset int    `json:"offset"`
+	Name   string `json:"name"`
+	Quoted bool   `json:"quoted,omitempty"`
+}
+
+// jsonList is the JSON representation of a listBinding.
+type jsonList struct {
+	Param      int    `json:"param"`
+	Comparison string `json:"comparison"`
+	In         string `json:"in,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. The SQL, binding plan and
+// expression tree of the statement are encoded, so that it can be
+// restored by LoadStatement without being parsed or compiled again.
+func (s *Statement) MarshalJSON() ([]byte, error) {
+	exp, err := json.Marshal(s.expression)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling statement expression")
+	}
+
+	j := jsonStatement{
+		SQL:        s.sql,
+		Plan:       s.BindingPlan(),
+		Limits:     s.limits,
+		Operators:  s.operators,
+		Allowed:    s.allowed,
+		Lenient:    s.lenient,
+		Unsafe:     s.unsafe,
+		Unused:     s.unused,
+		Aliases:    s.aliases,
+		Timeout:    s.timeout,
+		Escapes:    s.backslashEscapes,
+		Quoting:    s.quoting,
+		Generated:  s.generated,
+		Expression: exp,
+	}
+	for name := range s.argTypes {
+		j.Types = append(j.Types, name)
+	}
+	sort.Strings(j.Types)
+	for _, t := range s.templates {
+		j.Templates = append(j.Templates, jsonTemplate{Name: t.name, Offset: t.offset})
+	}
+	for _, id := range s.identifiers {
+		j.Identifiers = append(j.Identifiers, jsonIdentifier{Offset: id.offset, Name: id.name, Quoted: id.quoted})
+	}
+	for _, l := range s.lists {
+		j.Lists = append(j.Lists, jsonList{Param: l.param, Comparison: l.comparison, In: l.in})
+	}
+
+	return json.Marshal(j)
+}
+
+// LoadStatement returns the Statement encoded in the input JSON by
+// Statement.MarshalJSON. The input objects supply type information as for
+// Prepare, and must be of the same types as those the statement was
+// prepared with. The statement's bindings are checked against them.
+func LoadStatement(data []byte, args ...any) (*Statement, error) {
+	var j jsonStatement
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling statement")
+	}
+
+	exp, err := parse.UnmarshalExpression(j.Expression)
+	if err != nil {
+		return nil, err
+	}
+
+	argTypes, err := typesForStatement(args)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range j.Types {
+		if _, ok := argTypes[name]; !ok {
+			return nil, NewErrTypeInfoNotPresent(name)
+		}
+	}
+	for name := range argTypes {
+		if contains(j.Unused, name) {
+			delete(argTypes, name)
+		} else if !contains(j.Types, name) {
+			return nil, NewErrSuperfluousType(name)
+		}
+	}
+	if err := columnAliases(j.Aliases).validate(argTypes); err != nil {
+		return nil, err
+	}
+
+	stmt := &Statement{
+		expression: exp,
+		argTypes:   argTypes,
+		sql:        j.SQL,
+		limits:     j.Limits,
+		operators:  j.Operators,
+		allowed:    j.Allowed,
+		lenient:    j.Lenient,
+		unsafe:     j.Unsafe,
+		unused:     j.Unused,
+		aliases:    j.Aliases,
+		timeout:    j.Timeout,
+		quoting:    j.Quoting,
+		generated:  j.Generated,
+
+		backslashEscapes: j.Escapes,
+	}
+	for _, in := range j.Plan.Inputs {
+		field, err := loadField(argTypes, in.TypeName, in.Column, in.Field)
+		if err != nil {
+			return nil, err
+		}
+		stmt.inputs = append(stmt.inputs, inputBinding{typeName: in.TypeName, column: in.Column, field: field})
+	}
+	for _, out := range j.Plan.Outputs {
+		field, err := loadField(argTypes, out.TypeName, out.Tag, out.Field)
+		if err != nil {
+			return nil, err
+		}
+		stmt.outputs = append(stmt.outputs, outputBinding{
+			column:   out.Column,
+			typeName: out.TypeName,
+			field:    field,
+			tag:      out.Tag,
+			source:   out.Source,
+		})
+	}
+	for _, t := range j.Templates {
+		stmt.templates = append(stmt.templates, templateBinding{name: t.Name, offset: t.Offset})
+	}
+	for _, id := range j.Identifiers {
+		stmt.identifiers = append(stmt.identifiers, identifierBinding{offset: id.Offset, name: id.Name, quoted: id.Quoted})
+	}
+	for _, l := range j.Lists {
+		stmt.lists = append(stmt.lists, listBinding{param: l.Param, comparison: l.Comparison, in: l.In})
+	}
+	return stmt, nil
+}
+
+// loadField returns the reflected struct field of the named type that
+// has the input tag. The field must have the input name, otherwise the
+// type has changed since the statement was serialized.
+func loadField(argTypes typeMap, typeName, column, name string) (sqlairreflect.Field, error) {
+	info, ok := argTypes[typeName]
+	if !ok {
+		return sqlairreflect.Field{}, NewErrTypeInfoNotPresent(typeName)
+	}
+	st, ok := info.(sqlairreflect.Struct)
+	if !ok {
+		return sqlairreflect.Field{}, errors.Errorf("type %q is not a struct", typeName)
+	}
+
+	field, ok := st.Fields[column]
+	if !ok {
+		return sqlairreflect.Field{}, NewErrFieldNotPresent(typeName, column)
+	}
+	if field.Name != name {
+		return sqlairreflect.Field{}, errors.Errorf(
+			"tag %q of type %q is on field %q, not %q as when the statement was serialized", column, typeName, field.Name, name)
+	}
+	return field, nil
+}
+
+// contains returns true if the input string is in the input slice.
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package sqlair
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatementJSONRoundTrip(t *testing.T) {
+	prepared, err := Prepare(
+		"SELECT &Person.* FROM [[table]] WHERE id = $Person.id ORDER BY name", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	prepared, err = prepared.AllowIdentifiers(IdentifierAllowlist{"table": {"person"}})
+	assert.Nil(t, err)
+
+	data, err := json.Marshal(prepared)
+	assert.Nil(t, err)
+
+	stmt, err := LoadStatement(data, sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, prepared, stmt)
+
+	stmt, err = stmt.WithIdentifiers(map[string]string{"table": "person"})
+	assert.Nil(t, err)
+
+	var p sqlairtesting.Person
+	err = NewDB(setupPersonDB(t)).Query(context.Background(), stmt, sqlairtesting.Person{ID: "2"}).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, sqlairtesting.Person{ID: "2", Name: "Onos"}, p)
+
+	// A loaded statement can be derived from, as it retains its expression.
+	_, err = stmt.Derive(WithLimit("LIMIT 1"))
+	assert.Nil(t, err)
+}
+
+func TestStatementJSONRoundTripDialectBindings(t *testing.T) {
+	prepared, err := Prepare("SELECT &Person.* FROM person WHERE id = ANY($IDList.ids) LIMIT $PageSpec.size",
+		sqlairtesting.Person{}, IDList{}, PageSpec{})
+	assert.Nil(t, err)
+
+	data, err := json.Marshal(prepared)
+	assert.Nil(t, err)
+
+	stmt, err := LoadStatement(data, sqlairtesting.Person{}, IDList{}, PageSpec{})
+	assert.Nil(t, err)
+	assert.Equal(t, prepared, stmt)
+}
+
+func TestLoadStatementTypeErrors(t *testing.T) {
+	prepared, err := Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	data, err := json.Marshal(prepared)
+	assert.Nil(t, err)
+
+	_, err = LoadStatement(data)
+	assert.Equal(t, NewErrTypeInfoNotPresent("Person"), err)
+
+	type Address struct{}
+	_, err = LoadStatement(data, sqlairtesting.Person{}, Address{})
+	assert.Equal(t, NewErrSuperfluousType("Address"), err)
+
+	type Person struct {
+		Name string `db:"id"`
+		ID   string `db:"name"`
+	}
+	_, err = LoadStatement(data, Person{})
+	assert.EqualError(t, err,
+		`tag "id" of type "Person" is on field "Name", not "ID" as when the statement was serialized`)
+
+	_, err = LoadStatement([]byte(`{"types":["Person"],"expression":{"type":"Bogus"}}`), sqlairtesting.Person{})
+	assert.EqualError(t, err, `unknown expression type "Bogus"`)
+}
@@ -0,0 +1,226 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// Notification is a notification sent to a channel, such as by
+// PostgreSQL's NOTIFY, with its payload as sent.
+type Notification struct {
+	// Channel is the name of the channel to which the notification was sent.
+	Channel string `json:"channel"`
+
+	// Payload is the text sent with the notification.
+	Payload string `json:"payload"`
+}
+
+// Listener is a connection on which notifications are received. Database
+// drivers deliver notifications by their own APIs, such as the
+// WaitForNotification method of a pgx connection, so a Listener adapts
+// that of a single connection, on which Subscribe issues LISTEN. It may
+// be implemented for other databases, such as dqlite, by delivering
+// notifications of the database's own changes, provided that it accepts
+// the LISTEN and UNLISTEN statements.
+type Listener interface {
+	// ExecContext executes the LISTEN and UNLISTEN statements,
+	// as for the method of the same name of a *sql.Conn.
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+
+	// WaitForNotification blocks until a notification is received on one
+	// of the channels listened to, or the input context is done.
+	WaitForNotification(ctx context.Context) (Notification, error)
+}
+
+// Subscription receives the notifications sent to the channels to which
+// a Listener listens; see Subscribe. It is not safe for concurrent use.
+type Subscription struct {
+	listener Listener
+	channels []string
+}
+
+// Subscribe issues LISTEN on the input listener for each of the input
+// channels, and returns a Subscription to the notifications sent to them.
+// The Subscription must be closed when it is no longer required.
+//
+// Example:
+//
+//     sub, err := sqlair.Subscribe(ctx, listener, "person_changed")
+//     if err != nil {
+//         return err
+//     }
+//     defer sub.Close(ctx)
+//
+//     for {
+//         var p Person
+//         if _, err := sub.Next(ctx, &p); err != nil {
+//             return err
+//         }
+//         log.Printf("person %s changed", p.ID)
+//     }
+//
+func Subscribe(ctx context.Context, l Listener, channels ...string) (*Subscription, error) {
+	if len(channels) == 0 {
+		return nil, errors.New("no channels to subscribe to")
+	}
+
+	s := &Subscription{listener: l}
+	for _, channel := range channels {
+		if _, err := l.ExecContext(ctx, "LISTEN "+quoteIdentifier(channel)); err != nil {
+			_ = s.Close(ctx)
+			return nil, errors.Wrapf(err, "listening to channel %q", channel)
+		}
+		s.channels = append(s.channels, channel)
+	}
+	return s, nil
+}
+
+// Next blocks until a notification is received on one of the channels of
+// the subscription, or the input context is done, and returns it. Unless
+// the input output is nil, the payload of the notification is decoded into
+// it, as a JSON object whose keys are the "db" tags of the output struct's
+// fields. Fields decoded from columns in the same way, with transformers
+// and AfterSelect hooks, are decoded from the payload's keys, which are
+// otherwise ignored.
+func (s *Subscription) Next(ctx context.Context, output any) (Notification, error) {
+	n, err := s.listener.WaitForNotification(ctx)
+	if err != nil {
+		return Notification{}, err
+	}
+	if output != nil {
+		if err := decodePayload(ctx, n, output); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Close issues UNLISTEN on the subscription's listener for each
+// of its channels, so that notifications are no longer received.
+func (s *Subscription) Close(ctx context.Context) error {
+	for len(s.channels) > 0 {
+		channel := s.channels[0]
+		if _, err := s.listener.ExecContext(ctx, "UNLISTEN "+quoteIdentifier(channel)); err != nil {
+			return errors.Wrapf(err, "unlistening to channel %q", channel)
+		}
+		s.channels = s.channels[1:]
+	}
+	return nil
+}
+
+// Notify sends a notification to the input channel with the input payload,
+// as for PostgreSQL's pg_notify. The payload is sent as it is if it is a
+// string, otherwise it is a pointer to, or value of, a struct encoded as a
+// JSON object whose keys are the "db" tags of its fields, the values of
+// which are encoded by their transformers, if they have them. An error is
+// returned, and nothing is sent, if the payload is nil or a nil pointer.
+func (db *DB) Notify(ctx context.Context, channel string, payload any) error {
+	text, ok := payload.(string)
+	if !ok {
+		var err error
+		if text, err = encodePayload(ctx, payload); err != nil {
+			return err
+		}
+	}
+	_, err := db.Exec(ctx, notifyStmt, notification{Channel: channel, Payload: text})
+	return err
+}
+
+// notification supplies the inputs of notifyStmt.
+type notification struct {
+	Channel string `db:"channel"`
+	Payload string `db:"payload"`
+}
+
+// notifyStmt sends a notification; see DB.Notify.
+var notifyStmt = MustPrepare("SELECT pg_notify($notification.channel, $notification.payload)", notification{})
+
+// payloadStruct returns reflection information for the struct type of
+// the input payload, and its value. An error is returned if the payload
+// is nil or a nil pointer.
+func payloadStruct(payload any) (sqlairreflect.Struct, reflect.Value, error) {
+	v := reflect.ValueOf(payload)
+	if !v.IsValid() {
+		return sqlairreflect.Struct{}, v, errors.New("nil payload")
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return sqlairreflect.Struct{}, v, errors.Errorf("nil payload of type %T", payload)
+		}
+		v = v.Elem()
+	}
+	info, err := sqlairreflect.Cache().Reflect(v.Interface())
+	if err != nil {
+		return sqlairreflect.Struct{}, v, err
+	}
+	s, ok := info.(sqlairreflect.Struct)
+	if !ok {
+		return sqlairreflect.Struct{}, v, errors.Errorf("payload of type %T is not a struct", payload)
+	}
+	return s, v, nil
+}
+
+// encodePayload returns the input struct encoded as a JSON
+// object whose keys are the "db" tags of its fields.
+func encodePayload(ctx context.Context, payload any) (string, error) {
+	info, v, err := payloadStruct(payload)
+	if err != nil {
+		return "", err
+	}
+
+	values := make(map[string]any, len(info.Fields))
+	for tag, field := range info.Fields {
+		value, err := encodeField(ctx, info.Name(), tag, field, v.Field(field.Index).Interface())
+		if err != nil {
+			return "", err
+		}
+		values[tag] = value
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", errors.Wrapf(err, "encoding payload of type %q", info.Name())
+	}
+	return string(data), nil
+}
+
+// decodePayload decodes the payload of the input notification, a JSON
+// object, into the input output struct; see Subscription.Next.
+func decodePayload(ctx context.Context, n Notification, output any) error {
+	v := reflect.ValueOf(output)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.Errorf("expected non-nil pointer to output struct, got %T", output)
+	}
+	info, v, err := payloadStruct(output)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(n.Payload), &values); err != nil {
+		return errors.Wrapf(err, "decoding payload of notification on channel %q", n.Channel)
+	}
+	for tag, raw := range values {
+		field, ok := info.Fields[tag]
+		if !ok {
+			continue
+		}
+		dest := v.Field(field.Index).Addr().Interface()
+		if field.Transform == "" {
+			err = json.Unmarshal(raw, dest)
+		} else {
+			var value any
+			if err = json.Unmarshal(raw, &value); err == nil {
+				err = decodeField(ctx, &outputBinding{column: tag, field: field}, dest, value)
+			}
+		}
+		if err != nil {
+			return errors.Wrapf(err, "decoding key %q of payload on channel %q", tag, n.Channel)
+		}
+	}
+	return afterSelect(ctx, info.Name(), v)
+}
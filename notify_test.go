@@ -0,0 +1,122 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// loopbackListener is a Listener that delivers the notifications sent by
+// pg_notify through itself. It is also a Conn, so that they can be sent.
+type loopbackListener struct {
+	statements    []string
+	notifications []Notification
+}
+
+func (l *loopbackListener) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (l *loopbackListener) QueryContext(context.Context, string, ...any) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (l *loopbackListener) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	l.statements = append(l.statements, query)
+	if len(args) == 2 {
+		l.notifications = append(l.notifications, Notification{Channel: args[0].(string), Payload: args[1].(string)})
+	}
+	return nil, nil
+}
+
+func (l *loopbackListener) WaitForNotification(ctx context.Context) (Notification, error) {
+	if len(l.notifications) == 0 {
+		return Notification{}, errors.New("no notifications")
+	}
+	n := l.notifications[0]
+	l.notifications = l.notifications[1:]
+	return n, nil
+}
+
+// execListener is a Listener with only the methods that it requires.
+type execListener struct {
+	statements []string
+}
+
+func (l *execListener) ExecContext(_ context.Context, query string, _ ...any) (sql.Result, error) {
+	l.statements = append(l.statements, query)
+	return nil, nil
+}
+
+func (l *execListener) WaitForNotification(ctx context.Context) (Notification, error) {
+	return Notification{Channel: "person_changed"}, nil
+}
+
+func TestSubscribeExecListener(t *testing.T) {
+	ctx := context.Background()
+	l := &execListener{}
+	sub, err := Subscribe(ctx, l, "person_changed")
+	assert.Nil(t, err)
+	n, err := sub.Next(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "person_changed", n.Channel)
+	assert.Nil(t, sub.Close(ctx))
+	assert.Equal(t, []string{`LISTEN "person_changed"`, `UNLISTEN "person_changed"`}, l.statements)
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+	l := &loopbackListener{}
+	sub, err := Subscribe(ctx, l, "person_changed", `odd"name`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`LISTEN "person_changed"`, `LISTEN "odd""name"`}, l.statements)
+
+	db := NewDB(l, WithDialect(Dialect{NumberedPlaceholders: true}))
+	err = db.Notify(ctx, "person_changed", &sqlairtesting.Person{ID: "1", Name: "Lorn"})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT pg_notify($1, $2)", l.statements[2])
+
+	var p sqlairtesting.Person
+	n, err := sub.Next(ctx, &p)
+	assert.Nil(t, err)
+	assert.Equal(t, Notification{Channel: "person_changed", Payload: `{"id":"1","name":"Lorn"}`}, n)
+	assert.Equal(t, sqlairtesting.Person{ID: "1", Name: "Lorn"}, p)
+
+	// Keys without fields are ignored, and string payloads sent as they are.
+	err = db.Notify(ctx, "person_changed", `{"id": "2", "team": "blue"}`)
+	assert.Nil(t, err)
+	n, err = sub.Next(ctx, &p)
+	assert.Nil(t, err)
+	assert.Equal(t, sqlairtesting.Person{ID: "2", Name: "Lorn"}, p)
+
+	err = db.Notify(ctx, "person_changed", "not JSON")
+	assert.Nil(t, err)
+	_, err = sub.Next(ctx, &p)
+	assert.NotNil(t, err)
+
+	l.statements = nil
+	err = sub.Close(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`UNLISTEN "person_changed"`, `UNLISTEN "odd""name"`}, l.statements)
+
+	_, err = Subscribe(ctx, l)
+	assert.EqualError(t, err, "no channels to subscribe to")
+}
+
+func TestNotifyNilPayload(t *testing.T) {
+	ctx := context.Background()
+	l := &loopbackListener{}
+	db := NewDB(l, WithDialect(Dialect{NumberedPlaceholders: true}))
+
+	err := db.Notify(ctx, "person_changed", nil)
+	assert.EqualError(t, err, "nil payload")
+
+	var p *sqlairtesting.Person
+	err = db.Notify(ctx, "person_changed", p)
+	assert.EqualError(t, err, "nil payload of type *testing.Person")
+	assert.Empty(t, l.statements)
+}
@@ -0,0 +1,111 @@
+package sqlair
+
+// DBOption configures a DB. Options are passed to NewDB or NewReplicatedDB,
+// or to DB.With to configure a copy of a DB, such as one returned by Open,
+// so that new configuration does not change their signatures.
+//
+// Example:
+//
+//     db := sqlair.NewDB(conn,
+//         sqlair.WithDialect(sqlair.Dialect{NativeArrays: true}),
+//         sqlair.WithResultCache(sqlair.NewResultCache(time.Minute, 0)),
+//     )
+//
+type DBOption func(*DB)
+
+// With returns a reference to a new DB that runs statements using the same
+// connections and configuration as this one, except as changed by the
+// input options.
+func (db *DB) With(options ...DBOption) *DB {
+	c := &DB{dbConfig: db.dbConfig}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// PrepareOption configures the preparation of a statement. Options are
+// passed to Prepare along with the type objects, and are the only way in
+// which its preparation is configured.
+//
+// Example:
+//
+//     stmt, err := sqlair.Prepare(`
+//     SELECT &Person.*
+//       FROM person`, sqlair.WithStrictness(true), Person{})
+//
+type PrepareOption func(*prepareOptions)
+
+// prepareOptions holds the configuration of the
+// preparation of a statement; see PrepareOption.
+type prepareOptions struct {
+	// location, if not nil, is the position in Go source of
+	// the statement's literal; see WithLocation.
+	location *Location
+
+	// limits bound the statements accepted; see WithParseLimits.
+	limits ParseLimits
+
+	// escapes is true if a backslash within a string literal escapes
+	// the character following it; see WithBackslashEscapes.
+	escapes bool
+
+	// strict is true if statements containing unrecognised characters
+	// or unterminated string literals are refused; see WithStrictness.
+	strict bool
+
+	// allowUnused is true if objects of types that the statement does
+	// not use are ignored; see WithSuperfluousTypes.
+	allowUnused bool
+
+	// unsafe is true if the statement's literals are
+	// deliberately written into its DSL; see Unsafe.
+	unsafe bool
+
+	// filter, if not nil, adds its predicates for the
+	// tables that the statement names; see WithRowFilter.
+	filter *RowFilter
+
+	// quoting is the policy by which column names derived from
+	// struct tags are quoted; see WithIdentifierQuoting.
+	quoting IdentifierQuoting
+
+	// aliases holds, in order, the column aliases; see WithAlias.
+	aliases []columnAlias
+
+	// generated holds the names written into the DSL of a generated
+	// statement by generatedName and generatedTable.
+	generated []string
+
+	// cache, if not nil, holds the reflection information
+	// for the types of the objects; see WithCache.
+	cache *TypeCache
+}
+
+// WithStrictness returns an option determining whether Prepare refuses
+// statements containing unrecognised characters or unterminated string
+// literals, which usually indicate a statement built by concatenating
+// untrusted input. They are refused if strict is true, and are otherwise
+// passed to the database as written, which is the default.
+func WithStrictness(strict bool) PrepareOption {
+	return func(o *prepareOptions) {
+		o.strict = strict
+	}
+}
+
+// optionsFromArgs returns the configuration set by the PrepareOptions
+// among the input Prepare arguments, applied in order, along with the
+// remaining arguments, which are the type objects.
+func optionsFromArgs(args []any) (prepareOptions, []any) {
+	var o prepareOptions
+	remaining := args[:0:0]
+	for _, arg := range args {
+		option, ok := arg.(PrepareOption)
+		if !ok {
+			remaining = append(remaining, arg)
+			continue
+		}
+		option(&o)
+	}
+	return o, remaining
+}
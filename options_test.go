@@ -0,0 +1,72 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBOptions(t *testing.T) {
+	var entries []LogEntry
+	logger := func(ctx context.Context, e LogEntry) { entries = append(entries, e) }
+	cache := NewResultCache(time.Minute, 0)
+	dialect := Dialect{InlineLimits: true}
+
+	db := NewDB(setupPersonDB(t), WithDialect(dialect), WithResultCache(cache), WithLogger(logger))
+	assert.Equal(t, dialect, db.dialect)
+	assert.Equal(t, cache, db.cache)
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	var p sqlairtesting.Person
+	err = db.Query(context.Background(), stmt, sqlairtesting.Person{ID: "1"}).Get(&p)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, 1, cache.Len())
+
+	// With changes only the configuration of the options passed to it.
+	session := NewSession()
+	other := db.With(WithDialect(Dialect{}), WithSession(session))
+	assert.Equal(t, Dialect{}, other.dialect)
+	assert.Equal(t, session, other.session)
+	assert.Equal(t, cache, other.cache)
+	assert.Equal(t, dialect, db.dialect)
+	assert.Nil(t, db.session)
+
+	// Every part of the configuration is kept by With,
+	// including that of a DB with replicas.
+	probed := db.With(WithBackend(Backend{Name: "sqlite"})).With()
+	assert.Equal(t, "sqlite", probed.Backend().Name)
+	replicated := NewReplicatedDB(nil, []Conn{nil}, WithDialect(dialect)).With()
+	assert.Equal(t, dialect, replicated.dialect)
+	assert.Len(t, replicated.replicas, 1)
+}
+
+func TestWithStrictness(t *testing.T) {
+	const query = `SELECT &Person.* FROM person WHERE name = "Fred`
+
+	_, err := Prepare(query, WithStrictness(true), sqlairtesting.Person{})
+	assert.EqualError(t, err, `unterminated quoted identifier at line 1, column 43`)
+
+	// Later options override earlier ones.
+	_, err = Prepare(query, WithStrictness(true), WithStrictness(false), sqlairtesting.Person{})
+	assert.Nil(t, err)
+}
+
+func TestWithSuperfluousTypes(t *testing.T) {
+	type Unused struct{}
+
+	_, err := Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{}, Unused{})
+	assert.Equal(t, NewErrSuperfluousType("Unused"), err)
+
+	// Strictness has no bearing on superfluous types.
+	_, err = Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{}, WithStrictness(false), Unused{})
+	assert.Equal(t, NewErrSuperfluousType("Unused"), err)
+
+	stmt, err := Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{}, WithSuperfluousTypes(true), Unused{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Unused"}, stmt.unused)
+}
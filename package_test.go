@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"testing"
 
+	"github.com/canonical/sqlair/internal/parse"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,3 +27,30 @@ func runTx(t *testing.T, db *sql.DB, fn func(*sql.Tx) error) {
 	err = tx.Commit()
 	assert.Nil(t, err)
 }
+
+// expressionForStatement stands in for the parser, returning an expression
+// tree for the input DSL statement. Input sources and output targets are
+// recognised and every other token becomes an identity.
+func expressionForStatement(stmt string) parse.Expression {
+	exp := &parse.SQLExpression{}
+
+	tokens := tokensForStatement(stmt)
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if (token.Type == parse.BITAND || token.Type == parse.DOLLAR) && i+3 < len(tokens) &&
+			tokens[i+1].Type == parse.IDENT && tokens[i+2].Type == parse.PERIOD {
+			name := parse.NewIdentityExpression(tokens[i+1])
+			field := parse.NewIdentityExpression(tokens[i+3])
+			if token.Type == parse.BITAND {
+				exp.AppendExpression(parse.NewOutputTargetExpression(token, name, field))
+			} else {
+				exp.AppendExpression(parse.NewInputSourceExpression(token, name, field))
+			}
+			i += 3
+			continue
+		}
+		exp.AppendExpression(parse.NewIdentityExpression(token))
+	}
+
+	return exp
+}
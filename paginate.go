@@ -0,0 +1,421 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/canonical/sqlair/internal/parse"
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// Cursor marks the position in the results of a paginated
+// statement from which a page begins.
+// The zero Cursor begins at the first row.
+// A Cursor is serialized, as by encoding/json, as an opaque string
+// that can be handed to clients and read back to resume paging.
+type Cursor struct {
+	// started is true if the cursor follows a page of results.
+	started bool
+
+	// key is the sort key value of the last row of the preceding page.
+	// It is used for keyset pagination.
+	key any
+
+	// offset is the number of rows preceding the page.
+	// It is used for offset pagination.
+	offset int
+}
+
+// jsonCursor is the JSON representation of a Cursor, the sort key value of
+// which is recorded as a driver.Value along with its type, so that it is
+// read back as the same type.
+type jsonCursor struct {
+	Started bool            `json:"started,omitempty"`
+	Offset  int             `json:"offset,omitempty"`
+	KeyType string          `json:"keyType,omitempty"`
+	Key     json.RawMessage `json:"key,omitempty"`
+}
+
+// MarshalText implements encoding.TextMarshaler. The sort key value
+// must be convertible to a driver.Value.
+func (c Cursor) MarshalText() ([]byte, error) {
+	key, err := driver.DefaultParameterConverter.ConvertValue(c.key)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot serialize cursor")
+	}
+
+	jc := jsonCursor{Started: c.started, Offset: c.offset}
+	switch key.(type) {
+	case nil:
+	case int64:
+		jc.KeyType = "int64"
+	case float64:
+		jc.KeyType = "float64"
+	case bool:
+		jc.KeyType = "bool"
+	case []byte:
+		jc.KeyType = "bytes"
+	case string:
+		jc.KeyType = "string"
+	case time.Time:
+		jc.KeyType = "time"
+	default:
+		return nil, errors.Errorf("cannot serialize cursor with sort key of type %T", key)
+	}
+	if key != nil {
+		if jc.Key, err = json.Marshal(key); err != nil {
+			return nil, errors.Wrap(err, "cannot serialize cursor")
+		}
+	}
+
+	data, err := json.Marshal(jc)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot serialize cursor")
+	}
+	text := make([]byte, base64.RawURLEncoding.EncodedLen(len(data)))
+	base64.RawURLEncoding.Encode(text, data)
+	return text, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// Empty text is the zero Cursor.
+func (c *Cursor) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*c = Cursor{}
+		return nil
+	}
+
+	data := make([]byte, base64.RawURLEncoding.DecodedLen(len(text)))
+	n, err := base64.RawURLEncoding.Decode(data, text)
+	if err != nil {
+		return errors.Wrap(err, "invalid cursor")
+	}
+	var jc jsonCursor
+	if err := json.Unmarshal(data[:n], &jc); err != nil {
+		return errors.Wrap(err, "invalid cursor")
+	}
+
+	var key any
+	switch jc.KeyType {
+	case "":
+	case "int64":
+		key, err = unmarshalKey[int64](jc.Key)
+	case "float64":
+		key, err = unmarshalKey[float64](jc.Key)
+	case "bool":
+		key, err = unmarshalKey[bool](jc.Key)
+	case "bytes":
+		key, err = unmarshalKey[[]byte](jc.Key)
+	case "string":
+		key, err = unmarshalKey[string](jc.Key)
+	case "time":
+		key, err = unmarshalKey[time.Time](jc.Key)
+	default:
+		return errors.Errorf("invalid cursor: unknown sort key type %q", jc.KeyType)
+	}
+	if err != nil {
+		return errors.Wrap(err, "invalid cursor")
+	}
+	if jc.Offset < 0 {
+		return errors.Errorf("invalid cursor: negative offset %d", jc.Offset)
+	}
+
+	*c = Cursor{started: jc.Started, key: key, offset: jc.Offset}
+	return nil
+}
+
+// unmarshalKey returns the sort key value of type T
+// read from the input JSON.
+func unmarshalKey[T any](data json.RawMessage) (T, error) {
+	var key T
+	err := json.Unmarshal(data, &key)
+	return key, err
+}
+
+// Page describes a page of results decoded by a Paginator.
+type Page struct {
+	// Next is the cursor from which the following page begins.
+	Next Cursor
+
+	// Len is the number of rows in the page.
+	Len int
+
+	// Last is true if there are no rows following this page.
+	Last bool
+}
+
+// Paginator generates the statements for retrieving
+// the results of a SELECT statement a page at a time.
+type Paginator struct {
+	// first is the statement for the first page.
+	first *Statement
+
+	// next is the statement for pages following a cursor.
+	next *Statement
+
+	// keyset is true if pages follow the sort key value of the preceding
+	// page's last row, rather than skipping the preceding rows by offset.
+	keyset bool
+
+	// key is the output binding for the sort key column.
+	key outputBinding
+
+	// size is the maximum number of rows in a page.
+	size int
+}
+
+// pageBounds is the input source for the parameters with which a page of
+// a paginated statement is selected. A page is selected with one row more
+// than its size, so that whether another page follows it is known.
+type pageBounds struct {
+	// Key is the sort key value of the
+	// last row of the preceding page.
+	Key any `db:"key"`
+
+	// Limit is the number of rows selected.
+	Limit int `db:"limit"`
+
+	// Offset is the number of rows preceding the page.
+	Offset int `db:"offset"`
+}
+
+// NewKeysetPaginator returns a Paginator for the input statement, with
+// pages of at most size rows ordered by the input sort key column.
+// Each page begins after the sort key value of the preceding page's last
+// row, so the key must be unique and be decoded into an output target.
+func NewKeysetPaginator(s *Statement, key string, size int) (*Paginator, error) {
+	p, err := newPaginator(s, key, size)
+	if err != nil {
+		return nil, err
+	}
+
+	p.keyset = true
+	if p.first, err = pageStatement(s, key, false, false); err != nil {
+		return nil, err
+	}
+	if p.next, err = pageStatement(s, key, true, false); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// NewOffsetPaginator returns a Paginator for the input statement, with
+// pages of at most size rows ordered by the input sort key column.
+// Each page begins by skipping the rows of those preceding it,
+// so the key must be decoded into an output target.
+func NewOffsetPaginator(s *Statement, key string, size int) (*Paginator, error) {
+	p, err := newPaginator(s, key, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.first, err = pageStatement(s, key, false, true); err != nil {
+		return nil, err
+	}
+	p.next = p.first
+	return p, nil
+}
+
+// newPaginator returns a Paginator for the input statement after
+// verifying that the sort key is one of its output columns.
+func newPaginator(s *Statement, key string, size int) (*Paginator, error) {
+	if len(s.templates) > 0 {
+		return nil, errors.Errorf("identifier for template %q must be substituted before pagination", s.templates[0].name)
+	}
+	if size < 1 {
+		return nil, errors.Errorf("page size must be positive, got %d", size)
+	}
+
+	for _, out := range s.outputs {
+		if out.column == key {
+			return &Paginator{key: out, size: size}, nil
+		}
+	}
+	return nil, errors.Errorf("sort key %q is not an output column of the statement", key)
+}
+
+// pageStatement returns a statement selecting a page of the input
+// statement's results, ordered by the input sort key column. Its
+// expression tree is that of
+//
+//     SELECT * FROM (<statement>) AS page
+//      [WHERE <key> > $pageBounds.key]
+//      ORDER BY <key> LIMIT $pageBounds.limit [OFFSET $pageBounds.offset]
+//
+// the statement's own expressions being spliced in as the subquery, rather
+// than its SQL, so that it is neither parsed again nor disturbed by a
+// trailing semicolon. The page's rows are decoded into the outputs of the
+// statement, whose columns the wrapping query passes through.
+func pageStatement(s *Statement, key string, after, offset bool) (*Statement, error) {
+	boundsTypes, err := typesForStatement([]any{pageBounds{}})
+	if err != nil {
+		return nil, err
+	}
+	argTypes, err := mergeTypes(s.argTypes, boundsTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	subquery := &parse.SubqueryExpression{}
+	children := s.expression.Expressions()
+	if n := len(children); n > 0 && children[n-1].String() == ";" {
+		children = children[:n-1]
+	}
+	for _, child := range children {
+		subquery.AppendExpression(child)
+	}
+
+	exp := &parse.SQLExpression{}
+	exp.AppendExpression(parse.NewIdentityExpression(keywordToken("SELECT")))
+	exp.AppendExpression(parse.NewIdentityExpression(parse.Token{Type: parse.ASTERISK, Literal: "*"}))
+	exp.AppendExpression(parse.NewIdentityExpression(keywordToken("FROM")))
+	exp.AppendExpression(subquery)
+	exp.AppendExpression(parse.NewIdentityExpression(keywordToken("AS")))
+	exp.AppendExpression(parse.NewIdentityExpression(parse.Token{Type: parse.IDENT, Literal: "page"}))
+
+	if after {
+		exp.AppendExpression(parse.NewIdentityExpression(keywordToken("WHERE")))
+		exp.AppendExpression(parse.NewInfixExpression(
+			sortKeyExpression(key),
+			[]parse.Token{{Type: parse.GT, Literal: ">"}},
+			pageBoundsSource("key"),
+		))
+	}
+
+	orderBy := parse.NewOrderByExpression([]parse.Token{keywordToken("ORDER"), keywordToken("BY")})
+	orderBy.AppendExpression(parse.NewOrderingTermExpression(sortKeyExpression(key), nil))
+	exp.AppendExpression(orderBy)
+
+	if offset {
+		exp.AppendExpression(parse.NewLimitExpression(keywordToken("LIMIT"),
+			pageBoundsSource("limit"), keywordToken("OFFSET"), pageBoundsSource("offset")))
+	} else {
+		exp.AppendExpression(parse.NewLimitExpression(keywordToken("LIMIT"),
+			pageBoundsSource("limit"), parse.Token{}, nil))
+	}
+
+	page := *s
+	page.generated = append(s.generated[:len(s.generated):len(s.generated)], key)
+	return page.recompile(exp, argTypes)
+}
+
+// sortKeyExpression returns an expression for the input sort key column
+// of the results of a page. It is recorded as a generated name by
+// pageStatement, so that it is quoted for the dialect of the DB executing
+// the page's statement.
+func sortKeyExpression(key string) parse.Expression {
+	return parse.NewIdentityExpression(parse.Token{Type: parse.QUOTEDIDENT, Literal: quoteIdentifier(key)})
+}
+
+// pageBoundsSource returns an input source
+// expression for the input field of pageBounds.
+func pageBoundsSource(field string) parse.Expression {
+	return parse.NewInputSourceExpression(
+		parse.Token{Type: parse.DOLLAR, Literal: "$"},
+		parse.NewIdentityExpression(parse.Token{Type: parse.IDENT, Literal: "pageBounds"}),
+		parse.NewIdentityExpression(parse.Token{Type: parse.IDENT, Literal: field}),
+	)
+}
+
+// statementFor returns the statement for the page beginning at the
+// input cursor, along with the bounds with which it is selected.
+func (p *Paginator) statementFor(cursor Cursor) (*Statement, pageBounds) {
+	bounds := pageBounds{Limit: p.size + 1}
+	switch {
+	case !p.keyset:
+		bounds.Offset = cursor.offset
+		return p.next, bounds
+	case cursor.started:
+		bounds.Key = cursor.key
+		return p.next, bounds
+	}
+	return p.first, bounds
+}
+
+// Paginate returns a PageQuery for the page of the paginated statement's
+// results beginning at the input cursor, with parameters sourced from the
+// input objects. The statement is not executed until the page is requested.
+func (db *DB) Paginate(ctx context.Context, p *Paginator, cursor Cursor, inputs ...any) *PageQuery {
+	stmt, bounds := p.statementFor(cursor)
+	return &PageQuery{
+		query: &Query{
+			ctx:    ctx,
+			db:     db,
+			stmt:   stmt,
+			inputs: append(inputs[:len(inputs):len(inputs)], bounds),
+		},
+		paginator: p,
+		cursor:    cursor,
+	}
+}
+
+// PageQuery represents a page of a paginated statement's
+// results, to be retrieved with a specific set of inputs.
+type PageQuery struct {
+	query     *Query
+	paginator *Paginator
+	cursor    Cursor
+}
+
+// GetPage decodes the rows of the page, appending to the input slices as
+// for Query.GetAll. One of the slices must be of the type into which the
+// sort key is decoded, so that the cursor for the next page can be read.
+func (q *PageQuery) GetPage(slices ...any) (Page, error) {
+	key := q.paginator.key
+
+	var keySlice reflect.Value
+	lens := make([]int, len(slices))
+	values := make([]reflect.Value, len(slices))
+	for i, slice := range slices {
+		name, v := objectName(slice)
+		if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+			return Page{}, errors.Errorf("expected pointer to slice, got %T", slice)
+		}
+		if _, ok := slice.(Aliased); !ok {
+			name = sqlairreflect.TypeName(v.Elem().Type().Elem())
+		}
+		if name == key.typeName {
+			keySlice = v
+		}
+		values[i], lens[i] = v.Elem(), v.Elem().Len()
+	}
+	if !keySlice.IsValid() {
+		return Page{}, errors.Errorf("no slice of type %q supplied for sort key %q", key.typeName, key.column)
+	}
+
+	before := keySlice.Elem().Len()
+	if err := q.query.GetAll(slices...); err != nil {
+		return Page{}, err
+	}
+
+	// The row following the page, if there is one,
+	// is selected only to learn that it exists.
+	page := Page{
+		Len:  keySlice.Elem().Len() - before,
+		Next: q.cursor,
+		Last: true,
+	}
+	if page.Len > q.paginator.size {
+		for i, v := range values {
+			if n := lens[i] + q.paginator.size; v.Len() > n {
+				v.Set(v.Slice(0, n))
+			}
+		}
+		page.Len = q.paginator.size
+		page.Last = false
+	}
+	if page.Len == 0 {
+		return page, nil
+	}
+
+	sv := keySlice.Elem()
+	page.Next.started = true
+	page.Next.offset += page.Len
+	page.Next.key = sv.Index(sv.Len() - 1).Field(key.field.Index).Interface()
+	return page, nil
+}
@@ -0,0 +1,172 @@
+package sqlair
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeysetPaginator(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE name <> $Person.name", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	p, err := NewKeysetPaginator(stmt, "id", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM (SELECT id, name FROM person WHERE name <> ?) AS page ORDER BY "id" LIMIT ?`, p.first.sql)
+	assert.Equal(t, `SELECT * FROM (SELECT id, name FROM person WHERE name <> ?) AS page WHERE "id" > ? ORDER BY "id" LIMIT ?`, p.next.sql)
+
+	// The page holding the last rows is known to be the last.
+	var people []sqlairtesting.Person
+	page, err := db.Paginate(context.Background(), p, Cursor{}, sqlairtesting.Person{Name: "Onos"}).GetPage(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, page.Len)
+	assert.True(t, page.Last)
+	assert.Equal(t, []sqlairtesting.Person{{ID: "1", Name: "Lorn"}, {ID: "3", Name: "Fred"}}, people)
+
+	people = nil
+	page, err = db.Paginate(context.Background(), p, Cursor{}, sqlairtesting.Person{}).GetPage(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, page.Len)
+	assert.False(t, page.Last)
+	assert.Equal(t, []sqlairtesting.Person{{ID: "1", Name: "Lorn"}, {ID: "2", Name: "Onos"}}, people)
+
+	page, err = db.Paginate(context.Background(), p, page.Next, sqlairtesting.Person{}).GetPage(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, page.Len)
+	assert.True(t, page.Last)
+	assert.Equal(t, samplePeople(), people)
+}
+
+func TestPaginatorTrailingSemicolon(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+
+	stmt, err := Prepare("SELECT &Person.* FROM person;", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	p, err := NewOffsetPaginator(stmt, "id", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM (SELECT id, name FROM person) AS page ORDER BY "id" LIMIT ? OFFSET ?`, p.first.sql)
+
+	var people []sqlairtesting.Person
+	page, err := db.Paginate(context.Background(), p, Cursor{}).GetPage(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, page.Len)
+	assert.False(t, page.Last)
+}
+
+func TestCursorText(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+
+	stmt, err := Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	p, err := NewKeysetPaginator(stmt, "id", 1)
+	assert.Nil(t, err)
+
+	var people []sqlairtesting.Person
+	page, err := db.Paginate(context.Background(), p, Cursor{}).GetPage(&people)
+	assert.Nil(t, err)
+
+	// A cursor read back from its serialized form resumes paging.
+	data, err := json.Marshal(page.Next)
+	assert.Nil(t, err)
+	var cursor Cursor
+	err = json.Unmarshal(data, &cursor)
+	assert.Nil(t, err)
+	assert.Equal(t, page.Next, cursor)
+
+	_, err = db.Paginate(context.Background(), p, cursor).GetPage(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, samplePeople()[:2], people)
+
+	// Sort key values keep their types.
+	for _, key := range []any{int64(7), 1.5, true, []byte("id"), "id", time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC), nil} {
+		text, err := Cursor{started: true, key: key, offset: 3}.MarshalText()
+		assert.Nil(t, err)
+		var c Cursor
+		assert.Nil(t, c.UnmarshalText(text))
+		assert.Equal(t, Cursor{started: true, key: key, offset: 3}, c)
+	}
+
+	var c Cursor
+	assert.Nil(t, c.UnmarshalText(nil))
+	assert.Equal(t, Cursor{}, c)
+
+	err = c.UnmarshalText([]byte("not a cursor"))
+	assert.Error(t, err)
+
+	_, err = Cursor{key: struct{}{}}.MarshalText()
+	assert.Error(t, err)
+}
+
+func TestOffsetPaginator(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+
+	stmt, err := Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	p, err := NewOffsetPaginator(stmt, "id", 2)
+	assert.Nil(t, err)
+
+	var people []sqlairtesting.Person
+	var cursor Cursor
+	for {
+		page, err := db.Paginate(context.Background(), p, cursor).GetPage(&people)
+		assert.Nil(t, err)
+		if page.Last {
+			break
+		}
+		cursor = page.Next
+	}
+	assert.Equal(t, samplePeople(), people)
+}
+
+func TestPaginatorSortKeyNotOutputError(t *testing.T) {
+	stmt, err := Prepare("SELECT &Person.name FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	_, err = NewKeysetPaginator(stmt, "id", 10)
+	assert.EqualError(t, err, `sort key "id" is not an output column of the statement`)
+
+	_, err = NewOffsetPaginator(stmt, "name", 0)
+	assert.EqualError(t, err, "page size must be positive, got 0")
+}
+
+func TestPaginatorMissingKeySliceError(t *testing.T) {
+	type Address struct {
+		ID string `db:"id"`
+	}
+	db := NewDB(setupPersonDB(t))
+
+	stmt, err := Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	p, err := NewKeysetPaginator(stmt, "id", 2)
+	assert.Nil(t, err)
+
+	var addresses []Address
+	_, err = db.Paginate(context.Background(), p, Cursor{}).GetPage(&addresses)
+	assert.EqualError(t, err, `no slice of type "Person" supplied for sort key "id"`)
+}
+
+func TestKeysetPaginatorAliasedColumns(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+
+	stmt, err := Prepare("SELECT p.* AS &Manager.* FROM person AS p", Alias("Manager", sqlairtesting.Person{}))
+	assert.Nil(t, err)
+
+	p, err := NewKeysetPaginator(stmt, "p_id", 2)
+	assert.Nil(t, err)
+
+	var managers []sqlairtesting.Person
+	page, err := db.Paginate(context.Background(), p, Cursor{}).GetPage(Alias("Manager", &managers))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, page.Len)
+	assert.Equal(t, []sqlairtesting.Person{{ID: "1", Name: "Lorn"}, {ID: "2", Name: "Onos"}}, managers)
+}
@@ -0,0 +1,61 @@
+package sqlair
+
+import "github.com/canonical/sqlair/internal/parse"
+
+// ParseFunc parses dialect-specific syntax that Sqlair does not otherwise
+// understand, such as PostgreSQL's "#>" operator and its operand. It is
+// called with the cursor at the token for which it is registered, and
+// returns with the cursor at the last token of the syntax, which is passed
+// through to the database with its tokens separated by spaces.
+type ParseFunc = parse.ParseFunc
+
+// ParseCursor is the position of a ParseFunc within the tokens of the
+// statement being parsed. Its Token and Peek methods return the current
+// token and the one following it. Next advances to the following token,
+// and Operand parses the expression beginning with it, which, unlike the
+// tokens passed over by Next, may contain input sources and output targets.
+type ParseCursor = parse.Cursor
+
+// RegisterPrefixParseFunc registers the input function for parsing syntax
+// beginning with the input literal, so that the parser need not be forked
+// to support it. The literal is either a name that is not a keyword, or an
+// operator of up to three of the characters "!#%*+-/:<=>?@^|~" that Sqlair
+// does not already parse, such as "@@". If the function is nil, the syntax
+// is that of a prefix operator followed by its operand. Statements prepared
+// before the function is registered do not use it, so functions are
+// registered before statements are prepared, typically by an init function.
+// RegisterPrefixParseFunc panics if a function is already registered for
+// the literal, or if none can be.
+//
+// Example:
+//
+//     sqlair.RegisterPrefixParseFunc("@@", nil)
+//
+//     stmt, err := sqlair.Prepare(`SELECT &Person.* FROM person WHERE @@ $Search.query`, Person{}, Search{})
+//
+func RegisterPrefixParseFunc(literal string, fn ParseFunc) {
+	if err := parse.RegisterPrefix(literal, fn); err != nil {
+		panic("sqlair: " + err.Error())
+	}
+}
+
+// RegisterInfixParseFunc registers the input function for parsing syntax
+// that follows an expression, its left-hand operand, beginning with the
+// input literal, which is as for RegisterPrefixParseFunc. If the function
+// is nil, the syntax is that of an infix operator followed by its
+// right-hand operand. Registered infix syntax binds more tightly than
+// comparisons and less tightly than arithmetic, as do PostgreSQL's other
+// operators. RegisterInfixParseFunc panics if a function is already
+// registered for the literal, or if none can be.
+//
+// Example:
+//
+//     sqlair.RegisterInfixParseFunc("#>", nil)
+//
+//     stmt, err := sqlair.Prepare(`SELECT data #> $Path.keys AS &Doc.value FROM doc`, Path{}, Doc{})
+//
+func RegisterInfixParseFunc(literal string, fn ParseFunc) {
+	if err := parse.RegisterInfix(literal, fn); err != nil {
+		panic("sqlair: " + err.Error())
+	}
+}
@@ -0,0 +1,28 @@
+package sqlair
+
+import (
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	RegisterInfixParseFunc("!~", nil)
+}
+
+func TestRegisteredParseFunc(t *testing.T) {
+	stmt, err := Prepare("SELECT name!~$Person.name AS &Person.id FROM person WHERE name !~ '^a'", sqlairtesting.Person{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, `SELECT name!~ ? AS "Person.id" FROM person WHERE name !~ '^a'`, stmt.sql)
+	}
+}
+
+func TestRegisterParseFuncPanics(t *testing.T) {
+	assert.PanicsWithValue(t, `sqlair: parse function already registered for "!~"`, func() {
+		RegisterInfixParseFunc("!~", nil)
+	})
+	assert.PanicsWithValue(t, `sqlair: operator "=" is already parsed`, func() {
+		RegisterPrefixParseFunc("=", nil)
+	})
+}
@@ -0,0 +1,68 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// RunPinned calls the input function with a DB that runs every statement
+// on the same connection, taken from the pool of this DB's primary
+// connection, such as a *sql.DB. State that is scoped to a connection,
+// such as temporary tables and session variables set by SET or PRAGMA,
+// is therefore visible to every statement run with it. The connection is
+// returned to the pool when the function returns, or panics, so the DB
+// passed to it must not be retained.
+//
+// The DB passed to the function has the configuration of this one,
+// except that it has no replicas, and its results are neither served
+// from nor stored in the result cache, since results read from temporary
+// tables are not valid on other connections. The statements it runs
+// still invalidate the cached results of those that they modify. If the
+// primary connection is already a single connection, such as a *sql.Conn
+// or *sql.Tx, it is used as it is.
+//
+// Example:
+//
+//     err := db.RunPinned(ctx, func(ctx context.Context, conn *sqlair.DB) error {
+//         if _, err := conn.Exec(ctx, createScratch); err != nil {
+//             return err
+//         }
+//         if _, err := conn.Exec(ctx, fillScratch, filter); err != nil {
+//             return err
+//         }
+//         return conn.Query(ctx, joinScratch).GetAll(&people)
+//     })
+//
+func (db *DB) RunPinned(ctx context.Context, fn func(ctx context.Context, conn *DB) error) error {
+	var conn Conn
+	switch c := db.conn.(type) {
+	case *sql.Conn, *sql.Tx:
+		conn = c
+	case interface {
+		Conn(context.Context) (*sql.Conn, error)
+	}:
+		pinned, err := c.Conn(ctx)
+		if err != nil {
+			return errors.Wrap(err, "pinning connection")
+		}
+		defer func() { _ = pinned.Close() }()
+		conn = pinned
+	default:
+		return errors.Errorf("connection of type %T can not be pinned", db.conn)
+	}
+
+	return fn(ctx, db.With(withPinnedConn(conn)))
+}
+
+// withPinnedConn returns an option with which a DB runs every statement
+// on the input connection, without caching results; see DB.RunPinned.
+func withPinnedConn(conn Conn) DBOption {
+	return func(db *DB) {
+		db.conn = conn
+		db.replicas = nil
+		db.pool = nil
+		db.pinned = true
+	}
+}
@@ -0,0 +1,103 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPinned(t *testing.T) {
+	pool, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "pinned.db"))
+	assert.Nil(t, err)
+	defer pool.Close()
+	pool.SetMaxOpenConns(2)
+
+	db := NewDB(pool, WithResultCache(NewResultCache(0, 0)))
+	ctx := context.Background()
+
+	createScratch := MustPrepare("CREATE TEMP TABLE scratch (id TEXT, name TEXT)")
+	fillScratch := MustPrepare("INSERT INTO scratch (id, name) VALUES ($Person.id, $Person.name)", sqlairtesting.Person{})
+	readScratch := MustPrepare("SELECT &Person.* FROM scratch", sqlairtesting.Person{})
+
+	var people []sqlairtesting.Person
+	err = db.RunPinned(ctx, func(ctx context.Context, conn *DB) error {
+		if _, err := conn.Exec(ctx, createScratch); err != nil {
+			return err
+		}
+		if _, err := conn.Exec(ctx, fillScratch, sqlairtesting.Person{ID: "1", Name: "Lorn"}); err != nil {
+			return err
+		}
+
+		// The temporary table is not visible on other connections.
+		err := db.Query(ctx, readScratch).GetAll(&people)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "no such table: scratch")
+		}
+
+		return conn.Query(ctx, readScratch).GetAll(&people)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []sqlairtesting.Person{{ID: "1", Name: "Lorn"}}, people)
+
+	// The connection is released, with an error or without.
+	assert.Equal(t, 0, pool.Stats().InUse)
+	err = db.RunPinned(ctx, func(ctx context.Context, conn *DB) error {
+		return errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, 0, pool.Stats().InUse)
+}
+
+func TestRunPinnedResultCache(t *testing.T) {
+	cache := NewResultCache(0, 0)
+	db := NewDB(setupPersonDB(t), WithResultCache(cache))
+	ctx := context.Background()
+
+	selectStmt := MustPrepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	insertStmt := MustPrepare("INSERT INTO person (id, name) VALUES ($Person.id, $Person.name)", sqlairtesting.Person{})
+
+	var people []sqlairtesting.Person
+	assert.Nil(t, db.Query(ctx, selectStmt).GetAll(&people))
+	assert.Equal(t, 1, cache.Len())
+
+	err := db.RunPinned(ctx, func(ctx context.Context, conn *DB) error {
+		// Results read on the pinned connection are neither
+		// served from the cache nor stored in it.
+		var pinned []sqlairtesting.Person
+		if err := conn.Query(ctx, MustPrepare("SELECT &Person.* FROM person WHERE id = '1'", sqlairtesting.Person{})).GetAll(&pinned); err != nil {
+			return err
+		}
+		assert.Equal(t, 1, cache.Len())
+
+		// Writes on the pinned connection invalidate those cached.
+		_, err := conn.Exec(ctx, insertStmt, sqlairtesting.Person{ID: "4", Name: "Mark"})
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, cache.Len())
+
+	people = nil
+	assert.Nil(t, db.Query(ctx, selectStmt).GetAll(&people))
+	assert.Len(t, people, 4)
+}
+
+func TestRunPinnedTx(t *testing.T) {
+	pool := setupPersonDB(t)
+	tx, err := pool.Begin()
+	assert.Nil(t, err)
+	defer tx.Rollback()
+
+	err = NewDB(tx).RunPinned(context.Background(), func(ctx context.Context, conn *DB) error {
+		assert.Equal(t, tx, conn.conn)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	err = NewDB(struct{ Conn }{tx}).RunPinned(context.Background(), func(context.Context, *DB) error { return nil })
+	assert.EqualError(t, err, "connection of type struct { sqlair.Conn } can not be pinned")
+}
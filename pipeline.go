@@ -0,0 +1,115 @@
+package sqlair
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+)
+
+// Pipelined returns a copy of the query whose result rows are read from the
+// database by a separate goroutine, while they are decoded by the caller.
+// Up to the input number of rows are buffered between the two, so that
+// waiting for rows to arrive overlaps with decoding those already read.
+// Rows are decoded in the order in which the database returns them.
+// It suits large results over connections with high latency. Results
+// served from a ResultCache are already held in memory and are not
+// pipelined.
+//
+// Example:
+//
+//     err := db.Query(ctx, stmt).Pipelined(256).GetAll(&people)
+//
+func (q *Query) Pipelined(buffer int) *Query {
+	query := *q
+	if buffer < 1 {
+		buffer = 1
+	}
+	query.pipeline = buffer
+	return &query
+}
+
+// pipelinedRows reads result rows in a goroutine of its own,
+// passing them by channel to be decoded; see newPipelinedRows.
+type pipelinedRows struct {
+	rows *sql.Rows
+
+	// read receives the values of each row, in order.
+	// It is closed when there are no more rows.
+	read chan []any
+
+	// done is closed to stop reading rows early.
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// err is the error, if any, that ended reading.
+	// It must not be accessed until read is closed.
+	err error
+}
+
+// newPipelinedRows returns rows over those input, with the input columns,
+// which are read by a goroutine that buffers up to the input number of
+// rows, beginning immediately. The values of the rows are scanned as
+// they would be from the input rows. Closing the returned rows stops the
+// goroutine and closes the input rows.
+func newPipelinedRows(rows *sql.Rows, columns []string, buffer int) (*sql.Rows, error) {
+	p := &pipelinedRows{
+		rows: rows,
+		read: make(chan []any, buffer),
+		done: make(chan struct{}),
+	}
+	go p.readRows(len(columns))
+
+	pipelined, err := valueRows(columns, p.next, p.close)
+	if err != nil {
+		_ = p.close()
+		return nil, err
+	}
+	return pipelined, nil
+}
+
+// readRows sends the values of each row to the read channel
+// until there are no more rows, there is an error or it is stopped.
+func (p *pipelinedRows) readRows(columns int) {
+	defer close(p.read)
+
+	for p.rows.Next() {
+		row, err := readRow(p.rows, columns)
+		if err != nil {
+			p.err = err
+			return
+		}
+
+		select {
+		case p.read <- row:
+		case <-p.done:
+			return
+		}
+	}
+	p.err = p.rows.Err()
+}
+
+// next reads the values of the next row into the input destination, as
+// for driver.Rows, returning io.EOF when there are no more rows, or the
+// error that ended reading.
+func (p *pipelinedRows) next(dest []driver.Value) error {
+	row, ok := <-p.read
+	if !ok {
+		if p.err != nil {
+			return p.err
+		}
+		return io.EOF
+	}
+	for i, value := range row {
+		dest[i] = value
+	}
+	return nil
+}
+
+// close stops the reading goroutine before closing the rows.
+func (p *pipelinedRows) close() error {
+	p.closeOnce.Do(func() { close(p.done) })
+	for range p.read {
+	}
+	return p.rows.Close()
+}
@@ -0,0 +1,72 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryPipelined(t *testing.T) {
+	conn := setupPersonDB(t)
+	runTx(t, conn, func(tx *sql.Tx) error {
+		for i := 4; i <= 100; i++ {
+			if _, err := tx.Exec("INSERT INTO person VALUES (?, ?)", fmt.Sprintf("%03d", i), fmt.Sprintf("name%d", i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	db := NewDB(conn)
+
+	stmt, err := Prepare("SELECT &Person.*, 'extra' FROM person ORDER BY id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	var expected, people []sqlairtesting.Person
+	err = db.Query(context.Background(), stmt).GetAll(&expected)
+	assert.Nil(t, err)
+	assert.Len(t, expected, 100)
+
+	err = db.Query(context.Background(), stmt).Pipelined(8).GetAll(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, people)
+
+	// Closing the iterator early stops the goroutine reading the rows.
+	var p sqlairtesting.Person
+	err = db.Query(context.Background(), stmt).Pipelined(1).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, expected[0], p)
+
+	// The connection is released, so that it can be used again.
+	err = db.Query(context.Background(), stmt).Get(&p)
+	assert.Nil(t, err)
+}
+
+func TestQueryPipelinedError(t *testing.T) {
+	type Numbered struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	db := NewDB(setupPersonDB(t))
+
+	stmt, err := Prepare("SELECT &Numbered.* FROM person", Numbered{})
+	assert.Nil(t, err)
+
+	var numbered []Numbered
+	err = db.Query(context.Background(), stmt).Pipelined(4).GetAll(&numbered)
+	assert.Nil(t, err)
+	assert.Equal(t, []Numbered{{1, "Lorn"}, {2, "Onos"}, {3, "Fred"}}, numbered)
+
+	stmt, err = Prepare("SELECT name AS &Numbered.id FROM person", Numbered{})
+	assert.Nil(t, err)
+
+	// Values are scanned as they would be without pipelining.
+	err = db.Query(context.Background(), stmt).Pipelined(4).GetAll(&numbered)
+	unpipelined := db.Query(context.Background(), stmt).GetAll(&numbered)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, unpipelined.Error(), err.Error())
+	}
+}
@@ -0,0 +1,94 @@
+package sqlair
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BindingPlan describes how a prepared statement exchanges values with Go
+// objects: the source of each of its parameters and the destination of
+// each of its result columns. It is computed by Prepare.
+type BindingPlan struct {
+	// Inputs holds, in placeholder order, the source of each parameter.
+	Inputs []InputBinding `json:"inputs"`
+
+	// Outputs holds, in column order, the destination of each result
+	// column that is decoded into an output target.
+	Outputs []OutputBinding `json:"outputs"`
+}
+
+// InputBinding describes the source of a single statement parameter.
+type InputBinding struct {
+	// TypeName is the name of the type from which the parameter is sourced.
+	TypeName string `json:"typeName"`
+
+	// Column is the "db" tag of the field holding the parameter value.
+	Column string `json:"column"`
+
+	// Field is the name of the struct field holding the parameter value.
+	Field string `json:"field"`
+}
+
+// OutputBinding describes the destination of a single result column.
+type OutputBinding struct {
+	// Column is the name of the result column.
+	Column string `json:"column"`
+
+	// TypeName is the name of the type into which the column is decoded.
+	TypeName string `json:"typeName"`
+
+	// Field is the name of the struct field receiving the column value.
+	Field string `json:"field"`
+
+	// Tag is the "db" tag of the field receiving the column value.
+	Tag string `json:"tag"`
+
+	// Source is the qualified column selected under the name of a column
+	// expanded from a qualified wildcard, such as "p.id" for the column
+	// "p_id" of "p.* AS &Person.*". It is empty for other columns.
+	Source string `json:"source,omitempty"`
+}
+
+// BindingPlan returns the binding plan for the statement.
+// The returned plan is a copy, and may be modified by the caller.
+func (s *Statement) BindingPlan() BindingPlan {
+	plan := BindingPlan{
+		Inputs:  make([]InputBinding, len(s.inputs)),
+		Outputs: make([]OutputBinding, len(s.outputs)),
+	}
+	for i, in := range s.inputs {
+		plan.Inputs[i] = InputBinding{
+			TypeName: in.typeName,
+			Column:   in.column,
+			Field:    in.field.Name,
+		}
+	}
+	for i, out := range s.outputs {
+		plan.Outputs[i] = OutputBinding{
+			Column:   out.column,
+			TypeName: out.typeName,
+			Field:    out.field.Name,
+			Tag:      out.tag,
+			Source:   out.source,
+		}
+	}
+	return plan
+}
+
+// String returns a description of the plan with a line for each binding,
+// such as "$1 <- Person.ID", "name -> Person.Name" or, for a column with
+// a source, "p.name AS p_name -> Person.Name".
+func (p BindingPlan) String() string {
+	var b strings.Builder
+	for i, in := range p.Inputs {
+		fmt.Fprintf(&b, "$%d <- %s.%s\n", i+1, in.TypeName, in.Field)
+	}
+	for _, out := range p.Outputs {
+		column := out.Column
+		if out.Source != "" {
+			column = out.Source + " AS " + column
+		}
+		fmt.Fprintf(&b, "%s -> %s.%s\n", column, out.TypeName, out.Field)
+	}
+	return b.String()
+}
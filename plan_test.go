@@ -0,0 +1,31 @@
+package sqlair
+
+import (
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindingPlan(t *testing.T) {
+	stmt, err := Prepare(
+		"SELECT &Person.* FROM person WHERE id = $Person.id OR name = $Person.name", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	plan := stmt.BindingPlan()
+	assert.Equal(t, []InputBinding{
+		{TypeName: "Person", Column: "id", Field: "ID"},
+		{TypeName: "Person", Column: "name", Field: "Name"},
+	}, plan.Inputs)
+	assert.Equal(t, []OutputBinding{
+		{Column: "id", TypeName: "Person", Field: "ID", Tag: "id"},
+		{Column: "name", TypeName: "Person", Field: "Name", Tag: "name"},
+	}, plan.Outputs)
+
+	expected := "$1 <- Person.ID\n$2 <- Person.Name\nid -> Person.ID\nname -> Person.Name\n"
+	assert.Equal(t, expected, plan.String())
+
+	// Modifying the plan does not affect the statement.
+	plan.Inputs[0].Field = "Name"
+	assert.Equal(t, "ID", stmt.BindingPlan().Inputs[0].Field)
+}
@@ -0,0 +1,242 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Backend describes the database to which a DB is connected,
+// as detected by DB.Probe.
+type Backend struct {
+	// Name is the name of the database: "sqlite", "postgres" or "mysql".
+	Name string `json:"name"`
+
+	// Version is the version of the database, as reported by it.
+	Version string `json:"version"`
+
+	// Returning is true if the database accepts RETURNING clauses on
+	// INSERT, UPDATE and DELETE statements, as do PostgreSQL, SQLite
+	// from version 3.35.0 and MariaDB from version 10.5.
+	Returning bool `json:"returning"`
+
+	// Copy is true if rows can be loaded with COPY ... FROM STDIN by
+	// executing a prepared statement for each, as with the lib/pq driver
+	// for PostgreSQL; see BulkLoader.
+	Copy bool `json:"copy"`
+}
+
+// Backend returns the database to which the DB is connected, as detected
+// when it was probed, or the zero Backend if it has not been probed.
+func (db *DB) Backend() Backend {
+	return db.backend
+}
+
+// WithBackend returns an option with which a DB describes its database
+// as the input backend, rather than as that detected by DB.Probe.
+func WithBackend(b Backend) DBOption {
+	return func(db *DB) {
+		db.backend = b
+	}
+}
+
+// NewProbedDB is like NewDB, but first probes the database with the input
+// context, as for DB.Probe, so that the DB generates SQL for the dialect
+// of the database detected. Options, such as WithDialect, are applied
+// after the dialect is selected, so override it.
+//
+// Example:
+//
+//     db, err := sqlair.NewProbedDB(ctx, conn)
+//     if err != nil {
+//         return err
+//     }
+//     log.Printf("connected to %s %s", db.Backend().Name, db.Backend().Version)
+//
+func NewProbedDB(ctx context.Context, conn Conn, options ...DBOption) (*DB, error) {
+	db, err := NewDB(conn).Probe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.With(options...), nil
+}
+
+// Probe returns a reference to a new DB that runs statements using the same
+// connections and configuration as this one, generating SQL for the dialect
+// of the database to which its primary connection is connected. The
+// database is identified by the name of the driver of a connection that
+// reports it, such as a *sql.DB, and its version is queried. The
+// dialect selected for PostgreSQL reads generated keys with RETURNING,
+// numbers its placeholders and explains statements with ExplainText, and
+// has native arrays if the driver is pgx, which accepts slices as
+// parameters; that selected for MySQL quotes identifiers with backticks
+// and explains statements with ExplainTree; each of them quotes the
+// reserved words of its database.
+// That selected for SQLite is the default.
+// An error is returned if the version of the database can not be queried.
+func (db *DB) Probe(ctx context.Context) (*DB, error) {
+	name := backendForDriver(db.driver())
+	if name == "" {
+		var err error
+		if name, err = db.identifyBackend(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	backend := Backend{Name: name}
+	var err error
+	switch name {
+	case "sqlite":
+		backend.Version, err = db.queryVersion(ctx, "SELECT sqlite_version()")
+		backend.Returning = versionAtLeast(backend.Version, 3, 35)
+	case "postgres":
+		backend.Version, err = db.queryVersion(ctx, "SHOW server_version")
+		backend.Returning = true
+		backend.Copy = strings.HasPrefix(driverPackage(db.driver()), "github.com/lib/pq")
+	case "mysql":
+		backend.Version, err = db.queryVersion(ctx, "SELECT version()")
+		// Older clients are sent MariaDB's version following "5.5.5-".
+		mariaDB := strings.TrimPrefix(backend.Version, "5.5.5-")
+		backend.Returning = strings.Contains(mariaDB, "MariaDB") && versionAtLeast(mariaDB, 10, 5)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "querying version of %s database", name)
+	}
+
+	var dialect Dialect
+	switch name {
+	case "postgres":
+		dialect.ReturningKeys = true
+		dialect.NumberedPlaceholders = true
+		dialect.NativeArrays = isPgxDriver(db.driver())
+		dialect.Explain = ExplainText
+		dialect.ReservedWords = postgresReservedWords
+	case "mysql":
+		dialect.Explain = ExplainTree
+		dialect.BacktickQuotes = true
+		dialect.ReservedWords = mysqlReservedWords
+	}
+	return db.With(WithBackend(backend), WithDialect(dialect)), nil
+}
+
+// driver returns the driver of the DB's primary
+// connection, or nil if the connection does not report it.
+func (db *DB) driver() driver.Driver {
+	if d, ok := db.conn.(interface{ Driver() driver.Driver }); ok {
+		return d.Driver()
+	}
+	return nil
+}
+
+// backendDriverPackages maps the import paths of the packages of
+// well-known drivers to the names of the databases that they access.
+// Drivers in packages within them are also recognised.
+var backendDriverPackages = map[string]string{
+	"github.com/mattn/go-sqlite3":    "sqlite",
+	"modernc.org/sqlite":             "sqlite",
+	"github.com/canonical/go-dqlite": "sqlite",
+	"github.com/lib/pq":              "postgres",
+	"github.com/jackc/pgx":           "postgres",
+	"github.com/go-sql-driver/mysql": "mysql",
+}
+
+// backendForDriver returns the name of the database accessed by the
+// input driver, or "" if it is not that of a well-known package.
+func backendForDriver(d driver.Driver) string {
+	for pkg := driverPackage(d); pkg != ""; pkg = parentPackage(pkg) {
+		if name, ok := backendDriverPackages[pkg]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// driverPackage returns the import path of the package defining the
+// input driver's type, or "" if the driver is nil.
+func driverPackage(d driver.Driver) string {
+	if d == nil {
+		return ""
+	}
+	t := reflect.TypeOf(d)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.PkgPath()
+}
+
+// parentPackage returns the import path of the package
+// containing the input one, or "" if it has none.
+func parentPackage(pkg string) string {
+	i := strings.LastIndex(pkg, "/")
+	if i < 0 {
+		return ""
+	}
+	return pkg[:i]
+}
+
+// isPgxDriver returns true if the input driver is that of pgx.
+func isPgxDriver(d driver.Driver) bool {
+	return strings.HasPrefix(driverPackage(d), "github.com/jackc/pgx/")
+}
+
+// identifyBackend returns the name of the database to which the DB's
+// primary connection is connected, from the results of version queries,
+// for connections whose driver is not known.
+func (db *DB) identifyBackend(ctx context.Context) (string, error) {
+	if _, err := db.queryVersion(ctx, "SELECT sqlite_version()"); err == nil {
+		return "sqlite", nil
+	}
+	version, err := db.queryVersion(ctx, "SELECT version()")
+	if err != nil {
+		return "", errors.Wrap(err, "identifying database")
+	}
+	if strings.HasPrefix(version, "PostgreSQL") {
+		return "postgres", nil
+	}
+	return "mysql", nil
+}
+
+// queryVersion returns the single value returned by the input
+// version query, run on the DB's primary connection.
+func (db *DB) queryVersion(ctx context.Context, query string) (string, error) {
+	rows, err := db.conn.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var version string
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", errors.Errorf("%q returned no rows", query)
+	}
+	if err := rows.Scan(&version); err != nil {
+		return "", err
+	}
+	return version, rows.Close()
+}
+
+// versionAtLeast returns true if the leading numbers of the input version,
+// such as "3.35.5" or "10.6.12-MariaDB", are at least those input.
+func versionAtLeast(version string, min ...int) bool {
+	fields := strings.FieldsFunc(version, func(r rune) bool { return r < '0' || r > '9' })
+	for i, m := range min {
+		if i >= len(fields) {
+			return false
+		}
+		n, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return false
+		}
+		if n != m {
+			return n > m
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeSQLite(t *testing.T) {
+	conn := setupPersonDB(t)
+	db, err := NewProbedDB(context.Background(), conn)
+	assert.Nil(t, err)
+
+	var version string
+	err = conn.QueryRow("SELECT sqlite_version()").Scan(&version)
+	assert.Nil(t, err)
+	assert.Equal(t, Backend{Name: "sqlite", Version: version, Returning: versionAtLeast(version, 3, 35)}, db.Backend())
+	assert.Equal(t, Dialect{}, db.dialect)
+
+	// A connection that does not report its driver
+	// is identified from the version queries.
+	db, err = NewDB(struct{ Conn }{conn}).Probe(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "sqlite", db.Backend().Name)
+
+	// Options override the dialect selected.
+	db, err = NewProbedDB(context.Background(), conn, WithDialect(Dialect{InlineLimits: true}))
+	assert.Nil(t, err)
+	assert.Equal(t, Dialect{InlineLimits: true}, db.dialect)
+	assert.Equal(t, "sqlite", db.With().Backend().Name)
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version  string
+		min      []int
+		expected bool
+	}{
+		{"3.35.0", []int{3, 35}, true},
+		{"3.34.1", []int{3, 35}, false},
+		{"3.4", []int{3, 35}, false},
+		{"10.6.12-MariaDB-1:10.6.12+maria~ubu2004", []int{10, 5}, true},
+		{"8.0.33", []int{10, 5}, false},
+		{"", []int{3, 35}, false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, versionAtLeast(test.version, test.min...), test.version)
+	}
+}
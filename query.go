@@ -0,0 +1,523 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/canonical/sqlair/internal/parse"
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// Query represents a prepared statement to be run with a specific set of
+// inputs. The statement is executed when results are requested from it.
+type Query struct {
+	ctx    context.Context
+	db     *DB
+	stmt   *Statement
+	inputs []any
+
+	// pipeline is the number of rows buffered between the goroutine
+	// reading them and the decoder, or zero if they are not pipelined;
+	// see Pipelined.
+	pipeline int
+
+	// rows, if not nil, holds the rows already read for the query,
+	// such as by DB.SendBatch, which are decoded rather than the
+	// statement being executed.
+	rows *cachedResult
+
+	// err, if not nil, is returned in place of executing the statement.
+	err error
+}
+
+// Iter executes the query and returns an Iterator over its result rows.
+// Any error from execution is returned by the Iterator's Close method.
+// If the DB has a result cache, and is not pinned to a connection by
+// RunPinned, the rows of a read-only query are served from it when they
+// are cached, and are otherwise read in full and cached.
+func (q *Query) Iter() *Iterator {
+	switch {
+	case q.err != nil:
+		return &Iterator{err: q.err}
+	case q.rows != nil:
+		return q.cachedIterator(q.rows)
+	}
+
+	q.stmt.recordUse()
+	args, err := q.stmt.bindInputs(q.ctx, q.inputs)
+	if err != nil {
+		return &Iterator{err: err}
+	}
+	query, params, err := q.db.sqlFor(q.stmt, args)
+	if err != nil {
+		return &Iterator{err: q.stmt.redactError(err, args)}
+	}
+
+	cache := q.db.cache
+	var key string
+	if cache != nil && !q.db.pinned && parse.Classify(q.stmt.expression) == parse.KindQuery {
+		key = resultKey(query, params)
+		if result, ok := cache.get(key); ok {
+			return q.cachedIterator(result)
+		}
+	}
+
+	ctx, cancel := q.stmt.executionContext(q.ctx)
+	query = withComment(query, q.db.comment(ctx, q.stmt))
+	start := time.Now()
+	rows, err := q.db.connFor(q.stmt).QueryContext(ctx, query, params...)
+	if err = q.db.logExecution(ctx, q.stmt, args, start, err); err != nil {
+		cancel()
+		return &Iterator{err: err}
+	}
+	if key == "" {
+		q.db.ran(q.stmt)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		_ = rows.Close()
+		cancel()
+		return &Iterator{err: err}
+	}
+
+	if key != "" {
+		result, err := readResult(rows, columns, q.stmt.Tables().Read)
+		cancel()
+		if err != nil {
+			return &Iterator{err: err}
+		}
+		cache.put(key, result)
+		return q.cachedIterator(result)
+	}
+
+	if q.pipeline > 0 {
+		if rows, err = newPipelinedRows(rows, columns, q.pipeline); err != nil {
+			cancel()
+			return &Iterator{err: err}
+		}
+	}
+
+	return &Iterator{
+		ctx:      q.ctx,
+		cancel:   cancel,
+		db:       q.db,
+		stmt:     q.stmt,
+		rows:     rows,
+		bindings: q.stmt.outputsForColumns(columns),
+	}
+}
+
+// cachedIterator returns an Iterator over copies of the rows of the input
+// result, which are scanned with the same conversions as rows read from
+// the database.
+func (q *Query) cachedIterator(result *cachedResult) *Iterator {
+	rows, err := sliceRows(result.columns, result.rows)
+	if err != nil {
+		return &Iterator{err: err}
+	}
+	return &Iterator{
+		ctx:      q.ctx,
+		cancel:   func() {},
+		db:       q.db,
+		stmt:     q.stmt,
+		rows:     rows,
+		bindings: q.stmt.outputsForColumns(result.columns),
+	}
+}
+
+// Get decodes the first result row into the input outputs,
+// which must be pointers to structs used as output targets.
+// If there are no rows, sql.ErrNoRows is returned.
+func (q *Query) Get(outputs ...any) error {
+	iter := q.Iter()
+	if !iter.Next() {
+		if err := iter.Close(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+
+	if err := iter.Decode(outputs...); err != nil {
+		_ = iter.Close()
+		return err
+	}
+	return iter.Close()
+}
+
+// GetAll decodes every result row, appending to the input slices.
+// Each argument must be a pointer to a slice of structs used as an output
+// target; one element is appended to each slice for every row.
+func (q *Query) GetAll(slices ...any) error {
+	sliceValues := make([]reflect.Value, len(slices))
+	for i, slice := range slices {
+		_, v := objectName(slice)
+		if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+			return errors.Errorf("expected pointer to slice, got %T", slice)
+		}
+		sliceValues[i] = v.Elem()
+	}
+
+	iter := q.Iter()
+	outputs := make([]any, len(slices))
+	for iter.Next() {
+		elems := make([]reflect.Value, len(sliceValues))
+		for i, sv := range sliceValues {
+			elems[i] = reflect.New(sv.Type().Elem())
+			outputs[i] = elems[i].Interface()
+			if a, ok := slices[i].(Aliased); ok {
+				outputs[i] = Alias(a.name, outputs[i])
+			}
+		}
+
+		if err := iter.Decode(outputs...); err != nil {
+			_ = iter.Close()
+			return err
+		}
+
+		for i, sv := range sliceValues {
+			sv.Set(reflect.Append(sv, elems[i].Elem()))
+		}
+	}
+	return iter.Close()
+}
+
+// GetMap decodes every result row into the input map, which must be a
+// pointer to a map of structs used as an output target. Each row is stored
+// under the value of the field with the input key tag, which must be decoded
+// from a column and be convertible to the map's key type.
+// A row replaces any preceding row with the same key.
+func (q *Query) GetMap(key string, m any) error {
+	name, v := objectName(m)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Map {
+		return errors.Errorf("expected pointer to map, got %T", m)
+	}
+	mv := v.Elem()
+	if _, ok := m.(Aliased); !ok {
+		name = sqlairreflect.TypeName(mv.Type().Elem())
+	}
+
+	keyBinding := q.stmt.outputForTag(name, key)
+	if keyBinding == nil {
+		return errors.Errorf("key %q is not an output column of type %q", key, name)
+	}
+
+	keyType := mv.Type().Key()
+	if fieldType := mv.Type().Elem().Field(keyBinding.field.Index).Type; !fieldType.ConvertibleTo(keyType) {
+		return errors.Errorf("key %q of type %s can not be converted to map key type %s", key, fieldType, keyType)
+	}
+	if mv.IsNil() {
+		mv.Set(reflect.MakeMap(mv.Type()))
+	}
+
+	iter := q.Iter()
+	for iter.Next() {
+		elem := reflect.New(mv.Type().Elem())
+		var output any = elem.Interface()
+		if a, ok := m.(Aliased); ok {
+			output = Alias(a.name, output)
+		}
+
+		if err := iter.Decode(output); err != nil {
+			_ = iter.Close()
+			return err
+		}
+
+		k := elem.Elem().Field(keyBinding.field.Index).Convert(keyType)
+		mv.SetMapIndex(k, elem.Elem())
+	}
+	return iter.Close()
+}
+
+// Collect decodes joined rows into one-to-many structures, appending to
+// the input parents, which must be a pointer to a slice of structs used as
+// an output target. The struct type must have a slice field with the input
+// name, whose element type is also used as an output target. Rows with the
+// same value of the parent field with the input key tag, which must be
+// decoded from a column, are grouped into a single parent, and the child
+// decoded from each row is appended to that parent's slice field.
+//
+// Example:
+//
+//     type Owner struct {
+//         ID        string `db:"id"`
+//         Name      string `db:"name"`
+//         Addresses []Address
+//     }
+//
+//     stmt, err := sqlair.Prepare(`
+//     SELECT p.* AS &Owner.*, a.* AS &Address.*
+//       FROM person AS p
+//       JOIN address AS a ON a.person_id = p.id`, Owner{}, Address{})
+//
+//     var owners []Owner
+//     err = db.Query(ctx, stmt).Collect("id", "Addresses", &owners)
+//
+func (q *Query) Collect(key, field string, parents any) error {
+	v := reflect.ValueOf(parents)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice || v.Elem().Type().Elem().Kind() != reflect.Struct {
+		return errors.Errorf("expected pointer to slice of structs, got %T", parents)
+	}
+	sv := v.Elem()
+	parentType := sv.Type().Elem()
+	parentName := sqlairreflect.TypeName(parentType)
+
+	keyBinding := q.stmt.outputForTag(parentName, key)
+	if keyBinding == nil {
+		return errors.Errorf("key %q is not an output column of type %q", key, parentName)
+	}
+	if keyType := parentType.Field(keyBinding.field.Index).Type; !keyType.Comparable() {
+		return errors.Errorf("key %q of type %s is not comparable", key, keyType)
+	}
+
+	childField, ok := parentType.FieldByName(field)
+	if !ok || len(childField.Index) != 1 || childField.Type.Kind() != reflect.Slice {
+		return errors.Errorf("type %q has no slice field %q", parentName, field)
+	}
+	childType := childField.Type.Elem()
+
+	// indexes holds the index in the slice of the parent for each key.
+	indexes := make(map[any]int)
+
+	iter := q.Iter()
+	for iter.Next() {
+		parent := reflect.New(parentType)
+		child := reflect.New(childType)
+		if err := iter.Decode(parent.Interface(), child.Interface()); err != nil {
+			_ = iter.Close()
+			return err
+		}
+
+		k := parent.Elem().Field(keyBinding.field.Index).Interface()
+		i, ok := indexes[k]
+		if !ok {
+			i = sv.Len()
+			indexes[k] = i
+			sv.Set(reflect.Append(sv, parent.Elem()))
+		}
+
+		children := sv.Index(i).Field(childField.Index[0])
+		children.Set(reflect.Append(children, child.Elem()))
+	}
+	return iter.Close()
+}
+
+// ForEach decodes each result row into the input outputs, which must be
+// pointers to structs used as output targets, and then calls the input
+// function. The same outputs are reused for every row, being reset to their
+// zero values before each is decoded, so that rows can be processed without
+// allocating. The function must copy any values that it retains. If it
+// returns an error, iteration stops and that error is returned.
+//
+// Example:
+//
+//     var p Person
+//     err := db.Query(ctx, stmt).ForEach(func() error {
+//         total += len(p.Name)
+//         return nil
+//     }, &p)
+//
+func (q *Query) ForEach(fn func() error, outputs ...any) error {
+	values := make([]reflect.Value, len(outputs))
+	for i, output := range outputs {
+		_, v := objectName(output)
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			return errors.Errorf("expected non-nil pointer to output struct, got %T", output)
+		}
+		values[i] = v.Elem()
+	}
+
+	iter := q.Iter()
+	for iter.Next() {
+		for _, v := range values {
+			v.Set(reflect.Zero(v.Type()))
+		}
+
+		if err := iter.Decode(outputs...); err != nil {
+			_ = iter.Close()
+			return err
+		}
+		if err := fn(); err != nil {
+			_ = iter.Close()
+			return err
+		}
+	}
+	return iter.Close()
+}
+
+// Iterator steps through the result rows of an executed query,
+// decoding them into output target types.
+type Iterator struct {
+	ctx  context.Context
+	db   *DB
+	stmt *Statement
+	rows *sql.Rows
+	err  error
+
+	// cancel releases the context in which the query is executed.
+	cancel context.CancelFunc
+
+	// bindings holds the output binding for each result column,
+	// or nil for columns that do not map to an output target.
+	bindings []*outputBinding
+}
+
+// Next prepares the next result row for decoding, returning false if there
+// are no more rows or an error occurred. Close must be called when Next
+// returns false in order to determine if iteration ended with an error.
+//
+// Once the context with which the query was run is cancelled, or its
+// deadline passes, Next returns false, including for results served from a
+// ResultCache. The result rows are closed, releasing their connection
+// without waiting for Close, and Close returns the context's error.
+func (it *Iterator) Next() bool {
+	if it.err != nil || it.rows == nil {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		_ = it.rows.Close()
+		it.cancel()
+		return false
+	}
+	return it.rows.Next()
+}
+
+// Decode scans the current result row into the input outputs, which must
+// be pointers to structs used as output targets in the statement.
+// Columns for output targets without a supplied output are discarded.
+// Each output is then passed to its hook, if it has one; see AfterSelectHook.
+func (it *Iterator) Decode(outputs ...any) error {
+	if it.err != nil {
+		return it.err
+	}
+
+	start := time.Now()
+	defer func() { it.db.recordDecode(time.Since(start)) }()
+
+	dests := make(map[string]reflect.Value, len(outputs))
+	for _, output := range outputs {
+		name, v := objectName(output)
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			return errors.Errorf("expected non-nil pointer to output struct, got %T", output)
+		}
+		v = v.Elem()
+
+		if info, ok := it.stmt.argTypes[name]; !ok || info.Type() != v.Type() {
+			if it.stmt.lenient {
+				continue
+			}
+			return NewErrSuperfluousType(name)
+		}
+		dests[name] = v
+	}
+
+	buf := getScanBuffer(len(it.bindings))
+	defer putScanBuffer(buf)
+
+	var transformed bool
+	for i, b := range it.bindings {
+		if b != nil {
+			if dest, ok := dests[b.typeName]; ok {
+				if b.field.Transform != "" {
+					// The value read is decoded after scanning.
+					buf.ptrs[i] = &buf.values[i]
+					transformed = true
+					continue
+				}
+				buf.ptrs[i] = dest.Field(b.field.Index).Addr().Interface()
+				continue
+			}
+		}
+		buf.ptrs[i] = &buf.discard
+	}
+
+	if err := it.rows.Scan(buf.ptrs...); err != nil {
+		return err
+	}
+	if transformed {
+		for i, b := range it.bindings {
+			if buf.ptrs[i] != &buf.values[i] {
+				continue
+			}
+			dest := dests[b.typeName].Field(b.field.Index).Addr().Interface()
+			if err := decodeField(it.ctx, b, dest, buf.values[i]); err != nil {
+				return err
+			}
+		}
+	}
+	for _, output := range outputs {
+		name, _ := objectName(output)
+		if dest, ok := dests[name]; ok {
+			if err := afterSelect(it.ctx, name, dest); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// scanBuffer holds the destinations passed to Scan for a result row.
+// Buffers are pooled, so that decoding a row does not allocate them.
+type scanBuffer struct {
+	ptrs []any
+
+	// values receives the values of columns that are
+	// decoded by a transformer before being assigned.
+	values []any
+
+	// discard receives the values of columns
+	// that are not decoded into an output.
+	discard any
+}
+
+// scanBuffers holds the scan buffers not in use.
+var scanBuffers = sync.Pool{
+	New: func() any { return new(scanBuffer) },
+}
+
+// getScanBuffer returns a scan buffer from the pool,
+// with space for the input number of destinations.
+func getScanBuffer(n int) *scanBuffer {
+	buf := scanBuffers.Get().(*scanBuffer)
+	if cap(buf.ptrs) < n {
+		buf.ptrs = make([]any, n)
+		buf.values = make([]any, n)
+	}
+	buf.ptrs = buf.ptrs[:n]
+	buf.values = buf.values[:n]
+	return buf
+}
+
+// putScanBuffer returns the input scan buffer to the pool, after clearing
+// it so that the pool does not keep the outputs it referenced alive.
+func putScanBuffer(buf *scanBuffer) {
+	for i := range buf.ptrs {
+		buf.ptrs[i] = nil
+		buf.values[i] = nil
+	}
+	buf.discard = nil
+	scanBuffers.Put(buf)
+}
+
+// Close releases the iterator's result rows, returning any error that
+// occurred during execution or iteration. It may be called more than once.
+func (it *Iterator) Close() error {
+	if it.rows == nil {
+		return it.err
+	}
+
+	err := it.rows.Close()
+	it.cancel()
+	if it.err != nil {
+		return it.err
+	}
+	if err != nil {
+		return err
+	}
+	return it.rows.Err()
+}
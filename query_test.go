@@ -0,0 +1,434 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryGet(t *testing.T) {
+	db := setupPersonDB(t)
+
+	stmt, err := prepareExpression(
+		expressionForStatement("SELECT &Person.* FROM person WHERE id = $Person.id"),
+		[]any{sqlairtesting.Person{}},
+	)
+	assert.Nil(t, err)
+
+	var p sqlairtesting.Person
+	err = NewDB(db).Query(context.Background(), stmt, sqlairtesting.Person{ID: "2"}).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, sqlairtesting.Person{ID: "2", Name: "Onos"}, p)
+}
+
+func TestQueryGetNoRows(t *testing.T) {
+	db := setupPersonDB(t)
+
+	stmt, err := prepareExpression(
+		expressionForStatement("SELECT &Person.* FROM person WHERE id = $Person.id"),
+		[]any{sqlairtesting.Person{}},
+	)
+	assert.Nil(t, err)
+
+	var p sqlairtesting.Person
+	err = NewDB(db).Query(context.Background(), stmt, sqlairtesting.Person{ID: "99"}).Get(&p)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func TestQueryGetAll(t *testing.T) {
+	db := setupPersonDB(t)
+
+	stmt, err := prepareExpression(
+		expressionForStatement("SELECT &Person.* FROM person ORDER BY id"),
+		[]any{sqlairtesting.Person{}},
+	)
+	assert.Nil(t, err)
+
+	var people []sqlairtesting.Person
+	err = NewDB(db).Query(context.Background(), stmt).GetAll(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, samplePeople(), people)
+}
+
+func TestQueryIterDiscardsUnmappedColumns(t *testing.T) {
+	db := setupPersonDB(t)
+
+	stmt, err := prepareExpression(
+		expressionForStatement("SELECT &Person.name, 'extra' FROM person WHERE id = $Person.id"),
+		[]any{sqlairtesting.Person{}},
+	)
+	assert.Nil(t, err)
+
+	iter := NewDB(db).Query(context.Background(), stmt, sqlairtesting.Person{ID: "1"}).Iter()
+
+	var people []sqlairtesting.Person
+	for iter.Next() {
+		var p sqlairtesting.Person
+		assert.Nil(t, iter.Decode(&p))
+		people = append(people, p)
+	}
+	assert.Nil(t, iter.Close())
+	assert.Equal(t, []sqlairtesting.Person{{Name: "Lorn"}}, people)
+}
+
+func TestQueryMissingInputError(t *testing.T) {
+	db := setupPersonDB(t)
+
+	stmt, err := prepareExpression(
+		expressionForStatement("SELECT &Person.* FROM person WHERE id = $Person.id"),
+		[]any{sqlairtesting.Person{}},
+	)
+	assert.Nil(t, err)
+
+	var p sqlairtesting.Person
+	err = NewDB(db).Query(context.Background(), stmt).Get(&p)
+	assert.EqualError(t, err, `no input of type "Person" supplied for statement`)
+}
+
+func TestQueryUnusedInputError(t *testing.T) {
+	type Address struct{}
+	db := setupPersonDB(t)
+
+	stmt, err := prepareExpression(
+		expressionForStatement("SELECT &Person.* FROM person WHERE id = $Person.id"),
+		[]any{sqlairtesting.Person{}},
+	)
+	assert.Nil(t, err)
+
+	var p sqlairtesting.Person
+	err = NewDB(db).Query(context.Background(), stmt, sqlairtesting.Person{ID: "1"}, Address{}).Get(&p)
+	assert.Equal(t, NewErrSuperfluousType("Address"), err)
+}
+
+func TestQueryConcurrentStatementUse(t *testing.T) {
+	db := setupPersonDB(t)
+	sqlairDB := NewDB(db)
+
+	stmt, err := prepareExpression(
+		expressionForStatement("SELECT &Person.* FROM person WHERE id = $Person.id"),
+		[]any{sqlairtesting.Person{}},
+	)
+	assert.Nil(t, err)
+
+	people := samplePeople()
+	results := make([]sqlairtesting.Person, 50)
+	errs := make([]error, len(results))
+
+	// A single statement is shared by every goroutine.
+	wg := sync.WaitGroup{}
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			input := sqlairtesting.Person{ID: people[i%len(people)].ID}
+			errs[i] = sqlairDB.Query(context.Background(), stmt, input).Get(&results[i])
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		assert.Nil(t, errs[i])
+		assert.Equal(t, people[i%len(people)], result)
+	}
+}
+
+func TestQueryGetAllPage(t *testing.T) {
+	type Page struct {
+		Size   int `db:"size"`
+		Offset int `db:"offset"`
+	}
+	db := setupPersonDB(t)
+
+	stmt, err := Prepare(
+		"SELECT &Person.* FROM person ORDER BY id LIMIT $Page.size OFFSET $Page.offset",
+		sqlairtesting.Person{}, Page{},
+	)
+	assert.Nil(t, err)
+
+	var people []sqlairtesting.Person
+	err = NewDB(db).Query(context.Background(), stmt, Page{Size: 2, Offset: 1}).GetAll(&people)
+	assert.Nil(t, err)
+	assert.Equal(t, samplePeople()[1:], people)
+}
+
+func TestQueryGetMultipleTargets(t *testing.T) {
+	type Address struct {
+		ID     string `db:"id"`
+		Street string `db:"street"`
+	}
+	db := setupPersonDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE address (id TEXT, street TEXT)"); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO address VALUES ('1', 'Main St'), ('2', 'High St')")
+		return err
+	})
+
+	stmt, err := Prepare(
+		"SELECT &Person.*, &Address.* FROM person JOIN address USING (id) ORDER BY id",
+		sqlairtesting.Person{}, Address{},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t,
+		`SELECT id AS "Person.id", name , id AS "Address.id", street FROM person JOIN address USING (id) ORDER BY id`,
+		stmt.sql)
+
+	var p sqlairtesting.Person
+	var a Address
+	err = NewDB(db).Query(context.Background(), stmt).Get(&p, &a)
+	assert.Nil(t, err)
+	assert.Equal(t, sqlairtesting.Person{ID: "1", Name: "Lorn"}, p)
+	assert.Equal(t, Address{ID: "1", Street: "Main St"}, a)
+
+	var people []sqlairtesting.Person
+	var addresses []Address
+	err = NewDB(db).Query(context.Background(), stmt).GetAll(&people, &addresses)
+	assert.Nil(t, err)
+	assert.Equal(t, samplePeople()[:2], people)
+	assert.Equal(t, []Address{{ID: "1", Street: "Main St"}, {ID: "2", Street: "High St"}}, addresses)
+}
+
+func TestQuerySubqueryOutputTarget(t *testing.T) {
+	type PersonStats struct {
+		OrderCount int `db:"order_count"`
+	}
+	db := setupPersonDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE orders (id TEXT, pid TEXT)"); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO orders VALUES ('a', '1'), ('b', '1'), ('c', '2')")
+		return err
+	})
+
+	stmt, err := Prepare(`
+SELECT p.* AS &Person.*,
+       (SELECT count(*) FROM orders o WHERE o.pid = p.id) AS &PersonStats.order_count
+FROM person AS p ORDER BY p.id`, sqlairtesting.Person{}, PersonStats{})
+	assert.Nil(t, err)
+	assert.Equal(t,
+		`SELECT p.id AS p_id, p.name AS p_name , (SELECT count(*) FROM orders o WHERE o.pid = p.id) AS "PersonStats.order_count" FROM person AS p ORDER BY p.id`,
+		stmt.sql)
+
+	var people []sqlairtesting.Person
+	var stats []PersonStats
+	err = NewDB(db).Query(context.Background(), stmt).GetAll(&people, &stats)
+	assert.Nil(t, err)
+	assert.Equal(t, samplePeople(), people)
+	assert.Equal(t, []PersonStats{{OrderCount: 2}, {OrderCount: 1}, {OrderCount: 0}}, stats)
+
+	_, err = Prepare(
+		"SELECT (SELECT &Person.id FROM person) AS &PersonStats.order_count FROM person",
+		sqlairtesting.Person{}, PersonStats{})
+	assert.EqualError(t, err, `subquery decoded into output target "&PersonStats.order_count" can not contain output target "&Person.id"`)
+
+	_, err = Prepare(
+		"SELECT (SELECT count(*) FROM orders) AS &PersonStats.* FROM person",
+		PersonStats{})
+	assert.EqualError(t, err, `columns "(SELECT count(*) FROM orders)" can not be decoded into output target "&PersonStats.*"`)
+}
+
+func TestQueryGetMap(t *testing.T) {
+	db := setupPersonDB(t)
+
+	stmt, err := Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	people := map[string]sqlairtesting.Person{"9": {ID: "9"}}
+	err = NewDB(db).Query(context.Background(), stmt).GetMap("id", &people)
+	assert.Nil(t, err)
+	assert.Len(t, people, 4)
+	assert.Equal(t, sqlairtesting.Person{ID: "2", Name: "Onos"}, people["2"])
+
+	var byName map[string]sqlairtesting.Person
+	err = NewDB(db).Query(context.Background(), stmt).GetMap("name", &byName)
+	assert.Nil(t, err)
+	assert.Equal(t, sqlairtesting.Person{ID: "3", Name: "Fred"}, byName["Fred"])
+
+	var byInt map[int]sqlairtesting.Person
+	err = NewDB(db).Query(context.Background(), stmt).GetMap("id", &byInt)
+	assert.EqualError(t, err, `key "id" of type string can not be converted to map key type int`)
+
+	err = NewDB(db).Query(context.Background(), stmt).GetMap("age", &byName)
+	assert.EqualError(t, err, `key "age" is not an output column of type "Person"`)
+
+	err = NewDB(db).Query(context.Background(), stmt).GetMap("id", byName)
+	assert.EqualError(t, err, "expected pointer to map, got map[string]testing.Person")
+}
+
+func TestQueryCollect(t *testing.T) {
+	type Address struct {
+		PersonID string `db:"person_id"`
+		Street   string `db:"street"`
+	}
+	type Owner struct {
+		ID        string `db:"id"`
+		Name      string `db:"name"`
+		Addresses []Address
+	}
+	db := setupPersonDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE address (person_id TEXT, street TEXT)"); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO address VALUES ('1', 'Main St'), ('2', 'High St'), ('1', 'Low Rd')")
+		return err
+	})
+
+	stmt, err := Prepare(`
+SELECT p.* AS &Owner.*, a.* AS &Address.*
+  FROM person AS p
+  JOIN address AS a ON a.person_id = p.id
+ ORDER BY p.id, a.street`, Owner{}, Address{})
+	assert.Nil(t, err)
+
+	var owners []Owner
+	err = NewDB(db).Query(context.Background(), stmt).Collect("id", "Addresses", &owners)
+	assert.Nil(t, err)
+	assert.Equal(t, []Owner{
+		{ID: "1", Name: "Lorn", Addresses: []Address{{PersonID: "1", Street: "Low Rd"}, {PersonID: "1", Street: "Main St"}}},
+		{ID: "2", Name: "Onos", Addresses: []Address{{PersonID: "2", Street: "High St"}}},
+	}, owners)
+
+	err = NewDB(db).Query(context.Background(), stmt).Collect("id", "Name", &owners)
+	assert.EqualError(t, err, `type "Owner" has no slice field "Name"`)
+
+	err = NewDB(db).Query(context.Background(), stmt).Collect("street", "Addresses", &owners)
+	assert.EqualError(t, err, `key "street" is not an output column of type "Owner"`)
+
+	err = NewDB(db).Query(context.Background(), stmt).Collect("id", "Addresses", owners)
+	assert.EqualError(t, err, "expected pointer to slice of structs, got []sqlair.Owner")
+}
+
+func TestQueryForEach(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+
+	stmt, err := Prepare("SELECT &Person.name, 'extra' FROM person ORDER BY id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	// The output is reset before each row, so fields
+	// not in the results do not keep stale values.
+	p := sqlairtesting.Person{ID: "stale"}
+	var people []sqlairtesting.Person
+	err = db.Query(context.Background(), stmt).ForEach(func() error {
+		people = append(people, p)
+		return nil
+	}, &p)
+	assert.Nil(t, err)
+	assert.Equal(t, []sqlairtesting.Person{{Name: "Lorn"}, {Name: "Onos"}, {Name: "Fred"}}, people)
+
+	calls := 0
+	err = db.Query(context.Background(), stmt).ForEach(func() error {
+		calls++
+		return errors.New("stop")
+	}, &p)
+	assert.EqualError(t, err, "stop")
+	assert.Equal(t, 1, calls)
+
+	err = db.Query(context.Background(), stmt).ForEach(func() error { return nil }, p)
+	assert.EqualError(t, err, "expected non-nil pointer to output struct, got testing.Person")
+}
+
+func TestQueryIterCancelled(t *testing.T) {
+	sqlDB := setupPersonDB(t)
+	stmt, err := Prepare("SELECT &Person.* FROM person ORDER BY id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name  string
+		query func(ctx context.Context) *Query
+	}{{
+		name: "rows",
+		query: func(ctx context.Context) *Query {
+			return NewDB(sqlDB).Query(ctx, stmt)
+		},
+	}, {
+		name: "cached",
+		query: func(ctx context.Context) *Query {
+			return NewDB(sqlDB).With(WithResultCache(NewResultCache(time.Minute, 0))).Query(ctx, stmt)
+		},
+	}, {
+		name: "pipelined",
+		query: func(ctx context.Context) *Query {
+			return NewDB(sqlDB).Query(ctx, stmt).Pipelined(1)
+		},
+	}}
+	for _, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		iter := test.query(ctx).Iter()
+
+		var p sqlairtesting.Person
+		assert.True(t, iter.Next())
+		assert.Nil(t, iter.Decode(&p))
+		assert.Equal(t, "Lorn", p.Name)
+
+		cancel()
+		assert.False(t, iter.Next(), test.name)
+		assert.False(t, iter.Next(), test.name)
+		assert.Equal(t, context.Canceled, iter.Decode(&p))
+
+		// The connection, of which there is only one,
+		// is released without waiting for Close.
+		var people []sqlairtesting.Person
+		assert.Nil(t, NewDB(sqlDB).Query(context.Background(), stmt).GetAll(&people), test.name)
+		assert.Len(t, people, 3)
+
+		assert.Equal(t, context.Canceled, iter.Close(), test.name)
+		assert.Equal(t, context.Canceled, iter.Close(), test.name)
+	}
+}
+
+func TestScanBufferPooling(t *testing.T) {
+	buf := getScanBuffer(3)
+	assert.Len(t, buf.ptrs, 3)
+
+	var p sqlairtesting.Person
+	buf.ptrs[0] = &p.ID
+	buf.discard = "value"
+	putScanBuffer(buf)
+	assert.Equal(t, []any{nil, nil, nil}, buf.ptrs)
+	assert.Nil(t, buf.discard)
+
+	// A buffer taken from the pool is resized as required.
+	buf = getScanBuffer(5)
+	assert.Len(t, buf.ptrs, 5)
+	putScanBuffer(buf)
+}
+
+func samplePeople() []sqlairtesting.Person {
+	return []sqlairtesting.Person{
+		{ID: "1", Name: "Lorn"},
+		{ID: "2", Name: "Onos"},
+		{ID: "3", Name: "Fred"},
+	}
+}
+
+// setupPersonDB returns a database with a populated person table.
+// The database is limited to a single connection,
+// because each connection to an in-memory SQLite
+// database would otherwise see a different database.
+func setupPersonDB(t *testing.T) *sql.DB {
+	db := setupDB(t)
+	db.SetMaxOpenConns(1)
+
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE person (id TEXT, name TEXT)"); err != nil {
+			return err
+		}
+		for _, p := range samplePeople() {
+			if _, err := tx.Exec("INSERT INTO person VALUES (?, ?)", p.ID, p.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return db
+}
@@ -0,0 +1,70 @@
+package sqlair
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// IdentifierQuoting is the policy by which the column names that Sqlair
+// writes into the SQL, such as those of the fields expanded from
+// "&Person.*", are quoted. They are derived from struct tags, which may
+// name columns that can not be written as bare identifiers. Whatever the
+// policy, names that are reserved words are quoted. It is set by
+// WithIdentifierQuoting.
+type IdentifierQuoting int
+
+const (
+	// QuoteInvalidIdentifiers, the default, quotes names that can not be
+	// written as bare identifiers: those that begin with a digit or contain
+	// a character other than a letter, digit, combining mark or underscore.
+	// Letters and digits of any script are allowed, as they are by SQLite,
+	// PostgreSQL and MySQL.
+	QuoteInvalidIdentifiers IdentifierQuoting = iota
+
+	// QuoteNonASCIIIdentifiers also quotes names containing characters
+	// other than ASCII letters, digits and underscores, for databases
+	// whose bare identifiers are restricted to them.
+	QuoteNonASCIIIdentifiers
+)
+
+// WithIdentifierQuoting returns an option setting the policy
+// by which column names derived from struct tags are quoted.
+//
+// Example:
+//
+//     type Person struct {
+//         ID   string `db:"id"`
+//         Name string `db:"nom_complet"`
+//     }
+//
+//     stmt, err := sqlair.Prepare(`SELECT &Person.* FROM person`,
+//         sqlair.WithIdentifierQuoting(sqlair.QuoteNonASCIIIdentifiers), Person{})
+//
+func WithIdentifierQuoting(q IdentifierQuoting) PrepareOption {
+	return func(o *prepareOptions) {
+		o.quoting = q
+	}
+}
+
+// isBare returns true if the input name can be written
+// as a bare identifier under the quoting policy.
+func (q IdentifierQuoting) isBare(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_':
+		case r >= utf8.RuneSelf && q == QuoteNonASCIIIdentifiers:
+			return false
+		case unicode.IsLetter(r):
+		case unicode.IsDigit(r) || unicode.IsMark(r):
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
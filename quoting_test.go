@@ -0,0 +1,46 @@
+package sqlair
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Profile struct {
+	ID       string `db:"id"`
+	Name     string `db:"名前"`
+	FullName string `db:"full name"`
+	TwoFA    bool   `db:"2fa"`
+	Order    int    `db:"order"`
+}
+
+func TestIdentifierQuoting(t *testing.T) {
+	tests := []struct {
+		quoting  []any
+		expected string
+	}{{
+		nil,
+		`SELECT id, 名前, "full name", "2fa", "order" FROM profile WHERE 名前 = ?`,
+	}, {
+		[]any{WithIdentifierQuoting(QuoteNonASCIIIdentifiers)},
+		`SELECT id, "名前", "full name", "2fa", "order" FROM profile WHERE 名前 = ?`,
+	}}
+
+	for _, test := range tests {
+		stmt, err := Prepare("SELECT &Profile.* FROM profile WHERE 名前 = $Profile.名前", append(test.quoting, Profile{})...)
+		if assert.Nil(t, err) {
+			assert.Equal(t, test.expected, stmt.sql)
+		}
+	}
+}
+
+func TestIdentifierQuotingIsBare(t *testing.T) {
+	for name, bare := range map[string]bool{
+		"id": true, "_x1": true, "名前": true, "été": true,
+		"": false, "2fa": false, "\u0301e": false, "a-b": false, "a b": false, `a"b`: false,
+	} {
+		assert.Equal(t, bare, QuoteInvalidIdentifiers.isBare(name), name)
+	}
+	assert.False(t, QuoteNonASCIIIdentifiers.isBare("été"))
+	assert.True(t, QuoteNonASCIIIdentifiers.isBare("ete_1"))
+}
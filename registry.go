@@ -0,0 +1,172 @@
+package sqlair
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// MustPrepare is like Prepare, but panics if the statement can not be
+// prepared. It simplifies the initialisation of package-level variables
+// holding statements that are known to be valid.
+func MustPrepare(stmt string, args ...any) *Statement {
+	s, err := Prepare(stmt, args...)
+	if err != nil {
+		panic(errors.Wrapf(err, "preparing statement %q", stmt))
+	}
+	return s
+}
+
+// Registry holds named statements, registered by the packages that use
+// them, typically when they are initialised. Errors preparing them are
+// not reported until Validate is called, so that every statement in a
+// program can be checked at once when it starts, rather than each failing
+// when it is first used. The executions of the registered statements are
+// counted, and reported by Usage. A Registry is safe for concurrent use.
+//
+// Example:
+//
+//     var statements = sqlair.NewRegistry()
+//
+//     var getPerson = statements.Register("getPerson", `
+//     SELECT &Person.*
+//       FROM person
+//      WHERE id = $Person.id`, Person{})
+//
+//     func main() {
+//         ...
+//         if err := statements.Validate(ctx, db); err != nil {
+//             log.Fatal(err)
+//         }
+//     }
+//
+type Registry struct {
+	mutex   sync.Mutex
+	entries map[string]*registryEntry
+
+	// duplicated holds the names under which a statement was
+	// registered more than once. Only the first is recorded.
+	duplicated map[string]bool
+}
+
+// registryEntry is a statement registered with a Registry.
+type registryEntry struct {
+	stmt *Statement
+
+	// err is the error, if any, preparing the statement.
+	err error
+
+	// deprecated is the reason for which the statement is
+	// deprecated, or "" if it is not; see Registry.Deprecate.
+	deprecated string
+}
+
+// NewRegistry returns a reference to a new, empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		entries:    make(map[string]*registryEntry),
+		duplicated: make(map[string]bool),
+	}
+}
+
+// Register prepares the input DSL statement, using type information from
+// the input objects as for Prepare, and records it under the input name.
+// The prepared statement is returned, or nil if it could not be prepared,
+// in which case the error is reported by Validate. Registering a second
+// statement with the same name is also an error reported by Validate; the
+// second statement is not recorded, and nil is returned for it.
+func (r *Registry) Register(name, stmt string, args ...any) *Statement {
+	s, err := Prepare(stmt, args...)
+	if s != nil {
+		s.usage = &statementUsage{}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.entries[name]; ok {
+		r.duplicated[name] = true
+		return nil
+	}
+	r.entries[name] = &registryEntry{stmt: s, err: err}
+	if err != nil {
+		return nil
+	}
+	return s
+}
+
+// Statement returns the statement registered with the
+// input name, or nil if it is not valid or not registered.
+func (r *Registry) Statement(name string) *Statement {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry, ok := r.entries[name]
+	if !ok || entry.err != nil {
+		return nil
+	}
+	return entry.stmt
+}
+
+// Validate returns an ErrInvalidStatements if any of the registered
+// statements could not be prepared, or if the SQL of any is rejected when
+// it is prepared by the input database, such as for naming a column that
+// does not exist. Statements with identifier templates are not checked by
+// the database, since their SQL is not complete. A name under which more
+// than one statement was registered is also reported.
+func (r *Registry) Validate(ctx context.Context, db *DB) error {
+	errs := make(map[string]error)
+	entries := make(map[string]*registryEntry)
+	r.mutex.Lock()
+	for name, entry := range r.entries {
+		entries[name] = entry
+	}
+	for name := range r.duplicated {
+		errs[name] = errors.New("statement registered more than once")
+	}
+	r.mutex.Unlock()
+
+	for name, entry := range entries {
+		if _, ok := errs[name]; ok {
+			continue
+		}
+		if entry.err != nil {
+			errs[name] = entry.err
+			continue
+		}
+		if err := db.validate(ctx, entry.stmt); err != nil {
+			errs[name] = err
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewErrInvalidStatements(errs)
+	}
+	return nil
+}
+
+// validate prepares the SQL of the input statement
+// on the DB's primary connection, and then closes it.
+func (db *DB) validate(ctx context.Context, s *Statement) error {
+	if len(s.templates) > 0 {
+		return nil
+	}
+
+	// The counts of LIMIT clauses are
+	// inlined by some dialects; see sqlFor.
+	args := make([]any, len(s.inputs))
+	for i := range args {
+		args[i] = 0
+	}
+	query, _, err := db.sqlFor(s, args)
+	if err != nil {
+		return err
+	}
+
+	prepared, err := db.conn.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	return prepared.Close()
+}
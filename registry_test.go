@@ -0,0 +1,71 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMustPrepare(t *testing.T) {
+	stmt := MustPrepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Equal(t, "SELECT id, name FROM person", stmt.sql)
+
+	assert.PanicsWithError(t,
+		`preparing statement "SELECT &Person.* FROM person": identity "Person" has no associated object from which to derive type information`,
+		func() { MustPrepare("SELECT &Person.* FROM person") })
+}
+
+func TestRegistryValidate(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+	r := NewRegistry()
+
+	get := r.Register("get", "SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.NotNil(t, get)
+	assert.Equal(t, get, r.Statement("get"))
+
+	r.Register("page", "SELECT &Person.* FROM person LIMIT $Person.id", sqlairtesting.Person{})
+	r.Register("table", "SELECT &Person.* FROM [[table]]", sqlairtesting.Person{})
+	assert.Nil(t, r.Validate(context.Background(), db))
+	assert.Nil(t, r.Validate(context.Background(), db.With(WithDialect(Dialect{InlineLimits: true}))))
+
+	assert.Nil(t, r.Register("untyped", "SELECT &Person.* FROM person"))
+	assert.Nil(t, r.Statement("untyped"))
+	r.Register("schema", "SELECT &Person.* FROM nowhere", sqlairtesting.Person{})
+	assert.Nil(t, r.Register("get", "SELECT &Person.* FROM person", sqlairtesting.Person{}))
+	assert.Equal(t, get, r.Statement("get"))
+	assert.Nil(t, r.Statement("unknown"))
+
+	err := r.Validate(context.Background(), db)
+	assert.EqualError(t, err, `invalid statements: `+
+		`"get": statement registered more than once; `+
+		`"schema": no such table: nowhere; `+
+		`"untyped": identity "Person" has no associated object from which to derive type information`)
+	if assert.IsType(t, &ErrInvalidStatements{}, err) {
+		assert.Len(t, err.(*ErrInvalidStatements).Errors(), 3)
+	}
+}
+
+// registryConn is a connection that looks up
+// a statement in a Registry for each it prepares.
+type registryConn struct {
+	*sql.DB
+	registry *Registry
+}
+
+// PrepareContext implements Conn.
+func (c registryConn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	c.registry.Statement("get")
+	return c.DB.PrepareContext(ctx, query)
+}
+
+func TestRegistryValidateUnlocked(t *testing.T) {
+	r := NewRegistry()
+	r.Register("get", "SELECT &Person.* FROM person", sqlairtesting.Person{})
+
+	// The registry is not locked while the database prepares statements.
+	db := NewDB(registryConn{DB: setupPersonDB(t), registry: r})
+	assert.Nil(t, r.Validate(context.Background(), db))
+}
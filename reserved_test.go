@@ -0,0 +1,126 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Purchase struct {
+	ID    string `db:"id"`
+	Order string `db:"order"`
+	Group string `db:"Group"`
+}
+
+func TestCompileQuotesReservedColumns(t *testing.T) {
+	stmt, err := Prepare("SELECT &Purchase.* FROM purchase WHERE id = $Purchase.id", Purchase{})
+	assert.Nil(t, err)
+
+	assert.Equal(t, `SELECT id, "order", "Group" FROM purchase WHERE id = ?`, stmt.sql)
+	assert.Equal(t, "Group", stmt.outputs[2].column)
+}
+
+func TestQueryReservedColumns(t *testing.T) {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`CREATE TABLE purchase (id TEXT, "order" TEXT, "Group" TEXT)`); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO purchase VALUES ('1', 'books', 'a')`)
+		return err
+	})
+
+	stmt, err := Prepare("SELECT &Purchase.* FROM purchase", Purchase{})
+	assert.Nil(t, err)
+
+	var p Purchase
+	err = NewDB(db).Query(context.Background(), stmt).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, Purchase{ID: "1", Order: "books", Group: "a"}, p)
+}
+
+func TestDialectIdentifier(t *testing.T) {
+	d := Dialect{ReservedWords: NewReservedWords("order", "KEY")}
+
+	assert.Equal(t, `"Order"`, d.identifier("Order", false))
+	assert.Equal(t, `"key"`, d.identifier("key", false))
+	assert.Equal(t, "name", d.identifier("name", false))
+	assert.Equal(t, `"name"`, d.identifier("name", true))
+	assert.Equal(t, "user", Dialect{}.identifier("user", false))
+	assert.Equal(t, `"user"`, Dialect{ReservedWords: postgresReservedWords}.identifier("user", false))
+}
+
+func TestSQLForReservedWords(t *testing.T) {
+	type Account struct {
+		ID   string `db:"id"`
+		User string `db:"user"`
+		Key  string `db:"key"`
+	}
+	stmt, err := Prepare("SELECT &Account.* FROM account WHERE id = $Account.id", Account{})
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT id, user, "key" FROM account WHERE id = ?`, stmt.sql)
+
+	args, err := stmt.bindInputs(context.Background(), []any{Account{ID: "1"}})
+	assert.Nil(t, err)
+
+	query, _, err := NewDB(nil, WithDialect(Dialect{ReservedWords: postgresReservedWords})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT id, "user", key FROM account WHERE id = ?`, query)
+
+	query, _, err = NewDB(nil, WithDialect(Dialect{ReservedWords: mysqlReservedWords})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT id, user, "key" FROM account WHERE id = ?`, query)
+
+	// The identifiers are written for the dialect however the statement
+	// is changed or restored.
+	cond, err := PrepareCondition("user = 'fred'")
+	assert.Nil(t, err)
+	stmt, err = stmt.Where(cond)
+	assert.Nil(t, err)
+	data, err := json.Marshal(stmt)
+	assert.Nil(t, err)
+	stmt, err = LoadStatement(data, Account{})
+	assert.Nil(t, err)
+	query, _, err = NewDB(nil, WithDialect(Dialect{ReservedWords: postgresReservedWords})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT id, "user", key FROM account WHERE (id = ?) AND (user = 'fred')`, query)
+}
+
+func TestSQLForReservedWordsOfGeneratedStatements(t *testing.T) {
+	type Grant struct {
+		ID   int64  `db:"id,pk"`
+		User string `db:"user"`
+	}
+	grants, err := CRUD(Grant{}, "grant")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, `UPDATE grant SET user = ? WHERE id = ?`, grants.Update.sql)
+
+	args, err := grants.Update.bindInputs(context.Background(), []any{Grant{ID: 1, User: "fred"}})
+	assert.Nil(t, err)
+	query, _, err := NewDB(nil, WithDialect(Dialect{ReservedWords: postgresReservedWords})).sqlFor(grants.Update, args)
+	assert.Nil(t, err)
+	assert.Equal(t, `UPDATE "grant" SET "user" = ? WHERE id = ?`, query)
+
+	// The names of generated conditions are written for the dialect.
+	type Person struct {
+		ID   int64  `db:"user,pk"`
+		Name string `db:"name"`
+	}
+	cond, err := KeyCondition(Person{})
+	assert.Nil(t, err)
+	stmt, err := Prepare("SELECT &Person.name FROM person", Person{})
+	assert.Nil(t, err)
+	stmt, err = stmt.Where(cond)
+	assert.Nil(t, err)
+
+	args, err = stmt.bindInputs(context.Background(), []any{Person{ID: 1}})
+	assert.Nil(t, err)
+	query, _, err = NewDB(nil, WithDialect(Dialect{ReservedWords: postgresReservedWords})).sqlFor(stmt, args)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT name FROM person WHERE ("user" = ?)`, query)
+}
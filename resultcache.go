@@ -0,0 +1,274 @@
+package sqlair
+
+import (
+	"container/list"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/canonical/sqlair/internal/parse"
+)
+
+// ResultCache holds the result rows of read-only queries, so that running
+// a query again with the same parameters does not access the database.
+// Results expire after the cache's time to live, and are invalidated when
+// a statement that modifies one of the tables they were read from is run
+// by a DB using the cache. When the cache holds its maximum number of
+// results, the least recently used is evicted to make room for another.
+// A ResultCache is safe for concurrent use.
+type ResultCache struct {
+	mutex sync.Mutex
+	ttl   time.Duration
+
+	// now returns the current time. It is replaced in tests.
+	now func() time.Time
+
+	// results holds the elements of lru by key; see resultKey.
+	results map[string]*list.Element
+
+	// lru holds the cached results, most recently used first.
+	lru *list.List
+
+	// maxSize is the maximum number of results held.
+	// Zero means that the size is unbounded.
+	maxSize int
+
+	// sweep is the time after which expired results are next removed.
+	sweep time.Time
+
+	// keysByTable holds, for each table, the
+	// keys of the results read from that table.
+	keysByTable map[string]map[string]bool
+}
+
+// cachedResult is the result of a query held by a ResultCache.
+type cachedResult struct {
+	key     string
+	columns []string
+	rows    [][]any
+	tables  []string
+	expires time.Time
+}
+
+// NewResultCache returns a reference to a new ResultCache, holding results
+// for the input time to live, and holding at most maxSize results, or an
+// unbounded number if maxSize is zero.
+func NewResultCache(ttl time.Duration, maxSize int) *ResultCache {
+	return &ResultCache{
+		ttl:         ttl,
+		now:         time.Now,
+		results:     make(map[string]*list.Element),
+		lru:         list.New(),
+		maxSize:     maxSize,
+		keysByTable: make(map[string]map[string]bool),
+	}
+}
+
+// WithResultCache returns an option with which a DB holds the results of
+// queries that only read in the input cache. Statements that modify the
+// database invalidate the cached results for the tables they write. A
+// statement that can not be classified invalidates every cached result;
+// see Statement.Tables.
+func WithResultCache(c *ResultCache) DBOption {
+	return func(db *DB) {
+		db.cache = c
+	}
+}
+
+// Invalidate removes the cached results of queries that read from any of
+// the input tables, which are matched in any case, as by RowFilter.
+func (c *ResultCache) Invalidate(tables ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, table := range tables {
+		for key := range c.keysByTable[tableKey(table)] {
+			c.remove(key)
+		}
+	}
+}
+
+// InvalidateAll removes every cached result.
+func (c *ResultCache) InvalidateAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.results = make(map[string]*list.Element)
+	c.lru.Init()
+	c.keysByTable = make(map[string]map[string]bool)
+}
+
+// Len returns the number of results in the cache,
+// including any that have expired but not yet been removed.
+func (c *ResultCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.lru.Len()
+}
+
+// get returns the unexpired result with the input key, and true,
+// or false if there is none.
+func (c *ResultCache) get(key string) (*cachedResult, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.results[key]
+	if !ok {
+		return nil, false
+	}
+	result := elem.Value.(*cachedResult)
+	if !c.now().Before(result.expires) {
+		c.remove(key)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return result, true
+}
+
+// put adds the input result to the cache under the input key, first
+// removing any results that have expired, at most once in the cache's
+// time to live, and then the least recently used, if the cache is full.
+func (c *ResultCache) put(key string, result *cachedResult) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	if !now.Before(c.sweep) {
+		c.removeExpired(now)
+		c.sweep = now.Add(c.ttl)
+	}
+
+	c.remove(key)
+	if c.maxSize > 0 && c.lru.Len() >= c.maxSize {
+		c.remove(c.lru.Back().Value.(*cachedResult).key)
+	}
+
+	result.key = key
+	result.expires = now.Add(c.ttl)
+	c.results[key] = c.lru.PushFront(result)
+	for _, table := range result.tables {
+		table = tableKey(table)
+		if c.keysByTable[table] == nil {
+			c.keysByTable[table] = make(map[string]bool)
+		}
+		c.keysByTable[table][key] = true
+	}
+}
+
+// removeExpired removes the results that have expired
+// by the input time. The cache's mutex must be held.
+func (c *ResultCache) removeExpired(now time.Time) {
+	for key, elem := range c.results {
+		if !now.Before(elem.Value.(*cachedResult).expires) {
+			c.remove(key)
+		}
+	}
+}
+
+// remove removes the result with the input key.
+// The cache's mutex must be held.
+func (c *ResultCache) remove(key string) {
+	elem, ok := c.results[key]
+	if !ok {
+		return
+	}
+	delete(c.results, key)
+	c.lru.Remove(elem)
+	for _, table := range elem.Value.(*cachedResult).tables {
+		table = tableKey(table)
+		delete(c.keysByTable[table], key)
+		if len(c.keysByTable[table]) == 0 {
+			delete(c.keysByTable, table)
+		}
+	}
+}
+
+// invalidateFor removes the cached results that may be changed by running
+// the input statement: those read from the table it writes. Read-only
+// statements change none, and a statement for which the written table is
+// not known may change any.
+func (c *ResultCache) invalidateFor(s *Statement) {
+	switch parse.Classify(s.expression) {
+	case parse.KindQuery:
+		return
+	case parse.KindDML, parse.KindDDL:
+		if written := s.Tables().Written; len(written) > 0 {
+			c.Invalidate(written...)
+			return
+		}
+	}
+	c.InvalidateAll()
+}
+
+// resultKey returns the key under which the result of running
+// the input SQL with the input parameters is cached. Parameters are
+// keyed by the values that are passed to the driver for them, rather
+// than by the addresses of those passed by reference.
+func resultKey(sql string, args []any) string {
+	var sb strings.Builder
+	sb.WriteString(sql)
+	for _, arg := range args {
+		arg = keyValue(arg)
+		fmt.Fprintf(&sb, "\x00%T:%#v", arg, arg)
+	}
+	return sb.String()
+}
+
+// keyValue returns the value by which the input parameter is keyed:
+// the value of a driver.Valuer, or of the value to which a pointer refers.
+func keyValue(arg any) any {
+	if valuer, ok := arg.(driver.Valuer); ok {
+		if v := reflect.ValueOf(arg); v.Kind() != reflect.Ptr || !v.IsNil() {
+			if value, err := valuer.Value(); err == nil {
+				return value
+			}
+		}
+	}
+	v := reflect.ValueOf(arg)
+	if v.Kind() != reflect.Ptr {
+		return arg
+	}
+	if v.IsNil() {
+		return nil
+	}
+	return keyValue(v.Elem().Interface())
+}
+
+// readResult reads every row of the input rows,
+// which are closed, into a result for caching.
+func readResult(rows *sql.Rows, columns []string, tables []string) (*cachedResult, error) {
+	defer func() { _ = rows.Close() }()
+
+	result := &cachedResult{columns: columns, tables: tables}
+	for rows.Next() {
+		row, err := readRow(rows, len(columns))
+		if err != nil {
+			return nil, err
+		}
+		result.rows = append(result.rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, rows.Close()
+}
+
+// readRow returns the values of the current row of the input
+// rows, which has the input number of columns, as read from the
+// database without conversion.
+func readRow(rows *sql.Rows, columns int) ([]any, error) {
+	row := make([]any, columns)
+	ptrs := make([]any, columns)
+	for i := range row {
+		ptrs[i] = &row[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
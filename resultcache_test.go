@@ -0,0 +1,271 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultCacheServesQueries(t *testing.T) {
+	conn := setupPersonDB(t)
+	cache := NewResultCache(time.Minute, 0)
+	db := NewDB(conn).With(WithResultCache(cache))
+	ctx := context.Background()
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	var p sqlairtesting.Person
+	err = db.Query(ctx, stmt, sqlairtesting.Person{ID: "1"}).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, "Lorn", p.Name)
+	assert.Equal(t, 1, cache.Len())
+
+	// A change made without the DB is not seen until the result is invalidated.
+	_, err = conn.Exec("UPDATE person SET name = 'Kruppe' WHERE id = '1'")
+	assert.Nil(t, err)
+
+	err = db.Query(ctx, stmt, sqlairtesting.Person{ID: "1"}).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, "Lorn", p.Name)
+
+	// Different parameters are a different result.
+	err = db.Query(ctx, stmt, sqlairtesting.Person{ID: "2"}).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, "Onos", p.Name)
+	assert.Equal(t, 2, cache.Len())
+
+	cache.Invalidate("PERSON")
+	assert.Equal(t, 0, cache.Len())
+
+	err = db.Query(ctx, stmt, sqlairtesting.Person{ID: "1"}).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, "Kruppe", p.Name)
+}
+
+func TestResultCacheInvalidatedByWrites(t *testing.T) {
+	conn := setupPersonDB(t)
+	cache := NewResultCache(time.Minute, 0)
+	db := NewDB(conn).With(WithResultCache(cache))
+	ctx := context.Background()
+
+	stmt, err := Prepare("SELECT &Person.* FROM person ORDER BY id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	other, err := Prepare("SELECT 1 AS &Person.id FROM address", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	_, err = conn.Exec("CREATE TABLE address (id TEXT)")
+	assert.Nil(t, err)
+
+	var people []sqlairtesting.Person
+	assert.Nil(t, db.Query(ctx, stmt).GetAll(&people))
+	assert.Nil(t, db.Query(ctx, other).GetAll(&people))
+	assert.Equal(t, 2, cache.Len())
+
+	update, err := Prepare("UPDATE person SET name = $Person.name WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	_, err = db.Exec(ctx, update, sqlairtesting.Person{ID: "3", Name: "Fiddler"})
+	assert.Nil(t, err)
+
+	// Only the result read from the person table is invalidated.
+	assert.Equal(t, 1, cache.Len())
+
+	people = nil
+	assert.Nil(t, db.Query(ctx, stmt).GetAll(&people))
+	assert.Equal(t, "Fiddler", people[2].Name)
+
+	// A statement that can not be classified invalidates everything.
+	pragma, err := Prepare("PRAGMA user_version = 1")
+	assert.Nil(t, err)
+	_, err = db.Exec(ctx, pragma)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestResultCacheInvalidatedByWritesToQuotedTables(t *testing.T) {
+	conn := setupPersonDB(t)
+	cache := NewResultCache(time.Minute, 0)
+	db := NewDB(conn).With(WithResultCache(cache))
+	ctx := context.Background()
+
+	stmt, err := Prepare(`SELECT &Person.* FROM "Person" WHERE id = $Person.id`, sqlairtesting.Person{})
+	assert.Nil(t, err)
+	var p sqlairtesting.Person
+	assert.Nil(t, db.Query(ctx, stmt, sqlairtesting.Person{ID: "1"}).Get(&p))
+	assert.Equal(t, "Lorn", p.Name)
+
+	update, err := Prepare(`UPDATE "Person" SET name = 'Kruppe' WHERE id = '1'`)
+	assert.Nil(t, err)
+	_, err = db.Exec(ctx, update)
+	assert.Nil(t, err)
+
+	assert.Nil(t, db.Query(ctx, stmt, sqlairtesting.Person{ID: "1"}).Get(&p))
+	assert.Equal(t, "Kruppe", p.Name)
+
+	// The table is matched whether or not it is quoted.
+	update, err = Prepare("UPDATE person SET name = 'Lorn' WHERE id = '1'")
+	assert.Nil(t, err)
+	_, err = db.Exec(ctx, update)
+	assert.Nil(t, err)
+
+	assert.Nil(t, db.Query(ctx, stmt, sqlairtesting.Person{ID: "1"}).Get(&p))
+	assert.Equal(t, "Lorn", p.Name)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestResultCacheExpiry(t *testing.T) {
+	cache := NewResultCache(time.Minute, 0)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.put("key", &cachedResult{tables: []string{"person"}})
+	_, ok := cache.get("key")
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok = cache.get("key")
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.Len())
+	assert.Len(t, cache.keysByTable, 0)
+}
+
+func TestResultCacheEviction(t *testing.T) {
+	cache := NewResultCache(time.Minute, 2)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.put("a", &cachedResult{tables: []string{"person"}})
+	cache.put("b", &cachedResult{tables: []string{"person"}})
+	_, ok := cache.get("a")
+	assert.True(t, ok)
+
+	// The least recently used result is evicted.
+	cache.put("c", &cachedResult{tables: []string{"address"}})
+	assert.Equal(t, 2, cache.Len())
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	assert.Equal(t, map[string]map[string]bool{"person": {"a": true}, "address": {"c": true}}, cache.keysByTable)
+
+	// Expired results are swept when another is added.
+	now = now.Add(time.Minute)
+	cache.put("d", &cachedResult{})
+	assert.Equal(t, 1, cache.Len())
+	assert.Len(t, cache.keysByTable, 0)
+}
+
+func TestResultCacheCopiesValues(t *testing.T) {
+	conn := setupPersonDB(t)
+	db := NewDB(conn, WithResultCache(NewResultCache(time.Minute, 0)))
+	ctx := context.Background()
+
+	type Raw struct {
+		Name any    `db:"name"`
+		Data []byte `db:"data"`
+	}
+	stmt, err := Prepare("SELECT CAST(name AS BLOB) AS &Raw.name, CAST(name AS BLOB) AS &Raw.data FROM person WHERE id = '1'", Raw{})
+	assert.Nil(t, err)
+
+	var r Raw
+	assert.Nil(t, db.Query(ctx, stmt).Get(&r))
+	r.Name.([]byte)[0] = 'X'
+	r.Data[0] = 'X'
+
+	r = Raw{}
+	assert.Nil(t, db.Query(ctx, stmt).Get(&r))
+	assert.Equal(t, Raw{Name: []byte("Lorn"), Data: []byte("Lorn")}, r)
+}
+
+func TestResultKey(t *testing.T) {
+	a, b := "1", "1"
+	assert.Equal(t, resultKey("q", []any{&a}), resultKey("q", []any{&b}))
+	assert.Equal(t, resultKey("q", []any{"1"}), resultKey("q", []any{&a}))
+	assert.NotEqual(t, resultKey("q", []any{"1"}), resultKey("q", []any{"2"}))
+	assert.Equal(t, resultKey("q", []any{sql.NullString{String: "1", Valid: true}}), resultKey("q", []any{"1"}))
+}
+
+func TestAssignValue(t *testing.T) {
+	var s string
+	assert.Nil(t, assignValue(&s, []byte("text")))
+	assert.Equal(t, "text", s)
+	assert.Nil(t, assignValue(&s, int64(7)))
+	assert.Equal(t, "7", s)
+
+	var i int32
+	assert.Nil(t, assignValue(&i, int64(42)))
+	assert.Equal(t, int32(42), i)
+	assert.Error(t, assignValue(&i, "x"))
+
+	var b bool
+	assert.Nil(t, assignValue(&b, int64(1)))
+	assert.True(t, b)
+
+	var p *string
+	assert.Nil(t, assignValue(&p, "a"))
+	assert.Equal(t, "a", *p)
+	assert.Nil(t, assignValue(&p, nil))
+	assert.Nil(t, p)
+	assert.EqualError(t, assignValue(&s, nil), "converting NULL to string is unsupported")
+
+	var ns sql.NullString
+	assert.Nil(t, assignValue(&ns, "a"))
+	assert.Equal(t, sql.NullString{String: "a", Valid: true}, ns)
+
+	var tm time.Time
+	now := time.Now()
+	assert.Nil(t, assignValue(&tm, now))
+	assert.Equal(t, now, tm)
+
+	var f float64
+	assert.Nil(t, assignValue(&f, []byte("1.5")))
+	assert.Equal(t, 1.5, f)
+
+	var u uint8
+	assert.EqualError(t, assignValue(&u, int64(300)),
+		`converting driver.Value type int64 ("300") to a uint8: value out of range`)
+
+	// Bytes are copied rather than shared with the destination.
+	src := []byte("abc")
+	var bs []byte
+	assert.Nil(t, assignValue(&bs, src))
+	src[0] = 'x'
+	assert.Equal(t, []byte("abc"), bs)
+
+	assert.EqualError(t, assignValue(&tm, "now"), "unsupported Scan, storing driver.Value type string into type *time.Time")
+
+	// Values are converted as sql.Rows.Scan converts them.
+	for _, value := range []any{int64(5), 2.5, true, "7", []byte("8"), now, nil} {
+		for _, dest := range []func() any{
+			func() any { return new(string) },
+			func() any { return new(int) },
+			func() any { return new(float32) },
+			func() any { return new(bool) },
+			func() any { return new(*int64) },
+			func() any { return new(any) },
+			func() any { return new(sql.NullInt64) },
+		} {
+			direct, scanned := dest(), dest()
+			err := assignValue(direct, value)
+			scanErr := scanValue(scanned, value)
+			assert.Equal(t, scanErr == nil, err == nil, "%T into %T: %v, %v", value, direct, err, scanErr)
+			if err == nil {
+				assert.Equal(t, scanned, direct, "%T into %T", value, direct)
+			}
+		}
+	}
+}
+
+// scanValue assigns the input value to the input destination
+// by scanning it from rows holding it, as sql.Rows.Scan does.
+func scanValue(dest, value any) error {
+	rows, err := sliceRows([]string{""}, [][]any{{value}})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	rows.Next()
+	return rows.Scan(dest)
+}
@@ -0,0 +1,220 @@
+package sqlair
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/canonical/sqlair/internal/parse"
+	"github.com/pkg/errors"
+)
+
+// RowFilter holds predicates that are ANDed into the WHERE clause of every
+// SELECT, UPDATE or DELETE statement, prepared with the filter, that names
+// the tables for which they are registered. It allows rules such as the
+// isolation of tenants' rows to be enforced in one place, rather than by
+// every statement. A RowFilter is set by WithRowFilter.
+//
+// Example:
+//
+//     filter := sqlair.NewRowFilter()
+//     err := filter.Register("person", "person.tenant_id = $Tenant.id", Tenant{})
+//
+//     stmt, err := sqlair.Prepare(`
+//     SELECT p.* AS &Person.*
+//       FROM person AS p`, Person{}, sqlair.WithRowFilter(filter))
+//
+//     err = db.Query(ctx, stmt, Tenant{ID: tenantID}).GetAll(&people)
+//
+type RowFilter struct {
+	mutex sync.Mutex
+
+	// predicates holds the predicates registered for each table.
+	predicates map[string][]*rowPredicate
+}
+
+// rowPredicate is a predicate registered with a RowFilter.
+type rowPredicate struct {
+	// table is the name of the table as registered.
+	table string
+
+	// source is the DSL of the predicate.
+	source string
+
+	// args are the objects supplying type
+	// information for the predicate's inputs.
+	args []any
+
+	// conditions holds the predicate as prepared for each alias of its
+	// table, so that it is parsed only once for each, however many
+	// statements it is added to.
+	conditions map[string]*Condition
+}
+
+// NewRowFilter returns a reference to a new RowFilter with no predicates.
+func NewRowFilter() *RowFilter {
+	return &RowFilter{predicates: make(map[string][]*rowPredicate)}
+}
+
+// Register adds the input DSL predicate, such as
+// "person.tenant_id = $Tenant.id", for the input table. Type information
+// for the predicate's input sources is inferred from the input objects.
+// Columns of the table must be qualified by its name, which is replaced by
+// the table's alias in statements that give it one. The predicate is added
+// to statements naming the table in any case, whether quoted or not.
+func (f *RowFilter) Register(table, predicate string, args ...any) error {
+	cond, err := PrepareCondition(predicate, args...)
+	if err != nil {
+		return errors.Wrapf(err, "registering predicate for table %q", table)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	name := tableKey(table)
+	f.predicates[name] = append(f.predicates[name], &rowPredicate{
+		table:      table,
+		source:     predicate,
+		args:       args,
+		conditions: map[string]*Condition{table: cond},
+	})
+	return nil
+}
+
+// condition returns the predicate as prepared for the input alias of its
+// table, preparing it with the columns of the table requalified by the
+// alias if it has not been already. The filter's mutex must be held.
+func (p *rowPredicate) condition(alias string) (*Condition, error) {
+	if cond, ok := p.conditions[alias]; ok {
+		return cond, nil
+	}
+
+	source, err := requalify(p.source, p.table, alias)
+	if err != nil {
+		return nil, err
+	}
+	cond, err := PrepareCondition(source, p.args...)
+	if err != nil {
+		return nil, err
+	}
+	p.conditions[alias] = cond
+	return cond, nil
+}
+
+// WithRowFilter returns an option adding the input filter's
+// predicates for the tables that the statement names.
+func WithRowFilter(f *RowFilter) PrepareOption {
+	return func(o *prepareOptions) {
+		o.filter = f
+	}
+}
+
+// apply returns a copy of the input statement with the predicates for the
+// tables that it names added to its WHERE clause. INSERT statements are
+// returned unchanged unless they read from a filtered table, which is an
+// error, as is a filtered table in a subquery or common table expression,
+// where the predicate can not be added. Other statements,
+// such as CREATE TABLE, are returned unchanged.
+func (f *RowFilter) apply(s *Statement) (*Statement, error) {
+	switch parse.Classify(s.expression) {
+	case parse.KindDDL, parse.KindAdmin, parse.KindUnknown:
+		return s, nil
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var conditions []*Condition
+	for _, ref := range parse.TableReferences(s.expression) {
+		predicates, ok := f.predicates[tableKey(ref.Name)]
+		if !ok {
+			continue
+		}
+		if ref.Nested {
+			return nil, errors.Errorf("filtered table %q can not be used in a subquery or common table expression", ref.Name)
+		}
+
+		for _, p := range predicates {
+			cond, err := p.condition(ref.Alias)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, cond)
+		}
+	}
+	if len(conditions) == 0 {
+		return s, nil
+	}
+
+	// The table into which an INSERT writes is not filtered.
+	if isInsert(s.expression) {
+		access := s.Tables()
+		for _, table := range access.Read {
+			if _, ok := f.predicates[tableKey(table)]; ok {
+				return nil, errors.Errorf("filtered table %q can not be read by an INSERT statement", table)
+			}
+		}
+		return s, nil
+	}
+
+	return s.Where(conditions...)
+}
+
+// isInsert returns true if the input statement expression
+// tree is for an INSERT, or a REPLACE, statement.
+func isInsert(exp parse.Expression) bool {
+	return isStatement(exp, "INSERT", "REPLACE")
+}
+
+// isUpdate returns true if the input statement
+// expression tree is for an UPDATE statement.
+func isUpdate(exp parse.Expression) bool {
+	return isStatement(exp, "UPDATE")
+}
+
+// isStatement returns true if the input statement expression tree
+// begins, after any WITH clause, with one of the input keywords.
+func isStatement(exp parse.Expression, keywords ...string) bool {
+	for _, child := range exp.Expressions() {
+		if _, ok := child.(*parse.WithExpression); ok {
+			continue
+		}
+		return isOneOfKeywords(child, keywords...)
+	}
+	return false
+}
+
+// requalify returns the input DSL predicate with the qualifier of each
+// column of the input table replaced by the input alias.
+func requalify(predicate, table, alias string) (string, error) {
+	if strings.EqualFold(table, alias) {
+		return predicate, nil
+	}
+
+	tokens, err := parse.Tokens(predicate)
+	if err != nil {
+		return "", err
+	}
+
+	// Token offsets are within the predicate as trimmed by the lexer.
+	source := strings.TrimSpace(strings.TrimPrefix(predicate, "\uFEFF"))
+
+	var sb strings.Builder
+	last := 0
+	for i, tok := range tokens {
+		if !isNameToken(tok) || !strings.EqualFold(tok.Literal, table) {
+			continue
+		}
+		if i+1 >= len(tokens) || tokens[i+1].Type != parse.PERIOD {
+			continue
+		}
+		if i > 0 && (tokens[i-1].Type == parse.PERIOD || tokens[i-1].Type == parse.DOLLAR || tokens[i-1].Type == parse.BITAND) {
+			continue
+		}
+
+		sb.WriteString(source[last:tok.Pos.Offset])
+		sb.WriteString(alias)
+		last = tok.Pos.Offset + len(tok.Literal)
+	}
+	sb.WriteString(source[last:])
+	return sb.String(), nil
+}
@@ -0,0 +1,150 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Tenant struct {
+	ID string `db:"id"`
+}
+
+type Account struct {
+	ID       string `db:"id"`
+	TenantID string `db:"tenant_id"`
+	Name     string `db:"name"`
+}
+
+func newTenantFilter(t *testing.T) *RowFilter {
+	filter := NewRowFilter()
+	err := filter.Register("account", "account.tenant_id = $Tenant.id", Tenant{})
+	assert.Nil(t, err)
+	return filter
+}
+
+func TestRowFilterCompiles(t *testing.T) {
+	filter := newTenantFilter(t)
+
+	tests := []struct {
+		stmt     string
+		expected string
+	}{{
+		"SELECT &Account.* FROM account WHERE name = $Account.name",
+		"SELECT id, tenant_id, name FROM account WHERE (name = ?) AND (account.tenant_id = ?)",
+	}, {
+		"SELECT a.* AS &Account.* FROM account AS a ORDER BY a.id",
+		`SELECT a.id AS a_id, a.tenant_id AS a_tenant_id, a.name AS a_name FROM account AS a WHERE (a.tenant_id = ?) ORDER BY a.id`,
+	}, {
+		"UPDATE account SET name = $Account.name",
+		"UPDATE account SET name = ? WHERE (account.tenant_id = ?)",
+	}, {
+		"UPDATE Account a SET name = $Account.name",
+		"UPDATE Account a SET name = ? WHERE (a.tenant_id = ?)",
+	}, {
+		"INSERT INTO account (name) VALUES ($Account.name)",
+		"INSERT INTO account (name) VALUES (?)",
+	}, {
+		"SELECT &Account.* FROM person",
+		"SELECT id, tenant_id, name FROM person",
+	}, {
+		// Table names are matched in any case, quoted or not.
+		"SELECT &Account.* FROM ACCOUNT",
+		"SELECT id, tenant_id, name FROM ACCOUNT WHERE (account.tenant_id = ?)",
+	}, {
+		`SELECT &Account.* FROM "ACCOUNT"`,
+		`SELECT id, tenant_id, name FROM "ACCOUNT" WHERE ("ACCOUNT".tenant_id = ?)`,
+	}, {
+		`SELECT &Account.* FROM main."Account" AS a`,
+		`SELECT id, tenant_id, name FROM main."Account" AS a WHERE (a.tenant_id = ?)`,
+	}}
+
+	for _, test := range tests {
+		stmt, err := Prepare(test.stmt, Account{}, WithRowFilter(filter))
+		if assert.Nil(t, err, test.stmt) {
+			assert.Equal(t, test.expected, stmt.sql, test.stmt)
+		}
+	}
+
+	stmt, err := Prepare("DELETE FROM account", WithRowFilter(filter))
+	assert.Nil(t, err)
+	assert.Equal(t, "DELETE FROM account WHERE (account.tenant_id = ?)", stmt.sql)
+
+	stmt, err = Prepare("DELETE FROM ONLY person USING account AS a WHERE person.id = a.id", WithRowFilter(filter))
+	assert.Nil(t, err)
+	assert.Equal(t, "DELETE FROM ONLY person USING account AS a WHERE (person.id = a.id) AND (a.tenant_id = ?)", stmt.sql)
+}
+
+func TestRowFilterErrors(t *testing.T) {
+	filter := newTenantFilter(t)
+
+	_, err := Prepare("SELECT &Account.* FROM person WHERE id IN (SELECT id FROM account)", Account{}, WithRowFilter(filter))
+	assert.EqualError(t, err, `filtered table "account" can not be used in a subquery or common table expression`)
+
+	_, err = Prepare("INSERT INTO person (name) SELECT name FROM account", WithRowFilter(filter))
+	assert.EqualError(t, err, `filtered table "account" can not be read by an INSERT statement`)
+
+	_, err = Prepare(`INSERT INTO person (name) SELECT name FROM "Account"`, WithRowFilter(filter))
+	assert.EqualError(t, err, `filtered table "Account" can not be read by an INSERT statement`)
+
+	_, err = Prepare("SELECT &Account.* FROM account UNION SELECT &Account.* FROM account", Account{}, WithRowFilter(filter))
+	assert.Error(t, err)
+
+	err = NewRowFilter().Register("account", "account.tenant_id = $Tenant.id")
+	assert.EqualError(t, err, `registering predicate for table "account": identity "Tenant" has no associated object from which to derive type information`)
+}
+
+func TestRowFilterPreparesPredicateOnce(t *testing.T) {
+	filter := newTenantFilter(t)
+
+	for _, stmt := range []string{
+		"SELECT a.* AS &Account.* FROM account AS a",
+		"UPDATE account AS a SET name = $Account.name",
+		"SELECT &Account.* FROM account",
+	} {
+		_, err := Prepare(stmt, Account{}, WithRowFilter(filter))
+		assert.Nil(t, err, stmt)
+	}
+
+	// The predicate is prepared when registered for the table itself, and
+	// again only for the alias, however many statements use each.
+	conditions := filter.predicates["account"][0].conditions
+	assert.Len(t, conditions, 2)
+	cond := conditions["a"]
+
+	_, err := Prepare("DELETE FROM account AS a", WithRowFilter(filter))
+	assert.Nil(t, err)
+	assert.Same(t, cond, filter.predicates["account"][0].conditions["a"])
+}
+
+func TestRowFilterQuery(t *testing.T) {
+	conn := setupDB(t)
+	runTx(t, conn, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("CREATE TABLE account (id TEXT, tenant_id TEXT, name TEXT)"); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO account VALUES ('1', 'a', 'Lorn'), ('2', 'b', 'Onos'), ('3', 'a', 'Fred')")
+		return err
+	})
+	db := NewDB(conn)
+
+	stmt, err := Prepare("SELECT &Account.* FROM account ORDER BY id", Account{}, WithRowFilter(newTenantFilter(t)))
+	assert.Nil(t, err)
+
+	var accounts []Account
+	err = db.Query(context.Background(), stmt, Tenant{ID: "a"}).GetAll(&accounts)
+	assert.Nil(t, err)
+	assert.Equal(t, []Account{{ID: "1", TenantID: "a", Name: "Lorn"}, {ID: "3", TenantID: "a", Name: "Fred"}}, accounts)
+
+	// The tenant must be supplied.
+	err = db.Query(context.Background(), stmt).GetAll(&accounts)
+	assert.EqualError(t, err, `no input of type "Tenant" supplied for statement`)
+}
+
+func TestRequalify(t *testing.T) {
+	source, err := requalify(" Account.tenant_id = $Account.id AND x.account.id = account.id ", "account", "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "a.tenant_id = $Account.id AND x.account.id = a.id", source)
+}
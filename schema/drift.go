@@ -0,0 +1,219 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/canonical/sqlair"
+	"github.com/pkg/errors"
+)
+
+// Report describes the differences between a table in a database and
+// the columns generated for a struct type by CreateTable.
+type Report struct {
+	// Table is the name of the table.
+	Table string `json:"table"`
+
+	// MissingTable is true if the table does not exist,
+	// in which case every column is missing.
+	MissingTable bool `json:"missing_table,omitempty"`
+
+	// Missing holds the columns of tagged fields that
+	// are not in the table, in declaration order.
+	Missing []string `json:"missing,omitempty"`
+
+	// Extra holds the columns of the table that do
+	// not have a tagged field, in alphabetical order.
+	Extra []string `json:"extra,omitempty"`
+
+	// Mismatches holds the columns whose type or
+	// nullability differ from those of their fields.
+	Mismatches []Mismatch `json:"mismatches,omitempty"`
+}
+
+// Mismatch is a column whose type, or nullability,
+// differs from that generated for its field.
+type Mismatch struct {
+	Column string `json:"column"`
+
+	// Expected is the type generated for the field,
+	// followed by "NOT NULL" if it is not nullable.
+	Expected string `json:"expected"`
+
+	// Actual is the type of the column in the database,
+	// followed by "NOT NULL" if it is not nullable.
+	Actual string `json:"actual"`
+}
+
+// HasDrift returns true if the report holds any differences.
+func (r Report) HasDrift() bool {
+	return r.MissingTable || len(r.Missing) > 0 || len(r.Extra) > 0 || len(r.Mismatches) > 0
+}
+
+// String returns a description of the report, with a line for
+// each difference, or a single line if there are none.
+func (r Report) String() string {
+	if !r.HasDrift() {
+		return fmt.Sprintf("table %q matches\n", r.Table)
+	}
+
+	var b strings.Builder
+	if r.MissingTable {
+		fmt.Fprintf(&b, "table %q is missing\n", r.Table)
+	}
+	for _, c := range r.Missing {
+		fmt.Fprintf(&b, "table %q is missing column %q\n", r.Table, c)
+	}
+	for _, c := range r.Extra {
+		fmt.Fprintf(&b, "table %q has extra column %q\n", r.Table, c)
+	}
+	for _, m := range r.Mismatches {
+		fmt.Fprintf(&b, "column %q of table %q is %s, expected %s\n", m.Column, r.Table, m.Actual, m.Expected)
+	}
+	return b.String()
+}
+
+// Drift compares the columns of the input table, read from the database
+// through the input connection, with those generated by CreateTable for
+// the input object's struct type, dialect and type overrides. It is
+// intended to be run when a service starts, or by tests against a
+// database built by migrations, to detect models and schemas that differ.
+//
+// Types are compared without regard to case or to length modifiers, such
+// as that of VARCHAR(64). In SQLite, types are compared by their affinity,
+// so that INT and INTEGER match, as do VARCHAR and TEXT. In PostgreSQL,
+// types are named as in information_schema.columns, such as "CHARACTER
+// VARYING" rather than VARCHAR, so overrides should use those names.
+// The nullability of primary key columns is not compared.
+//
+// Example:
+//
+//     report, err := schema.Drift(ctx, db, Person{}, "person", schema.SQLite, nil)
+//     if err != nil {
+//         return err
+//     }
+//     if report.HasDrift() {
+//         return errors.New(report.String())
+//     }
+//
+func Drift(ctx context.Context, conn sqlair.Conn, obj any, table string, d Dialect, overrides map[string]string) (Report, error) {
+	columns, _, err := tableColumns(obj, d, overrides)
+	if err != nil {
+		return Report{}, err
+	}
+
+	actual, err := introspect(ctx, conn, table, d)
+	if err != nil {
+		return Report{}, errors.Wrapf(err, "reading columns of table %q", table)
+	}
+
+	report := Report{Table: table, MissingTable: len(actual) == 0}
+	for _, c := range columns {
+		a, ok := actual[c.name]
+		if !ok {
+			report.Missing = append(report.Missing, c.name)
+			continue
+		}
+		delete(actual, c.name)
+
+		expected := dbColumn{typ: c.typ, notNull: !c.nullable}
+		if c.field.PrimaryKey {
+			expected.notNull, a.notNull = false, false
+		}
+		if !sameType(expected.typ, a.typ, d) || expected.notNull != a.notNull {
+			report.Mismatches = append(report.Mismatches, Mismatch{
+				Column:   c.name,
+				Expected: expected.String(),
+				Actual:   a.String(),
+			})
+		}
+	}
+	for name := range actual {
+		report.Extra = append(report.Extra, name)
+	}
+	sort.Strings(report.Extra)
+	return report, nil
+}
+
+// dbColumn is the type and nullability of a column.
+type dbColumn struct {
+	typ     string
+	notNull bool
+}
+
+// String returns the type of the column,
+// followed by "NOT NULL" if it is not nullable.
+func (c dbColumn) String() string {
+	if c.notNull {
+		return c.typ + " NOT NULL"
+	}
+	return c.typ
+}
+
+// introspect returns the columns of the input table, keyed by
+// name, or none if it does not exist, as read from the database.
+func introspect(ctx context.Context, conn sqlair.Conn, table string, d Dialect) (map[string]dbColumn, error) {
+	query := `SELECT name, type, "notnull" FROM pragma_table_info(?)`
+	if d == PostgreSQL {
+		query = `SELECT column_name, upper(data_type), is_nullable = 'NO'
+  FROM information_schema.columns
+ WHERE table_name = $1 AND table_schema = current_schema()`
+	}
+
+	rows, err := conn.QueryContext(ctx, query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns := make(map[string]dbColumn)
+	for rows.Next() {
+		var name string
+		var c dbColumn
+		if err := rows.Scan(&name, &c.typ, &c.notNull); err != nil {
+			return nil, err
+		}
+		columns[name] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return columns, rows.Close()
+}
+
+// sameType returns true if the input expected and actual
+// column types are equivalent in the input dialect.
+func sameType(expected, actual string, d Dialect) bool {
+	if d == SQLite {
+		return sqliteAffinity(expected) == sqliteAffinity(actual)
+	}
+	return baseType(expected) == baseType(actual)
+}
+
+// baseType returns the input type in upper
+// case, without any length modifier.
+func baseType(typ string) string {
+	if i := strings.IndexByte(typ, '('); i >= 0 {
+		typ = typ[:i]
+	}
+	return strings.ToUpper(strings.TrimSpace(typ))
+}
+
+// sqliteAffinity returns the affinity of a column of the input declared
+// type in SQLite. See https://www.sqlite.org/datatype3.html.
+func sqliteAffinity(typ string) string {
+	typ = strings.ToUpper(typ)
+	switch {
+	case strings.Contains(typ, "INT"):
+		return "INTEGER"
+	case strings.Contains(typ, "CHAR"), strings.Contains(typ, "CLOB"), strings.Contains(typ, "TEXT"):
+		return "TEXT"
+	case strings.Contains(typ, "BLOB"), typ == "":
+		return "BLOB"
+	case strings.Contains(typ, "REAL"), strings.Contains(typ, "FLOA"), strings.Contains(typ, "DOUB"):
+		return "REAL"
+	}
+	return "NUMERIC"
+}
@@ -0,0 +1,80 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestDriftNone(t *testing.T) {
+	db := openDB(t)
+	ddl, err := CreateTable(Person{}, "person", SQLite, nil)
+	assert.Nil(t, err)
+	_, err = db.Exec(ddl)
+	assert.Nil(t, err)
+
+	report, err := Drift(context.Background(), db, Person{}, "person", SQLite, nil)
+	assert.Nil(t, err)
+	assert.False(t, report.HasDrift())
+	assert.Equal(t, "table \"person\" matches\n", report.String())
+}
+
+func TestDrift(t *testing.T) {
+	db := openDB(t)
+	_, err := db.Exec(`CREATE TABLE membership (
+    group_id VARCHAR(36),
+    user_id INT,
+    weight TEXT,
+    joined TIMESTAMP
+)`)
+	assert.Nil(t, err)
+
+	report, err := Drift(context.Background(), db, Person{}, "person", SQLite, nil)
+	assert.Nil(t, err)
+	assert.True(t, report.MissingTable)
+	assert.Len(t, report.Missing, 7)
+
+	// Types are compared by affinity; the nullability
+	// of the primary key columns is not compared.
+	report, err = Drift(context.Background(), db, Membership{}, "membership", SQLite, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, Report{
+		Table: "membership",
+		Extra: []string{"joined"},
+		Mismatches: []Mismatch{
+			{Column: "weight", Expected: "REAL NOT NULL", Actual: "TEXT"},
+		},
+	}, report)
+	assert.Equal(t, "table \"membership\" has extra column \"joined\"\n"+
+		"column \"weight\" of table \"membership\" is TEXT, expected REAL NOT NULL\n", report.String())
+
+	type narrow struct {
+		GroupID string `db:"group_id,pk"`
+		Weight  string `db:"weight"`
+		Role    string `db:"role"`
+	}
+	report, err = Drift(context.Background(), db, narrow{}, "membership", SQLite, map[string]string{"weight": "TEXT"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"role"}, report.Missing)
+	assert.Equal(t, []string{"joined", "user_id"}, report.Extra)
+	assert.Equal(t, []Mismatch{{Column: "weight", Expected: "TEXT NOT NULL", Actual: "TEXT"}}, report.Mismatches)
+}
+
+func TestSameType(t *testing.T) {
+	assert.True(t, sameType("INTEGER", "int", SQLite))
+	assert.True(t, sameType("TEXT", "VARCHAR(64)", SQLite))
+	assert.False(t, sameType("REAL", "TEXT", SQLite))
+	assert.True(t, sameType("NUMERIC(20)", "NUMERIC", PostgreSQL))
+	assert.True(t, sameType("TIMESTAMP WITH TIME ZONE", "timestamp with time zone", PostgreSQL))
+	assert.False(t, sameType("BIGINT", "INTEGER", PostgreSQL))
+}
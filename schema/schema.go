@@ -0,0 +1,222 @@
+// Package schema generates the DDL for tables holding
+// the rows of the struct types used with Sqlair.
+package schema
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"time"
+
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// Dialect identifies the database for which DDL is generated.
+type Dialect int
+
+const (
+	// SQLite is the dialect of SQLite and dqlite.
+	SQLite Dialect = iota
+
+	// PostgreSQL is the dialect of PostgreSQL.
+	PostgreSQL
+)
+
+// String returns the name of the dialect.
+func (d Dialect) String() string {
+	switch d {
+	case SQLite:
+		return "SQLite"
+	case PostgreSQL:
+		return "PostgreSQL"
+	}
+	return "unknown"
+}
+
+// CreateTable returns a CREATE TABLE statement, for the input dialect, for
+// the input table with a column for each tagged field of the input object's
+// struct type, in the order in which the fields are declared. The type of
+// each column is that of the field in the dialect, unless the input
+// overrides, keyed by column name, hold another. Columns of pointer types,
+// and of types such as sql.NullString, are nullable; others are NOT NULL.
+//
+// The fields tagged "pk" form the table's primary key. A field tagged
+// "autoincrement" has its values generated by the database, as an
+// AUTOINCREMENT key in SQLite, where it must be the only field of the
+// primary key, or as an identity column in PostgreSQL. Fields with
+// transformers are given the type of the field, which may need to be
+// overridden with that of the values encoded for the database.
+//
+// Example:
+//
+//     type Person struct {
+//         ID      int64     `db:"id,pk,autoincrement"`
+//         Name    string    `db:"name"`
+//         Email   *string   `db:"email"`
+//         Created time.Time `db:"created"`
+//     }
+//
+//     ddl, err := schema.CreateTable(Person{}, "person", schema.SQLite,
+//         map[string]string{"name": "VARCHAR(64)"})
+//
+func CreateTable(obj any, table string, d Dialect, overrides map[string]string) (string, error) {
+	columns, key, err := tableColumns(obj, d, overrides)
+	if err != nil {
+		return "", err
+	}
+
+	var definitions []string
+	var inlineKey bool
+	for _, c := range columns {
+		definition := quote(c.name) + " " + c.typ
+		switch {
+		case c.field.AutoIncrement && d == SQLite:
+			// SQLite only generates keys for a column
+			// declared as the table's INTEGER PRIMARY KEY.
+			if len(key) != 1 || key[0] != c.name {
+				return "", errors.Errorf("autoincrement column %q must be the only primary key column for SQLite", c.name)
+			}
+			definition = quote(c.name) + " INTEGER PRIMARY KEY AUTOINCREMENT"
+			inlineKey = true
+		case c.field.AutoIncrement:
+			definition += " GENERATED BY DEFAULT AS IDENTITY"
+		case !c.nullable:
+			definition += " NOT NULL"
+		}
+		definitions = append(definitions, definition)
+	}
+
+	if len(key) > 0 && !inlineKey {
+		quoted := make([]string, len(key))
+		for i, column := range key {
+			quoted[i] = quote(column)
+		}
+		definitions = append(definitions, "PRIMARY KEY ("+strings.Join(quoted, ", ")+")")
+	}
+
+	return "CREATE TABLE " + quote(table) + " (\n    " + strings.Join(definitions, ",\n    ") + "\n)", nil
+}
+
+// column describes the column for a tagged struct field.
+type column struct {
+	name     string
+	typ      string
+	nullable bool
+	field    sqlairreflect.Field
+}
+
+// tableColumns returns the columns, in the input dialect, for the tagged
+// fields of the input object's struct type, in the order in which they are
+// declared, along with the columns of its primary key. The types of the
+// columns are overridden by those of the input overrides.
+func tableColumns(obj any, d Dialect, overrides map[string]string) ([]column, []string, error) {
+	info, err := sqlairreflect.Cache().Reflect(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	st, ok := info.(sqlairreflect.Struct)
+	if !ok {
+		return nil, nil, errors.Errorf("type %q is not a struct", info.Name())
+	}
+	if d != SQLite && d != PostgreSQL {
+		return nil, nil, errors.Errorf("unknown dialect %d", d)
+	}
+
+	for name := range overrides {
+		if _, ok := st.Fields[name]; !ok {
+			return nil, nil, errors.Errorf("type %q has no field with tag %q for type override", st.Name(), name)
+		}
+	}
+
+	var columns []column
+	for _, name := range st.Tags() {
+		field := st.Fields[name]
+		typ, nullable, err := columnType(st.Type().Field(field.Index).Type, d)
+		if override, ok := overrides[name]; ok {
+			typ, err = override, nil
+		}
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "column %q", name)
+		}
+		if field.AutoIncrement && d == SQLite {
+			typ = "INTEGER"
+		}
+		columns = append(columns, column{name: name, typ: typ, nullable: nullable, field: field})
+	}
+	return columns, st.PrimaryKey(), nil
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+
+	// nullTypes holds the type of the valid
+	// values of each of the sql.Null types.
+	nullTypes = map[reflect.Type]reflect.Type{
+		reflect.TypeOf(sql.NullString{}):  reflect.TypeOf(""),
+		reflect.TypeOf(sql.NullInt64{}):   reflect.TypeOf(int64(0)),
+		reflect.TypeOf(sql.NullInt32{}):   reflect.TypeOf(int32(0)),
+		reflect.TypeOf(sql.NullInt16{}):   reflect.TypeOf(int16(0)),
+		reflect.TypeOf(sql.NullByte{}):    reflect.TypeOf(byte(0)),
+		reflect.TypeOf(sql.NullFloat64{}): reflect.TypeOf(float64(0)),
+		reflect.TypeOf(sql.NullBool{}):    reflect.TypeOf(false),
+		reflect.TypeOf(sql.NullTime{}):    timeType,
+	}
+)
+
+// columnType returns the type, in the input dialect, of the column for a
+// field of the input type, and whether the column is nullable.
+func columnType(t reflect.Type, d Dialect) (string, bool, error) {
+	nullable := false
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+	if valid, ok := nullTypes[t]; ok {
+		t = valid
+		nullable = true
+	}
+
+	switch {
+	case t == timeType:
+		return dialectType(d, "TIMESTAMP", "TIMESTAMP WITH TIME ZONE"), nullable, nil
+	case t == bytesType:
+		return dialectType(d, "BLOB", "BYTEA"), nullable, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return dialectType(d, "BOOLEAN", "BOOLEAN"), nullable, nil
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return dialectType(d, "INTEGER", "SMALLINT"), nullable, nil
+	case reflect.Int32, reflect.Uint16:
+		return "INTEGER", nullable, nil
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return dialectType(d, "INTEGER", "BIGINT"), nullable, nil
+	case reflect.Uint, reflect.Uint64:
+		return dialectType(d, "INTEGER", "NUMERIC(20)"), nullable, nil
+	case reflect.Float32:
+		return "REAL", nullable, nil
+	case reflect.Float64:
+		return dialectType(d, "REAL", "DOUBLE PRECISION"), nullable, nil
+	case reflect.String:
+		return "TEXT", nullable, nil
+	}
+	return "", false, errors.Errorf("no column type for %s; supply an override", t)
+}
+
+// dialectType returns the input SQLite
+// or PostgreSQL type for the input dialect.
+func dialectType(d Dialect, sqlite, postgres string) string {
+	if d == PostgreSQL {
+		return postgres
+	}
+	return sqlite
+}
+
+// quote returns the input identifier quoted with double quotes, as
+// accepted by both dialects, with any double quotes within it doubled.
+func quote(id string) string {
+	return `"` + strings.ReplaceAll(id, `"`, `""`) + `"`
+}
@@ -0,0 +1,114 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/canonical/sqlair"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+type Person struct {
+	ID      int64          `db:"id,pk,autoincrement"`
+	Name    string         `db:"name"`
+	Email   *string        `db:"email"`
+	Nick    sql.NullString `db:"nick"`
+	Admin   bool           `db:"admin"`
+	Created time.Time      `db:"created"`
+	Avatar  []byte         `db:"avatar"`
+	Ignored string
+}
+
+type Membership struct {
+	GroupID string  `db:"group_id,pk"`
+	UserID  int32   `db:"user_id,pk"`
+	Weight  float64 `db:"weight"`
+}
+
+func TestCreateTableSQLite(t *testing.T) {
+	ddl, err := CreateTable(Person{}, "person", SQLite, map[string]string{"name": "VARCHAR(64)"})
+	assert.Nil(t, err)
+	assert.Equal(t, `CREATE TABLE "person" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "name" VARCHAR(64) NOT NULL,
+    "email" TEXT,
+    "nick" TEXT,
+    "admin" BOOLEAN NOT NULL,
+    "created" TIMESTAMP NOT NULL,
+    "avatar" BLOB NOT NULL
+)`, ddl)
+
+	// The table is usable with the statements generated for the type.
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(ddl)
+	assert.Nil(t, err)
+
+	people, err := sqlair.CRUD(Person{}, "person")
+	assert.Nil(t, err)
+	ctx := context.Background()
+	p := &Person{Name: "Fred", Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Avatar: []byte{}}
+	_, err = sqlair.NewDB(db).Exec(ctx, people.Insert, p)
+	assert.Nil(t, err)
+
+	var got Person
+	assert.Nil(t, sqlair.NewDB(db).Query(ctx, people.Select, Person{ID: p.ID}).Get(&got))
+	assert.Equal(t, "Fred", got.Name)
+	assert.Nil(t, got.Email)
+}
+
+func TestCreateTablePostgreSQL(t *testing.T) {
+	ddl, err := CreateTable(Person{}, "person", PostgreSQL, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `CREATE TABLE "person" (
+    "id" BIGINT GENERATED BY DEFAULT AS IDENTITY,
+    "name" TEXT NOT NULL,
+    "email" TEXT,
+    "nick" TEXT,
+    "admin" BOOLEAN NOT NULL,
+    "created" TIMESTAMP WITH TIME ZONE NOT NULL,
+    "avatar" BYTEA NOT NULL,
+    PRIMARY KEY ("id")
+)`, ddl)
+}
+
+func TestCreateTableCompositeKey(t *testing.T) {
+	ddl, err := CreateTable(Membership{}, "membership", SQLite, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `CREATE TABLE "membership" (
+    "group_id" TEXT NOT NULL,
+    "user_id" INTEGER NOT NULL,
+    "weight" REAL NOT NULL,
+    PRIMARY KEY ("group_id", "user_id")
+)`, ddl)
+}
+
+func TestCreateTableErrors(t *testing.T) {
+	type unsupported struct {
+		Tags map[string]string `db:"tags"`
+	}
+	_, err := CreateTable(unsupported{}, "t", SQLite, nil)
+	assert.EqualError(t, err, `column "tags": no column type for map[string]string; supply an override`)
+
+	ddl, err := CreateTable(unsupported{}, "t", SQLite, map[string]string{"tags": "JSON"})
+	assert.Nil(t, err)
+	assert.Equal(t, "CREATE TABLE \"t\" (\n    \"tags\" JSON NOT NULL\n)", ddl)
+
+	_, err = CreateTable(unsupported{}, "t", SQLite, map[string]string{"labels": "JSON"})
+	assert.EqualError(t, err, `type "unsupported" has no field with tag "labels" for type override`)
+
+	type serial struct {
+		ID     int64  `db:"id,pk,autoincrement"`
+		Region string `db:"region,pk"`
+	}
+	_, err = CreateTable(serial{}, "t", SQLite, nil)
+	assert.EqualError(t, err, `autoincrement column "id" must be the only primary key column for SQLite`)
+
+	_, err = CreateTable(1, "t", SQLite, nil)
+	assert.EqualError(t, err, `type "int" is not a struct`)
+}
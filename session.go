@@ -0,0 +1,93 @@
+package sqlair
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/canonical/sqlair/internal/parse"
+)
+
+// Session records the tables written by the statements run in a logical
+// session, such as the handling of a single request, so that a DB with
+// replicas runs later queries reading those tables on the primary, where
+// the writes are visible, rather than on a replica that may lag behind it.
+// A statement that modifies the database without being known to write
+// particular tables, such as a PRAGMA, sends every later query in the
+// session to the primary. A Session is safe for concurrent use.
+//
+// Example:
+//
+//     db := sqlair.NewReplicatedDB(primary, []sqlair.Conn{replica}, sqlair.WithSession(sqlair.NewSession()))
+//
+//     _, err := db.Exec(ctx, updatePerson, person)
+//
+//     // Run on the primary, since the person table was written.
+//     err = db.Query(ctx, selectPerson, person).Get(&person)
+//
+type Session struct {
+	mutex sync.Mutex
+
+	// written holds the tables written in the session.
+	written map[string]bool
+
+	// writtenAll is true if a statement that may
+	// have written any table was run in the session.
+	writtenAll bool
+}
+
+// NewSession returns a reference to a new Session
+// in which no tables have been written.
+func NewSession() *Session {
+	return &Session{written: make(map[string]bool)}
+}
+
+// WithSession returns an option with which a DB records the tables written
+// by the statements it runs in the input session. Queries reading any of
+// the tables written in the session are run on the primary.
+func WithSession(s *Session) DBOption {
+	return func(db *DB) {
+		db.session = s
+	}
+}
+
+// recordWrites records the tables that may be changed by running the
+// input statement: those it writes. Read-only statements change none,
+// and a statement for which the written table is not known may change any.
+func (s *Session) recordWrites(stmt *Statement) {
+	var written []string
+	switch parse.Classify(stmt.expression) {
+	case parse.KindQuery:
+		return
+	case parse.KindDML, parse.KindDDL:
+		written = stmt.Tables().Written
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(written) == 0 {
+		s.writtenAll = true
+	}
+	for _, table := range written {
+		s.written[strings.ToLower(table)] = true
+	}
+}
+
+// readsWritten returns true if the input
+// statement reads a table written in the session.
+func (s *Session) readsWritten(stmt *Statement) bool {
+	read := stmt.Tables().Read
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.writtenAll {
+		return true
+	}
+	for _, table := range read {
+		if s.written[strings.ToLower(table)] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionReadsYourWrites(t *testing.T) {
+	// Each database has a pet table naming the same pet differently,
+	// so that the database answering a query can be told.
+	conns := make([]*sql.DB, 2)
+	for i, name := range []string{"primary", "replica"} {
+		conns[i] = setupPersonDB(t)
+		_, err := conns[i].Exec("CREATE TABLE pet (name TEXT)")
+		assert.Nil(t, err)
+		_, err = conns[i].Exec("INSERT INTO pet VALUES (?)", name)
+		assert.Nil(t, err)
+	}
+	db := NewReplicatedDB(conns[0], []Conn{conns[1]})
+	session := db.With(WithSession(NewSession()))
+	ctx := context.Background()
+
+	type Pet struct {
+		Name string `db:"name"`
+	}
+	selectPet, err := Prepare("SELECT &Pet.* FROM pet", Pet{})
+	assert.Nil(t, err)
+	petName := func(db *DB) string {
+		var p Pet
+		err := db.Query(ctx, selectPet).Get(&p)
+		assert.Nil(t, err)
+		return p.Name
+	}
+	assert.Equal(t, "replica", petName(session))
+
+	// Writing the person table leaves reads of the pet table on replicas.
+	update, err := Prepare("UPDATE person SET name = 'Fred' WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	_, err = session.Exec(ctx, update, sqlairtesting.Person{ID: "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "replica", petName(session))
+
+	// Reads of the person table see the write.
+	selectPerson, err := Prepare("SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	var p sqlairtesting.Person
+	err = session.Query(ctx, selectPerson, sqlairtesting.Person{ID: "1"}).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, "Fred", p.Name)
+
+	// Once the session writes the pet table, its reads go to the primary,
+	// while those outside the session, and derived DBs, are unaffected.
+	insert, err := Prepare("INSERT INTO pet (name) VALUES ('Rex')")
+	assert.Nil(t, err)
+	err = session.Query(ctx, insert).Iter().Close()
+	assert.Nil(t, err)
+	assert.Equal(t, "primary", petName(session))
+	assert.Equal(t, "primary", petName(session.With(WithDialect(Dialect{}))))
+	assert.Equal(t, "replica", petName(db))
+}
+
+func TestSessionUnknownWrites(t *testing.T) {
+	conns := []*sql.DB{setupPersonDB(t), setupPersonDB(t)}
+	_, err := conns[0].Exec("UPDATE person SET name = 'primary'")
+	assert.Nil(t, err)
+	session := NewReplicatedDB(conns[0], []Conn{conns[1]}, WithSession(NewSession()))
+	ctx := context.Background()
+
+	// A statement whose writes are not known sends every read to the primary.
+	pragma, err := Prepare("PRAGMA user_version = 1")
+	assert.Nil(t, err)
+	_, err = session.Exec(ctx, pragma)
+	assert.Nil(t, err)
+
+	query, err := Prepare("SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	var p sqlairtesting.Person
+	err = session.Query(ctx, query, sqlairtesting.Person{ID: "1"}).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, "primary", p.Name)
+}
@@ -0,0 +1,342 @@
+package sqlairtest
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"regexp"
+	"sync"
+
+	"github.com/canonical/sqlair"
+	"github.com/pkg/errors"
+)
+
+// FakeDB is an in-memory stand-in for a database, for testing code that
+// runs Sqlair statements without a real database. It records the
+// statements executed by its DB, and serves canned results for those
+// matching the responses registered with it. Statements matching no
+// response return no rows, and affect none. A FakeDB is safe for
+// concurrent use.
+//
+// Example:
+//
+//     fake := sqlairtest.NewFakeDB()
+//     fake.OnStatement(getPerson).Rows([]string{"id", "name"}, []any{"1", "Fred"})
+//
+//     person, err := store.GetPerson(ctx, fake.DB(), "1")
+//
+//     executed := fake.Executed()
+//
+type FakeDB struct {
+	mutex sync.Mutex
+	db    *sqlair.DB
+
+	// responses holds the registered responses,
+	// the most recently registered being last.
+	responses []*Response
+
+	// executed holds the statements executed, in order.
+	executed []Execution
+}
+
+// Execution is a statement executed by a FakeDB.
+type Execution struct {
+	// SQL is the statement's SQL, as passed to the database.
+	SQL string
+
+	// Args holds the statement's parameters.
+	Args []any
+}
+
+// NewFakeDB returns a reference to a new FakeDB with no responses.
+func NewFakeDB() *FakeDB {
+	f := &FakeDB{}
+
+	// Slices are passed to the fake as parameters, rather than expanded
+	// into lists, so that the SQL executed is that of the statement.
+	f.db = sqlair.OpenConnector(fakeConnector{fake: f}, sqlair.WithDialect(sqlair.Dialect{NativeArrays: true}))
+	return f
+}
+
+// DB returns the DB with which to run statements against the fake.
+func (f *FakeDB) DB() *sqlair.DB {
+	return f.db
+}
+
+// On returns a new response for statements with SQL matching the input
+// regular expression, which panics if it does not compile. Where more than
+// one response matches a statement, that registered most recently is used.
+func (f *FakeDB) On(pattern string) *Response {
+	re := regexp.MustCompile(pattern)
+	return f.addResponse(re.MatchString)
+}
+
+// OnStatement returns a new response for the input statement, such as one
+// held by a Registry under a name. Where more than one response matches a
+// statement, that registered most recently is used.
+func (f *FakeDB) OnStatement(s *sqlair.Statement) *Response {
+	var sql string
+	for _, d := range s.Describe().SQL {
+		if d.Dialect == (sqlair.Dialect{NativeArrays: true}) {
+			sql = d.SQL
+		}
+	}
+	return f.addResponse(func(query string) bool { return query == sql })
+}
+
+// addResponse adds and returns a response
+// for SQL accepted by the input function.
+func (f *FakeDB) addResponse(match func(string) bool) *Response {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	r := &Response{match: match}
+	f.responses = append(f.responses, r)
+	return r
+}
+
+// Executed returns the statements executed by the fake, in order.
+func (f *FakeDB) Executed() []Execution {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return append([]Execution(nil), f.executed...)
+}
+
+// Reset removes the fake's responses and its record of executed statements.
+func (f *FakeDB) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.responses = nil
+	f.executed = nil
+}
+
+// execute records the execution of the input SQL with the
+// input parameters, and returns the response for it, if any.
+func (f *FakeDB) execute(query string, args []driver.NamedValue) *Response {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	f.executed = append(f.executed, Execution{SQL: query, Args: values})
+
+	for i := len(f.responses) - 1; i >= 0; i-- {
+		if r := f.responses[i]; r.match(query) {
+			return r
+		}
+	}
+	return &Response{}
+}
+
+// Response is the canned result of statements run against a FakeDB.
+// Its methods return the response, so that they can be chained.
+type Response struct {
+	match func(string) bool
+
+	mutex        sync.Mutex
+	columns      []string
+	rows         [][]any
+	rowsAffected int64
+	err          error
+}
+
+// Rows sets the result rows of the response, each holding
+// a value for each of the input columns, in order.
+func (r *Response) Rows(columns []string, rows ...[]any) *Response {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.columns = columns
+	r.rows = rows
+	return r
+}
+
+// RowsAffected sets the number of rows affected by statements
+// run with Exec, as reported by their results.
+func (r *Response) RowsAffected(n int64) *Response {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.rowsAffected = n
+	return r
+}
+
+// Error sets the error returned by the database for the statements.
+func (r *Response) Error(err error) *Response {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.err = err
+	return r
+}
+
+// fakeConnector opens connections to a FakeDB.
+type fakeConnector struct {
+	fake *FakeDB
+}
+
+// Connect implements driver.Connector.
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return fakeConn{fake: c.fake}, nil
+}
+
+// Driver implements driver.Connector.
+func (c fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+// fakeDriver is the driver of connections to a FakeDB.
+// A FakeDB can not be opened by name.
+type fakeDriver struct{}
+
+// Open implements driver.Driver.
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("a FakeDB can not be opened by name")
+}
+
+// fakeConn is a connection to a FakeDB.
+type fakeConn struct {
+	fake *FakeDB
+}
+
+// Prepare implements driver.Conn.
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{fake: c.fake, query: query}, nil
+}
+
+// Close implements driver.Conn.
+func (c fakeConn) Close() error {
+	return nil
+}
+
+// Begin implements driver.Conn.
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+// CheckNamedValue implements driver.NamedValueChecker,
+// passing every parameter to the fake as supplied.
+func (c fakeConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+// ExecContext implements driver.ExecerContext.
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r := c.fake.execute(query, args)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return driver.RowsAffected(r.rowsAffected), nil
+}
+
+// QueryContext implements driver.QueryerContext.
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	r := c.fake.execute(query, args)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &fakeRows{columns: r.columns, rows: r.rows}, nil
+}
+
+// fakeStmt is a statement prepared on a connection to a FakeDB.
+type fakeStmt struct {
+	fake  *FakeDB
+	query string
+}
+
+// Close implements driver.Stmt.
+func (s fakeStmt) Close() error {
+	return nil
+}
+
+// NumInput implements driver.Stmt. The
+// number of parameters is not checked.
+func (s fakeStmt) NumInput() int {
+	return -1
+}
+
+// Exec implements driver.Stmt.
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return fakeConn{fake: s.fake}.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+// Query implements driver.Stmt.
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return fakeConn{fake: s.fake}.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+// namedValues returns the input parameters as ordinal named values.
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+// fakeTx is a transaction on a connection to
+// a FakeDB, which has no effect on the fake.
+type fakeTx struct{}
+
+// Commit implements driver.Tx.
+func (fakeTx) Commit() error {
+	return nil
+}
+
+// Rollback implements driver.Tx.
+func (fakeTx) Rollback() error {
+	return nil
+}
+
+// fakeRows holds the canned rows of a response.
+type fakeRows struct {
+	columns []string
+	rows    [][]any
+
+	// pos is the number of rows read.
+	pos int
+}
+
+// Columns implements driver.Rows.
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+// Close implements driver.Rows.
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+// Next implements driver.Rows, converting the values of
+// the next row as for parameters passed to a driver.
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	row := r.rows[r.pos]
+	r.pos++
+
+	if len(row) != len(dest) {
+		return errors.Errorf("row %d has %d values for %d columns", r.pos, len(row), len(dest))
+	}
+	for i, v := range row {
+		value, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			return errors.Wrapf(err, "row %d, column %q", r.pos, r.columns[i])
+		}
+		dest[i] = value
+	}
+	return nil
+}
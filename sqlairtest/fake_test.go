@@ -0,0 +1,86 @@
+package sqlairtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/canonical/sqlair"
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type Filter struct {
+	IDs []string `db:"ids"`
+}
+
+func TestFakeDBQuery(t *testing.T) {
+	fake := NewFakeDB()
+	ctx := context.Background()
+
+	registry := sqlair.NewRegistry()
+	registry.Register("getPerson", "SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{})
+	fake.OnStatement(registry.Statement("getPerson")).Rows([]string{"id", "name"}, []any{"1", "Fred"})
+
+	var p sqlairtesting.Person
+	err := fake.DB().Query(ctx, registry.Statement("getPerson"), sqlairtesting.Person{ID: "1"}).Get(&p)
+	assert.Nil(t, err)
+	assert.Equal(t, sqlairtesting.Person{ID: "1", Name: "Fred"}, p)
+
+	// Slices are passed as parameters.
+	stmt, err := sqlair.Prepare("SELECT &Person.* FROM person WHERE id = ANY($Filter.ids)", sqlairtesting.Person{}, Filter{})
+	assert.Nil(t, err)
+	fake.On(`ANY\(\?\)`).Rows([]string{"id", "name"}, []any{"1", "Fred"}, []any{"2", "Onos"})
+
+	var people []sqlairtesting.Person
+	err = fake.DB().Query(ctx, stmt, Filter{IDs: []string{"1", "2"}}).GetAll(&people)
+	assert.Nil(t, err)
+	assert.Len(t, people, 2)
+
+	assert.Equal(t, []Execution{
+		{SQL: "SELECT id, name FROM person WHERE id = ?", Args: []any{"1"}},
+		{SQL: "SELECT id, name FROM person WHERE id = ANY(?)", Args: []any{[]string{"1", "2"}}},
+	}, fake.Executed())
+}
+
+func TestFakeDBExec(t *testing.T) {
+	fake := NewFakeDB()
+	ctx := context.Background()
+
+	stmt, err := sqlair.Prepare("UPDATE person SET name = $Person.name WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	// Statements without a response affect no rows.
+	result, err := fake.DB().Exec(ctx, stmt, sqlairtesting.Person{ID: "1", Name: "Fred"})
+	assert.Nil(t, err)
+	n, err := result.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), n)
+
+	fake.On("^UPDATE person").RowsAffected(1)
+	result, err = fake.DB().Exec(ctx, stmt, sqlairtesting.Person{ID: "1", Name: "Fred"})
+	assert.Nil(t, err)
+	n, err = result.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// The most recently registered response is used.
+	fake.On("^UPDATE").Error(errors.New("read only"))
+	_, err = fake.DB().Exec(ctx, stmt, sqlairtesting.Person{ID: "1", Name: "Fred"})
+	assert.EqualError(t, err, "read only")
+
+	assert.Len(t, fake.Executed(), 3)
+	fake.Reset()
+	assert.Empty(t, fake.Executed())
+}
+
+func TestFakeDBRowMismatch(t *testing.T) {
+	fake := NewFakeDB()
+	stmt, err := sqlair.Prepare("SELECT &Person.* FROM person", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	fake.OnStatement(stmt).Rows([]string{"id", "name"}, []any{"1"})
+
+	var p sqlairtesting.Person
+	err = fake.DB().Query(context.Background(), stmt).Get(&p)
+	assert.EqualError(t, err, "row 1 has 1 values for 2 columns")
+}
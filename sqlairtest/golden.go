@@ -0,0 +1,122 @@
+// Package sqlairtest provides helpers for testing code that uses Sqlair.
+package sqlairtest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/canonical/sqlair"
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+)
+
+// UpdateEnv is the environment variable which, when not empty, causes
+// Golden to write the golden files rather than compare against them.
+const UpdateEnv = "SQLAIR_UPDATE_GOLDEN"
+
+// Golden prepares the DSL statement in each file with the extension ".sql"
+// in the input directory, and compares the description of the statement,
+// as returned by Statement.Describe, with the contents of the file of the
+// same name with the extension ".golden". The description holds the SQL
+// compiled for each dialect, along with the statement's bindings and
+// warnings. A statement that can not be prepared is described by its error.
+// Each file is compared in a subtest named for it.
+//
+// The statements are prepared with the input arguments, each being passed
+// only to those statements that use its type, as well as any option of a
+// type declared by Sqlair, such as sqlair.WithStrictness(true). When the environment
+// variable named by UpdateEnv is set, the golden files are written instead,
+// so that they can be reviewed and committed.
+//
+// Example:
+//
+//     func TestQueries(t *testing.T) {
+//         sqlairtest.Golden(t, "testdata/queries", Person{}, Address{})
+//     }
+//
+func Golden(t *testing.T, dir string, args ...any) {
+	t.Helper()
+
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatalf("no .sql files in %q", dir)
+	}
+
+	update := os.Getenv(UpdateEnv) != ""
+	for _, input := range inputs {
+		input := input
+		name := strings.TrimSuffix(filepath.Base(input), ".sql")
+		t.Run(name, func(t *testing.T) {
+			dsl, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := describe(string(dsl), args)
+
+			golden := strings.TrimSuffix(input, ".sql") + ".golden"
+			if update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v; set %s=1 to create it", err, UpdateEnv)
+			}
+			if got != string(want) {
+				t.Errorf("description of %s does not match %s; set %s=1 to update it\n--- want:\n%s--- got:\n%s",
+					input, golden, UpdateEnv, want, got)
+			}
+		})
+	}
+}
+
+// describe returns the description of the input DSL statement, prepared
+// with those of the input arguments that it uses, or of its error.
+func describe(dsl string, args []any) string {
+	stmt, err := sqlair.Prepare(dsl, argsFor(dsl, args)...)
+	if err != nil {
+		return "error: " + err.Error() + "\n"
+	}
+	return stmt.Describe().String()
+}
+
+// argsFor returns the input arguments that are either objects of types
+// named in the input DSL statement, or options declared by Sqlair.
+func argsFor(dsl string, args []any) []any {
+	names := make(map[string]bool)
+	if tokens, err := sqlair.Tokens(dsl); err == nil {
+		for i := 1; i < len(tokens); i++ {
+			switch tokens[i-1].Type.String() {
+			case "DOLLAR", "BITAND":
+				names[tokens[i].Literal] = true
+			}
+		}
+	}
+
+	pkgPath := reflect.TypeOf(sqlair.Statement{}).PkgPath()
+	var used []any
+	for _, arg := range args {
+		t := reflect.TypeOf(arg)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t == nil {
+			continue
+		}
+
+		// The arguments of generic types are not among the tokens.
+		name, _, _ := strings.Cut(sqlairreflect.TypeName(t), "[")
+		if names[name] || t.PkgPath() == pkgPath {
+			used = append(used, arg)
+		}
+	}
+	return used
+}
@@ -0,0 +1,44 @@
+package sqlairtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/canonical/sqlair"
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+type Page struct {
+	Size int `db:"size"`
+}
+
+func TestGolden(t *testing.T) {
+	Golden(t, "testdata", sqlairtesting.Person{}, Page{})
+}
+
+func TestGoldenUpdate(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "select.sql")
+	assert.Nil(t, os.WriteFile(input, []byte("SELECT &Person.* FROM person"), 0644))
+
+	t.Setenv(UpdateEnv, "1")
+	Golden(t, dir, sqlairtesting.Person{})
+
+	golden, err := os.ReadFile(filepath.Join(dir, "select.golden"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(golden), "SELECT id, name FROM person")
+
+	// The written file matches when compared.
+	t.Setenv(UpdateEnv, "")
+	Golden(t, dir, sqlairtesting.Person{})
+}
+
+func TestArgsFor(t *testing.T) {
+	args := argsFor("SELECT &Person.* FROM person", []any{sqlairtesting.Person{}, Page{}, sqlair.WithStrictness(true)})
+	if assert.Len(t, args, 2) {
+		assert.Equal(t, sqlairtesting.Person{}, args[0])
+		assert.IsType(t, sqlair.WithStrictness(true), args[1])
+	}
+}
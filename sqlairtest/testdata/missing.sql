@@ -0,0 +1 @@
+SELECT &Person.* FROM person WHERE id = $Person.surname
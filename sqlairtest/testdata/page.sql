@@ -0,0 +1,4 @@
+SELECT &Person.*
+  FROM person
+ ORDER BY name
+ LIMIT $Page.size
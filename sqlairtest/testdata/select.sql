@@ -0,0 +1,3 @@
+SELECT &Person.*
+  FROM person
+ WHERE id = $Person.id
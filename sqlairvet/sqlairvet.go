@@ -0,0 +1,284 @@
+// Package sqlairvet provides an analyzer reporting the Sqlair statements,
+// prepared from constant strings, that would fail to be prepared, so that
+// they can be caught by go vet rather than when the program is run.
+//
+// The analyzer can be run by go vet through the sqlairvet command:
+//
+//     go install github.com/canonical/sqlair/cmd/sqlairvet
+//     go vet -vettool=$(which sqlairvet) ./...
+//
+package sqlairvet
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"sort"
+
+	"github.com/canonical/sqlair/internal/parse"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// sqlairPackage is the path of the package whose calls are checked.
+const sqlairPackage = "github.com/canonical/sqlair"
+
+const doc = `check the statements passed to sqlair.Prepare
+
+The sqlair checker reports calls to sqlair.Prepare, sqlair.MustPrepare
+and Registry.Register whose statement is a constant that can not be
+parsed, or whose arguments do not supply exactly the types named by the
+statement's input and output expressions.`
+
+// Analyzer reports calls preparing Sqlair statements from constant
+// strings that can not be parsed, or whose type objects do not match
+// the types to which the statements refer.
+var Analyzer = &analysis.Analyzer{
+	Name:     "sqlair",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// preparers maps the full names of the functions preparing statements
+// to the index of the statement among their arguments. The type
+// objects and options follow the statement.
+var preparers = map[string]int{
+	sqlairPackage + ".Prepare":                   0,
+	sqlairPackage + ".MustPrepare":               0,
+	"(*" + sqlairPackage + ".Registry).Register": 1,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		fn := typeutil.StaticCallee(pass.TypesInfo, call)
+		if fn == nil {
+			return
+		}
+		index, ok := preparers[fn.FullName()]
+		if !ok || call.Ellipsis.IsValid() || len(call.Args) <= index {
+			return
+		}
+		checkPrepare(pass, call, index)
+	})
+	return nil, nil
+}
+
+// preparation describes the arguments following the statement
+// in a call preparing it.
+type preparation struct {
+	// escapes and allowUnused are the options
+	// set by the arguments that affect the checks.
+	escapes     bool
+	allowUnused bool
+
+	// types holds the argument for each type name supplied.
+	types map[string]ast.Expr
+
+	// unknown is true if an argument's type name or options
+	// could not be determined. The types are then not checked.
+	unknown bool
+}
+
+// checkPrepare reports the problems with the call preparing the
+// statement at the input index of its arguments, if it is constant.
+func checkPrepare(pass *analysis.Pass, call *ast.CallExpr, index int) {
+	tv := pass.TypesInfo.Types[call.Args[index]]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return
+	}
+	stmt := constant.StringVal(tv.Value)
+
+	prep, ok := readArgs(pass, call.Args[index+1:])
+	if !ok {
+		return
+	}
+
+	lex := parse.NewLexer(stmt)
+	lex.SetBackslashEscapes(prep.escapes)
+	exp, err := parse.NewParser(lex).Run()
+	if err != nil {
+		pass.Reportf(call.Args[index].Pos(), "sqlair statement can not be parsed: %v", err)
+		return
+	}
+	if prep.unknown {
+		return
+	}
+
+	used := make(map[string]bool)
+	_ = parse.Walk(exp, func(exp parse.Expression) error {
+		if e, ok := exp.(parse.TypeMappingExpression); ok {
+			used[e.TypeName().String()] = true
+		}
+		return nil
+	})
+
+	var missing []string
+	for name := range used {
+		if _, ok := prep.types[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		pass.Reportf(call.Args[index].Pos(), "sqlair statement uses type %q, which is not supplied", name)
+	}
+
+	if prep.allowUnused {
+		return
+	}
+	for name, arg := range prep.types {
+		if !used[name] {
+			pass.Reportf(arg.Pos(), "sqlair statement does not use type %q, which is supplied", name)
+		}
+	}
+}
+
+// readArgs returns a description of the input arguments following the
+// statement in a call preparing it, reporting those that Prepare would
+// reject. It returns false if the arguments include an option that
+// could change how the statement is parsed in a way that is not known.
+func readArgs(pass *analysis.Pass, args []ast.Expr) (preparation, bool) {
+	prep := preparation{types: make(map[string]ast.Expr)}
+	for _, arg := range args {
+		typ := pass.TypesInfo.TypeOf(arg)
+		if typ == nil {
+			prep.unknown = true
+			continue
+		}
+
+		if isSqlairType(typ, "Aliased") {
+			name, ok := aliasName(pass, arg)
+			if !ok {
+				prep.unknown = true
+				continue
+			}
+			addType(pass, &prep, name, arg)
+			continue
+		}
+		if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+
+		switch {
+		case isSqlairType(typ, "PrepareOption"):
+			set, ok := optionCall(pass, arg)
+			if !ok {
+				return prep, false
+			}
+			switch set.name {
+			case "WithBackslashEscapes":
+				prep.escapes = set.value
+			case "WithSuperfluousTypes":
+				prep.allowUnused = set.value
+			}
+		default:
+			if _, ok := typ.Underlying().(*types.Interface); ok {
+				prep.unknown = true
+				continue
+			}
+			named, ok := typ.(*types.Named)
+			if !ok {
+				switch typ.(type) {
+				case *types.Struct, *types.Map:
+					pass.Reportf(arg.Pos(), "type %s has no name with which to refer to it; declare a named type", typ)
+				}
+				prep.unknown = true
+				continue
+			}
+			addType(pass, &prep, typeName(named), arg)
+		}
+	}
+	return prep, true
+}
+
+// addType records the argument supplying the named type,
+// reporting it if the name has already been supplied.
+func addType(pass *analysis.Pass, prep *preparation, name string, arg ast.Expr) {
+	if _, ok := prep.types[name]; ok {
+		pass.Reportf(arg.Pos(), "names for supplied types are not unique; %q is ambiguous", name)
+		return
+	}
+	prep.types[name] = arg
+}
+
+// typeName returns the name by which statements refer to the input type,
+// as returned by reflection: its name, along with any type arguments,
+// without package qualifiers.
+func typeName(named *types.Named) string {
+	return types.TypeString(named, func(*types.Package) string { return "" })
+}
+
+// isSqlairPackage returns true if the input type is declared by Sqlair.
+func isSqlairPackage(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+	pkg := named.Obj().Pkg()
+	return pkg != nil && pkg.Path() == sqlairPackage
+}
+
+// isSqlairType returns true if the input type
+// is the one of the input name declared by Sqlair.
+func isSqlairType(typ types.Type, name string) bool {
+	return isSqlairPackage(typ) && typ.(*types.Named).Obj().Name() == name
+}
+
+// aliasName returns the name passed to sqlair.Alias
+// by the input argument, if it is a constant.
+func aliasName(pass *analysis.Pass, arg ast.Expr) (string, bool) {
+	call, ok := arg.(*ast.CallExpr)
+	if !ok || len(call.Args) != 2 {
+		return "", false
+	}
+	fn := typeutil.StaticCallee(pass.TypesInfo, call)
+	if fn == nil || fn.FullName() != sqlairPackage+".Alias" {
+		return "", false
+	}
+	tv := pass.TypesInfo.Types[call.Args[0]]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// optionSetting describes an option returned by a call to
+// a Sqlair function that takes a single boolean.
+type optionSetting struct {
+	name  string
+	value bool
+}
+
+// optionCall returns the setting made by the input PrepareOption
+// argument. It returns false if the argument is not a call to a Sqlair
+// function returning options, or if it is a call to one returning
+// options that affect the checks, but with an argument that is not
+// constant.
+func optionCall(pass *analysis.Pass, arg ast.Expr) (optionSetting, bool) {
+	call, ok := arg.(*ast.CallExpr)
+	if !ok {
+		return optionSetting{}, false
+	}
+	fn := typeutil.StaticCallee(pass.TypesInfo, call)
+	if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != sqlairPackage {
+		return optionSetting{}, false
+	}
+
+	name := fn.Name()
+	if name != "WithBackslashEscapes" && name != "WithSuperfluousTypes" {
+		return optionSetting{name: name}, true
+	}
+	if len(call.Args) != 1 {
+		return optionSetting{}, false
+	}
+	tv := pass.TypesInfo.Types[call.Args[0]]
+	if tv.Value == nil || tv.Value.Kind() != constant.Bool {
+		return optionSetting{}, false
+	}
+	return optionSetting{name: name, value: constant.BoolVal(tv.Value)}, true
+}
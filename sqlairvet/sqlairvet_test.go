@@ -0,0 +1,104 @@
+package sqlairvet
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// wantComment matches the comments holding the patterns of the
+// diagnostics expected to be reported for the lines they end.
+var wantComment = regexp.MustCompile("// want (.*)$")
+
+// wantPattern matches one of the back-quoted patterns of a want comment.
+var wantPattern = regexp.MustCompile("`[^`]*`")
+
+// runAnalyzer runs the analyzer over the package in the input file,
+// returning the messages reported for each line.
+func runAnalyzer(t *testing.T, filename string) (*ast.File, *token.FileSet, map[int][]string) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Instances:  make(map[*ast.Ident]types.Instance),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := config.Check("testdata", fset, []*ast.File{file}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reported := make(map[int][]string)
+	pass := &analysis.Pass{
+		Analyzer:  Analyzer,
+		Fset:      fset,
+		Files:     []*ast.File{file},
+		Pkg:       pkg,
+		TypesInfo: info,
+		ResultOf: map[*analysis.Analyzer]any{
+			inspect.Analyzer: inspector.New([]*ast.File{file}),
+		},
+		Report: func(d analysis.Diagnostic) {
+			line := fset.Position(d.Pos).Line
+			reported[line] = append(reported[line], d.Message)
+		},
+	}
+	if _, err := Analyzer.Run(pass); err != nil {
+		t.Fatal(err)
+	}
+	return file, fset, reported
+}
+
+func TestAnalyzer(t *testing.T) {
+	file, fset, reported := runAnalyzer(t, "testdata/prepare.go")
+
+	want := make(map[int][]string)
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			match := wantComment.FindStringSubmatch(comment.Text)
+			if match == nil {
+				continue
+			}
+			line := fset.Position(comment.Pos()).Line
+			for _, quoted := range wantPattern.FindAllString(match[1], -1) {
+				pattern, err := strconv.Unquote(quoted)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want[line] = append(want[line], pattern)
+			}
+		}
+	}
+
+	for line, patterns := range want {
+		messages := reported[line]
+		if !assert.Len(t, messages, len(patterns), "line %d: %q", line, messages) {
+			continue
+		}
+		for i, pattern := range patterns {
+			assert.Regexp(t, pattern, messages[i], "line %d", line)
+		}
+	}
+	for line, messages := range reported {
+		if _, ok := want[line]; !ok {
+			t.Errorf("line %d: unexpected diagnostics %q", line, messages)
+		}
+	}
+}
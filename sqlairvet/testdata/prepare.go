@@ -0,0 +1,52 @@
+package testdata
+
+import "github.com/canonical/sqlair"
+
+type Person struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+type Address struct {
+	ID int `db:"id"`
+}
+
+type Page[T any] struct {
+	Limit int `db:"limit"`
+}
+
+const people = "SELECT &Person.* FROM person"
+
+var registry = sqlair.NewRegistry()
+
+func prepare(stmt string, option sqlair.PrepareOption, object any, args ...any) {
+	sqlair.Prepare(people, Person{})
+	sqlair.Prepare("SELECT &Person.* FROM person WHERE id = $Address.id", &Person{}, &Address{})
+	sqlair.Prepare("SELECT &Person.* FROM person LIMIT $Page[Person].limit", Person{}, Page[Person]{})
+	sqlair.Prepare("SELECT &P.*, &M.* FROM person AS p JOIN person AS m", sqlair.Alias("P", Person{}), sqlair.Alias("M", Person{}))
+
+	sqlair.Prepare("SELECT &Person FROM person", Person{}) // want `sqlair statement can not be parsed: expected type name and field after "&" at line 1, column 8`
+	sqlair.MustPrepare("SELECT a) FROM person")            // want `sqlair statement can not be parsed: unexpected "\)"`
+	registry.Register("people", "SELECT (a, ) FROM t")     // want `sqlair statement can not be parsed: expected expression before "\)"`
+
+	sqlair.Prepare("SELECT &Person.* FROM person WHERE id = $Address.id", Person{}) // want `sqlair statement uses type "Address", which is not supplied`
+	sqlair.Prepare(people, Person{}, Address{})                                     // want `sqlair statement does not use type "Address", which is supplied`
+	sqlair.Prepare(people, Person{}, &Person{})                                     // want `names for supplied types are not unique; "Person" is ambiguous`
+	sqlair.Prepare(people, struct{ ID int }{})                                      // want `type struct{ID int} has no name with which to refer to it; declare a named type`
+	registry.Register("people", people, sqlair.Alias("P", Person{}))                // want `sqlair statement uses type "Person", which is not supplied` `sqlair statement does not use type "P", which is supplied`
+
+	// Options are applied in turn.
+	sqlair.Prepare(people, Person{}, sqlair.WithSuperfluousTypes(true), Address{})
+	sqlair.Prepare(people, Person{}, sqlair.WithSuperfluousTypes(true), sqlair.WithSuperfluousTypes(false), Address{}) // want `sqlair statement does not use type "Address", which is supplied`
+	sqlair.Prepare(people, Person{}, sqlair.WithStrictness(true), sqlair.WithLocation(sqlair.Here()))
+	sqlair.Prepare(`SELECT &Person.* FROM person WHERE name = 'O\'Brien'`, Person{}, sqlair.WithBackslashEscapes(true))
+
+	// Statements and arguments that are not known are not checked.
+	sqlair.Prepare(stmt, Person{})
+	sqlair.Prepare(people, args...)
+	sqlair.Prepare(people, Person{}, option, Address{})
+	sqlair.Prepare(people, Person{}, object)
+	sqlair.Prepare(people, Person{}, sqlair.WithSuperfluousTypes(len(args) > 0), Address{})
+	sqlair.Prepare("SELECT &Person.* FROM person", sqlair.Alias(stmt, Person{}))
+	sqlair.Prepare("SELECT (a, ) FROM t", option)
+}
@@ -1,8 +1,15 @@
 package sqlair
 
 import (
+	"context"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+
 	"github.com/canonical/sqlair/internal/parse"
 	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
 )
 
 // typeMap is a convenience type alias for reflection
@@ -11,12 +18,91 @@ type typeMap = map[string]sqlairreflect.Info
 
 // Statement represents a prepared Sqlair DSL statement
 // that can be executed by the database.
+// Everything derived from the DSL is computed by Prepare and never modified
+// afterwards, so a single Statement is safe for concurrent use by multiple
+// goroutines.
 type Statement struct {
 	// expression is the parsed expression tree for this statement.
 	expression parse.Expression
 
 	// argTypes holds the reflection info for types used in this statement.
 	argTypes typeMap
+
+	// sql is the statement to be passed to the database, with input
+	// sources replaced by placeholders and output targets expanded
+	// into columns.
+	sql string
+
+	// inputs holds, in placeholder order,
+	// the sources of the statement's parameters.
+	inputs []inputBinding
+
+	// outputs holds the destinations of the statement's result columns.
+	outputs []outputBinding
+
+	// limits holds the indexes, in placeholder order, of the parameters
+	// that are the counts of LIMIT and OFFSET clauses; see Dialect.
+	limits []int
+
+	// lists holds the parameters that are slices of values compared
+	// with a quantifier such as ANY, which are expanded into lists
+	// of parameters for dialects without native arrays.
+	lists []listBinding
+
+	// operators holds, in order, the indexes among the question marks
+	// outside quotes in sql of those that are within operators, such as
+	// PostgreSQL's "?|", rather than being placeholders.
+	operators []int
+
+	// templates holds, in order, the positions in sql at which identifiers
+	// must be substituted before the statement can be executed.
+	templates []templateBinding
+
+	// allowed holds, for each template, the identifiers
+	// that may be substituted for it.
+	allowed IdentifierAllowlist
+
+	// identifiers holds, in order, the identifiers written into sql by
+	// Sqlair, which are written for the dialect of the DB executing it.
+	identifiers []identifierBinding
+
+	// generated holds the names written into the DSL of a generated
+	// statement, or of conditions added to the statement, by
+	// generatedName and generatedTable.
+	generated []string
+
+	// lenient is true if objects of types not used by the statement
+	// are ignored, rather than being an error, when it is executed.
+	lenient bool
+
+	// unsafe is true if the statement's literals are deliberately
+	// written into its DSL; see Unsafe.
+	unsafe bool
+
+	// unused holds, sorted, the names of the types supplied to Prepare
+	// that the statement does not use; see WithSuperfluousTypes.
+	unused []string
+
+	// aliases holds the columns from which output fields
+	// are decoded in place of those named by their tags.
+	aliases columnAliases
+
+	// quoting is the policy by which column names
+	// derived from struct tags are quoted.
+	quoting IdentifierQuoting
+
+	// timeout is the time limit for executing the statement,
+	// given by a directive, or zero if it has none.
+	timeout time.Duration
+
+	// backslashEscapes is true if a backslash within a string literal
+	// of the statement escapes the character following it; see
+	// WithBackslashEscapes.
+	backslashEscapes bool
+
+	// usage, if not nil, counts the executions of the statement
+	// registered with a Registry; see Registry.Usage.
+	usage *statementUsage
 }
 
 // Prepare accepts a raw DSL string and optionally,
@@ -25,33 +111,145 @@ type Statement struct {
 // - Any input objects have their reflection information retrieved/generated.
 // - The reflection information is matched with the parser output to generate
 //   a Statement that can be passed to the database for execution.
+// - The SQL for the database and the bindings for its parameters and result
+//   columns are compiled from the expression tree.
+// Options, such as WithStrictness, passed along with the objects configure
+// its preparation; see PrepareOption.
+// A leading comment such as "-- sqlair:timeout=5s" is a directive
+// limiting the time for which the statement may run.
+// Findings that do not prevent the statement from being run are
+// reported by its Warnings method rather than as errors.
 func Prepare(stmt string, args ...any) (*Statement, error) {
-	lex := parse.NewLexer(stmt)
-	parser := parse.NewParser(lex)
+	opts, args := optionsFromArgs(args)
+
+	parser := opts.limits.newParser(newLexer(stmt, opts.escapes))
+	exp, err := parser.Run()
+	if err != nil {
+		if opts.location != nil {
+			err = opts.location.locateError(stmt, err)
+		}
+		return nil, limitError(err)
+	}
+
+	return prepareStatement(exp, parser.Directives(), opts, args)
+}
 
+// PrepareReader is like Prepare, but reads the DSL statement from the input
+// reader as it is parsed, rather than requiring it to be held in a string.
+// It suits very large statements, such as those read from files.
+func PrepareReader(r io.Reader, args ...any) (*Statement, error) {
+	opts, args := optionsFromArgs(args)
+
+	lex := parse.NewReaderLexer(r)
+	lex.SetBackslashEscapes(opts.escapes)
+	parser := opts.limits.newParser(lex)
 	exp, err := parser.Run()
+	if err != nil {
+		return nil, limitError(err)
+	}
+
+	return prepareStatement(exp, parser.Directives(), opts, args)
+}
+
+// prepareStatement returns a Statement for the input expression tree,
+// using type information from the input args, configured by the input
+// options and directives. If the options are strict, the expression is
+// audited first. The statement is passed to any ConcatenationHook unless
+// it is unsafe.
+func prepareStatement(exp parse.Expression, directives map[string]string, opts prepareOptions, args []any) (*Statement, error) {
+	if opts.strict {
+		if err := parse.Audit(exp, opts.escapes); err != nil {
+			return nil, err
+		}
+	}
+
+	stmt, err := prepareWithOptions(exp, opts, args)
 	if err != nil {
 		return nil, err
 	}
+	stmt.backslashEscapes = opts.escapes
+	stmt.unsafe = opts.unsafe
+	if err := stmt.applyDirectives(directives); err != nil {
+		return nil, err
+	}
+
+	stmt.reportConcatenation()
+	if opts.filter != nil {
+		return opts.filter.apply(stmt)
+	}
+	return stmt, nil
+}
 
-	argTypes, err := typesForStatement(args)
+// prepareExpression returns a Statement for the input expression tree,
+// using type information from the input args, configured by any
+// options among them.
+func prepareExpression(exp parse.Expression, args []any) (*Statement, error) {
+	opts, args := optionsFromArgs(args)
+	return prepareWithOptions(exp, opts, args)
+}
+
+// prepareWithOptions returns a Statement for the input expression tree,
+// using type information from the input args, configured by the input
+// options.
+func prepareWithOptions(exp parse.Expression, opts prepareOptions, args []any) (*Statement, error) {
+	aliases, err := newColumnAliases(opts.aliases)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := interpret(exp, argTypes); err != nil {
+	argTypes, err := typesInCache(opts.typeCache(), args)
+	if err != nil {
+		return nil, err
+	}
+
+	unused, err := interpretTypes(exp, argTypes)
+	if err != nil {
+		return nil, err
+	}
+	if len(unused) > 0 && !opts.allowUnused {
+		return nil, NewErrSuperfluousType(unused[0])
+	}
+	for _, name := range unused {
+		delete(argTypes, name)
+	}
+	if err := aliases.validate(argTypes); err != nil {
+		return nil, err
+	}
+
+	comp := newCompiler(argTypes, aliases, opts.quoting, opts.generated)
+	if err := comp.compileStatement(exp); err != nil {
 		return nil, err
 	}
 
 	return &Statement{
-		expression: exp,
-		argTypes:   argTypes,
+		expression:  exp,
+		argTypes:    argTypes,
+		sql:         comp.sql.String(),
+		inputs:      comp.inputs,
+		outputs:     comp.outputs,
+		templates:   comp.templates,
+		identifiers: comp.identifiers,
+		generated:   opts.generated,
+		limits:      comp.limits,
+		lists:       comp.lists,
+		operators:   comp.operators,
+		lenient:     opts.allowUnused,
+		unused:      unused,
+		aliases:     aliases,
+		quoting:     opts.quoting,
 	}, nil
 }
 
-// typesForStatement returns reflection information for the input arguments.
-// The reflected type name of each argument must be unique in the list,
-// which means declaring new local types to avoid ambiguity.
+// typesForStatement returns reflection information for the input
+// arguments, as for typesInCache, from the cache shared by statements.
+func typesForStatement(args []any) (typeMap, error) {
+	return typesInCache(sqlairreflect.Cache(), args)
+}
+
+// typesInCache returns reflection information for the input arguments,
+// held by the input cache. The reflected type name of each argument must
+// be unique in the list, which means declaring new local types to avoid
+// ambiguity.
 //
 // Example:
 //
@@ -66,17 +264,30 @@ func Prepare(stmt string, args ...any) (*Statement, error) {
 //         ON p.manager_id = m.id
 //      WHERE p.name = 'Fred'`, Person{}, Manager{})
 //
-func typesForStatement(args []any) (typeMap, error) {
-	c := sqlairreflect.Cache()
+func typesInCache(c *sqlairreflect.TypeCache, args []any) (typeMap, error) {
 	argTypes := make(typeMap)
 
 	for _, arg := range args {
-		reflected, err := c.Reflect(arg)
+		value := arg
+		if a, ok := arg.(Aliased); ok {
+			if err := validateAlias(a.name); err != nil {
+				return nil, err
+			}
+			value = a.value
+		}
+
+		reflected, err := c.Reflect(value)
 		if err != nil {
 			return nil, err
 		}
 
 		name := reflected.Name()
+		if a, ok := arg.(Aliased); ok {
+			name = a.name
+		}
+		if name == "" {
+			return nil, errors.Errorf("type %s has no name with which to refer to it; declare a named type", reflected.Type())
+		}
 		if _, ok := argTypes[name]; ok {
 			return nil, NewErrTypeNameNotUnique(name)
 		}
@@ -84,6 +295,9 @@ func typesForStatement(args []any) (typeMap, error) {
 		argTypes[name] = reflected
 	}
 
+	if err := checkTransformers(argTypes); err != nil {
+		return nil, err
+	}
 	return argTypes, nil
 }
 
@@ -92,6 +306,20 @@ func typesForStatement(args []any) (typeMap, error) {
 // - All type information is actually required by the input/output targets.
 // - TODO (manadart 2022-07-15): Add further interpreter behaviour.
 func interpret(statementExp parse.Expression, argTypes typeMap) error {
+	unused, err := interpretTypes(statementExp, argTypes)
+	if err != nil {
+		return err
+	}
+	if len(unused) > 0 {
+		return NewErrSuperfluousType(unused[0])
+	}
+	return nil
+}
+
+// interpretTypes ensures that each input/output target in the input
+// expression tree has type information in argTypes, and returns the sorted
+// names of the types in argTypes that are not required by any of them.
+func interpretTypes(statementExp parse.Expression, argTypes typeMap) ([]string, error) {
 	var err error
 	seen := make(map[string]bool)
 
@@ -107,18 +335,19 @@ func interpret(statementExp parse.Expression, argTypes typeMap) error {
 	}
 
 	if err := parse.Walk(statementExp, visit); err != nil {
-		return err
+		return nil, err
 	}
 
-	// Now compare the type names that we saw against what we have information
-	// for. If unused types were supplied, it is an error condition.
+	// Now compare the type names that we saw against
+	// what we have information for.
+	var unused []string
 	for name := range argTypes {
 		if _, ok := seen[name]; !ok {
-			return NewErrSuperfluousType(name)
+			unused = append(unused, name)
 		}
 	}
-
-	return nil
+	sort.Strings(unused)
+	return unused, nil
 }
 
 // validateExpressionType ensures that the type name identity from the input
@@ -135,3 +364,126 @@ func validateExpressionType(
 	seen[typeName] = true
 	return seen, nil
 }
+
+// recompile returns a copy of the statement for the input expression
+// tree and type information, with its SQL and bindings compiled anew.
+func (s *Statement) recompile(exp parse.Expression, argTypes typeMap) (*Statement, error) {
+	comp := newCompiler(argTypes, s.aliases, s.quoting, s.generated)
+	if err := comp.compileStatement(exp); err != nil {
+		return nil, err
+	}
+
+	stmt := *s
+	stmt.expression = exp
+	stmt.argTypes = argTypes
+	stmt.sql = comp.sql.String()
+	stmt.inputs = comp.inputs
+	stmt.outputs = comp.outputs
+	stmt.templates = comp.templates
+	stmt.identifiers = comp.identifiers
+	stmt.limits = comp.limits
+	stmt.lists = comp.lists
+	stmt.operators = comp.operators
+	return &stmt, nil
+}
+
+// mergeTypes returns a new map with the type information from both of the
+// inputs. If both have information for a type name, it must be for the same
+// type, otherwise an error is returned.
+func mergeTypes(a, b typeMap) (typeMap, error) {
+	merged := make(typeMap, len(a)+len(b))
+	for name, info := range a {
+		merged[name] = info
+	}
+	for name, info := range b {
+		if existing, ok := merged[name]; ok && existing.Type() != info.Type() {
+			return nil, NewErrTypeNameNotUnique(name)
+		}
+		merged[name] = info
+	}
+	return merged, nil
+}
+
+// bindInputs returns the parameters for executing the statement, sourced
+// in placeholder order from the fields of the input objects. If the
+// statement modifies data, each input that is the source of a parameter
+// is first passed to its hook, with the input context, and validated;
+// see BeforeInsertHook and Validator.
+func (s *Statement) bindInputs(ctx context.Context, inputs []any) ([]any, error) {
+	if len(s.templates) > 0 {
+		return nil, errors.Errorf("no identifier substituted for template %q", s.templates[0].name)
+	}
+
+	values := make(map[string]reflect.Value, len(inputs))
+	for _, input := range inputs {
+		name, v := objectName(input)
+		v = reflect.Indirect(v)
+		if !v.IsValid() {
+			return nil, errors.New("nil input supplied for statement")
+		}
+
+		if info, ok := s.argTypes[name]; !ok || info.Type() != v.Type() {
+			if s.lenient {
+				continue
+			}
+			return nil, NewErrSuperfluousType(name)
+		}
+		values[name] = v
+	}
+
+	var prepared map[string]bool
+	var hook *inputHook
+	if parse.Classify(s.expression) == parse.KindDML {
+		prepared = make(map[string]bool, len(values))
+		hook = inputHookFor(s.expression)
+	}
+
+	args := make([]any, len(s.inputs))
+	for i, in := range s.inputs {
+		v, ok := values[in.typeName]
+		if !ok {
+			return nil, errors.Errorf("no input of type %q supplied for statement", in.typeName)
+		}
+		if prepared != nil && !prepared[in.typeName] {
+			var err error
+			if v, err = prepareInput(ctx, hook, in.typeName, v); err != nil {
+				return nil, redactFields(err, s.argTypes[in.typeName], values[in.typeName])
+			}
+			values[in.typeName] = v
+			prepared[in.typeName] = true
+		}
+		arg, err := encodeField(ctx, in.typeName, in.column, in.field, v.Field(in.field.Index).Interface())
+		if err != nil {
+			return nil, redactFields(err, s.argTypes[in.typeName], v)
+		}
+		args[i] = arg
+	}
+	return args, nil
+}
+
+// outputsForColumns returns the output binding for each of the input
+// result columns, or nil where a column does not map to an output target.
+func (s *Statement) outputsForColumns(columns []string) []*outputBinding {
+	bindings := make([]*outputBinding, len(columns))
+	for i, column := range columns {
+		for j := range s.outputs {
+			if s.outputs[j].column == column {
+				bindings[i] = &s.outputs[j]
+				break
+			}
+		}
+	}
+	return bindings
+}
+
+// outputForTag returns the output binding for the field with the input tag
+// of the named type, or nil if the field is not decoded from any column.
+// The column may have an alias distinguishing it from those of other types.
+func (s *Statement) outputForTag(typeName, tag string) *outputBinding {
+	for i, out := range s.outputs {
+		if out.typeName == typeName && out.tag == tag {
+			return &s.outputs[i]
+		}
+	}
+	return nil
+}
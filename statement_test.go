@@ -1,7 +1,11 @@
 package sqlair
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/canonical/sqlair/internal/parse"
 	sqlairtesting "github.com/canonical/sqlair/internal/testing"
@@ -82,3 +86,48 @@ func tokensForStatement(stmt string) []parse.Token {
 
 	return tokens
 }
+
+func TestPrepareReader(t *testing.T) {
+	query := `
+SELECT p.* AS &Person.*
+  FROM person AS p
+ WHERE p.name = $Person.name`
+
+	expected, err := Prepare(query, sqlairtesting.Person{})
+	assert.Nil(t, err)
+
+	stmt, err := PrepareReader(iotest.HalfReader(strings.NewReader(query)), sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, expected.sql, stmt.sql)
+	assert.Equal(t, expected.inputs, stmt.inputs)
+	assert.Equal(t, expected.outputs, stmt.outputs)
+
+	_, err = PrepareReader(strings.NewReader("SELECT (a"))
+	assert.EqualError(t, err, "unclosed parenthesis at line 1, column 8")
+
+	_, err = PrepareReader(iotest.ErrReader(errors.New("boom")))
+	assert.EqualError(t, err, "reading statement: boom")
+}
+
+type Named[T any] struct {
+	ID    string `db:"id"`
+	Name  string `db:"name"`
+	Extra T
+}
+
+func TestPrepareGenericType(t *testing.T) {
+	stmt, err := Prepare(
+		"SELECT &Named[Person].* FROM person WHERE id = $Named[Person].id", Named[sqlairtesting.Person]{})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name FROM person WHERE id = ?", stmt.sql)
+
+	var n Named[sqlairtesting.Person]
+	err = NewDB(setupPersonDB(t)).Query(context.Background(), stmt, Named[sqlairtesting.Person]{ID: "2"}).Get(&n)
+	assert.Nil(t, err)
+	assert.Equal(t, "Onos", n.Name)
+}
+
+func TestPrepareAnonymousStructError(t *testing.T) {
+	_, err := Prepare("SELECT name FROM person", struct{ Name string }{})
+	assert.EqualError(t, err, "type struct { Name string } has no name with which to refer to it; declare a named type")
+}
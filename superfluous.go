@@ -0,0 +1,22 @@
+package sqlair
+
+// WithSuperfluousTypes returns an option determining whether objects of
+// types that the statement does not use are ignored, rather than being an
+// error, both among the type objects and among the inputs and outputs with
+// which the statement is run. Allowing them suits helper functions, shared
+// by statements using different subsets of a common set of types, that pass
+// them all. Each type ignored by Prepare is reported by the statement's
+// Warnings. They are an error by default. It is also a DeriveOption,
+// setting whether they are allowed for the statement derived.
+//
+// Example:
+//
+//     stmt, err := sqlair.Prepare(`
+//     SELECT &Person.*
+//       FROM person`, sqlair.WithSuperfluousTypes(true), Person{}, Address{})
+//
+func WithSuperfluousTypes(allowed bool) PrepareOption {
+	return func(o *prepareOptions) {
+		o.allowUnused = allowed
+	}
+}
@@ -0,0 +1,37 @@
+package sqlair
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowSuperfluousTypes(t *testing.T) {
+	type Unused struct{}
+	db := NewDB(setupPersonDB(t))
+
+	_, err := Prepare("SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{}, Unused{})
+	assert.Equal(t, NewErrSuperfluousType("Unused"), err)
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id = $Person.id", WithSuperfluousTypes(true), sqlairtesting.Person{}, Unused{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		`type "Unused" is not used by the statement`,
+		"objects of types not used by the statement are ignored, rather than being an error",
+	}, stmt.Warnings())
+
+	var p sqlairtesting.Person
+	err = db.Query(context.Background(), stmt, sqlairtesting.Person{ID: "1"}, Unused{}).Get(&p, &Unused{})
+	assert.Nil(t, err)
+	assert.Equal(t, sqlairtesting.Person{ID: "1", Name: "Lorn"}, p)
+
+	// The statement can be loaded with the same objects.
+	data, err := json.Marshal(stmt)
+	assert.Nil(t, err)
+	loaded, err := LoadStatement(data, sqlairtesting.Person{}, Unused{})
+	assert.Nil(t, err)
+	assert.Equal(t, stmt.Warnings(), loaded.Warnings())
+}
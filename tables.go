@@ -0,0 +1,42 @@
+package sqlair
+
+import (
+	"strings"
+
+	"github.com/canonical/sqlair/internal/parse"
+)
+
+// TableAccess describes the tables that a statement reads and writes.
+// Table names are without any schema qualifier, and in lower case,
+// unless they are quoted, in which case they are unquoted in their own case.
+type TableAccess struct {
+	// Read holds the tables read by the statement, including those
+	// read by its subqueries and the common table expressions it uses.
+	Read []string `json:"read,omitempty"`
+
+	// Written holds the table modified by a statement that is not a
+	// query, such as that following INSERT INTO, UPDATE or DELETE FROM,
+	// or each of those whose aliases a MySQL DELETE lists before FROM.
+	Written []string `json:"written,omitempty"`
+}
+
+// Tables returns the tables read and written by the statement, as named
+// in its DSL. Tables are recognised by the keywords preceding them, such
+// as FROM, JOIN and INTO, so those that are accessed only by means the
+// DSL does not show, such as triggers or views, are not included.
+// A statement that is neither a query, DML nor DDL, such as a PRAGMA,
+// is not known to write to any table.
+func (s *Statement) Tables() TableAccess {
+	read, written := parse.TableAccess(s.expression)
+	return TableAccess{Read: read, Written: written}
+}
+
+// tableKey returns the input table name, as reported by Tables, folded to
+// lower case, so that the references to a table are matched however its
+// name is written, quoted or not. This matches names as SQLite and MySQL
+// do, while for databases in which quoted names are case-sensitive, such
+// as PostgreSQL, it errs on the side of matching names that differ only
+// in case.
+func tableKey(name string) string {
+	return strings.ToLower(name)
+}
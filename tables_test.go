@@ -0,0 +1,35 @@
+package sqlair
+
+import (
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatementTables(t *testing.T) {
+	stmt, err := Prepare(`
+SELECT &Person.*
+  FROM person AS p
+  JOIN Address AS a ON a.person_id = p.id
+ WHERE p.id IN (SELECT person_id FROM main.phone)`, sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, TableAccess{Read: []string{"person", "address", "phone"}}, stmt.Tables())
+
+	stmt, err = Prepare(
+		"INSERT INTO person (id, name) SELECT id, name FROM staff WHERE id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, TableAccess{Read: []string{"staff"}, Written: []string{"person"}}, stmt.Tables())
+
+	stmt, err = Prepare(`
+DELETE FROM ONLY person
+ USING manager AS m
+ WHERE person.id = m.id
+RETURNING person.* AS &Person.*`, sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, TableAccess{Read: []string{"manager"}, Written: []string{"person"}}, stmt.Tables())
+
+	stmt, err = Prepare("DELETE p, m FROM person AS p JOIN manager AS m ON p.id = m.id WHERE p.id = $Person.id", sqlairtesting.Person{})
+	assert.Nil(t, err)
+	assert.Equal(t, TableAccess{Written: []string{"person", "manager"}}, stmt.Tables())
+}
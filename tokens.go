@@ -0,0 +1,21 @@
+package sqlair
+
+import "github.com/canonical/sqlair/internal/parse"
+
+// Token is a lexical token of a DSL statement, with its type,
+// literal text and position within the statement.
+type Token = parse.Token
+
+// TokenType identifies the type of a Token.
+// Its String method returns a stable name, such as "IDENT" or "STRING".
+type TokenType = parse.TokenType
+
+// Position is the location of a Token within its statement.
+type Position = parse.Position
+
+// Tokens returns the tokens of the input DSL statement, without parsing it.
+// It is intended for tools such as syntax highlighters and editor plugins.
+// An error is returned if the statement cannot be tokenised.
+func Tokens(stmt string) ([]Token, error) {
+	return parse.Tokens(stmt)
+}
@@ -0,0 +1,26 @@
+package sqlair
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokens(t *testing.T) {
+	tokens, err := Tokens("SELECT &Person.* FROM person WHERE id = $Person.id")
+	assert.Nil(t, err)
+
+	var types []string
+	for _, tok := range tokens {
+		types = append(types, tok.Type.String())
+	}
+	expected := []string{
+		"KEYWORD", "BITAND", "IDENT", "PERIOD", "ASTERISK", "KEYWORD", "IDENT",
+		"KEYWORD", "IDENT", "EQUAL", "DOLLAR", "IDENT", "PERIOD", "IDENT",
+	}
+	assert.Equal(t, expected, types)
+	assert.Equal(t, Position{Offset: 7, Line: 1, Column: 8}, tokens[1].Pos)
+
+	_, err = Tokens("SELECT 'unterminated")
+	assert.EqualError(t, err, "unterminated string literal at line 1, column 8")
+}
@@ -0,0 +1,136 @@
+package sqlair
+
+import (
+	"context"
+	"sync"
+
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// Transformer converts the values of struct fields as they are exchanged
+// with the database, such as to encrypt sensitive data at rest. It applies
+// to fields with a "db" tag option of the name with which it is
+// registered, such as "encrypted" in `db:"ssn,encrypted"`. Its functions
+// are passed the context in which the statement is run, so that values such
+// as the tenant whose key encrypts the data are available to them.
+type Transformer struct {
+	// Encode returns the parameter passed to the database
+	// in place of the input field value.
+	Encode func(ctx context.Context, value any) (any, error)
+
+	// Decode returns the value assigned to the field in place of the
+	// input value read from the database, as returned by the driver.
+	// It is converted to the type of the field as by sql.Rows.Scan.
+	Decode func(ctx context.Context, value any) (any, error)
+}
+
+// transformers holds the registered transformers by tag option.
+var transformers = struct {
+	sync.RWMutex
+	byOption map[string]Transformer
+}{byOption: make(map[string]Transformer)}
+
+// RegisterTransformer makes the input transformer available for fields
+// with the input "db" tag option. Statements using types with fields that
+// have an option for which no transformer is registered can not be
+// prepared, so transformers are registered before statements are
+// prepared, typically by an init function. RegisterTransformer panics if
+// a transformer is already registered for the option, or if either of
+// the transformer's functions is nil.
+//
+// Example:
+//
+//     sqlair.RegisterTransformer("encrypted", sqlair.Transformer{
+//         Encode: func(ctx context.Context, v any) (any, error) {
+//             return encrypt(keyFor(ctx), v.(string))
+//         },
+//         Decode: func(ctx context.Context, v any) (any, error) {
+//             return decrypt(keyFor(ctx), v.([]byte))
+//         },
+//     })
+//
+//     type Person struct {
+//         ID  string `db:"id"`
+//         SSN string `db:"ssn,encrypted"`
+//     }
+//
+func RegisterTransformer(option string, t Transformer) {
+	if t.Encode == nil || t.Decode == nil {
+		panic("sqlair: transformer for tag option " + option + " is missing a function")
+	}
+
+	transformers.Lock()
+	defer transformers.Unlock()
+
+	if _, ok := transformers.byOption[option]; ok {
+		panic("sqlair: transformer already registered for tag option " + option)
+	}
+	transformers.byOption[option] = t
+}
+
+// transformerFor returns the transformer registered
+// for the input tag option, and true, if there is one.
+func transformerFor(option string) (Transformer, bool) {
+	transformers.RLock()
+	defer transformers.RUnlock()
+
+	t, ok := transformers.byOption[option]
+	return t, ok
+}
+
+// checkTransformers returns an error if a field of any of the input
+// types has a tag option for which no transformer is registered.
+func checkTransformers(argTypes typeMap) error {
+	for name, info := range argTypes {
+		st, ok := info.(sqlairreflect.Struct)
+		if !ok {
+			continue
+		}
+		for _, tag := range st.Tags() {
+			field := st.Fields[tag]
+			if field.Transform == "" {
+				continue
+			}
+			if _, ok := transformerFor(field.Transform); !ok {
+				return errors.Errorf("no transformer registered for tag option %q of field %q of type %q",
+					field.Transform, tag, name)
+			}
+		}
+	}
+	return nil
+}
+
+// encodeField returns the parameter for the input value of the input
+// field, of the named type, encoded by its transformer if it has one.
+func encodeField(ctx context.Context, typeName, column string, field sqlairreflect.Field, value any) (any, error) {
+	if field.Transform == "" {
+		return value, nil
+	}
+	t, ok := transformerFor(field.Transform)
+	if !ok {
+		return nil, errors.Errorf("no transformer registered for tag option %q", field.Transform)
+	}
+
+	encoded, err := t.Encode(ctx, value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "encoding field %q of %q", column, typeName)
+	}
+	return encoded, nil
+}
+
+// decodeField assigns the input value, as read from the database, to the
+// input destination pointer for the input output binding, decoding it by
+// the field's transformer.
+func decodeField(ctx context.Context, b *outputBinding, dest, value any) error {
+	t, ok := transformerFor(b.field.Transform)
+	if !ok {
+		return errors.Errorf("no transformer registered for tag option %q", b.field.Transform)
+	}
+
+	decoded, err := t.Decode(ctx, value)
+	if err == nil {
+		err = assignValue(dest, decoded)
+	}
+	return errors.Wrapf(err, "decoding column %q", b.column)
+}
@@ -0,0 +1,152 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type prefixKey struct{}
+
+func init() {
+	RegisterTransformer("reversed", Transformer{
+		Encode: func(ctx context.Context, v any) (any, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.Errorf("can not reverse %T", v)
+			}
+			return contextPrefix(ctx) + reverse(s), nil
+		},
+		Decode: func(ctx context.Context, v any) (any, error) {
+			var s string
+			switch v := v.(type) {
+			case string:
+				s = v
+			case []byte:
+				s = string(v)
+			default:
+				return nil, errors.Errorf("can not reverse %T", v)
+			}
+			prefix := contextPrefix(ctx)
+			if !strings.HasPrefix(s, prefix) {
+				return nil, errors.Errorf("value is not prefixed with %q", prefix)
+			}
+			return reverse(strings.TrimPrefix(s, prefix)), nil
+		},
+	})
+}
+
+// contextPrefix returns the prefix with which values are
+// stored by the "reversed" transformer for the input context.
+func contextPrefix(ctx context.Context) string {
+	prefix, _ := ctx.Value(prefixKey{}).(string)
+	return prefix
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+type Secret struct {
+	ID    string `db:"id"`
+	Value string `db:"value,reversed"`
+}
+
+type Unregistered struct {
+	Value string `db:"value,unknown"`
+}
+
+func TestTransform(t *testing.T) {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE secret (id TEXT, value TEXT)")
+		return err
+	})
+	sqlairDB := NewDB(db)
+	ctx := context.Background()
+
+	insert, err := Prepare("INSERT INTO secret (id, value) VALUES ($Secret.id, $Secret.value)", Secret{})
+	assert.Nil(t, err)
+	_, err = sqlairDB.Exec(ctx, insert, Secret{ID: "1", Value: "hunter2"})
+	assert.Nil(t, err)
+
+	// The value is stored encoded.
+	var stored string
+	assert.Nil(t, db.QueryRow("SELECT value FROM secret").Scan(&stored))
+	assert.Equal(t, "2retnuh", stored)
+
+	selectStmt, err := Prepare("SELECT &Secret.* FROM secret WHERE id = $Secret.id", Secret{})
+	assert.Nil(t, err)
+	var s Secret
+	assert.Nil(t, sqlairDB.Query(ctx, selectStmt, Secret{ID: "1"}).Get(&s))
+	assert.Equal(t, Secret{ID: "1", Value: "hunter2"}, s)
+
+	// The encoded value is compared when filtering.
+	byValue, err := Prepare("SELECT &Secret.* FROM secret WHERE value = $Secret.value", Secret{})
+	assert.Nil(t, err)
+	var all []Secret
+	assert.Nil(t, sqlairDB.Query(ctx, byValue, Secret{Value: "hunter2"}).GetAll(&all))
+	assert.Equal(t, []Secret{{ID: "1", Value: "hunter2"}}, all)
+
+	_, err = sqlairDB.Exec(ctx, insert, Secret{ID: "2"})
+	assert.Nil(t, err)
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE secret SET value = NULL WHERE id = '2'")
+		return err
+	})
+	err = sqlairDB.Query(ctx, selectStmt, Secret{ID: "2"}).Get(&s)
+	assert.EqualError(t, err, `decoding column "value": can not reverse <nil>`)
+}
+
+func TestTransformContext(t *testing.T) {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE secret (id TEXT, value TEXT)")
+		return err
+	})
+	sqlairDB := NewDB(db)
+	ctx := context.WithValue(context.Background(), prefixKey{}, "tenant1:")
+
+	insert, err := Prepare("INSERT INTO secret (id, value) VALUES ($Secret.id, $Secret.value)", Secret{})
+	assert.Nil(t, err)
+	_, err = sqlairDB.Exec(ctx, insert, Secret{ID: "1", Value: "abc"})
+	assert.Nil(t, err)
+
+	var stored string
+	assert.Nil(t, db.QueryRow("SELECT value FROM secret").Scan(&stored))
+	assert.Equal(t, "tenant1:cba", stored)
+
+	selectStmt, err := Prepare("SELECT &Secret.* FROM secret", Secret{})
+	assert.Nil(t, err)
+	var s Secret
+	assert.Nil(t, sqlairDB.Query(ctx, selectStmt).Get(&s))
+	assert.Equal(t, "abc", s.Value)
+
+	// Values are decoded with the context of the query.
+	other := context.WithValue(context.Background(), prefixKey{}, "tenant2:")
+	err = sqlairDB.Query(other, selectStmt).Get(&s)
+	assert.EqualError(t, err, `decoding column "value": value is not prefixed with "tenant2:"`)
+}
+
+func TestTransformUnregistered(t *testing.T) {
+	_, err := Prepare("SELECT &Unregistered.* FROM secret", Unregistered{})
+	assert.EqualError(t, err, `no transformer registered for tag option "unknown" of field "value" of type "Unregistered"`)
+}
+
+func TestRegisterTransformerPanics(t *testing.T) {
+	identity := func(_ context.Context, v any) (any, error) { return v, nil }
+	assert.PanicsWithValue(t, "sqlair: transformer already registered for tag option reversed", func() {
+		RegisterTransformer("reversed", Transformer{Encode: identity, Decode: identity})
+	})
+	assert.PanicsWithValue(t, "sqlair: transformer for tag option missing is missing a function", func() {
+		RegisterTransformer("missing", Transformer{Encode: identity})
+	})
+}
@@ -0,0 +1,166 @@
+package sqlair
+
+import (
+	"reflect"
+
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/pkg/errors"
+)
+
+// Subtree returns a statement selecting the row of the input table with
+// the key of an input, and every row descending from it by way of the
+// input parent column, each mapped to a struct of the input object's type.
+// The rows are found by a recursive common table expression. The type
+// must have a primary key of a single field, to which the parent column
+// refers; see KeyCondition. The table's name may be qualified by a schema,
+// and must otherwise be a valid identifier, as for CRUD. A table or column
+// name that is a reserved word is quoted. The rows can be assembled into a tree by Query.GetTree.
+//
+// Example:
+//
+//     type Category struct {
+//         ID       string  `db:"id,pk"`
+//         ParentID *string `db:"parent_id"`
+//         Name     string  `db:"name"`
+//         Children []Category
+//     }
+//
+//     stmt, err := sqlair.Subtree(Category{}, "category", "parent_id")
+//
+//     var roots []Category
+//     err = db.Query(ctx, stmt, Category{ID: id}).GetTree("id", "parent_id", "Children", &roots)
+//
+func Subtree(obj any, table, parent string) (*Statement, error) {
+	argTypes, err := typesForStatement([]any{obj})
+	if err != nil {
+		return nil, err
+	}
+	name, _ := objectName(obj)
+
+	root, err := keyPredicate(name, argTypes[name])
+	if err != nil {
+		return nil, err
+	}
+	info := argTypes[name].(sqlairreflect.Struct)
+	key := info.PrimaryKey()
+	if len(key) != 1 {
+		return nil, errors.Errorf("type %q has a primary key of %d fields, not one", name, len(key))
+	}
+	names := append(generatedNames(info, table), parent)
+	if table, err = generatedTable(table); err != nil {
+		return nil, err
+	}
+
+	subtree := "WITH RECURSIVE subtree AS (" +
+		"SELECT * FROM " + table + " WHERE " + root +
+		" UNION ALL " +
+		"SELECT child.* FROM " + table + " AS child JOIN subtree ON child." + generatedName(parent) + " = subtree." + generatedName(key[0]) +
+		") SELECT &" + name + ".* FROM subtree"
+	return prepareGenerated(subtree, obj, names)
+}
+
+// GetTree decodes every result row into a node, a struct used as an output
+// target, and assembles the nodes into trees, appending the roots to the
+// input slice, which must be a pointer to a slice of the node type. The
+// type must have a slice field of its own type with the input name, to
+// which the children of each node are appended in row order. The parent of
+// a node is the node whose field with the input key tag has the value of
+// its field with the input parent tag, which may be a pointer to that
+// type; nodes with no parent among the rows are roots. Nodes with a parent
+// that is not descended from a root, as in a cycle, are discarded.
+func (q *Query) GetTree(key, parent, children string, roots any) error {
+	v := reflect.ValueOf(roots)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice || v.Elem().Type().Elem().Kind() != reflect.Struct {
+		return errors.Errorf("expected pointer to slice of structs, got %T", roots)
+	}
+	sv := v.Elem()
+	nodeType := sv.Type().Elem()
+	nodeName := sqlairreflect.TypeName(nodeType)
+
+	keyBinding := q.stmt.outputForTag(nodeName, key)
+	if keyBinding == nil {
+		return errors.Errorf("key %q is not an output column of type %q", key, nodeName)
+	}
+	keyType := nodeType.Field(keyBinding.field.Index).Type
+	if !keyType.Comparable() {
+		return errors.Errorf("key %q of type %s is not comparable", key, keyType)
+	}
+	parentBinding := q.stmt.outputForTag(nodeName, parent)
+	if parentBinding == nil {
+		return errors.Errorf("parent %q is not an output column of type %q", parent, nodeName)
+	}
+	parentType := nodeType.Field(parentBinding.field.Index).Type
+	if parentType.Kind() == reflect.Ptr {
+		parentType = parentType.Elem()
+	}
+	if !parentType.ConvertibleTo(keyType) {
+		return errors.Errorf("parent %q of type %s can not be converted to key type %s", parent, parentType, keyType)
+	}
+
+	childField, ok := nodeType.FieldByName(children)
+	if !ok || len(childField.Index) != 1 || childField.Type != reflect.SliceOf(nodeType) {
+		return errors.Errorf("type %q has no field %q of type %s", nodeName, children, reflect.SliceOf(nodeType))
+	}
+
+	// parents holds the parent key of each node, or nil if it has none,
+	// and keys holds the keys of the nodes.
+	var nodes []reflect.Value
+	var parents []any
+	keys := make(map[any]bool)
+
+	iter := q.Iter()
+	for iter.Next() {
+		node := reflect.New(nodeType)
+		if err := iter.Decode(node.Interface()); err != nil {
+			_ = iter.Close()
+			return err
+		}
+		nodes = append(nodes, node.Elem())
+
+		keys[node.Elem().Field(keyBinding.field.Index).Interface()] = true
+		p := node.Elem().Field(parentBinding.field.Index)
+		if p.Kind() == reflect.Ptr {
+			if p.IsNil() {
+				parents = append(parents, nil)
+				continue
+			}
+			p = p.Elem()
+		}
+		parents = append(parents, p.Convert(keyType).Interface())
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	// childIndexes holds the indexes in nodes of the children of each key.
+	var rootIndexes []int
+	childIndexes := make(map[any][]int)
+	for i, p := range parents {
+		if p == nil || !keys[p] {
+			rootIndexes = append(rootIndexes, i)
+		} else {
+			childIndexes[p] = append(childIndexes[p], i)
+		}
+	}
+
+	// build returns the node with the input index, with its descendants.
+	// Each node is built at most once, so that a node that is its own
+	// ancestor, by way of a duplicated key, is not built forever.
+	built := make([]bool, len(nodes))
+	var build func(i int) reflect.Value
+	build = func(i int) reflect.Value {
+		built[i] = true
+		node := nodes[i]
+		cv := node.Field(childField.Index[0])
+		for _, j := range childIndexes[node.Field(keyBinding.field.Index).Interface()] {
+			if !built[j] {
+				cv.Set(reflect.Append(cv, build(j)))
+			}
+		}
+		return node
+	}
+	for _, i := range rootIndexes {
+		sv.Set(reflect.Append(sv, build(i)))
+	}
+	return nil
+}
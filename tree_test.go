@@ -0,0 +1,119 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Category struct {
+	ID       string  `db:"id,pk"`
+	ParentID *string `db:"parent_id"`
+	Name     string  `db:"name"`
+	Children []Category
+}
+
+func setupCategoryDB(t *testing.T) *DB {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+CREATE TABLE category (id TEXT, parent_id TEXT, name TEXT);
+INSERT INTO category VALUES
+    ('1', NULL, 'tools'),
+    ('2', '1', 'saws'),
+    ('3', '2', 'hacksaws'),
+    ('4', '1', 'hammers'),
+    ('5', NULL, 'paint')`)
+		return err
+	})
+	return NewDB(db)
+}
+
+func TestRecursiveQuery(t *testing.T) {
+	db := setupCategoryDB(t)
+
+	stmt, err := Prepare(`
+WITH RECURSIVE ancestor AS (
+    SELECT * FROM category WHERE id = $Category.id
+    UNION ALL
+    SELECT c.* FROM category AS c JOIN ancestor AS a ON c.id = a.parent_id
+)
+SELECT &Category.* FROM ancestor`, Category{})
+	assert.Nil(t, err)
+
+	var names []string
+	var c Category
+	err = db.Query(context.Background(), stmt, Category{ID: "3"}).ForEach(func() error {
+		names = append(names, c.Name)
+		return nil
+	}, &c)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hacksaws", "saws", "tools"}, names)
+}
+
+func TestSubtree(t *testing.T) {
+	db := setupCategoryDB(t)
+	ctx := context.Background()
+
+	stmt, err := Subtree(Category{}, "category", "parent_id")
+	assert.Nil(t, err)
+	assert.Equal(t, `WITH RECURSIVE subtree AS (SELECT * FROM category WHERE id = ? UNION ALL SELECT child.* FROM category AS child JOIN subtree ON child.parent_id = subtree.id) SELECT id, parent_id, name FROM subtree`, stmt.sql)
+
+	var roots []Category
+	err = db.Query(ctx, stmt, Category{ID: "1"}).GetTree("id", "parent_id", "Children", &roots)
+	assert.Nil(t, err)
+	one, two := "1", "2"
+	assert.Equal(t, []Category{{
+		ID:   "1",
+		Name: "tools",
+		Children: []Category{{
+			ID:       "2",
+			ParentID: &one,
+			Name:     "saws",
+			Children: []Category{{ID: "3", ParentID: &two, Name: "hacksaws"}},
+		}, {
+			ID:       "4",
+			ParentID: &one,
+			Name:     "hammers",
+		}},
+	}}, roots)
+
+	// A subtree's root has a parent that is not among the rows.
+	roots = nil
+	err = db.Query(ctx, stmt, Category{ID: "2"}).GetTree("id", "parent_id", "Children", &roots)
+	assert.Nil(t, err)
+	if assert.Len(t, roots, 1) {
+		assert.Equal(t, "saws", roots[0].Name)
+		assert.Len(t, roots[0].Children, 1)
+	}
+
+	// Every row of a table can be assembled into trees.
+	all, err := Prepare("SELECT &Category.* FROM category", Category{})
+	assert.Nil(t, err)
+	roots = nil
+	err = db.Query(ctx, all).GetTree("id", "parent_id", "Children", &roots)
+	assert.Nil(t, err)
+	if assert.Len(t, roots, 2) {
+		assert.Equal(t, "tools", roots[0].Name)
+		assert.Equal(t, "paint", roots[1].Name)
+	}
+}
+
+func TestTreeErrors(t *testing.T) {
+	_, err := Subtree(Membership{}, "membership", "parent_id")
+	assert.EqualError(t, err, `type "Membership" has a primary key of 2 fields, not one`)
+	_, err = Subtree(Category{}, "category; DROP TABLE category", "parent_id")
+	assert.EqualError(t, err, `invalid table name "category; DROP TABLE category"`)
+
+	db := setupCategoryDB(t)
+	stmt, err := Subtree(Category{}, "category", "parent_id")
+	assert.Nil(t, err)
+	query := db.Query(context.Background(), stmt, Category{ID: "1"})
+
+	var roots []Category
+	assert.EqualError(t, query.GetTree("id", "parent", "Children", &roots), `parent "parent" is not an output column of type "Category"`)
+	assert.EqualError(t, query.GetTree("id", "parent_id", "Name", &roots), `type "Category" has no field "Name" of type []sqlair.Category`)
+	assert.EqualError(t, query.GetTree("id", "parent_id", "Children", roots), `expected pointer to slice of structs, got []sqlair.Category`)
+}
@@ -0,0 +1,72 @@
+package sqlair
+
+import (
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+)
+
+// TypeCache holds the reflection information for the types of the objects
+// passed to Prepare, so that each type is reflected only once. By default,
+// statements share a single cache holding at most 1024 types; WithCache
+// prepares them with another, such as one isolated for a test. When a
+// cache holds its maximum number of types, the least recently reflected
+// is evicted to make room for another. A TypeCache is safe for concurrent
+// use.
+type TypeCache struct {
+	cache *sqlairreflect.TypeCache
+}
+
+// TypeCacheStats holds counters describing the use of a TypeCache.
+type TypeCacheStats struct {
+	// Hits is the number of types whose information was held.
+	Hits uint64
+
+	// Misses is the number of types that were reflected.
+	Misses uint64
+
+	// Evictions is the number of types evicted from the cache.
+	Evictions uint64
+
+	// Size is the number of types currently held.
+	Size int
+}
+
+// NewTypeCache returns a reference to a new TypeCache holding at most
+// maxSize types, or an unbounded number if maxSize is zero.
+func NewTypeCache(maxSize int) *TypeCache {
+	return &TypeCache{cache: sqlairreflect.NewCache(maxSize)}
+}
+
+// Stats returns the counters for the cache's use to date.
+func (c *TypeCache) Stats() TypeCacheStats {
+	stats := c.cache.Stats()
+	return TypeCacheStats{
+		Hits:      stats.Hits,
+		Misses:    stats.Misses,
+		Evictions: stats.Evictions,
+		Size:      stats.Size,
+	}
+}
+
+// WithCache returns an option with which the types of the objects passed
+// to Prepare, or PrepareCondition, are reflected using the input cache
+// rather than the cache shared by other statements.
+//
+// Example:
+//
+//     types := sqlair.NewTypeCache(0)
+//     stmt, err := sqlair.Prepare(`SELECT &Person.* FROM person`, sqlair.WithCache(types), Person{})
+//
+func WithCache(c *TypeCache) PrepareOption {
+	return func(o *prepareOptions) {
+		o.cache = c
+	}
+}
+
+// typeCache returns the cache with which the types of the
+// objects passed along with the options are reflected.
+func (o prepareOptions) typeCache() *sqlairreflect.TypeCache {
+	if o.cache == nil {
+		return sqlairreflect.Cache()
+	}
+	return o.cache.cache
+}
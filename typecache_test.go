@@ -0,0 +1,30 @@
+package sqlair
+
+import (
+	"testing"
+
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithCache(t *testing.T) {
+	type Visit struct {
+		ID string `db:"id"`
+	}
+	type Venue struct {
+		ID string `db:"id"`
+	}
+
+	types := NewTypeCache(1)
+	shared := sqlairreflect.Cache().Stats()
+
+	_, err := Prepare("SELECT &Visit.* FROM visit", WithCache(types), Visit{})
+	assert.Nil(t, err)
+	_, err = Prepare("SELECT &Visit.* FROM visit WHERE id = $Visit.id", WithCache(types), Visit{})
+	assert.Nil(t, err)
+	_, err = PrepareCondition("id = $Venue.id", WithCache(types), Venue{})
+	assert.Nil(t, err)
+
+	assert.Equal(t, TypeCacheStats{Hits: 1, Misses: 2, Evictions: 1, Size: 1}, types.Stats())
+	assert.Equal(t, shared, sqlairreflect.Cache().Stats())
+}
@@ -0,0 +1,147 @@
+package sqlair
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// statementUsage counts the executions of a statement registered with a
+// Registry. It is updated atomically, and is shared by the statements
+// derived from the registered one, which are counted as its executions.
+type statementUsage struct {
+	// executions counts the times the statement has been run, and
+	// lastNanos is the Unix time in nanoseconds at which it last was,
+	// or zero if it has not been. They are first in the struct so
+	// that they are aligned on 32-bit platforms.
+	executions uint64
+	lastNanos  int64
+}
+
+// recordUse counts an execution of the statement,
+// if it is registered with a Registry.
+func (s *Statement) recordUse() {
+	if s.usage == nil {
+		return
+	}
+	atomic.AddUint64(&s.usage.executions, 1)
+	atomic.StoreInt64(&s.usage.lastNanos, time.Now().UnixNano())
+}
+
+// StatementUsage reports the use of a statement registered with a Registry.
+type StatementUsage struct {
+	// Name is the name under which the statement is registered.
+	Name string
+
+	// Executions counts the times the statement, or one derived from it,
+	// has been run by a DB, including those for which results were
+	// served from a result cache or the database returned an error.
+	Executions uint64
+
+	// LastExecuted is the time at which the statement was last
+	// run, or the zero time if it has not been.
+	LastExecuted time.Time
+
+	// Deprecated is the reason for which the statement is
+	// deprecated, or "" if it is not; see Registry.Deprecate.
+	Deprecated string
+}
+
+// UsageReport reports the use of the statements registered with a
+// Registry, the most executed first, so that hot paths lead and
+// statements that are never run, and may be dead, trail.
+type UsageReport []StatementUsage
+
+// Usage returns a report of the use of the valid registered statements
+// since they were registered, or since ResetUsage was last called.
+func (r *Registry) Usage() UsageReport {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var report UsageReport
+	for name, entry := range r.entries {
+		if entry.err != nil {
+			continue
+		}
+		usage := StatementUsage{
+			Name:       name,
+			Executions: atomic.LoadUint64(&entry.stmt.usage.executions),
+			Deprecated: entry.deprecated,
+		}
+		if nanos := atomic.LoadInt64(&entry.stmt.usage.lastNanos); nanos != 0 {
+			usage.LastExecuted = time.Unix(0, nanos)
+		}
+		report = append(report, usage)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Executions != report[j].Executions {
+			return report[i].Executions > report[j].Executions
+		}
+		return report[i].Name < report[j].Name
+	})
+	return report
+}
+
+// ResetUsage sets the counts of the executions
+// of the registered statements to zero.
+func (r *Registry) ResetUsage() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, entry := range r.entries {
+		if entry.err == nil {
+			atomic.StoreUint64(&entry.stmt.usage.executions, 0)
+			atomic.StoreInt64(&entry.stmt.usage.lastNanos, 0)
+		}
+	}
+}
+
+// Deprecate marks the statement registered with the input name as
+// deprecated for the input reason, such as the statement replacing it,
+// so that the usage report shows whether it is still run. An error is
+// returned if no statement is registered with the name.
+func (r *Registry) Deprecate(name, reason string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry, ok := r.entries[name]
+	if !ok {
+		return errors.Errorf("no statement registered with name %q", name)
+	}
+	entry.deprecated = reason
+	return nil
+}
+
+// Unused returns the names of the statements in the
+// report that have not been executed, in name order.
+func (r UsageReport) Unused() []string {
+	var names []string
+	for _, u := range r {
+		if u.Executions == 0 {
+			names = append(names, u.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String returns a line for each statement in the report,
+// giving its executions and whether it is deprecated.
+func (r UsageReport) String() string {
+	var b strings.Builder
+	for _, u := range r {
+		fmt.Fprintf(&b, "%s: %d executions", u.Name, u.Executions)
+		if !u.LastExecuted.IsZero() {
+			fmt.Fprintf(&b, ", last at %s", u.LastExecuted.Format(time.RFC3339))
+		}
+		if u.Deprecated != "" {
+			fmt.Fprintf(&b, " (deprecated: %s)", u.Deprecated)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package sqlair
+
+import (
+	"context"
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryUsage(t *testing.T) {
+	db := NewDB(setupPersonDB(t))
+	ctx := context.Background()
+	r := NewRegistry()
+
+	get := r.Register("get", "SELECT &Person.* FROM person WHERE id = $Person.id", sqlairtesting.Person{})
+	all := r.Register("all", "SELECT &Person.* FROM person", sqlairtesting.Person{})
+	r.Register("old", "SELECT &Person.* FROM person ORDER BY name", sqlairtesting.Person{})
+	r.Register("invalid", "SELECT &Person.* FROM person")
+	assert.Nil(t, r.Deprecate("old", `use "all"`))
+	assert.EqualError(t, r.Deprecate("unknown", ""), `no statement registered with name "unknown"`)
+
+	var p sqlairtesting.Person
+	for i := 0; i < 2; i++ {
+		err := db.Query(ctx, get, sqlairtesting.Person{ID: "1"}).Get(&p)
+		assert.Nil(t, err)
+	}
+	var people []sqlairtesting.Person
+	err := db.Query(ctx, all).GetAll(&people)
+	assert.Nil(t, err)
+
+	// Executions of derived statements are those of the registered one.
+	limited, err := all.Derive(WithLimit("LIMIT 1"))
+	assert.Nil(t, err)
+	rows, err := limited.QueryRows(ctx, db)
+	assert.Nil(t, err)
+	assert.Nil(t, rows.Close())
+
+	report := r.Usage()
+	if assert.Len(t, report, 3) {
+		assert.Equal(t, "all", report[0].Name)
+		assert.Equal(t, uint64(2), report[0].Executions)
+		assert.Equal(t, "get", report[1].Name)
+		assert.Equal(t, uint64(2), report[1].Executions)
+		assert.False(t, report[1].LastExecuted.IsZero())
+		assert.Equal(t, StatementUsage{Name: "old", Deprecated: `use "all"`}, report[2])
+	}
+	assert.Equal(t, []string{"old"}, report.Unused())
+	assert.Contains(t, report.String(), "old: 0 executions (deprecated: use \"all\")\n")
+
+	r.ResetUsage()
+	assert.Equal(t, []string{"all", "get", "old"}, r.Usage().Unused())
+}
@@ -0,0 +1,46 @@
+package sqlair
+
+import "reflect"
+
+// Validator is implemented by input types that check their own values.
+// Before a statement that modifies data, such as an INSERT or UPDATE,
+// is executed, the Validate method of each input supplying its
+// parameters is called. If it returns an error, the statement is not
+// executed, and an ErrInvalidInput wrapping the error is returned.
+//
+// Example:
+//
+//     func (p Person) Validate() error {
+//         if p.Name == "" {
+//             return errors.New("name is required")
+//         }
+//         return nil
+//     }
+//
+type Validator interface {
+	Validate() error
+}
+
+// validatorType is the reflected type of Validator.
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// validate calls the Validate method of the input struct value if its
+// type, or a pointer to it, implements Validator.
+func validate(v reflect.Value) error {
+	if !reflect.PtrTo(v.Type()).Implements(validatorType) {
+		return nil
+	}
+	return addressable(v).Addr().Interface().(Validator).Validate()
+}
+
+// addressable returns the input value if it is addressable, or else an
+// addressable copy of it, so that methods with pointer receivers can be
+// called on it.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Elem()
+}
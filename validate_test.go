@@ -0,0 +1,86 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type Member struct {
+	ID   string `db:"id"`
+	Name string `db:"name"`
+}
+
+func (m Member) Validate() error {
+	if m.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type Guest struct {
+	ID   string `db:"id"`
+	Name string `db:"name"`
+}
+
+func (g *Guest) Validate() error {
+	if g.ID == "" {
+		return errors.New("id is required")
+	}
+	return nil
+}
+
+func TestValidateInputsForDML(t *testing.T) {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE member (id TEXT, name TEXT)")
+		return err
+	})
+	sqlairDB := NewDB(db)
+	ctx := context.Background()
+
+	insert, err := Prepare("INSERT INTO member (id, name) VALUES ($Member.id, $Member.name)", Member{})
+	assert.Nil(t, err)
+
+	_, err = sqlairDB.Exec(ctx, insert, Member{ID: "1"})
+	assert.EqualError(t, err, `input "Member" is invalid: name is required`)
+	assert.Equal(t, "name is required", errors.Cause(err).Error())
+	var invalid *ErrInvalidInput
+	assert.ErrorAs(t, err, &invalid)
+
+	_, err = sqlairDB.Exec(ctx, insert, Member{ID: "1", Name: "Lorn"})
+	assert.Nil(t, err)
+
+	// Validate methods with pointer receivers are called
+	// for inputs supplied by value or by reference.
+	insert, err = Prepare("INSERT INTO member (id, name) VALUES ($Guest.id, $Guest.name)", Guest{})
+	assert.Nil(t, err)
+
+	_, err = sqlairDB.Exec(ctx, insert, Guest{Name: "Onos"})
+	assert.EqualError(t, err, `input "Guest" is invalid: id is required`)
+	_, err = sqlairDB.Exec(ctx, insert, &Guest{Name: "Onos"})
+	assert.EqualError(t, err, `input "Guest" is invalid: id is required`)
+
+	var count int
+	assert.Nil(t, db.QueryRow("SELECT count(*) FROM member").Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestValidateNotCalledForQueries(t *testing.T) {
+	db := setupDB(t)
+	runTx(t, db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE member (id TEXT, name TEXT)")
+		return err
+	})
+
+	stmt, err := Prepare("SELECT &Member.* FROM member WHERE name = $Member.name", Member{})
+	assert.Nil(t, err)
+
+	var members []Member
+	err = NewDB(db).Query(context.Background(), stmt, Member{}).GetAll(&members)
+	assert.Nil(t, err)
+	assert.Empty(t, members)
+}
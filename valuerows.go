@@ -0,0 +1,398 @@
+package sqlair
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// valueDB is a database without any storage, the only query of which
+// returns the rows of values supplied as its parameter; see valueRows.
+// Rows of values already read from a database, such as cached results,
+// are returned from it, so that they are scanned by sql.Rows.Scan exactly
+// as they would be if they were scanned from the database.
+var valueDB = sql.OpenDB(valueConnector{})
+
+// valueRows returns rows with the input columns, the values of which are
+// read by the input function as if from a driver. The function returns
+// io.EOF when there are no more rows. The input close function, if not
+// nil, is called when the rows are closed.
+func valueRows(columns []string, next func(dest []driver.Value) error, close func() error) (*sql.Rows, error) {
+	source := &valueSource{columns: columns, next: next, close: close}
+	return valueDB.QueryContext(context.Background(), "", source)
+}
+
+// sliceRows returns rows with the input columns and values.
+// Each row's values are copied as they are read, so that
+// the input values are not modified through the rows.
+func sliceRows(columns []string, rows [][]any) (*sql.Rows, error) {
+	return valueRows(columns, func(dest []driver.Value) error {
+		if len(rows) == 0 {
+			return io.EOF
+		}
+		for i, value := range rows[0] {
+			dest[i] = copyValue(value)
+		}
+		rows = rows[1:]
+		return nil
+	}, nil)
+}
+
+// assignValue assigns the input value, as returned by a driver or decoded
+// by a transformer, to the input destination pointer, converting it by
+// the rules with which sql.Rows.Scan converts a column's value.
+func assignValue(dest, value any) error {
+	switch v := value.(type) {
+	case string:
+		switch d := dest.(type) {
+		case *string:
+			*d = v
+			return nil
+		case *[]byte:
+			*d = []byte(v)
+			return nil
+		}
+	case []byte:
+		switch d := dest.(type) {
+		case *string:
+			*d = string(v)
+			return nil
+		case *any:
+			*d = cloneBytes(v)
+			return nil
+		case *[]byte:
+			*d = cloneBytes(v)
+			return nil
+		}
+	case time.Time:
+		switch d := dest.(type) {
+		case *time.Time:
+			*d = v
+			return nil
+		case *string:
+			*d = v.Format(time.RFC3339Nano)
+			return nil
+		case *[]byte:
+			*d = v.AppendFormat(nil, time.RFC3339Nano)
+			return nil
+		}
+	case nil:
+		switch d := dest.(type) {
+		case *any:
+			*d = nil
+			return nil
+		case *[]byte:
+			*d = nil
+			return nil
+		}
+	}
+
+	switch d := dest.(type) {
+	case *string:
+		if s, ok := formatValue(value); ok {
+			*d = s
+			return nil
+		}
+	case *[]byte:
+		if s, ok := formatValue(value); ok {
+			*d = []byte(s)
+			return nil
+		}
+	case *bool:
+		b, err := driver.Bool.ConvertValue(value)
+		if err == nil {
+			*d = b.(bool)
+		}
+		return err
+	case *any:
+		*d = value
+		return nil
+	case sql.Scanner:
+		return d.Scan(value)
+	}
+
+	dp := reflect.ValueOf(dest)
+	if dp.Kind() != reflect.Ptr {
+		return errors.New("destination not a pointer")
+	}
+	if dp.IsNil() {
+		return errors.New("destination pointer is nil")
+	}
+	dv := dp.Elem()
+
+	sv := reflect.ValueOf(value)
+	if sv.IsValid() && sv.Type().AssignableTo(dv.Type()) {
+		if b, ok := value.([]byte); ok {
+			dv.Set(reflect.ValueOf(cloneBytes(b)))
+		} else {
+			dv.Set(sv)
+		}
+		return nil
+	}
+	if sv.IsValid() && dv.Kind() == sv.Kind() && sv.Type().ConvertibleTo(dv.Type()) {
+		dv.Set(sv.Convert(dv.Type()))
+		return nil
+	}
+
+	switch dv.Kind() {
+	case reflect.Ptr:
+		if value == nil {
+			dv.Set(reflect.Zero(dv.Type()))
+			return nil
+		}
+		elem := reflect.New(dv.Type().Elem())
+		if err := assignValue(elem.Interface(), value); err != nil {
+			return err
+		}
+		dv.Set(elem)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == nil {
+			break
+		}
+		s := valueString(sv)
+		i, err := strconv.ParseInt(s, 10, dv.Type().Bits())
+		if err != nil {
+			return conversionError(value, s, dv, err)
+		}
+		dv.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == nil {
+			break
+		}
+		s := valueString(sv)
+		u, err := strconv.ParseUint(s, 10, dv.Type().Bits())
+		if err != nil {
+			return conversionError(value, s, dv, err)
+		}
+		dv.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		if value == nil {
+			break
+		}
+		s := valueString(sv)
+		f, err := strconv.ParseFloat(s, dv.Type().Bits())
+		if err != nil {
+			return conversionError(value, s, dv, err)
+		}
+		dv.SetFloat(f)
+		return nil
+	case reflect.String:
+		switch v := value.(type) {
+		case string:
+			dv.SetString(v)
+			return nil
+		case []byte:
+			dv.SetString(string(v))
+			return nil
+		}
+	}
+
+	if value == nil {
+		return errors.Errorf("converting NULL to %s is unsupported", dv.Kind())
+	}
+	return errors.Errorf("unsupported Scan, storing driver.Value type %T into type %T", value, dest)
+}
+
+// formatValue returns the input value, if it is a number or a bool,
+// formatted as sql.Rows.Scan formats it for a string destination.
+func formatValue(value any) (string, bool) {
+	sv := reflect.ValueOf(value)
+	switch sv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Bool:
+		return valueString(sv), true
+	}
+	return "", false
+}
+
+// valueString returns the input value formatted as a string, from which
+// sql.Rows.Scan parses numbers for numeric destinations.
+func valueString(sv reflect.Value) string {
+	switch sv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(sv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(sv.Uint(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(sv.Float(), 'g', -1, 64)
+	case reflect.Float32:
+		return strconv.FormatFloat(sv.Float(), 'g', -1, 32)
+	case reflect.Bool:
+		return strconv.FormatBool(sv.Bool())
+	}
+	switch v := sv.Interface().(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprintf("%v", sv.Interface())
+}
+
+// conversionError returns the error for the failure to parse the input
+// value, formatted as the input string, for the input destination.
+func conversionError(value any, s string, dv reflect.Value, err error) error {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		err = numErr.Err
+	}
+	return errors.Errorf("converting driver.Value type %T (%q) to a %s: %v", value, s, dv.Kind(), err)
+}
+
+// cloneBytes returns a copy of the input bytes, or nil if they are nil.
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
+
+// copyValue returns a copy of the input value that shares no memory
+// with it, so that values held by Sqlair, such as cached results, are
+// not modified through the destinations to which they are assigned.
+func copyValue(value any) any {
+	if value == nil {
+		return nil
+	}
+	return copyReflected(reflect.ValueOf(value)).Interface()
+}
+
+// copyReflected returns a copy of the input value, copying the
+// slices, maps and pointers that it holds, rather than sharing them.
+func copyReflected(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			reflect.Copy(c, v)
+			return c
+		}
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyReflected(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			c.SetMapIndex(iter.Key(), copyReflected(iter.Value()))
+		}
+		return c
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(copyReflected(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(copyReflected(v.Elem()))
+		return c
+	}
+	return v
+}
+
+// valueConnector opens connections to valueDB.
+type valueConnector struct{}
+
+// Connect implements driver.Connector.
+func (valueConnector) Connect(context.Context) (driver.Conn, error) {
+	return valueConn{}, nil
+}
+
+// Driver implements driver.Connector.
+func (valueConnector) Driver() driver.Driver {
+	return valueDriver{}
+}
+
+// valueDriver is the driver of valueDB.
+type valueDriver struct{}
+
+// Open implements driver.Driver.
+func (valueDriver) Open(string) (driver.Conn, error) {
+	return valueConn{}, nil
+}
+
+// valueConn is a connection to valueDB. Its queries return the
+// valueSource that is their only parameter, which is accepted
+// as it is, rather than being converted to a driver.Value.
+type valueConn struct{}
+
+// Prepare implements driver.Conn.
+func (valueConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("statements can not be prepared for values")
+}
+
+// Close implements driver.Conn.
+func (valueConn) Close() error {
+	return nil
+}
+
+// Begin implements driver.Conn.
+func (valueConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions can not be begun for values")
+}
+
+// QueryContext implements driver.QueryerContext.
+func (valueConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) != 1 {
+		return nil, errors.Errorf("expected 1 source of values, got %d", len(args))
+	}
+	source, ok := args[0].Value.(*valueSource)
+	if !ok {
+		return nil, errors.Errorf("expected source of values, got %T", args[0].Value)
+	}
+	return source, nil
+}
+
+// CheckNamedValue implements driver.NamedValueChecker,
+// accepting every parameter as it is.
+func (valueConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+// valueSource is the rows of values returned by a valueDB query.
+type valueSource struct {
+	columns []string
+	next    func(dest []driver.Value) error
+	close   func() error
+}
+
+// Columns implements driver.Rows.
+func (s *valueSource) Columns() []string {
+	return s.columns
+}
+
+// Close implements driver.Rows.
+func (s *valueSource) Close() error {
+	if s.close == nil {
+		return nil
+	}
+	return s.close()
+}
+
+// Next implements driver.Rows.
+func (s *valueSource) Next(dest []driver.Value) error {
+	return s.next(dest)
+}
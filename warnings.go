@@ -0,0 +1,134 @@
+package sqlair
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/canonical/sqlair/internal/parse"
+	sqlairreflect "github.com/canonical/sqlair/internal/reflect"
+)
+
+// Warnings returns a description of each part of the statement that is
+// accepted by Prepare but may not be intended. Unlike errors, warnings do
+// not prevent the statement from being run; they can be reported, such
+// as by tests run in CI, without failing.
+//
+// Example:
+//
+//     stmt := sqlair.MustPrepare("SELECT *, &Person.* FROM person", Person{})
+//     for _, w := range stmt.Warnings() {
+//         log.Printf("warning: %s", w)
+//     }
+//
+func (s *Statement) Warnings() []string {
+	var warnings []string
+	for _, t := range s.templates {
+		warnings = append(warnings, fmt.Sprintf("identifier template %q must be substituted before execution", t.name))
+	}
+	for _, l := range s.lists {
+		if l.in == "" {
+			warnings = append(warnings, listComparisonError(l).Error())
+		}
+	}
+	warnings = append(warnings, wildcardWarnings(s.expression.Expressions())...)
+	warnings = append(warnings, s.undecodedWarnings()...)
+	warnings = append(warnings, s.groupByWarnings(s.expression.Expressions())...)
+	warnings = append(warnings, s.concatenationWarnings()...)
+
+	for _, name := range s.unused {
+		warnings = append(warnings, fmt.Sprintf("type %q is not used by the statement", name))
+	}
+	if s.lenient {
+		warnings = append(warnings, "objects of types not used by the statement are ignored, rather than being an error")
+	}
+	return warnings
+}
+
+// wildcardWarnings returns a warning for each wildcard column, such as "*"
+// or "p.*", selected alongside output targets by the query with the input
+// top-level expressions. The columns of the wildcard are only known to the
+// database, so it can not be verified that they are distinct from those
+// of the output targets; a column of the same name selected by the wildcard
+// may be decoded in place of the target's.
+func wildcardWarnings(exps []parse.Expression) []string {
+	var wildcards []string
+	hasTarget := false
+	for i, exp := range exps {
+		switch exp := exp.(type) {
+		case *parse.CompoundExpression:
+			var warnings []string
+			for _, branch := range exp.Expressions() {
+				warnings = append(warnings, wildcardWarnings(branch.Expressions())...)
+			}
+			return warnings
+		case *parse.OutputTargetExpression:
+			hasTarget = true
+			continue
+		}
+
+		if !isWildcard(exps[i]) {
+			continue
+		}
+		// A wildcard decoded into an output target is expanded.
+		if i+1 < len(exps) && isKeywordIdentity(exps[i+1], "AS") {
+			continue
+		}
+		wildcards = append(wildcards, exps[i].String())
+	}
+	if !hasTarget {
+		return nil
+	}
+
+	warnings := make([]string, len(wildcards))
+	for i, w := range wildcards {
+		warnings[i] = fmt.Sprintf("columns selected by %q may not be distinct from those of output targets", w)
+	}
+	return warnings
+}
+
+// isWildcard returns true if the input expression
+// is a wildcard column, such as "*" or "p.*".
+func isWildcard(exp parse.Expression) bool {
+	switch exp := exp.(type) {
+	case *parse.IdentityExpression:
+		return exp.String() == "*"
+	case *parse.QualifiedIdentityExpression:
+		return exp.Name().String() == "*"
+	}
+	return false
+}
+
+// undecodedWarnings returns a warning for each tagged field of an output
+// type that no result column is decoded into. Such fields are left
+// unchanged by Decode, which is easily overlooked when a field is added
+// to a type used with an explicit column list.
+func (s *Statement) undecodedWarnings() []string {
+	decoded := make(map[string]map[string]bool)
+	var outputTypes []string
+	for _, out := range s.outputs {
+		if decoded[out.typeName] == nil {
+			decoded[out.typeName] = make(map[string]bool)
+			outputTypes = append(outputTypes, out.typeName)
+		}
+		decoded[out.typeName][out.field.Name] = true
+	}
+
+	var warnings []string
+	for _, name := range outputTypes {
+		info, ok := s.argTypes[name].(sqlairreflect.Struct)
+		if !ok {
+			continue
+		}
+		var unused []string
+		for tag, field := range info.Fields {
+			if !decoded[name][field.Name] {
+				unused = append(unused, tag)
+			}
+		}
+		sort.Strings(unused)
+		for _, tag := range unused {
+			warnings = append(warnings, fmt.Sprintf("column %q of output type %q is not decoded", tag, name))
+		}
+	}
+	return warnings
+}
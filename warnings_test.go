@@ -0,0 +1,43 @@
+package sqlair
+
+import (
+	"testing"
+
+	sqlairtesting "github.com/canonical/sqlair/internal/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		stmt     string
+		warnings []string
+	}{
+		{"SELECT &Person.* FROM person WHERE id = $Person.id", nil},
+		{"SELECT * FROM person WHERE id = $Person.id", nil},
+		{"SELECT * AS &Person.* FROM person", nil},
+		{"SELECT p.* AS &Person.*, count(*) FROM person AS p", nil},
+		{"SELECT *, &Person.* FROM person", []string{
+			`columns selected by "*" may not be distinct from those of output targets`,
+		}},
+		{"SELECT m.*, p.* AS &Person.* FROM person AS p JOIN manager AS m", []string{
+			`columns selected by "m.*" may not be distinct from those of output targets`,
+		}},
+		{"SELECT &Person.* FROM person UNION SELECT p.*, &Person.* FROM person AS p", []string{
+			`columns selected by "p.*" may not be distinct from those of output targets`,
+		}},
+		{"SELECT name AS &Person.name FROM person", []string{
+			`column "id" of output type "Person" is not decoded`,
+		}},
+	}
+
+	for _, test := range tests {
+		stmt, err := Prepare(test.stmt, sqlairtesting.Person{})
+		if assert.Nil(t, err, test.stmt) {
+			assert.Equal(t, test.warnings, stmt.Warnings(), test.stmt)
+		}
+	}
+
+	stmt, err := Prepare("SELECT &Person.* FROM person WHERE id < ALL($IDList.ids)", sqlairtesting.Person{}, IDList{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`comparison "< ALL" of a slice requires a dialect with native arrays`}, stmt.Warnings())
+}